@@ -1,14 +1,30 @@
 package app
 
 import (
+	"auth-service/internal/audit"
 	"auth-service/internal/config"
+	grpcdelivery "auth-service/internal/delivery/grpc"
 	"auth-service/internal/delivery/router"
+	"auth-service/internal/limiter"
+	"auth-service/internal/password"
 	"auth-service/internal/repository"
 	"auth-service/internal/usecase"
 	"auth-service/pkg/database"
 	"auth-service/pkg/jwt"
+	"context"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"backend/pkg/authpb"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
 )
 
 type AuthApp struct {
@@ -16,6 +32,7 @@ type AuthApp struct {
 	Repository   *repository.Repository
 	UseCase      *usecase.AuthUseCase
 	tokenManager jwt.TokenManager
+	auditLog     *audit.Logger
 }
 
 func NewAuthApp(cfg *config.Config) *AuthApp {
@@ -38,11 +55,30 @@ func NewAuthApp(cfg *config.Config) *AuthApp {
 		log.Fatalf("Failed to create token manager: %v", err)
 	}
 
+	loginGuard, err := newLoginGuard(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create login limiter: %v", err)
+	}
+
+	passwords, err := newPasswordChecker(cfg)
+	if err != nil {
+		log.Fatalf("Failed to create password policy: %v", err)
+	}
+
+	// Журнал аудита пишется в БД в фоне
+	auditLog := audit.NewLogger(repos.AuditRepository, cfg.Audit.BufferSize)
+
 	// Инициализация use case с конфигурацией
 	authUC := usecase.NewAuthUseCase(
 		repos.UserRepository,
 		repos.SessionRepository,
+		repos.RoleRepository,
+		repos.AuditRepository,
+		repos.PasswordHistory,
 		tokenManager,
+		loginGuard,
+		auditLog,
+		passwords,
 		&usecase.Config{
 			AccessTokenTTL:  cfg.JWT.AccessTokenTTL,
 			RefreshTokenTTL: cfg.JWT.RefreshTokenTTL,
@@ -54,13 +90,149 @@ func NewAuthApp(cfg *config.Config) *AuthApp {
 		Repository:   repos,
 		UseCase:      authUC,
 		tokenManager: tokenManager,
+		auditLog:     auditLog,
+	}
+}
+
+// newPasswordChecker создает проверку паролей по политике и, если задан каталог, по утечкам
+func newPasswordChecker(cfg *config.Config) (*password.Checker, error) {
+	policy := password.Policy{
+		MinLength:     cfg.Password.MinLength,
+		RequireUpper:  cfg.Password.RequireUpper,
+		RequireLower:  cfg.Password.RequireLower,
+		RequireDigit:  cfg.Password.RequireDigit,
+		RequireSymbol: cfg.Password.RequireSymbol,
+		History:       cfg.Password.History,
+		BcryptCost:    cfg.Password.BcryptCost,
+	}
+
+	if cfg.Password.BreachedDir == "" {
+		return password.NewChecker(policy, nil, 1), nil
+	}
+
+	breached, err := password.Open(cfg.Password.BreachedDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+
+	return password.NewChecker(policy, breached, cfg.Password.BreachedMinCount), nil
+}
+
+// newLoginGuard создает лимитеры попыток входа по аккаунту и по IP
+func newLoginGuard(cfg *config.Config) (*limiter.Guard, error) {
+	limits := cfg.LoginLimit
+	accountPolicy := limiter.Policy{
+		FreeAttempts:     limits.AccountAttempts,
+		BaseDelay:        limits.BaseDelay,
+		MaxDelay:         limits.MaxDelay,
+		LockoutThreshold: limits.AccountLockout,
+		LockoutDuration:  limits.LockoutDuration,
+		Window:           limits.FailureWindow,
+	}
+	ipPolicy := limiter.Policy{
+		FreeAttempts:     limits.IPAttempts,
+		BaseDelay:        limits.BaseDelay,
+		MaxDelay:         limits.MaxDelay,
+		LockoutThreshold: limits.IPLockout,
+		LockoutDuration:  limits.LockoutDuration,
+		Window:           limits.FailureWindow,
+	}
+
+	switch limits.Backend {
+	case "memory":
+		return limiter.NewGuard(
+			limiter.NewMemoryLimiter(accountPolicy),
+			limiter.NewMemoryLimiter(ipPolicy),
+		), nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.Ping(ctx).Err(); err != nil {
+			return nil, fmt.Errorf("failed to connect to redis: %w", err)
+		}
+
+		return limiter.NewGuard(
+			limiter.NewRedisLimiter(client, "auth:login:", accountPolicy),
+			limiter.NewRedisLimiter(client, "auth:login:", ipPolicy),
+		), nil
+	default:
+		return nil, fmt.Errorf("unknown login limiter backend: %s", limits.Backend)
 	}
 }
 
+// Run запускает HTTP и gRPC серверы и ждет SIGINT или SIGTERM. При остановке серверы
+// дожидаются текущих запросов, после чего в журнал дописываются события из очереди
 func (a *AuthApp) Run() error {
+	// Выполняется последним, когда запросы уже не пишут события аудита
+	defer a.auditLog.Close()
+
+	// gRPC API нужен другим сервисам для проверки токенов и списка отозванных
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%s", a.Config.Server.GRPCPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen gRPC port: %w", err)
+	}
+	grpcServer := grpc.NewServer()
+	authpb.RegisterAuthServiceServer(grpcServer, grpcdelivery.NewServer(a.UseCase))
+
 	// Создаем роутер
-	router := router.NewRouter(a.UseCase, a.tokenManager)
+	router := router.NewRouter(a.UseCase, a.tokenManager, a.Config.Server.TrustedProxies)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%s", a.Config.Server.HTTPPort),
+		Handler: router.Init(),
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	serveErrors := make(chan error, 2)
+	go func() {
+		log.Printf("Starting gRPC server on port %s", a.Config.Server.GRPCPort)
+		if err := grpcServer.Serve(listener); err != nil {
+			serveErrors <- fmt.Errorf("gRPC server failed: %w", err)
+		}
+	}()
+	go func() {
+		log.Printf("Starting HTTP server on port %s", a.Config.Server.HTTPPort)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serveErrors <- fmt.Errorf("HTTP server failed: %w", err)
+		}
+	}()
+
+	var runErr error
+	select {
+	case sig := <-quit:
+		log.Printf("Received %s, shutting down", sig)
+	case runErr = <-serveErrors:
+		log.Printf("Shutting down: %v", runErr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.Config.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server forced to shutdown: %v", err)
+	}
+
+	// GracefulStop ждет текущих вызовов, по таймауту соединения закрываются принудительно
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		log.Printf("gRPC server forced to shutdown: %v", ctx.Err())
+		grpcServer.Stop()
+	}
 
-	// Запускаем сервер
-	return router.Run(fmt.Sprintf(":%s", a.Config.Server.HTTPPort))
+	log.Printf("Server exited")
+	return runErr
 }
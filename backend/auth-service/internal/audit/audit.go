@@ -0,0 +1,168 @@
+// Package audit асинхронно записывает события безопасности в журнал аудита.
+package audit
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBufferSize    = 1024
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	writeTimeout         = 5 * time.Second
+)
+
+// Store хранилище журнала аудита
+type Store interface {
+	Insert(ctx context.Context, events []*entity.AuditEvent) error
+}
+
+// Actor пользователь и клиент, от имени которых выполняется запрос
+type Actor struct {
+	ID        *int
+	Name      string
+	IP        string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor сохраняет в контексте данные о том, кто выполняет запрос
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext возвращает данные, сохраненные WithActor
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// Logger ставит события в очередь и записывает их пачками в фоне,
+// поэтому запись в журнал не задерживает обработку запросов
+type Logger struct {
+	store         Store
+	events        chan *entity.AuditEvent
+	batchSize     int
+	flushInterval time.Duration
+	now           func() time.Time
+
+	done    chan struct{}
+	closing sync.Once
+	dropped int64
+	mutex   sync.Mutex
+}
+
+// NewLogger создает журнал и запускает фоновую запись
+func NewLogger(store Store, bufferSize int) *Logger {
+	l := newLogger(store, bufferSize, defaultBatchSize)
+	go l.run()
+	return l
+}
+
+func newLogger(store Store, bufferSize, batchSize int) *Logger {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+
+	return &Logger{
+		store:         store,
+		events:        make(chan *entity.AuditEvent, bufferSize),
+		batchSize:     batchSize,
+		flushInterval: defaultFlushInterval,
+		now:           time.Now,
+		done:          make(chan struct{}),
+	}
+}
+
+// Record добавляет событие в очередь. Недостающие актор, IP и User-Agent берутся из контекста.
+// Если очередь переполнена, событие пишется в лог и отбрасывается.
+func (l *Logger) Record(ctx context.Context, event entity.AuditEvent) {
+	if actor, ok := ActorFromContext(ctx); ok {
+		if event.ActorID == nil && event.Actor == "" {
+			event.ActorID = actor.ID
+			event.Actor = actor.Name
+		}
+		if event.IP == "" {
+			event.IP = actor.IP
+		}
+		if event.UserAgent == "" {
+			event.UserAgent = actor.UserAgent
+		}
+	}
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = l.now()
+	}
+
+	select {
+	case l.events <- &event:
+	default:
+		l.mutex.Lock()
+		l.dropped++
+		l.mutex.Unlock()
+		log.Printf("AUDIT queue full, dropped action=%s actor=%q target=%q ip=%q metadata=%v",
+			event.Action, event.Actor, event.Target, event.IP, event.Metadata)
+	}
+}
+
+// Dropped возвращает число событий, не попавших в очередь
+func (l *Logger) Dropped() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.dropped
+}
+
+// Close записывает оставшиеся события и останавливает фоновую запись
+func (l *Logger) Close() {
+	l.closing.Do(func() {
+		close(l.events)
+	})
+	<-l.done
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*entity.AuditEvent, 0, l.batchSize)
+	for {
+		select {
+		case event, ok := <-l.events:
+			if !ok {
+				l.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= l.batchSize {
+				l.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			l.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (l *Logger) flush(batch []*entity.AuditEvent) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	if err := l.store.Insert(ctx, batch); err != nil {
+		// Не теряем события бесследно: при недоступности БД они остаются в логе
+		for _, event := range batch {
+			log.Printf("AUDIT write failed (%v): action=%s actor=%q target=%q ip=%q metadata=%v",
+				err, event.Action, event.Actor, event.Target, event.IP, event.Metadata)
+		}
+	}
+}
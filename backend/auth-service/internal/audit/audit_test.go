@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryStore struct {
+	mutex   sync.Mutex
+	batches [][]*entity.AuditEvent
+	block   chan struct{}
+}
+
+func (s *memoryStore) Insert(ctx context.Context, events []*entity.AuditEvent) error {
+	if s.block != nil {
+		<-s.block
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batches = append(s.batches, append([]*entity.AuditEvent(nil), events...))
+	return nil
+}
+
+func (s *memoryStore) events() []*entity.AuditEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var events []*entity.AuditEvent
+	for _, batch := range s.batches {
+		events = append(events, batch...)
+	}
+	return events
+}
+
+func TestLogger_RecordAndClose(t *testing.T) {
+	store := &memoryStore{}
+	logger := NewLogger(store, 10)
+
+	logger.Record(context.Background(), entity.AuditEvent{Action: entity.AuditSignIn, Target: "user:1"})
+	logger.Record(context.Background(), entity.AuditEvent{Action: entity.AuditLogout, Target: "user:1"})
+	logger.Close()
+
+	events := store.events()
+	require.Len(t, events, 2)
+	assert.Equal(t, entity.AuditSignIn, events[0].Action)
+	assert.Equal(t, entity.AuditLogout, events[1].Action)
+	assert.False(t, events[0].CreatedAt.IsZero())
+}
+
+func TestLogger_ActorFromContext(t *testing.T) {
+	store := &memoryStore{}
+	logger := NewLogger(store, 10)
+
+	id := 7
+	ctx := WithActor(context.Background(), Actor{ID: &id, Name: "admin", IP: "10.0.0.1", UserAgent: "curl"})
+
+	logger.Record(ctx, entity.AuditEvent{Action: entity.AuditUserDelete, Target: "user:3"})
+	logger.Record(ctx, entity.AuditEvent{Action: entity.AuditSignIn, Actor: "other", IP: "10.0.0.2"})
+	logger.Close()
+
+	events := store.events()
+	require.Len(t, events, 2)
+
+	require.NotNil(t, events[0].ActorID)
+	assert.Equal(t, 7, *events[0].ActorID)
+	assert.Equal(t, "admin", events[0].Actor)
+	assert.Equal(t, "10.0.0.1", events[0].IP)
+	assert.Equal(t, "curl", events[0].UserAgent)
+
+	// Явно заданные поля не перезаписываются
+	assert.Nil(t, events[1].ActorID)
+	assert.Equal(t, "other", events[1].Actor)
+	assert.Equal(t, "10.0.0.2", events[1].IP)
+}
+
+func TestLogger_RecordDoesNotBlock(t *testing.T) {
+	store := &memoryStore{block: make(chan struct{})}
+	logger := newLogger(store, 1, 1)
+	go logger.run()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			logger.Record(context.Background(), entity.AuditEvent{Action: entity.AuditSignInFailed})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked while the store is unavailable")
+	}
+
+	assert.Greater(t, logger.Dropped(), int64(0))
+
+	close(store.block)
+	logger.Close()
+}
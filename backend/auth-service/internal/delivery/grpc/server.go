@@ -0,0 +1,80 @@
+package grpc
+
+import (
+	"auth-service/internal/usecase"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	pb "backend/pkg/authpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server gRPC API проверки токенов для других сервисов
+type Server struct {
+	pb.UnimplementedAuthServiceServer
+	useCase *usecase.AuthUseCase
+}
+
+func NewServer(useCase *usecase.AuthUseCase) *Server {
+	return &Server{useCase: useCase}
+}
+
+// ValidateToken проверяет токен с учетом завершенных сессий. Недействительный токен —
+// ответ с is_valid=false, ошибка означает, что проверить токен не удалось.
+func (s *Server) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
+	if req.GetToken() == "" {
+		return &pb.ValidateTokenResponse{}, nil
+	}
+
+	user, expiresAt, err := s.useCase.ValidateToken(ctx, req.GetToken())
+	if errors.Is(err, usecase.ErrInvalidToken) {
+		return &pb.ValidateTokenResponse{}, nil
+	}
+	if err != nil {
+		log.Printf("failed to validate token: %v", err)
+		return nil, status.Error(codes.Unavailable, "failed to validate token")
+	}
+
+	return &pb.ValidateTokenResponse{
+		IsValid:     true,
+		UserId:      int64(user.ID),
+		Username:    user.Username,
+		Roles:       user.Roles,
+		Permissions: user.Permissions,
+		ExpiresAt:   timestamppb.New(expiresAt),
+	}, nil
+}
+
+// ListRevocations возвращает пользователей, чьи токены отозваны после since
+func (s *Server) ListRevocations(ctx context.Context, req *pb.ListRevocationsRequest) (*pb.ListRevocationsResponse, error) {
+	// Время ответа берется до запроса, чтобы отзывы, сделанные во время него, пришли в следующий раз
+	now := time.Now()
+
+	var since time.Time
+	if req.GetSince() != nil {
+		since = req.GetSince().AsTime()
+	}
+
+	revocations, err := s.useCase.Revocations(ctx, since)
+	if err != nil {
+		log.Printf("failed to list token revocations: %v", err)
+		return nil, status.Error(codes.Unavailable, "failed to list revocations")
+	}
+
+	resp := &pb.ListRevocationsResponse{
+		Revocations: make([]*pb.Revocation, 0, len(revocations)),
+		Now:         timestamppb.New(now),
+	}
+	for _, revocation := range revocations {
+		resp.Revocations = append(resp.Revocations, &pb.Revocation{
+			UserId:    int64(revocation.UserID),
+			RevokedAt: timestamppb.New(revocation.RevokedAt),
+		})
+	}
+	return resp, nil
+}
@@ -2,8 +2,10 @@ package http
 
 import (
 	"auth-service/internal/entity"
+	"auth-service/internal/password"
 	"auth-service/internal/repository"
 	"auth-service/internal/usecase"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -132,14 +134,19 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 		return
 	}
 
-	var user entity.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var input entity.UserUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	user.ID = userID
-	if err := h.useCase.UpdateUser(c.Request.Context(), &user); err != nil {
+	user, err := h.useCase.UpdateUser(c.Request.Context(), userID, input)
+	if err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) || errors.Is(err, password.ErrReused) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
@@ -10,6 +10,7 @@ import (
 
 	"auth-service/internal/delivery/user_handler"
 	"auth-service/internal/entity"
+	"backend/pkg/rbac"
 )
 
 type RouterHandler struct {
@@ -24,6 +25,8 @@ func NewRouterHandler(userHandler *user_handler.UserHandler) *RouterHandler {
 
 func (h *RouterHandler) InitRoutes() *gin.Engine {
 	router := gin.New()
+	// Прокси не доверяем: IP клиента берется из адреса соединения
+	_ = router.SetTrustedProxies(nil)
 
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
@@ -51,7 +54,7 @@ func (h *RouterHandler) InitRoutes() *gin.Engine {
 		api.PUT("/me", h.userHandler.UpdateMe)
 
 		// Маршруты для управления пользователями
-		users := api.Group("/users", h.adminMiddleware())
+		users := api.Group("/users", h.permissionMiddleware(rbac.RoleAssign))
 		{
 			users.PUT("/:id/role", h.userHandler.UpdateUserRole)
 		}
@@ -60,12 +63,12 @@ func (h *RouterHandler) InitRoutes() *gin.Engine {
 	return router
 }
 
-// adminMiddleware проверяет, является ли пользователь администратором
-func (h *RouterHandler) adminMiddleware() gin.HandlerFunc {
+// permissionMiddleware проверяет, есть ли у пользователя разрешение perm
+func (h *RouterHandler) permissionMiddleware(perm string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user := c.MustGet("user").(*entity.User)
-		if !user.IsAdmin {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		if !(rbac.Access{Permissions: user.Permissions}).Has(perm) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
 			c.Abort()
 			return
 		}
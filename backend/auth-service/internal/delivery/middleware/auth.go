@@ -1,12 +1,16 @@
 package middleware
 
 import (
+	"auth-service/internal/audit"
+	"auth-service/internal/entity"
 	"auth-service/internal/usecase"
 	"auth-service/pkg/jwt"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"backend/pkg/rbac"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -56,7 +60,58 @@ func (m *AuthMiddleware) Auth() gin.HandlerFunc {
 			return
 		}
 
+		// Загружаем роли и разрешения пользователя
+		access, err := m.authUC.LoadAccess(c.Request.Context(), user)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load user roles"})
+			return
+		}
+
+		// Данные для журнала аудита
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), audit.Actor{
+			ID:        &user.ID,
+			Name:      user.Username,
+			IP:        c.ClientIP(),
+			UserAgent: c.GetHeader("User-Agent"),
+		}))
+
 		c.Set("user", user)
+		c.Set("access", access)
 		c.Next()
 	}
 }
+
+// Client сохраняет IP и User-Agent клиента для журнала аудита на публичных маршрутах
+func (m *AuthMiddleware) Client() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), audit.Actor{
+			IP:        c.ClientIP(),
+			UserAgent: c.GetHeader("User-Agent"),
+		}))
+		c.Next()
+	}
+}
+
+// RequirePermission пропускает только пользователей с разрешением perm, должен подключаться после Auth
+func (m *AuthMiddleware) RequirePermission(perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !HasPermission(c, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "permission required: " + perm})
+			return
+		}
+		c.Next()
+	}
+}
+
+// HasPermission проверяет разрешение текущего пользователя
+func HasPermission(c *gin.Context, perm string) bool {
+	if access, ok := c.Get("access"); ok {
+		return access.(rbac.Access).Has(perm)
+	}
+
+	user, ok := c.Get("user")
+	if !ok {
+		return false
+	}
+	return rbac.Access{Permissions: user.(*entity.User).Permissions}.Has(perm)
+}
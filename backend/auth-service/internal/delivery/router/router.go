@@ -1,11 +1,15 @@
 package router
 
 import (
+	"log"
+
 	"auth-service/internal/delivery/middleware"
 	"auth-service/internal/delivery/user_handler"
 	"auth-service/internal/usecase"
 	"auth-service/pkg/jwt"
 
+	"backend/pkg/rbac"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -13,19 +17,27 @@ import (
 )
 
 type Router struct {
-	authUC       *usecase.AuthUseCase
-	tokenManager jwt.TokenManager
+	authUC         *usecase.AuthUseCase
+	tokenManager   jwt.TokenManager
+	trustedProxies []string
 }
 
-func NewRouter(authUC *usecase.AuthUseCase, tokenManager jwt.TokenManager) *Router {
+// NewRouter создает роутер. X-Forwarded-For учитывается только от trustedProxies,
+// иначе клиент мог бы подменой заголовка обходить блокировку входа по IP
+func NewRouter(authUC *usecase.AuthUseCase, tokenManager jwt.TokenManager, trustedProxies []string) *Router {
 	return &Router{
-		authUC:       authUC,
-		tokenManager: tokenManager,
+		authUC:         authUC,
+		tokenManager:   tokenManager,
+		trustedProxies: trustedProxies,
 	}
 }
 
 func (r *Router) Init() *gin.Engine {
 	router := gin.Default()
+	if err := router.SetTrustedProxies(r.trustedProxies); err != nil {
+		log.Printf("Invalid trusted proxies %v, client IPs are taken from connections: %v", r.trustedProxies, err)
+		_ = router.SetTrustedProxies(nil)
+	}
 
 	// CORS настройка
 	config := cors.DefaultConfig()
@@ -47,7 +59,7 @@ func (r *Router) Init() *gin.Engine {
 	userHandler := user_handler.NewUserHandler(r.authUC)
 
 	// Public routes
-	auth := router.Group("/auth")
+	auth := router.Group("/auth", authMiddleware.Client())
 	{
 		auth.POST("/sign-up", userHandler.SignUp)
 		auth.POST("/sign-in", userHandler.SignIn)
@@ -65,10 +77,31 @@ func (r *Router) Init() *gin.Engine {
 		api.GET("/users/:id", userHandler.GetUser)
 
 		// Admin endpoints
-		api.GET("/users", userHandler.GetUsers)
-		api.PUT("/users/:id/role", userHandler.UpdateUserRole)
-		api.DELETE("/users/:id", userHandler.DeleteUser)
-		api.GET("/stats", userHandler.GetStats)
+		api.GET("/users", authMiddleware.RequirePermission(rbac.UserView), userHandler.GetUsers)
+		api.PUT("/users/:id/role", authMiddleware.RequirePermission(rbac.RoleAssign), userHandler.UpdateUserRole)
+		api.DELETE("/users/:id", authMiddleware.RequirePermission(rbac.UserDelete), userHandler.DeleteUser)
+		api.GET("/stats", authMiddleware.RequirePermission(rbac.StatsView), userHandler.GetStats)
+		api.POST("/users/:id/unlock", authMiddleware.RequirePermission(rbac.UserUnlock), userHandler.UnlockUser)
+		api.PUT("/users/:id/password", authMiddleware.RequirePermission(rbac.UserPasswordReset), userHandler.ResetPassword)
+		api.DELETE("/users/:id/sessions", authMiddleware.RequirePermission(rbac.SessionTerminate), userHandler.TerminateUserSessions)
+
+		// Audit log
+		api.GET("/audit/events", authMiddleware.RequirePermission(rbac.AuditView), userHandler.GetAuditEvents)
+
+		// Role assignment
+		api.GET("/users/:id/roles", authMiddleware.RequirePermission(rbac.RoleAssign), userHandler.GetUserRoles)
+		api.POST("/users/:id/roles", authMiddleware.RequirePermission(rbac.RoleAssign), userHandler.AssignRole)
+		api.DELETE("/users/:id/roles/:role", authMiddleware.RequirePermission(rbac.RoleAssign), userHandler.RevokeRole)
+
+		// Role management
+		roles := api.Group("/roles", authMiddleware.RequirePermission(rbac.RoleManage))
+		{
+			roles.GET("", userHandler.GetRoles)
+			roles.GET("/permissions", userHandler.GetPermissions)
+			roles.POST("", userHandler.CreateRole)
+			roles.PUT("/:name", userHandler.UpdateRole)
+			roles.DELETE("/:name", userHandler.DeleteRole)
+		}
 	}
 
 	return router
@@ -0,0 +1,128 @@
+package user_handler
+
+import (
+	"auth-service/internal/entity"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get Audit Events
+// @Tags admin
+// @Description Query the security audit log, newest first. format=csv or format=json download the result as a file
+// @Security ApiKeyAuth
+// @Produce json,text/csv
+// @Param action query string false "Action, auth.* matches a prefix"
+// @Param actor_id query int false "Actor user ID"
+// @Param target query string false "Target, e.g. user:42"
+// @Param ip query string false "Client IP"
+// @Param from query string false "From time (RFC3339)"
+// @Param to query string false "To time (RFC3339)"
+// @Param limit query int false "Limit (default 100, max 10000)"
+// @Param offset query int false "Offset"
+// @Param format query string false "Export format" Enums(json, csv)
+// @Success 200 {array} entity.AuditEvent
+// @Failure 400,401,403,500 {object} map[string]interface{}
+// @Router /api/audit/events [get]
+func (h *UserHandler) GetAuditEvents(c *gin.Context) {
+	var filter entity.AuditFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.Query("format")
+	if format != "" && format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	events, total, err := h.authUC.GetAuditEvents(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get audit events"})
+		return
+	}
+
+	filename := "audit_events_" + time.Now().UTC().Format("20060102T150405Z")
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	switch format {
+	case "csv":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		writeAuditCSV(c, events)
+	case "json":
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+		c.JSON(http.StatusOK, events)
+	default:
+		c.JSON(http.StatusOK, gin.H{
+			"events": events,
+			"total":  total,
+		})
+	}
+}
+
+func writeAuditCSV(c *gin.Context, events []*entity.AuditEvent) {
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"id", "created_at", "actor_id", "actor", "action", "target", "ip", "user_agent", "metadata"})
+
+	for _, event := range events {
+		actorID := ""
+		if event.ActorID != nil {
+			actorID = strconv.Itoa(*event.ActorID)
+		}
+
+		metadata := ""
+		if len(event.Metadata) > 0 {
+			data, _ := json.Marshal(event.Metadata)
+			metadata = string(data)
+		}
+
+		w.Write([]string{
+			strconv.FormatInt(event.ID, 10),
+			event.CreatedAt.UTC().Format(time.RFC3339),
+			actorID,
+			event.Actor,
+			event.Action,
+			event.Target,
+			event.IP,
+			event.UserAgent,
+			metadata,
+		})
+	}
+
+	w.Flush()
+}
+
+// @Summary Terminate User Sessions
+// @Tags admin
+// @Description Terminate all sessions of a user
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/users/{id}/sessions [delete]
+func (h *UserHandler) TerminateUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.authUC.TerminateUserSessions(c.Request.Context(), userID); err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to terminate sessions"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
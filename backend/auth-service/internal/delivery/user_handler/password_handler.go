@@ -0,0 +1,75 @@
+package user_handler
+
+import (
+	"auth-service/internal/entity"
+	"auth-service/internal/password"
+	"auth-service/internal/usecase"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Reset User Password
+// @Tags admin
+// @Description Set a new password for a user and terminate all of their sessions
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body entity.ResetPasswordInput true "new password"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/users/{id}/password [put]
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var input entity.ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUC.ResetPassword(c.Request.Context(), userID, input.Password); err != nil {
+		if passwordError(c, err) {
+			return
+		}
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reset password"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// passwordError отвечает клиенту, если err — ошибка проверки пароля
+func passwordError(c *gin.Context, err error) bool {
+	var policyErr *password.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "password does not meet policy",
+			"violations": policyErr.Violations,
+		})
+	case errors.Is(err, password.ErrReused):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":      "password does not meet policy",
+			"violations": []string{"must not match one of your recent passwords"},
+		})
+	case errors.Is(err, usecase.ErrCurrentPasswordRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrInvalidCurrentPassword):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package user_handler
+
+import (
+	"auth-service/internal/entity"
+	"auth-service/internal/usecase"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/pkg/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get Roles
+// @Tags roles
+// @Description Get list of all roles
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} entity.Role
+// @Failure 401,403,500 {object} map[string]interface{}
+// @Router /api/roles [get]
+func (h *UserHandler) GetRoles(c *gin.Context) {
+	roles, err := h.authUC.GetRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get roles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// @Summary Get Permissions
+// @Tags roles
+// @Description Get list of permissions that can be granted to roles
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} string
+// @Failure 401,403 {object} map[string]interface{}
+// @Router /api/roles/permissions [get]
+func (h *UserHandler) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, rbac.Known())
+}
+
+// @Summary Create Role
+// @Tags roles
+// @Description Create a custom role
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param input body entity.RoleCreate true "role"
+// @Success 201 {object} entity.Role
+// @Failure 400,401,403,409,500 {object} map[string]interface{}
+// @Router /api/roles [post]
+func (h *UserHandler) CreateRole(c *gin.Context) {
+	var input entity.RoleCreate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.authUC.CreateRole(c.Request.Context(), input)
+	if err != nil {
+		roleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// @Summary Update Role
+// @Tags roles
+// @Description Update role description and permissions
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param name path string true "Role name"
+// @Param input body entity.RoleUpdate true "role"
+// @Success 200 {object} entity.Role
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/roles/{name} [put]
+func (h *UserHandler) UpdateRole(c *gin.Context) {
+	var input entity.RoleUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.authUC.UpdateRole(c.Request.Context(), c.Param("name"), input)
+	if err != nil {
+		roleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// @Summary Delete Role
+// @Tags roles
+// @Description Delete a custom role, system roles cannot be deleted
+// @Security ApiKeyAuth
+// @Produce json
+// @Param name path string true "Role name"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/roles/{name} [delete]
+func (h *UserHandler) DeleteRole(c *gin.Context) {
+	if err := h.authUC.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		roleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Get User Roles
+// @Tags roles
+// @Description Get roles assigned to user
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {array} entity.UserRole
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/users/{id}/roles [get]
+func (h *UserHandler) GetUserRoles(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	roles, err := h.authUC.GetUserRoles(c.Request.Context(), userID)
+	if err != nil {
+		roleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// @Summary Assign Role
+// @Tags roles
+// @Description Assign role to user globally or in a forum category
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body entity.AssignRoleInput true "role"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/users/{id}/roles [post]
+func (h *UserHandler) AssignRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var input entity.AssignRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := c.MustGet("user").(*entity.User)
+	if err := h.authUC.AssignRole(c.Request.Context(), actor, userID, input); err != nil {
+		roleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary Revoke Role
+// @Tags roles
+// @Description Revoke role from user
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role path string true "Role name"
+// @Param category_id query int false "Forum category ID"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/users/{id}/roles/{role} [delete]
+func (h *UserHandler) RevokeRole(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var categoryID *int64
+	if value := c.Query("category_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category id"})
+			return
+		}
+		categoryID = &id
+	}
+
+	if err := h.authUC.RevokeRole(c.Request.Context(), userID, c.Param("role"), categoryID); err != nil {
+		roleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// roleError переводит ошибки работы с ролями в HTTP статусы
+func roleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrUnknownPermission),
+		errors.Is(err, usecase.ErrSystemRole),
+		errors.Is(err, usecase.ErrImplicitRole),
+		strings.HasPrefix(err.Error(), "cannot remove the last admin"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, usecase.ErrSelfAssign),
+		errors.Is(err, usecase.ErrPermissionNotHeld):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case strings.HasSuffix(err.Error(), "not found"),
+		err.Error() == "role is not assigned":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "role already exists":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"auth-service/internal/entity"
 	"auth-service/internal/usecase"
 	"context"
+	"errors"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -37,6 +39,9 @@ func (h *UserHandler) SignUp(c *gin.Context) {
 	}
 
 	if err := h.authUC.SignUp(c.Request.Context(), input); err != nil {
+		if passwordError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -51,7 +56,7 @@ func (h *UserHandler) SignUp(c *gin.Context) {
 // @Produce json
 // @Param input body entity.SignInInput true "credentials"
 // @Success 200 {object} entity.Tokens
-// @Failure 400,401,500 {object} map[string]interface{}
+// @Failure 400,401,429,500 {object} map[string]interface{}
 // @Router /auth/sign-in [post]
 func (h *UserHandler) SignIn(c *gin.Context) {
 	var input entity.SignInInput
@@ -65,6 +70,16 @@ func (h *UserHandler) SignIn(c *gin.Context) {
 
 	tokens, err := h.authUC.SignIn(c.Request.Context(), &input, userAgent, clientIP)
 	if err != nil {
+		var lockedErr *usecase.LoginLockedError
+		if errors.As(err, &lockedErr) {
+			retryAfter := int(math.Ceil(lockedErr.RetryAfter.Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       err.Error(),
+				"retry_after": retryAfter,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -139,40 +154,45 @@ func (h *UserHandler) GetMe(c *gin.Context) {
 
 // @Summary Update Me
 // @Tags profile
-// @Description Update current user profile
+// @Description Update current user profile. Changing the password requires current_password and must satisfy the password policy
 // @Security ApiKeyAuth
 // @Accept json
 // @Produce json
-// @Param input body entity.User true "user update info"
+// @Param input body entity.UserUpdate true "user update info"
 // @Success 200 {object} entity.User
-// @Failure 400,401,500 {object} map[string]interface{}
+// @Failure 400,401,403,500 {object} map[string]interface{}
 // @Router /api/me [put]
 func (h *UserHandler) UpdateMe(c *gin.Context) {
 	user := c.MustGet("user").(*entity.User)
 
-	var updatedUser entity.User
-	if err := c.ShouldBindJSON(&updatedUser); err != nil {
+	var input entity.UserUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updatedUser.ID = user.ID
-	if err := h.authUC.UpdateUser(c.Request.Context(), &updatedUser); err != nil {
+	updatedUser, err := h.authUC.UpdateProfile(c.Request.Context(), user.ID, input)
+	if err != nil {
+		if passwordError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	updatedUser.Roles = user.Roles
+	updatedUser.Permissions = user.Permissions
 	c.JSON(http.StatusOK, updatedUser)
 }
 
 // @Summary Update User Role
 // @Tags admin
-// @Description Update user's admin status
+// @Description Replace user's global role, or toggle admin with is_admin
 // @Security ApiKeyAuth
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
-// @Param input body entity.User true "user info"
+// @Param input body entity.UpdateRoleInput true "role"
 // @Success 200 {object} entity.User
 // @Failure 400,401,403,500 {object} map[string]interface{}
 // @Router /api/admin/users/{id}/role [put]
@@ -183,14 +203,15 @@ func (h *UserHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	var input entity.User
+	var input entity.UpdateRoleInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.authUC.UpdateUserRole(c.Request.Context(), userID, input.IsAdmin); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	actor := c.MustGet("user").(*entity.User)
+	if err := h.authUC.UpdateUserRole(c.Request.Context(), actor, userID, input); err != nil {
+		roleError(c, err)
 		return
 	}
 
@@ -276,6 +297,45 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// @Summary Unlock User
+// @Tags admin
+// @Description Lift the sign-in lockout of a user and, optionally, of an IP address
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param input body entity.UnlockInput false "IP address to unlock"
+// @Success 204 "No Content"
+// @Failure 400,401,403,404,500 {object} map[string]interface{}
+// @Router /api/users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var input entity.UnlockInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	actor := c.MustGet("user").(*entity.User)
+	if err := h.authUC.UnlockUser(c.Request.Context(), actor, userID, input.IP); err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unlock user"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // @Summary Get User by ID
 // @Tags users
 // @Description Get user information by ID
@@ -301,7 +361,16 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-// ParseToken проверяет JWT токен и возвращает пользователя
+// ParseToken проверяет JWT токен и возвращает пользователя с его ролями и разрешениями
 func (h *UserHandler) ParseToken(ctx context.Context, token string) (*entity.User, error) {
-	return h.authUC.ParseToken(ctx, token)
+	user, err := h.authUC.ParseToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.authUC.LoadAccess(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
 }
@@ -0,0 +1,47 @@
+package entity
+
+import "time"
+
+// Audit actions
+const (
+	AuditSignIn            = "auth.sign_in"
+	AuditSignInFailed      = "auth.sign_in_failed"
+	AuditSignUp            = "auth.sign_up"
+	AuditLockout           = "auth.lockout"
+	AuditUnlock            = "auth.unlock"
+	AuditPasswordChange    = "auth.password_change"
+	AuditPasswordReset     = "auth.password_reset"
+	AuditLogout            = "session.logout"
+	AuditSessionsTerminate = "session.terminate"
+	AuditRoleAssign        = "user.role_assign"
+	AuditRoleRevoke        = "user.role_revoke"
+	AuditUserDelete        = "user.delete"
+	AuditRoleCreate        = "role.create"
+	AuditRoleUpdate        = "role.update"
+	AuditRoleDelete        = "role.delete"
+)
+
+// AuditEvent запись журнала аудита
+type AuditEvent struct {
+	ID        int64                  `db:"id" json:"id"`
+	ActorID   *int                   `db:"actor_id" json:"actor_id,omitempty"`
+	Actor     string                 `db:"actor" json:"actor"`
+	Action    string                 `db:"action" json:"action"`
+	Target    string                 `db:"target" json:"target"`
+	IP        string                 `db:"ip" json:"ip"`
+	UserAgent string                 `db:"user_agent" json:"user_agent"`
+	Metadata  map[string]interface{} `db:"metadata" json:"metadata,omitempty"`
+	CreatedAt time.Time              `db:"created_at" json:"created_at"`
+}
+
+// AuditFilter параметры выборки журнала аудита
+type AuditFilter struct {
+	Action  string     `form:"action"`
+	ActorID *int       `form:"actor_id"`
+	Target  string     `form:"target"`
+	IP      string     `form:"ip"`
+	From    *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To      *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Limit   int        `form:"limit" binding:"omitempty,min=1,max=10000"`
+	Offset  int        `form:"offset" binding:"omitempty,min=0"`
+}
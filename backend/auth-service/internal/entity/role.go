@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+// Role именованный набор разрешений
+type Role struct {
+	ID          int       `db:"id" json:"id"`
+	Name        string    `db:"name" json:"name"`
+	Description string    `db:"description" json:"description"`
+	Permissions []string  `db:"permissions" json:"permissions"`
+	IsSystem    bool      `db:"is_system" json:"is_system"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// UserRole назначение роли пользователю, глобально или в категории форума
+type UserRole struct {
+	UserID      int       `db:"user_id" json:"user_id"`
+	Role        string    `db:"role" json:"role"`
+	CategoryID  *int64    `db:"category_id" json:"category_id,omitempty"`
+	Permissions []string  `db:"permissions" json:"permissions"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+type RoleCreate struct {
+	Name        string   `json:"name" binding:"required,min=2,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+type RoleUpdate struct {
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+type AssignRoleInput struct {
+	Role       string `json:"role" binding:"required"`
+	CategoryID *int64 `json:"category_id"`
+}
@@ -24,3 +24,9 @@ type SessionInfo struct {
 	CreatedAt time.Time `json:"created_at"`
 	IsActive  bool      `json:"is_active"`
 }
+
+// Revocation отзыв токенов пользователя: все токены, выпущенные до RevokedAt, недействительны
+type Revocation struct {
+	UserID    int       `db:"user_id" json:"user_id"`
+	RevokedAt time.Time `db:"revoked_at" json:"revoked_at"`
+}
@@ -11,23 +11,41 @@ type User struct {
 	IsBlocked bool      `db:"is_blocked" json:"is_blocked"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+
+	// Заполняются из ролей пользователя, в таблице users не хранятся
+	Roles       []string `db:"-" json:"roles,omitempty"`
+	Permissions []string `db:"-" json:"permissions,omitempty"`
 }
 
 type UserCreate struct {
 	Username string `json:"username" validate:"required,min=3,max=50"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 	IsAdmin  bool   `json:"is_admin"`
 }
 
 type UserUpdate struct {
 	Username *string `json:"username" validate:"omitempty,min=3,max=50"`
 	Email    *string `json:"email" validate:"omitempty,email"`
-	Password *string `json:"password" validate:"omitempty,min=6"`
+	Password *string `json:"password"`
+	// CurrentPassword обязателен при смене собственного пароля
+	CurrentPassword *string `json:"current_password"`
+}
+
+// ResetPasswordInput новый пароль, заданный администратором
+type ResetPasswordInput struct {
+	Password string `json:"password" binding:"required"`
 }
 
+// UpdateRoleInput задает глобальную роль пользователя. IsAdmin оставлен
+// для совместимости со старыми клиентами и используется, если Role не указан
 type UpdateRoleInput struct {
-	IsAdmin bool `json:"is_admin"`
+	Role    string `json:"role"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+type UnlockInput struct {
+	IP string `json:"ip" binding:"omitempty,ip"`
 }
 
 type UserListResponse struct {
@@ -0,0 +1,183 @@
+package limiter
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Limiter tracks failed attempts per key and decides how long the key
+// has to wait before the next attempt is allowed.
+type Limiter interface {
+	// Wait returns the remaining block for key; zero means the attempt may proceed.
+	Wait(ctx context.Context, key string) (time.Duration, error)
+	// Reserve atomically checks the block for key and reserves an attempt. Attempts in flight
+	// count as failures, so a reservation is refused when they would block it if they failed.
+	// Zero means the attempt is reserved and must end with Fail or Release.
+	Reserve(ctx context.Context, key string) (time.Duration, error)
+	// Fail records a failed attempt for key, releases its reservation and returns the resulting block.
+	Fail(ctx context.Context, key string) (Block, error)
+	// Release ends a reserved attempt that did not fail.
+	Release(ctx context.Context, key string) error
+	// Reset forgets all failures for key and lifts any active block.
+	Reset(ctx context.Context, key string) error
+}
+
+// Block describes the state of a key after a failed attempt.
+type Block struct {
+	Failures int
+	Duration time.Duration
+	Lockout  bool
+}
+
+// Policy configures exponential backoff and lockout for a Limiter.
+type Policy struct {
+	// FreeAttempts is the number of failures tolerated without any delay.
+	FreeAttempts int
+	// BaseDelay is the first backoff delay; every next failure doubles it.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay.
+	MaxDelay time.Duration
+	// LockoutThreshold is the number of failures that triggers a lockout. Zero disables lockouts.
+	LockoutThreshold int
+	// LockoutDuration is how long a locked key stays blocked.
+	LockoutDuration time.Duration
+	// Window is how long failures are remembered after the last one.
+	Window time.Duration
+}
+
+// reservationTTL bounds how long an attempt may stay reserved, so that reservations
+// of a crashed instance do not block the key forever.
+const reservationTTL = 30 * time.Second
+
+// reservationWait returns the delay for a new attempt when pending attempts are in flight
+// after the given number of failures: the block they would cause if they all failed.
+func (p Policy) reservationWait(failures, pending int) time.Duration {
+	if pending == 0 {
+		return 0
+	}
+	return p.block(failures + pending).Duration
+}
+
+// block calculates the block that follows the given number of failures.
+func (p Policy) block(failures int) Block {
+	b := Block{Failures: failures}
+
+	if p.LockoutThreshold > 0 && failures >= p.LockoutThreshold {
+		b.Duration = p.LockoutDuration
+		b.Lockout = true
+		return b
+	}
+
+	if failures <= p.FreeAttempts || p.BaseDelay <= 0 {
+		return b
+	}
+
+	shift := failures - p.FreeAttempts - 1
+	delay := p.MaxDelay
+	if shift < 32 {
+		delay = p.BaseDelay << uint(shift)
+	}
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+	b.Duration = delay
+
+	return b
+}
+
+// Guard combines per-account and per-IP limiters for sign-in attempts.
+type Guard struct {
+	accounts Limiter
+	ips      Limiter
+}
+
+// NewGuard creates a new sign-in guard
+func NewGuard(accounts, ips Limiter) *Guard {
+	return &Guard{
+		accounts: accounts,
+		ips:      ips,
+	}
+}
+
+// Wait returns the longest remaining block of the account and the IP.
+func (g *Guard) Wait(ctx context.Context, email, ip string) (time.Duration, error) {
+	accountWait, err := g.accounts.Wait(ctx, accountKey(email))
+	if err != nil {
+		return 0, err
+	}
+
+	ipWait, err := g.ips.Wait(ctx, ipKey(ip))
+	if err != nil {
+		return 0, err
+	}
+
+	if ipWait > accountWait {
+		return ipWait, nil
+	}
+	return accountWait, nil
+}
+
+// Reserve reserves a sign-in attempt for the account and the IP. It returns the longest wait
+// when either of them is blocked, zero means the attempt must end with Fail or Succeed.
+func (g *Guard) Reserve(ctx context.Context, email, ip string) (time.Duration, error) {
+	accountWait, err := g.accounts.Reserve(ctx, accountKey(email))
+	if err != nil || accountWait > 0 {
+		return accountWait, err
+	}
+
+	ipWait, err := g.ips.Reserve(ctx, ipKey(ip))
+	if err != nil || ipWait > 0 {
+		if releaseErr := g.accounts.Release(ctx, accountKey(email)); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+		return ipWait, err
+	}
+
+	return 0, nil
+}
+
+// Fail records a failed sign-in for both the account and the IP.
+func (g *Guard) Fail(ctx context.Context, email, ip string) (account Block, addr Block, err error) {
+	account, err = g.accounts.Fail(ctx, accountKey(email))
+	if err != nil {
+		return Block{}, Block{}, err
+	}
+
+	addr, err = g.ips.Fail(ctx, ipKey(ip))
+	if err != nil {
+		return Block{}, Block{}, err
+	}
+
+	return account, addr, nil
+}
+
+// Succeed releases the reservation and clears the account failures after a successful sign-in.
+// IP failures are kept so that one valid account can't be used to reset them.
+func (g *Guard) Succeed(ctx context.Context, email, ip string) error {
+	if err := g.ips.Release(ctx, ipKey(ip)); err != nil {
+		return err
+	}
+	if err := g.accounts.Release(ctx, accountKey(email)); err != nil {
+		return err
+	}
+	return g.accounts.Reset(ctx, accountKey(email))
+}
+
+// UnlockAccount lifts the block for the account.
+func (g *Guard) UnlockAccount(ctx context.Context, email string) error {
+	return g.accounts.Reset(ctx, accountKey(email))
+}
+
+// UnlockIP lifts the block for the IP address.
+func (g *Guard) UnlockIP(ctx context.Context, ip string) error {
+	return g.ips.Reset(ctx, ipKey(ip))
+}
+
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,152 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	// pending attempts were reserved at reservedAt and have not ended yet
+	pending    int
+	reservedAt time.Time
+}
+
+// MemoryLimiter keeps failures in process memory. It is meant for a single
+// auth-service instance; use RedisLimiter when running several replicas.
+type MemoryLimiter struct {
+	policy    Policy
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// NewMemoryLimiter creates a new in-memory limiter
+func NewMemoryLimiter(policy Policy) *MemoryLimiter {
+	return &MemoryLimiter{
+		policy:    policy,
+		entries:   make(map[string]*memoryEntry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Wait(ctx context.Context, key string) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	entry, ok := l.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	if l.expired(entry, now) {
+		delete(l.entries, key)
+		return 0, nil
+	}
+
+	if wait := entry.blockedUntil.Sub(now); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+func (l *MemoryLimiter) Reserve(ctx context.Context, key string) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	entry, ok := l.entries[key]
+	if !ok || l.expired(entry, now) {
+		entry = &memoryEntry{}
+		l.entries[key] = entry
+	}
+
+	if wait := entry.blockedUntil.Sub(now); wait > 0 {
+		return wait, nil
+	}
+	if wait := l.policy.reservationWait(entry.failures, entry.pending); wait > 0 {
+		return wait, nil
+	}
+
+	entry.pending++
+	entry.reservedAt = now
+	return 0, nil
+}
+
+func (l *MemoryLimiter) Release(ctx context.Context, key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if entry, ok := l.entries[key]; ok && entry.pending > 0 {
+		entry.pending--
+	}
+	return nil
+}
+
+func (l *MemoryLimiter) Fail(ctx context.Context, key string) (Block, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	entry, ok := l.entries[key]
+	if !ok || l.expired(entry, now) {
+		entry = &memoryEntry{}
+		l.entries[key] = entry
+	}
+
+	entry.failures++
+	entry.lastFailure = now
+	if entry.pending > 0 {
+		entry.pending--
+	}
+
+	block := l.policy.block(entry.failures)
+	if block.Duration > 0 {
+		entry.blockedUntil = now.Add(block.Duration)
+	}
+
+	return block, nil
+}
+
+func (l *MemoryLimiter) Reset(ctx context.Context, key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.entries, key)
+	return nil
+}
+
+// expired reports whether the entry can be forgotten. Reservations older than
+// reservationTTL are dropped: their attempts have been abandoned.
+func (l *MemoryLimiter) expired(entry *memoryEntry, now time.Time) bool {
+	if entry.pending > 0 && now.Sub(entry.reservedAt) > reservationTTL {
+		entry.pending = 0
+	}
+	return now.After(entry.blockedUntil) && now.Sub(entry.lastFailure) > l.policy.Window && entry.pending == 0
+}
+
+// sweep drops expired entries so the map doesn't grow without bound.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, entry := range l.entries {
+		if l.expired(entry, now) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(policy Policy) (*MemoryLimiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewMemoryLimiter(policy)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestPolicy_Block(t *testing.T) {
+	policy := Policy{
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		MaxDelay:         10 * time.Second,
+		LockoutThreshold: 8,
+		LockoutDuration:  time.Hour,
+	}
+
+	tests := []struct {
+		failures int
+		expected Block
+	}{
+		{failures: 1, expected: Block{Failures: 1}},
+		{failures: 2, expected: Block{Failures: 2}},
+		{failures: 3, expected: Block{Failures: 3, Duration: time.Second}},
+		{failures: 4, expected: Block{Failures: 4, Duration: 2 * time.Second}},
+		{failures: 5, expected: Block{Failures: 5, Duration: 4 * time.Second}},
+		{failures: 7, expected: Block{Failures: 7, Duration: 10 * time.Second}},
+		{failures: 8, expected: Block{Failures: 8, Duration: time.Hour, Lockout: true}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, policy.block(tt.failures), "failures=%d", tt.failures)
+	}
+}
+
+func TestMemoryLimiter_BackoffAndLockout(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter(Policy{
+		FreeAttempts:     1,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 3,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	})
+
+	block, err := l.Fail(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, block.Duration)
+
+	wait, err := l.Wait(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	block, err = l.Fail(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, block.Duration)
+
+	wait, err = l.Wait(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+
+	*now = now.Add(2 * time.Second)
+	wait, err = l.Wait(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	block, err = l.Fail(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.True(t, block.Lockout)
+
+	wait, err = l.Wait(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, wait)
+
+	// Other keys are not affected
+	wait, err = l.Wait(ctx, "account:b@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	require.NoError(t, l.Reset(ctx, "account:a@example.com"))
+	wait, err = l.Wait(ctx, "account:a@example.com")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestMemoryLimiter_WindowExpiry(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter(Policy{
+		FreeAttempts: 1,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Minute,
+		Window:       time.Minute,
+	})
+
+	_, err := l.Fail(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+
+	*now = now.Add(2 * time.Minute)
+
+	block, err := l.Fail(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, block.Failures)
+	assert.Zero(t, block.Duration)
+}
+
+func TestGuard_AccountKeyIsCaseInsensitive(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{LockoutThreshold: 1, LockoutDuration: time.Minute, Window: time.Hour}
+	guard := NewGuard(NewMemoryLimiter(policy), NewMemoryLimiter(Policy{Window: time.Hour}))
+
+	account, _, err := guard.Fail(ctx, "User@Example.com", "10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, account.Lockout)
+
+	wait, err := guard.Wait(ctx, "user@example.com ", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+
+	require.NoError(t, guard.UnlockAccount(ctx, "USER@example.com"))
+	wait, err = guard.Wait(ctx, "user@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestMemoryLimiter_ConcurrentReservations(t *testing.T) {
+	ctx := context.Background()
+	l, now := newTestLimiter(Policy{
+		FreeAttempts:     2,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutThreshold: 5,
+		LockoutDuration:  15 * time.Minute,
+		Window:           time.Hour,
+	})
+	key := "account:a@example.com"
+
+	// Attempts in flight count as failures: only the free attempts and the one
+	// that would trigger the first delay may run at the same time
+	for i := 0; i < 3; i++ {
+		wait, err := l.Reserve(ctx, key)
+		require.NoError(t, err)
+		assert.Zero(t, wait, "attempt %d", i)
+	}
+	wait, err := l.Reserve(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+
+	// A released attempt frees its place
+	require.NoError(t, l.Release(ctx, key))
+	wait, err = l.Reserve(ctx, key)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Fail(ctx, key)
+		require.NoError(t, err)
+	}
+	wait, err = l.Reserve(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+
+	// Abandoned reservations expire
+	*now = now.Add(2 * time.Second)
+	wait, err = l.Reserve(ctx, key)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	wait, err = l.Reserve(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Second, wait)
+
+	*now = now.Add(reservationTTL + time.Second)
+	wait, err = l.Reserve(ctx, key)
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+}
+
+func TestGuard_ParallelWrongPasswords(t *testing.T) {
+	ctx := context.Background()
+	policy := Policy{FreeAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute, Window: time.Hour}
+	guard := NewGuard(NewMemoryLimiter(policy), NewMemoryLimiter(Policy{Window: time.Hour}))
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := guard.Reserve(ctx, "a@example.com", "10.0.0.1")
+			if err != nil || wait > 0 {
+				return
+			}
+			allowed.Add(1)
+			_, _, _ = guard.Fail(ctx, "a@example.com", "10.0.0.1")
+		}()
+	}
+	wg.Wait()
+
+	// Without reservations all 50 attempts would pass the check before the first failure
+	assert.LessOrEqual(t, allowed.Load(), int32(4))
+
+	// A refused IP reservation releases the account one
+	ipGuard := NewGuard(NewMemoryLimiter(Policy{FreeAttempts: 1, BaseDelay: time.Second, Window: time.Hour}),
+		NewMemoryLimiter(Policy{LockoutThreshold: 1, LockoutDuration: time.Minute, Window: time.Hour}))
+	_, _, err := ipGuard.Fail(ctx, "b@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	wait, err := ipGuard.Reserve(ctx, "c@example.com", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Greater(t, wait, time.Duration(0))
+	wait, err = ipGuard.Reserve(ctx, "c@example.com", "10.0.0.3")
+	require.NoError(t, err)
+	assert.Zero(t, wait)
+	require.NoError(t, ipGuard.Succeed(ctx, "c@example.com", "10.0.0.3"))
+}
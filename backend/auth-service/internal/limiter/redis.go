@@ -0,0 +1,122 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLimiter keeps failures in Redis (or any server speaking its protocol),
+// so that every auth-service replica shares the same counters.
+type RedisLimiter struct {
+	client redis.Cmdable
+	prefix string
+	policy Policy
+}
+
+// NewRedisLimiter creates a new Redis-backed limiter
+func NewRedisLimiter(client redis.Cmdable, prefix string, policy Policy) *RedisLimiter {
+	return &RedisLimiter{
+		client: client,
+		prefix: prefix,
+		policy: policy,
+	}
+}
+
+func (l *RedisLimiter) Wait(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := l.client.PTTL(ctx, l.blockKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// PTTL returns negative values when the key is missing or has no expiry
+	if ttl <= 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// reserveScript returns {block ttl, failures, pending} and increments pending when the key is not blocked.
+// KEYS: block, fail, pending; ARGV: reservation ttl in milliseconds.
+var reserveScript = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl > 0 then
+	return {ttl, 0, 0}
+end
+local failures = tonumber(redis.call('GET', KEYS[2]) or '0')
+local pending = redis.call('INCR', KEYS[3])
+redis.call('PEXPIRE', KEYS[3], ARGV[1])
+return {0, failures, pending}
+`)
+
+// releaseScript decrements pending without going below zero. KEYS: pending.
+var releaseScript = redis.NewScript(`
+local pending = redis.call('DECR', KEYS[1])
+if pending <= 0 then
+	redis.call('DEL', KEYS[1])
+end
+return pending
+`)
+
+func (l *RedisLimiter) Reserve(ctx context.Context, key string) (time.Duration, error) {
+	res, err := reserveScript.Run(ctx, l.client,
+		[]string{l.blockKey(key), l.failKey(key), l.pendingKey(key)}, reservationTTL.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	if res[0] > 0 {
+		return time.Duration(res[0]) * time.Millisecond, nil
+	}
+
+	// INCR hands every concurrent caller its own position, attempts before it are in flight
+	if wait := l.policy.reservationWait(int(res[1]), int(res[2])-1); wait > 0 {
+		if err := l.Release(ctx, key); err != nil {
+			return 0, err
+		}
+		return wait, nil
+	}
+	return 0, nil
+}
+
+func (l *RedisLimiter) Release(ctx context.Context, key string) error {
+	return releaseScript.Run(ctx, l.client, []string{l.pendingKey(key)}).Err()
+}
+
+func (l *RedisLimiter) Fail(ctx context.Context, key string) (Block, error) {
+	var incr *redis.IntCmd
+	_, err := l.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, l.failKey(key))
+		pipe.PExpire(ctx, l.failKey(key), l.policy.Window)
+		releaseScript.Eval(ctx, pipe, []string{l.pendingKey(key)})
+		return nil
+	})
+	if err != nil {
+		return Block{}, err
+	}
+
+	block := l.policy.block(int(incr.Val()))
+	if block.Duration > 0 {
+		if err := l.client.Set(ctx, l.blockKey(key), block.Failures, block.Duration).Err(); err != nil {
+			return Block{}, err
+		}
+	}
+
+	return block, nil
+}
+
+func (l *RedisLimiter) Reset(ctx context.Context, key string) error {
+	return l.client.Del(ctx, l.failKey(key), l.blockKey(key)).Err()
+}
+
+func (l *RedisLimiter) failKey(key string) string {
+	return l.prefix + "fail:" + key
+}
+
+func (l *RedisLimiter) blockKey(key string) string {
+	return l.prefix + "block:" + key
+}
+
+func (l *RedisLimiter) pendingKey(key string) string {
+	return l.prefix + "pending:" + key
+}
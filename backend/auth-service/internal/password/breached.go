@@ -0,0 +1,107 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const prefixLength = 5
+
+// BreachedList возвращает, сколько раз пароль встречался в утечках
+type BreachedList interface {
+	Count(ctx context.Context, password string) (int, error)
+}
+
+// RangeSource отдает диапазон хэшей по первым пяти символам SHA-1 (k-anonymity, как в HIBP).
+// Каждая строка диапазона имеет вид "SUFFIX:COUNT", где SUFFIX — оставшиеся 35 символов хэша.
+type RangeSource interface {
+	Range(ctx context.Context, prefix string) (io.ReadCloser, error)
+}
+
+// RangeList проверяет пароли по диапазонам хэшей, не передавая источнику сам хэш
+type RangeList struct {
+	source RangeSource
+}
+
+func NewRangeList(source RangeSource) *RangeList {
+	return &RangeList{source: source}
+}
+
+func (l *RangeList) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	r, err := l.source.Range(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		candidate, count, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			return 0, fmt.Errorf("invalid range line %q", line)
+		}
+		return n, nil
+	}
+
+	return 0, scanner.Err()
+}
+
+// DirSource читает диапазоны из каталога с файлами по префиксу (00000, 00001, ... FFFFF),
+// в формате, который сохраняет HIBP downloader
+type DirSource struct {
+	dir string
+}
+
+func NewDirSource(dir string) (*DirSource, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	return &DirSource{dir: dir}, nil
+}
+
+func (s *DirSource) Range(ctx context.Context, prefix string) (io.ReadCloser, error) {
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		f, err := os.Open(filepath.Join(s.dir, name))
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	// Диапазона нет — в нем нет и утекших паролей
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+// Open создает список утекших паролей из каталога диапазонов
+func Open(dir string) (*RangeList, error) {
+	source, err := NewDirSource(dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewRangeList(source), nil
+}
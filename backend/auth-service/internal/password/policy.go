@@ -0,0 +1,164 @@
+// Package password проверяет пароли на соответствие политике и по базе утекших паролей.
+package password
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcrypt учитывает только первые 72 байта пароля
+const maxBcryptLength = 72
+
+// Policy требования к паролю
+type Policy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// History запрещает повторять столько последних паролей, включая текущий
+	History int
+	// BcryptCost стоимость хэширования новых паролей, хэши с меньшей стоимостью пересчитываются при входе
+	BcryptCost int
+}
+
+// PolicyError содержит все нарушения политики, чтобы показать их пользователю разом
+type PolicyError struct {
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet policy: " + strings.Join(e.Violations, "; ")
+}
+
+// ErrReused возвращается, если пароль совпадает с одним из последних
+var ErrReused = errors.New("password was used recently")
+
+// Checker проверяет пароли по политике и списку утекших паролей
+type Checker struct {
+	policy   Policy
+	breached BreachedList
+	minCount int
+}
+
+// NewChecker создает проверку. breached может быть nil, тогда утечки не проверяются.
+// Пароль считается утекшим, если встречается в списке не менее minCount раз.
+func NewChecker(policy Policy, breached BreachedList, minCount int) *Checker {
+	if policy.BcryptCost == 0 {
+		policy.BcryptCost = bcrypt.DefaultCost
+	}
+	if policy.MaxLength == 0 || policy.MaxLength > maxBcryptLength {
+		policy.MaxLength = maxBcryptLength
+	}
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return &Checker{
+		policy:   policy,
+		breached: breached,
+		minCount: minCount,
+	}
+}
+
+// Policy возвращает действующую политику
+func (c *Checker) Policy() Policy {
+	return c.policy
+}
+
+// Validate проверяет пароль. Ошибки политики возвращаются как *PolicyError.
+func (c *Checker) Validate(ctx context.Context, password string) error {
+	var violations []string
+
+	length := utf8.RuneCountInString(password)
+	if length < c.policy.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", c.policy.MinLength))
+	}
+	if len(password) > c.policy.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes long", c.policy.MaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	if c.policy.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+	if c.policy.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+	if c.policy.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+	if c.policy.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a special character")
+	}
+
+	if c.breached != nil && password != "" {
+		count, err := c.breached.Count(ctx, password)
+		if err != nil {
+			return fmt.Errorf("breached password check failed: %w", err)
+		}
+		if count >= c.minCount {
+			violations = append(violations, "appears in a known data breach, choose another password")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// CheckReuse возвращает ErrReused, если пароль совпадает с одним из хэшей.
+// Проверяются не больше History последних хэшей.
+func (c *Checker) CheckReuse(password string, hashes []string) error {
+	if len(hashes) > c.policy.History {
+		hashes = hashes[:c.policy.History]
+	}
+
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrReused
+		}
+	}
+
+	return nil
+}
+
+// Hash хэширует пароль со стоимостью из политики
+func (c *Checker) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), c.policy.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// NeedsRehash сообщает, что хэш создан с меньшей стоимостью, чем требует политика
+func (c *Checker) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false
+	}
+	return cost < c.policy.BcryptCost
+}
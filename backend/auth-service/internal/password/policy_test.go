@@ -0,0 +1,127 @@
+package password
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestChecker_Validate(t *testing.T) {
+	checker := NewChecker(Policy{
+		MinLength:    8,
+		RequireUpper: true,
+		RequireLower: true,
+		RequireDigit: true,
+	}, nil, 1)
+
+	tests := []struct {
+		name       string
+		password   string
+		violations int
+	}{
+		{name: "valid", password: "Secret123"},
+		{name: "too short", password: "Se1", violations: 1},
+		{name: "no upper and digit", password: "secretsecret", violations: 2},
+		{name: "everything missing", password: "", violations: 4},
+		{name: "unicode letters", password: "Пароль123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checker.Validate(context.Background(), tt.password)
+			if tt.violations == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *PolicyError
+			require.True(t, errors.As(err, &policyErr))
+			assert.Len(t, policyErr.Violations, tt.violations)
+		})
+	}
+}
+
+func TestChecker_ValidateMaxLength(t *testing.T) {
+	checker := NewChecker(Policy{MinLength: 1}, nil, 1)
+
+	err := checker.Validate(context.Background(), strings.Repeat("a", 73))
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr))
+	assert.Contains(t, policyErr.Violations[0], "at most 72")
+}
+
+func TestChecker_Breached(t *testing.T) {
+	dir := t.TempDir()
+	writeRange(t, dir, "password", 1000)
+	writeRange(t, dir, "rare-Password1", 1)
+
+	list, err := Open(dir)
+	require.NoError(t, err)
+
+	checker := NewChecker(Policy{MinLength: 1}, list, 2)
+
+	err = checker.Validate(context.Background(), "password")
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr))
+	assert.Contains(t, policyErr.Violations[0], "data breach")
+
+	// Ниже порога
+	assert.NoError(t, checker.Validate(context.Background(), "rare-Password1"))
+	// Диапазона нет
+	assert.NoError(t, checker.Validate(context.Background(), "unique-Password-42"))
+}
+
+func TestChecker_CheckReuse(t *testing.T) {
+	checker := NewChecker(Policy{History: 2, BcryptCost: bcrypt.MinCost}, nil, 1)
+
+	hashes := make([]string, 0, 3)
+	for _, p := range []string{"newest", "older", "oldest"} {
+		hash, err := checker.Hash(p)
+		require.NoError(t, err)
+		hashes = append(hashes, hash)
+	}
+
+	assert.ErrorIs(t, checker.CheckReuse("newest", hashes), ErrReused)
+	assert.ErrorIs(t, checker.CheckReuse("older", hashes), ErrReused)
+	// Выходит за пределы истории
+	assert.NoError(t, checker.CheckReuse("oldest", hashes))
+	assert.NoError(t, checker.CheckReuse("different", hashes))
+}
+
+func TestChecker_NeedsRehash(t *testing.T) {
+	checker := NewChecker(Policy{BcryptCost: bcrypt.MinCost + 1}, nil, 1)
+
+	weak, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+	strong, err := checker.Hash("secret")
+	require.NoError(t, err)
+
+	assert.True(t, checker.NeedsRehash(string(weak)))
+	assert.False(t, checker.NeedsRehash(strong))
+	assert.False(t, checker.NeedsRehash("not a hash"))
+}
+
+// writeRange добавляет пароль в файл диапазона, как в выгрузке HIBP
+func writeRange(t *testing.T, dir, password string, count int) {
+	t.Helper()
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	f, err := os.OpenFile(filepath.Join(dir, hash[:5]), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	defer f.Close()
+
+	_, err = f.WriteString("0000000000000000000000000000000000A:3\r\n" + hash[5:] + ":" + strconv.Itoa(count) + "\r\n")
+	require.NoError(t, err)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type AuditPostgres struct {
+	db *pgxpool.Pool
+}
+
+func NewAuditPostgres(db *pgxpool.Pool) *AuditPostgres {
+	return &AuditPostgres{db: db}
+}
+
+// Insert записывает пачку событий одним запросом
+func (r *AuditPostgres) Insert(ctx context.Context, events []*entity.AuditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for _, event := range events {
+		metadata := event.Metadata
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		batch.Queue(`
+			INSERT INTO audit_events (actor_id, actor, action, target, ip, user_agent, metadata, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			event.ActorID,
+			event.Actor,
+			event.Action,
+			event.Target,
+			event.IP,
+			event.UserAgent,
+			metadata,
+			event.CreatedAt,
+		)
+	}
+
+	results := r.db.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for range events {
+		if _, err := results.Exec(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find возвращает события по фильтру, от новых к старым, и их общее количество
+func (r *AuditPostgres) Find(ctx context.Context, filter entity.AuditFilter) ([]*entity.AuditEvent, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	addCondition := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Action != "" {
+		// "auth.*" выбирает все действия с префиксом auth.
+		if prefix, ok := strings.CutSuffix(filter.Action, "*"); ok {
+			addCondition("action LIKE $%d", prefix+"%")
+		} else {
+			addCondition("action = $%d", filter.Action)
+		}
+	}
+	if filter.ActorID != nil {
+		addCondition("actor_id = $%d", *filter.ActorID)
+	}
+	if filter.Target != "" {
+		addCondition("target = $%d", filter.Target)
+	}
+	if filter.IP != "" {
+		addCondition("ip = $%d", filter.IP)
+	}
+	if filter.From != nil {
+		addCondition("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		addCondition("created_at < $%d", *filter.To)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM audit_events "+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, actor_id, actor, action, target, ip, user_agent, metadata, created_at
+		FROM audit_events
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var events []*entity.AuditEvent
+	for rows.Next() {
+		event := &entity.AuditEvent{}
+		err := rows.Scan(
+			&event.ID,
+			&event.ActorID,
+			&event.Actor,
+			&event.Action,
+			&event.Target,
+			&event.IP,
+			&event.UserAgent,
+			&event.Metadata,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
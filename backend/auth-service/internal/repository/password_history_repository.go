@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type PasswordHistoryPostgres struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordHistoryPostgres(db *pgxpool.Pool) *PasswordHistoryPostgres {
+	return &PasswordHistoryPostgres{db: db}
+}
+
+// Add сохраняет хэш и удаляет записи старше последних keep
+func (r *PasswordHistoryPostgres) Add(ctx context.Context, userID int, passwordHash string, keep int) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO password_history (user_id, password_hash, created_at)
+		VALUES ($1, $2, NOW())`,
+		userID, passwordHash,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(ctx, `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)`,
+		userID, keep,
+	)
+	return err
+}
+
+// Replace заменяет хэш в существующих записях истории, не добавляя новых
+func (r *PasswordHistoryPostgres) Replace(ctx context.Context, userID int, oldHash, newHash string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE password_history SET password_hash = $3
+		WHERE user_id = $1 AND password_hash = $2`,
+		userID, oldHash, newHash,
+	)
+	return err
+}
+
+// GetRecent возвращает до limit последних хэшей, от новых к старым
+func (r *PasswordHistoryPostgres) GetRecent(ctx context.Context, userID, limit int) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT password_hash FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`,
+		userID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, rows.Err()
+}
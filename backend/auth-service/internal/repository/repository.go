@@ -26,16 +26,43 @@ type ISessionRepository interface {
 	GetByRefreshToken(ctx context.Context, refreshToken string) (entity.Session, error)
 	GetByAccessToken(ctx context.Context, accessToken string) (entity.Session, error)
 	Delete(ctx context.Context, id int) error
-	DeleteAllUserSessions(ctx context.Context, userID int) error
+	DeleteAllUserSessions(ctx context.Context, userID int, revokedAt time.Time) error
 	UpdateTokens(ctx context.Context, id int, accessToken, refreshToken string, accessExpires, refreshExpires time.Time) error
-	DeactivateSession(ctx context.Context, id int) error
+	DeactivateSession(ctx context.Context, id int, revokedAt time.Time) error
 	GetUserSessions(ctx context.Context, userID int) ([]entity.Session, error)
+	// ListRevocations возвращает отзывы токенов, сделанные после since
+	ListRevocations(ctx context.Context, since time.Time) ([]entity.Revocation, error)
+}
+
+type RoleRepository interface {
+	GetAll(ctx context.Context) ([]*entity.Role, error)
+	GetByName(ctx context.Context, name string) (*entity.Role, error)
+	Create(ctx context.Context, role *entity.Role) error
+	Update(ctx context.Context, role *entity.Role) error
+	Delete(ctx context.Context, id int) error
+	GetUserRoles(ctx context.Context, userID int) ([]*entity.UserRole, error)
+	AssignToUser(ctx context.Context, userID, roleID int, categoryID *int64) error
+	RevokeFromUser(ctx context.Context, userID, roleID int, categoryID *int64) error
+}
+
+type AuditRepository interface {
+	Insert(ctx context.Context, events []*entity.AuditEvent) error
+	Find(ctx context.Context, filter entity.AuditFilter) ([]*entity.AuditEvent, int, error)
+}
+
+type PasswordHistoryRepository interface {
+	Add(ctx context.Context, userID int, passwordHash string, keep int) error
+	GetRecent(ctx context.Context, userID, limit int) ([]string, error)
+	Replace(ctx context.Context, userID int, oldHash, newHash string) error
 }
 
 type Repository struct {
 	UserRepository
 	StatsRepository
 	SessionRepository ISessionRepository
+	RoleRepository    RoleRepository
+	AuditRepository   AuditRepository
+	PasswordHistory   PasswordHistoryRepository
 }
 
 func NewRepository(db *pgxpool.Pool) *Repository {
@@ -43,5 +70,8 @@ func NewRepository(db *pgxpool.Pool) *Repository {
 		UserRepository:    NewUserRepository(db),
 		StatsRepository:   NewStatsRepository(db),
 		SessionRepository: NewSessionRepository(db),
+		RoleRepository:    NewRolePostgres(db),
+		AuditRepository:   NewAuditPostgres(db),
+		PasswordHistory:   NewPasswordHistoryPostgres(db),
 	}
 }
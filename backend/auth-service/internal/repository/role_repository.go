@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+type RolePostgres struct {
+	db *pgxpool.Pool
+}
+
+func NewRolePostgres(db *pgxpool.Pool) *RolePostgres {
+	return &RolePostgres{db: db}
+}
+
+func (r *RolePostgres) GetAll(ctx context.Context) ([]*entity.Role, error) {
+	query := `
+		SELECT id, name, description, permissions, is_system, created_at, updated_at
+		FROM roles
+		ORDER BY id`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*entity.Role
+	for rows.Next() {
+		role := &entity.Role{}
+		err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.Description,
+			&role.Permissions,
+			&role.IsSystem,
+			&role.CreatedAt,
+			&role.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *RolePostgres) GetByName(ctx context.Context, name string) (*entity.Role, error) {
+	role := &entity.Role{}
+	query := `
+		SELECT id, name, description, permissions, is_system, created_at, updated_at
+		FROM roles
+		WHERE name = $1`
+
+	err := r.db.QueryRow(ctx, query, name).Scan(
+		&role.ID,
+		&role.Name,
+		&role.Description,
+		&role.Permissions,
+		&role.IsSystem,
+		&role.CreatedAt,
+		&role.UpdatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, errors.New("role not found")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+func (r *RolePostgres) Create(ctx context.Context, role *entity.Role) error {
+	query := `
+		INSERT INTO roles (name, description, permissions, is_system, created_at, updated_at)
+		VALUES ($1, $2, $3, false, NOW(), NOW())
+		RETURNING id, created_at, updated_at`
+
+	err := r.db.QueryRow(ctx, query, role.Name, role.Description, role.Permissions).
+		Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return errors.New("role already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *RolePostgres) Update(ctx context.Context, role *entity.Role) error {
+	query := `
+		UPDATE roles
+		SET description = $1, permissions = $2, updated_at = NOW()
+		WHERE id = $3`
+
+	result, err := r.db.Exec(ctx, query, role.Description, role.Permissions, role.ID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("role not found")
+	}
+
+	return nil
+}
+
+func (r *RolePostgres) Delete(ctx context.Context, id int) error {
+	result, err := r.db.Exec(ctx, `DELETE FROM roles WHERE id = $1 AND is_system = false`, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("role not found")
+	}
+
+	return nil
+}
+
+func (r *RolePostgres) GetUserRoles(ctx context.Context, userID int) ([]*entity.UserRole, error) {
+	query := `
+		SELECT ur.user_id, r.name, ur.category_id, r.permissions, ur.created_at
+		FROM user_roles ur
+		JOIN roles r ON r.id = ur.role_id
+		WHERE ur.user_id = $1
+		ORDER BY ur.created_at`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var userRoles []*entity.UserRole
+	for rows.Next() {
+		userRole := &entity.UserRole{}
+		err := rows.Scan(
+			&userRole.UserID,
+			&userRole.Role,
+			&userRole.CategoryID,
+			&userRole.Permissions,
+			&userRole.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		userRoles = append(userRoles, userRole)
+	}
+
+	return userRoles, rows.Err()
+}
+
+func (r *RolePostgres) AssignToUser(ctx context.Context, userID, roleID int, categoryID *int64) error {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, category_id, created_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT DO NOTHING`
+
+	_, err := r.db.Exec(ctx, query, userID, roleID, categoryID)
+	return err
+}
+
+func (r *RolePostgres) RevokeFromUser(ctx context.Context, userID, roleID int, categoryID *int64) error {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1 AND role_id = $2 AND category_id IS NOT DISTINCT FROM $3`
+
+	result, err := r.db.Exec(ctx, query, userID, roleID, categoryID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return errors.New("role is not assigned")
+	}
+
+	return nil
+}
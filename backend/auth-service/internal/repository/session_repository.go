@@ -96,12 +96,20 @@ func (r *SessionPostgres) UpdateTokens(ctx context.Context, id int, accessToken,
 	return err
 }
 
-func (r *SessionPostgres) DeactivateSession(ctx context.Context, id int) error {
+// DeactivateSession завершает сессию и отзывает токены ее пользователя, выпущенные до revokedAt.
+// Время отзыва задает приложение: с ним сравнивается время выпуска токена, а не часы БД
+func (r *SessionPostgres) DeactivateSession(ctx context.Context, id int, revokedAt time.Time) error {
 	query := `
-		UPDATE sessions 
-		SET is_active = false, updated_at = NOW()
-		WHERE id = $1`
-	_, err := r.db.Exec(ctx, query, id)
+		WITH deactivated AS (
+			UPDATE sessions 
+			SET is_active = false, updated_at = NOW()
+			WHERE id = $1
+			RETURNING user_id
+		)
+		INSERT INTO token_revocations (user_id, revoked_at)
+		SELECT DISTINCT user_id, $2::timestamptz FROM deactivated
+		ON CONFLICT (user_id) DO UPDATE SET revoked_at = EXCLUDED.revoked_at`
+	_, err := r.db.Exec(ctx, query, id, revokedAt)
 	return err
 }
 
@@ -111,12 +119,44 @@ func (r *SessionPostgres) Delete(ctx context.Context, id int) error {
 	return err
 }
 
-func (r *SessionPostgres) DeleteAllUserSessions(ctx context.Context, userID int) error {
-	query := `DELETE FROM sessions WHERE user_id = $1`
-	_, err := r.db.Exec(ctx, query, userID)
+// DeleteAllUserSessions удаляет сессии пользователя и отзывает его токены, выпущенные до revokedAt
+func (r *SessionPostgres) DeleteAllUserSessions(ctx context.Context, userID int, revokedAt time.Time) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM sessions WHERE user_id = $1
+		)
+		INSERT INTO token_revocations (user_id, revoked_at)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET revoked_at = EXCLUDED.revoked_at`
+	_, err := r.db.Exec(ctx, query, userID, revokedAt)
 	return err
 }
 
+// ListRevocations возвращает отзывы токенов, сделанные после since
+func (r *SessionPostgres) ListRevocations(ctx context.Context, since time.Time) ([]entity.Revocation, error) {
+	query := `
+		SELECT user_id, revoked_at
+		FROM token_revocations
+		WHERE revoked_at > $1
+		ORDER BY revoked_at`
+
+	rows, err := r.db.Query(ctx, query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revocations []entity.Revocation
+	for rows.Next() {
+		var revocation entity.Revocation
+		if err := rows.Scan(&revocation.UserID, &revocation.RevokedAt); err != nil {
+			return nil, err
+		}
+		revocations = append(revocations, revocation)
+	}
+	return revocations, rows.Err()
+}
+
 func (r *SessionPostgres) GetUserSessions(ctx context.Context, userID int) ([]entity.Session, error) {
 	query := `
 		SELECT id, user_id, access_token, refresh_token, 
@@ -23,7 +23,8 @@ func NewUserPostgres(db *pgxpool.Pool) *UserPostgres {
 func (r *UserPostgres) Create(ctx context.Context, user *entity.User) error {
 	query := `
 		INSERT INTO users (username, email, password_hash, is_admin, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $5)`
+		VALUES ($1, $2, $3, $4, $5, $5)
+		RETURNING id`
 
 	now := time.Now()
 
@@ -34,13 +35,13 @@ func (r *UserPostgres) Create(ctx context.Context, user *entity.User) error {
 		log.Printf("Inserting user with password hash length: %d", len(user.Password))
 	}
 
-	_, err := r.db.Exec(ctx, query,
+	err := r.db.QueryRow(ctx, query,
 		user.Username,
 		user.Email,
 		user.Password,
 		user.IsAdmin,
 		now,
-	)
+	).Scan(&user.ID)
 
 	if err != nil {
 		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"log"
+	"strconv"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 10000
+)
+
+// audit записывает событие безопасности в журнал аудита, не блокируя запрос
+func (u *AuthUseCase) audit(ctx context.Context, event entity.AuditEvent) {
+	if u.auditLog == nil {
+		log.Printf("AUDIT action=%s actor=%q target=%q ip=%q metadata=%v",
+			event.Action, event.Actor, event.Target, event.IP, event.Metadata)
+		return
+	}
+
+	u.auditLog.Record(ctx, event)
+}
+
+// GetAuditEvents возвращает события журнала аудита по фильтру
+func (u *AuthUseCase) GetAuditEvents(ctx context.Context, filter entity.AuditFilter) ([]*entity.AuditEvent, int, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	}
+	if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	events, total, err := u.auditRepo.Find(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if events == nil {
+		events = make([]*entity.AuditEvent, 0)
+	}
+
+	return events, total, nil
+}
+
+func userTarget(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
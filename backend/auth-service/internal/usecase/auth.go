@@ -1,7 +1,10 @@
 package usecase
 
 import (
+	"auth-service/internal/audit"
 	"auth-service/internal/entity"
+	"auth-service/internal/limiter"
+	"auth-service/internal/password"
 	"auth-service/internal/repository"
 	"auth-service/pkg/jwt"
 	"context"
@@ -17,8 +20,15 @@ import (
 type AuthUseCase struct {
 	userRepo     repository.UserRepository
 	sessionRepo  repository.ISessionRepository
+	roleRepo     repository.RoleRepository
+	auditRepo    repository.AuditRepository
 	tokenManager jwt.TokenManager
-	config       *Config
+	loginGuard   *limiter.Guard
+	auditLog     *audit.Logger
+	passwords    *password.Checker
+	// passwordHistory хранит хэши прошлых паролей для запрета повторов
+	passwordHistory repository.PasswordHistoryRepository
+	config          *Config
 }
 
 type Config struct {
@@ -26,18 +36,42 @@ type Config struct {
 	RefreshTokenTTL time.Duration
 }
 
-func NewAuthUseCase(userRepo repository.UserRepository, sessionRepo repository.ISessionRepository, tokenManager jwt.TokenManager, config *Config) *AuthUseCase {
+// LoginLockedError возвращается, когда вход временно заблокирован из-за неудачных попыток
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+func NewAuthUseCase(userRepo repository.UserRepository, sessionRepo repository.ISessionRepository, roleRepo repository.RoleRepository, auditRepo repository.AuditRepository, passwordHistory repository.PasswordHistoryRepository, tokenManager jwt.TokenManager, loginGuard *limiter.Guard, auditLog *audit.Logger, passwords *password.Checker, config *Config) *AuthUseCase {
+	if passwords == nil {
+		passwords = password.NewChecker(password.Policy{MinLength: 6}, nil, 1)
+	}
+
 	return &AuthUseCase{
-		userRepo:     userRepo,
-		sessionRepo:  sessionRepo,
-		tokenManager: tokenManager,
-		config:       config,
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		roleRepo:        roleRepo,
+		auditRepo:       auditRepo,
+		tokenManager:    tokenManager,
+		loginGuard:      loginGuard,
+		auditLog:        auditLog,
+		passwords:       passwords,
+		passwordHistory: passwordHistory,
+		config:          config,
 	}
 }
 
 func (u *AuthUseCase) CreateUser(ctx context.Context, input entity.UserCreate) error {
+	// Проверяем пароль по политике
+	if err := u.checkNewPassword(ctx, 0, input.Password); err != nil {
+		return err
+	}
+
 	// Хэшируем пароль
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashedPassword, err := u.passwords.Hash(input.Password)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
 		return err
@@ -46,19 +80,39 @@ func (u *AuthUseCase) CreateUser(ctx context.Context, input entity.UserCreate) e
 	user := &entity.User{
 		Username: input.Username,
 		Email:    input.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 	}
 
-	return u.userRepo.Create(ctx, user)
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	u.rememberPassword(ctx, user.ID, hashedPassword)
+
+	u.audit(ctx, entity.AuditEvent{
+		Action:   entity.AuditSignUp,
+		Target:   userTarget(user.ID),
+		Metadata: map[string]interface{}{"username": user.Username, "email": user.Email},
+	})
+
+	return nil
 }
 
 func (u *AuthUseCase) SignIn(ctx context.Context, input *entity.SignInInput, userAgent, ip string) (*entity.Tokens, error) {
 	// Получаем пользователя по email
 	log.Printf("Attempting to sign in user with email: %s", input.Email)
 
+	// Проверяем, не заблокированы ли аккаунт или IP после неудачных попыток
+	if err := u.checkLoginAllowed(ctx, input.Email, ip); err != nil {
+		u.auditSignInFailed(ctx, nil, input.Email, ip, userAgent, "locked")
+		return nil, err
+	}
+
 	user, err := u.userRepo.GetByEmail(ctx, input.Email)
 	if err != nil {
 		log.Printf("Error finding user by email %s: %v", input.Email, err)
+		u.auditSignInFailed(ctx, nil, input.Email, ip, userAgent, "unknown_user")
+		u.registerLoginFailure(ctx, input.Email, ip)
 		return nil, errors.New("invalid credentials")
 	}
 
@@ -68,13 +122,140 @@ func (u *AuthUseCase) SignIn(ctx context.Context, input *entity.SignInInput, use
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password))
 	if err != nil {
 		log.Printf("Password comparison failed for user %s: %v", input.Email, err)
+		u.auditSignInFailed(ctx, &user.ID, input.Email, ip, userAgent, "invalid_password")
+		u.registerLoginFailure(ctx, input.Email, ip)
 		return nil, errors.New("invalid credentials")
 	}
 
 	log.Printf("Password verified successfully for user: %s", user.Email)
 
+	// Пересчитываем хэш, если политика требует большую стоимость bcrypt
+	u.upgradePasswordHash(ctx, user, input.Password)
+
+	if u.loginGuard != nil {
+		if err := u.loginGuard.Succeed(ctx, input.Email, ip); err != nil {
+			log.Printf("Failed to reset login failures for %s: %v", input.Email, err)
+		}
+	}
+
 	// Если все хорошо, создаем сессию
-	return u.createSession(ctx, user.ID, userAgent, ip)
+	tokens, err := u.createSession(ctx, user, userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		ActorID:   &user.ID,
+		Actor:     user.Username,
+		Action:    entity.AuditSignIn,
+		Target:    userTarget(user.ID),
+		IP:        ip,
+		UserAgent: userAgent,
+	})
+
+	return tokens, nil
+}
+
+// auditSignInFailed фиксирует неудачную попытку входа
+func (u *AuthUseCase) auditSignInFailed(ctx context.Context, userID *int, email, ip, userAgent, reason string) {
+	u.audit(ctx, entity.AuditEvent{
+		ActorID:   userID,
+		Actor:     email,
+		Action:    entity.AuditSignInFailed,
+		Target:    "account:" + email,
+		IP:        ip,
+		UserAgent: userAgent,
+		Metadata:  map[string]interface{}{"reason": reason},
+	})
+}
+
+// checkLoginAllowed резервирует попытку входа или возвращает LoginLockedError, если она сейчас запрещена.
+// Проверка и резервирование атомарны, поэтому параллельные попытки не обходят задержку.
+// Зарезервированная попытка завершается registerLoginFailure или Succeed.
+// При недоступности хранилища лимитера вход не блокируется.
+func (u *AuthUseCase) checkLoginAllowed(ctx context.Context, email, ip string) error {
+	if u.loginGuard == nil {
+		return nil
+	}
+
+	wait, err := u.loginGuard.Reserve(ctx, email, ip)
+	if err != nil {
+		log.Printf("Login limiter unavailable, skipping check for %s: %v", email, err)
+		return nil
+	}
+
+	if wait > 0 {
+		log.Printf("Sign in for %s from %s rejected, retry in %s", email, ip, wait)
+		return &LoginLockedError{RetryAfter: wait}
+	}
+
+	return nil
+}
+
+// registerLoginFailure учитывает неудачную попытку входа и фиксирует блокировки в аудите
+func (u *AuthUseCase) registerLoginFailure(ctx context.Context, email, ip string) {
+	if u.loginGuard == nil {
+		return
+	}
+
+	account, addr, err := u.loginGuard.Fail(ctx, email, ip)
+	if err != nil {
+		log.Printf("Failed to register login failure for %s: %v", email, err)
+		return
+	}
+
+	if account.Lockout {
+		u.audit(ctx, entity.AuditEvent{
+			Action:   entity.AuditLockout,
+			Target:   "account:" + email,
+			IP:       ip,
+			Metadata: map[string]interface{}{"failures": account.Failures, "duration": account.Duration.String()},
+		})
+	}
+	if addr.Lockout {
+		u.audit(ctx, entity.AuditEvent{
+			Action:   entity.AuditLockout,
+			Target:   "ip:" + ip,
+			IP:       ip,
+			Metadata: map[string]interface{}{"failures": addr.Failures, "duration": addr.Duration.String()},
+		})
+	}
+}
+
+// UnlockUser снимает блокировку входа с пользователя и, опционально, с IP-адреса
+func (u *AuthUseCase) UnlockUser(ctx context.Context, actor *entity.User, userID int, ip string) error {
+	if u.loginGuard == nil {
+		return nil
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.loginGuard.UnlockAccount(ctx, user.Email); err != nil {
+		return err
+	}
+	u.audit(ctx, entity.AuditEvent{
+		ActorID: &actor.ID,
+		Actor:   actor.Username,
+		Action:  entity.AuditUnlock,
+		Target:  "account:" + user.Email,
+	})
+
+	if ip != "" {
+		if err := u.loginGuard.UnlockIP(ctx, ip); err != nil {
+			return err
+		}
+		u.audit(ctx, entity.AuditEvent{
+			ActorID: &actor.ID,
+			Actor:   actor.Username,
+			Action:  entity.AuditUnlock,
+			Target:  "ip:" + ip,
+		})
+	}
+
+	return nil
 }
 
 func (u *AuthUseCase) RefreshTokens(ctx context.Context, refreshToken, userAgent, ip string) (*entity.Tokens, error) {
@@ -87,12 +268,31 @@ func (u *AuthUseCase) RefreshTokens(ctx context.Context, refreshToken, userAgent
 		return nil, errors.New("refresh token expired")
 	}
 
-	return u.createSession(ctx, session.UserID, userAgent, ip)
+	user, err := u.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.createSession(ctx, user, userAgent, ip)
 }
 
-func (u *AuthUseCase) createSession(ctx context.Context, userID int, userAgent, ip string) (*entity.Tokens, error) {
+func (u *AuthUseCase) createSession(ctx context.Context, user *entity.User, userAgent, ip string) (*entity.Tokens, error) {
+	userID := user.ID
+
+	// Роли и разрешения попадают в токен, чтобы их могли проверять другие сервисы
+	access, err := u.GetUserAccess(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Деактивируем все предыдущие сессии пользователя. Отзыв записывается до подписи
+	// новых токенов, поэтому они выпущены не раньше отзыва и под него не попадают
+	if err := u.sessionRepo.DeleteAllUserSessions(ctx, userID, revocationCutoff()); err != nil {
+		return nil, err
+	}
+
 	// Создаем новые токены
-	accessToken, err := u.tokenManager.NewJWT(fmt.Sprintf("%d", userID), u.config.AccessTokenTTL)
+	accessToken, err := u.tokenManager.NewAccessToken(fmt.Sprintf("%d", userID), user.Username, access, u.config.AccessTokenTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -113,11 +313,6 @@ func (u *AuthUseCase) createSession(ctx context.Context, userID int, userAgent,
 		IsActive:      true,
 	}
 
-	// Деактивируем все предыдущие сессии пользователя
-	if err := u.sessionRepo.DeleteAllUserSessions(ctx, userID); err != nil {
-		return nil, err
-	}
-
 	// Создаем новую сессию
 	_, err = u.sessionRepo.Create(ctx, session)
 	if err != nil {
@@ -136,33 +331,73 @@ func (u *AuthUseCase) Logout(ctx context.Context, refreshToken string) error {
 		return err
 	}
 
-	return u.sessionRepo.DeactivateSession(ctx, session.ID)
+	if err := u.sessionRepo.DeactivateSession(ctx, session.ID, revocationCutoff()); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		ActorID:  &session.UserID,
+		Action:   entity.AuditLogout,
+		Target:   userTarget(session.UserID),
+		Metadata: map[string]interface{}{"session_id": session.ID},
+	})
+
+	return nil
 }
 
 func (u *AuthUseCase) GetUserByID(ctx context.Context, id int) (*entity.User, error) {
 	return u.userRepo.GetByID(ctx, id)
 }
 
-func (u *AuthUseCase) UpdateUser(ctx context.Context, user *entity.User) error {
-	if user.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-		if err != nil {
-			return err
-		}
-		user.Password = string(hashedPassword)
-	}
+// UpdateUser обновляет данные пользователя без проверки текущего пароля, для администраторов
+func (u *AuthUseCase) UpdateUser(ctx context.Context, userID int, input entity.UserUpdate) (*entity.User, error) {
+	return u.updateUser(ctx, userID, input, false)
+}
 
-	return u.userRepo.Update(ctx, user)
+// UpdateProfile обновляет профиль текущего пользователя, смена пароля требует текущий пароль
+func (u *AuthUseCase) UpdateProfile(ctx context.Context, userID int, input entity.UserUpdate) (*entity.User, error) {
+	return u.updateUser(ctx, userID, input, true)
 }
 
-func (u *AuthUseCase) UpdateUserRole(ctx context.Context, userID int, isAdmin bool) error {
+func (u *AuthUseCase) updateUser(ctx context.Context, userID int, input entity.UserUpdate, verifyCurrent bool) (*entity.User, error) {
 	user, err := u.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if input.Username != nil {
+		user.Username = *input.Username
+	}
+	if input.Email != nil {
+		user.Email = *input.Email
+	}
+
+	if input.Password == nil {
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if verifyCurrent {
+		if input.CurrentPassword == nil {
+			return nil, ErrCurrentPasswordRequired
+		}
+		if err := comparePassword(user.Password, *input.CurrentPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.setPassword(ctx, user, *input.Password); err != nil {
+		return nil, err
 	}
 
-	user.IsAdmin = isAdmin
-	return u.userRepo.Update(ctx, user)
+	u.audit(ctx, entity.AuditEvent{
+		Action: entity.AuditPasswordChange,
+		Target: userTarget(user.ID),
+	})
+
+	return user, nil
 }
 
 func (u *AuthUseCase) GetUserSessions(ctx context.Context, userID int) ([]entity.SessionInfo, int, error) {
@@ -187,7 +422,20 @@ func (u *AuthUseCase) GetUserSessions(ctx context.Context, userID int) ([]entity
 }
 
 func (u *AuthUseCase) TerminateUserSessions(ctx context.Context, userID int) error {
-	return u.sessionRepo.DeleteAllUserSessions(ctx, userID)
+	if _, err := u.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.DeleteAllUserSessions(ctx, userID, revocationCutoff()); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action: entity.AuditSessionsTerminate,
+		Target: userTarget(userID),
+	})
+
+	return nil
 }
 
 // ParseToken парсит и проверяет JWT токен
@@ -252,10 +500,20 @@ func (u *AuthUseCase) DeleteUser(ctx context.Context, userID int) error {
 	}
 
 	// Удаляем все сессии пользователя
-	if err := u.sessionRepo.DeleteAllUserSessions(ctx, userID); err != nil {
+	if err := u.sessionRepo.DeleteAllUserSessions(ctx, userID, revocationCutoff()); err != nil {
 		return err
 	}
 
 	// Удаляем пользователя
-	return u.userRepo.Delete(ctx, userID)
+	if err := u.userRepo.Delete(ctx, userID); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action:   entity.AuditUserDelete,
+		Target:   userTarget(userID),
+		Metadata: map[string]interface{}{"username": user.Username, "email": user.Email},
+	})
+
+	return nil
 }
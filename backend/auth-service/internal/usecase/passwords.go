@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"errors"
+	"log"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrCurrentPasswordRequired = errors.New("current password is required to change password")
+	ErrInvalidCurrentPassword  = errors.New("current password is incorrect")
+)
+
+// checkNewPassword проверяет пароль по политике и, для существующего пользователя, по истории паролей
+func (u *AuthUseCase) checkNewPassword(ctx context.Context, userID int, password string) error {
+	if err := u.passwords.Validate(ctx, password); err != nil {
+		return err
+	}
+
+	history := u.passwords.Policy().History
+	if userID == 0 || history <= 0 || u.passwordHistory == nil {
+		return nil
+	}
+
+	hashes, err := u.passwordHistory.GetRecent(ctx, userID, history)
+	if err != nil {
+		return err
+	}
+
+	return u.passwords.CheckReuse(password, hashes)
+}
+
+// rememberPassword сохраняет хэш в истории паролей пользователя
+func (u *AuthUseCase) rememberPassword(ctx context.Context, userID int, hash string) {
+	if u.passwordHistory == nil {
+		return
+	}
+
+	keep := u.passwords.Policy().History
+	if keep < 1 {
+		keep = 1
+	}
+
+	if err := u.passwordHistory.Add(ctx, userID, hash, keep); err != nil {
+		log.Printf("Failed to save password history for user %d: %v", userID, err)
+	}
+}
+
+// setPassword проверяет и сохраняет новый пароль пользователя
+func (u *AuthUseCase) setPassword(ctx context.Context, user *entity.User, password string) error {
+	if err := u.checkNewPassword(ctx, user.ID, password); err != nil {
+		return err
+	}
+
+	hash, err := u.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	user.Password = hash
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	u.rememberPassword(ctx, user.ID, hash)
+	return nil
+}
+
+// ResetPassword задает пользователю новый пароль и завершает все его сессии
+func (u *AuthUseCase) ResetPassword(ctx context.Context, userID int, password string) error {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := u.setPassword(ctx, user, password); err != nil {
+		return err
+	}
+
+	if err := u.sessionRepo.DeleteAllUserSessions(ctx, userID, revocationCutoff()); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action: entity.AuditPasswordReset,
+		Target: userTarget(userID),
+	})
+
+	return nil
+}
+
+// upgradePasswordHash пересчитывает хэш, созданный с устаревшей стоимостью bcrypt.
+// Вызывается после успешной проверки пароля, ошибки не мешают входу.
+// Пароль не меняется, поэтому в истории заменяется старый хэш, а не добавляется запись.
+func (u *AuthUseCase) upgradePasswordHash(ctx context.Context, user *entity.User, password string) {
+	if !u.passwords.NeedsRehash(user.Password) {
+		return
+	}
+
+	hash, err := u.passwords.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %d: %v", user.ID, err)
+		return
+	}
+
+	oldHash := user.Password
+	user.Password = hash
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		log.Printf("Failed to store upgraded password hash for user %d: %v", user.ID, err)
+		return
+	}
+
+	if u.passwordHistory != nil {
+		if err := u.passwordHistory.Replace(ctx, user.ID, oldHash, hash); err != nil {
+			log.Printf("Failed to update password history for user %d: %v", user.ID, err)
+		}
+	}
+	log.Printf("Upgraded password hash cost for user %d", user.ID)
+}
+
+func comparePassword(hash, password string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+		return ErrInvalidCurrentPassword
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"auth-service/internal/password"
+	"auth-service/internal/repository"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeHistory хранит историю паролей в памяти, от старых к новым
+type fakeHistory struct {
+	repository.PasswordHistoryRepository
+	hashes map[int][]string
+}
+
+func (f *fakeHistory) Add(ctx context.Context, userID int, passwordHash string, keep int) error {
+	f.hashes[userID] = append(f.hashes[userID], passwordHash)
+	return nil
+}
+
+func (f *fakeHistory) Replace(ctx context.Context, userID int, oldHash, newHash string) error {
+	for i, hash := range f.hashes[userID] {
+		if hash == oldHash {
+			f.hashes[userID][i] = newHash
+		}
+	}
+	return nil
+}
+
+func TestUpgradePasswordHash_ReplacesHistoryEntry(t *testing.T) {
+	ctx := context.Background()
+	oldHash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	users := &fakeUsers{users: map[int]*entity.User{1: {ID: 1, Password: string(oldHash)}}}
+	history := &fakeHistory{hashes: map[int][]string{1: {"previous", string(oldHash)}}}
+	passwords := password.NewChecker(password.Policy{BcryptCost: bcrypt.MinCost + 1, History: 5}, nil, 1)
+	uc := NewAuthUseCase(users, nil, nil, nil, history, nil, nil, nil, passwords, &Config{})
+
+	uc.upgradePasswordHash(ctx, users.users[1], "secret-password")
+
+	newHash := users.users[1].Password
+	assert.NotEqual(t, string(oldHash), newHash)
+	cost, err := bcrypt.Cost([]byte(newHash))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, cost)
+
+	// Тот же пароль остается одной записью истории
+	assert.Equal(t, []string{"previous", newHash}, history.hashes[1])
+
+	// Хэш с нужной стоимостью не пересчитывается
+	uc.upgradePasswordHash(ctx, users.users[1], "secret-password")
+	assert.Equal(t, newHash, users.users[1].Password)
+	assert.Len(t, history.hashes[1], 2)
+}
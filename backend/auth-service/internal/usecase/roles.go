@@ -0,0 +1,403 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"backend/pkg/rbac"
+)
+
+var (
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrSystemRole        = errors.New("system role cannot be deleted")
+	ErrImplicitRole      = errors.New("role user is granted to everyone and cannot be assigned")
+	ErrSelfAssign        = errors.New("cannot change your own roles")
+	ErrPermissionNotHeld = errors.New("cannot assign a role with permissions you do not hold")
+)
+
+// GetUserAccess собирает роли и разрешения пользователя. Роль user есть у всех,
+// роли, выданные в категории форума, попадают в Scoped
+func (u *AuthUseCase) GetUserAccess(ctx context.Context, user *entity.User) (rbac.Access, error) {
+	access := rbac.Access{Roles: []string{rbac.RoleUser}}
+
+	var userRoles []*entity.UserRole
+	if u.roleRepo != nil {
+		var err error
+		userRoles, err = u.roleRepo.GetUserRoles(ctx, user.ID)
+		if err != nil {
+			return rbac.Access{}, err
+		}
+
+		base, err := u.roleRepo.GetByName(ctx, rbac.RoleUser)
+		if err == nil {
+			userRoles = append(userRoles, &entity.UserRole{UserID: user.ID, Role: base.Name, Permissions: base.Permissions})
+		}
+	}
+
+	// Пользователи, ставшие админами до появления ролей
+	if user.IsAdmin {
+		userRoles = append(userRoles, &entity.UserRole{UserID: user.ID, Role: rbac.RoleAdmin, Permissions: []string{rbac.All}})
+	}
+
+	roles := map[string]bool{rbac.RoleUser: true}
+	perms := make(map[string]bool)
+	scoped := make(map[string]map[string]bool)
+
+	for _, userRole := range userRoles {
+		if userRole.CategoryID != nil {
+			key := strconv.FormatInt(*userRole.CategoryID, 10)
+			if scoped[key] == nil {
+				scoped[key] = make(map[string]bool)
+			}
+			for _, p := range userRole.Permissions {
+				scoped[key][p] = true
+			}
+			continue
+		}
+
+		if !roles[userRole.Role] {
+			roles[userRole.Role] = true
+			access.Roles = append(access.Roles, userRole.Role)
+		}
+		for _, p := range userRole.Permissions {
+			perms[p] = true
+		}
+	}
+
+	access.Permissions = sortedKeys(perms)
+	if len(scoped) > 0 {
+		access.Scoped = make(map[string][]string, len(scoped))
+		for key, set := range scoped {
+			access.Scoped[key] = sortedKeys(set)
+		}
+	}
+
+	return access, nil
+}
+
+// LoadAccess заполняет Roles и Permissions пользователя
+func (u *AuthUseCase) LoadAccess(ctx context.Context, user *entity.User) (rbac.Access, error) {
+	access, err := u.GetUserAccess(ctx, user)
+	if err != nil {
+		return rbac.Access{}, err
+	}
+
+	user.Roles = access.Roles
+	user.Permissions = access.Permissions
+	return access, nil
+}
+
+func (u *AuthUseCase) GetRoles(ctx context.Context) ([]*entity.Role, error) {
+	return u.roleRepo.GetAll(ctx)
+}
+
+func (u *AuthUseCase) CreateRole(ctx context.Context, input entity.RoleCreate) (*entity.Role, error) {
+	if err := validatePermissions(input.Permissions); err != nil {
+		return nil, err
+	}
+
+	role := &entity.Role{
+		Name:        input.Name,
+		Description: input.Description,
+		Permissions: input.Permissions,
+	}
+
+	if err := u.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action:   entity.AuditRoleCreate,
+		Target:   "role:" + role.Name,
+		Metadata: map[string]interface{}{"permissions": role.Permissions},
+	})
+
+	return role, nil
+}
+
+func (u *AuthUseCase) UpdateRole(ctx context.Context, name string, input entity.RoleUpdate) (*entity.Role, error) {
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Description != nil {
+		role.Description = *input.Description
+	}
+	if input.Permissions != nil {
+		if err := validatePermissions(input.Permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = input.Permissions
+	}
+
+	if err := u.roleRepo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action:   entity.AuditRoleUpdate,
+		Target:   "role:" + role.Name,
+		Metadata: map[string]interface{}{"permissions": role.Permissions},
+	})
+
+	return role, nil
+}
+
+func (u *AuthUseCase) DeleteRole(ctx context.Context, name string) error {
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		return ErrSystemRole
+	}
+
+	if err := u.roleRepo.Delete(ctx, role.ID); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action: entity.AuditRoleDelete,
+		Target: "role:" + role.Name,
+	})
+
+	return nil
+}
+
+func (u *AuthUseCase) GetUserRoles(ctx context.Context, userID int) ([]*entity.UserRole, error) {
+	if _, err := u.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return u.roleRepo.GetUserRoles(ctx, userID)
+}
+
+// AssignRole выдает роль пользователю глобально или в категории форума.
+// actor может выдавать только роли, все разрешения которых есть у него самого
+func (u *AuthUseCase) AssignRole(ctx context.Context, actor *entity.User, userID int, input entity.AssignRoleInput) error {
+	if input.Role == rbac.RoleUser {
+		return ErrImplicitRole
+	}
+	if actor.ID == userID {
+		return ErrSelfAssign
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	role, err := u.roleRepo.GetByName(ctx, input.Role)
+	if err != nil {
+		return err
+	}
+
+	if err := u.checkCanGrant(ctx, actor, role, input.CategoryID); err != nil {
+		return err
+	}
+
+	if err := u.roleRepo.AssignToUser(ctx, userID, role.ID, input.CategoryID); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action:   entity.AuditRoleAssign,
+		Target:   userTarget(userID),
+		Metadata: roleMetadata(role.Name, input.CategoryID),
+	})
+
+	if role.Name == rbac.RoleAdmin && input.CategoryID == nil && !user.IsAdmin {
+		user.IsAdmin = true
+		return u.userRepo.Update(ctx, user)
+	}
+
+	return nil
+}
+
+// RevokeRole отзывает роль у пользователя
+func (u *AuthUseCase) RevokeRole(ctx context.Context, userID int, name string, categoryID *int64) error {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if role.Name == rbac.RoleAdmin && categoryID == nil {
+		if err := u.ensureNotLastAdmin(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	if err := u.roleRepo.RevokeFromUser(ctx, userID, role.ID, categoryID); err != nil {
+		return err
+	}
+
+	u.audit(ctx, entity.AuditEvent{
+		Action:   entity.AuditRoleRevoke,
+		Target:   userTarget(userID),
+		Metadata: roleMetadata(role.Name, categoryID),
+	})
+
+	if role.Name == rbac.RoleAdmin && categoryID == nil && user.IsAdmin {
+		user.IsAdmin = false
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return err
+		}
+	}
+
+	return u.revokeTokens(ctx, userID)
+}
+
+// revokeTokens завершает сессии пользователя и отзывает его токены после снятия роли:
+// форум и чат проверяют разрешения из токена, и до истечения он давал бы снятые права
+func (u *AuthUseCase) revokeTokens(ctx context.Context, userID int) error {
+	return u.sessionRepo.DeleteAllUserSessions(ctx, userID, revocationCutoff())
+}
+
+// UpdateUserRole заменяет глобальные роли пользователя одной ролью.
+// Без Role переключает только роль admin по флагу IsAdmin
+func (u *AuthUseCase) UpdateUserRole(ctx context.Context, actor *entity.User, userID int, input entity.UpdateRoleInput) error {
+	if actor.ID == userID {
+		return ErrSelfAssign
+	}
+
+	if input.Role == "" {
+		if input.IsAdmin {
+			return u.AssignRole(ctx, actor, userID, entity.AssignRoleInput{Role: rbac.RoleAdmin})
+		}
+		return u.revokeIfAssigned(ctx, userID, rbac.RoleAdmin)
+	}
+
+	if input.Role != rbac.RoleUser {
+		role, err := u.roleRepo.GetByName(ctx, input.Role)
+		if err != nil {
+			return err
+		}
+		// Проверяем до снятия текущих ролей, чтобы отказ не оставил пользователя без них
+		if err := u.checkCanGrant(ctx, actor, role, nil); err != nil {
+			return err
+		}
+	}
+
+	current, err := u.roleRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, userRole := range current {
+		if userRole.CategoryID != nil || userRole.Role == input.Role {
+			continue
+		}
+		if err := u.RevokeRole(ctx, userID, userRole.Role, nil); err != nil {
+			return err
+		}
+	}
+
+	if input.Role == rbac.RoleUser {
+		// Админ без записи в user_roles (выдан до появления ролей)
+		return u.revokeIfAssigned(ctx, userID, rbac.RoleAdmin)
+	}
+
+	return u.AssignRole(ctx, actor, userID, entity.AssignRoleInput{Role: input.Role})
+}
+
+// checkCanGrant разрешает выдать роль, только если все ее разрешения есть у actor
+// глобально или в категории выдачи. Роль с разрешением * (admin) может выдать только обладатель *
+func (u *AuthUseCase) checkCanGrant(ctx context.Context, actor *entity.User, role *entity.Role, categoryID *int64) error {
+	access, err := u.GetUserAccess(ctx, actor)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range role.Permissions {
+		held := access.Has(p)
+		if !held && categoryID != nil {
+			held = access.HasIn(p, *categoryID)
+		}
+		if !held {
+			return fmt.Errorf("%w: %s", ErrPermissionNotHeld, p)
+		}
+	}
+	return nil
+}
+
+func (u *AuthUseCase) revokeIfAssigned(ctx context.Context, userID int, name string) error {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = u.RevokeRole(ctx, userID, name, nil)
+	if err != nil && err.Error() == "role is not assigned" {
+		if name == rbac.RoleAdmin && user.IsAdmin {
+			user.IsAdmin = false
+			if err := u.userRepo.Update(ctx, user); err != nil {
+				return err
+			}
+			return u.revokeTokens(ctx, userID)
+		}
+		return nil
+	}
+
+	return err
+}
+
+// ensureNotLastAdmin не дает снять роль admin с последнего администратора
+func (u *AuthUseCase) ensureNotLastAdmin(ctx context.Context, user *entity.User) error {
+	if !user.IsAdmin {
+		return nil
+	}
+
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	adminCount := 0
+	for _, other := range users {
+		if other.IsAdmin {
+			adminCount++
+		}
+	}
+	if adminCount <= 1 {
+		return errors.New("cannot remove the last admin user")
+	}
+
+	return nil
+}
+
+func roleMetadata(role string, categoryID *int64) map[string]interface{} {
+	metadata := map[string]interface{}{"role": role}
+	if categoryID != nil {
+		metadata["category_id"] = *categoryID
+	}
+	return metadata
+}
+
+func validatePermissions(perms []string) error {
+	for _, p := range perms {
+		if !rbac.IsKnown(p) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, p)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"auth-service/internal/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/pkg/rbac"
+)
+
+// fakeUsers хранит пользователей в памяти
+type fakeUsers struct {
+	repository.UserRepository
+	users map[int]*entity.User
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int) (*entity.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+func (f *fakeUsers) GetAll(ctx context.Context) ([]*entity.User, error) {
+	users := make([]*entity.User, 0, len(f.users))
+	for _, user := range f.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (f *fakeUsers) Update(ctx context.Context, user *entity.User) error {
+	f.users[user.ID] = user
+	return nil
+}
+
+// fakeRoles хранит роли и их назначения в памяти
+type fakeRoles struct {
+	repository.RoleRepository
+	roles    map[string]*entity.Role
+	assigned map[int][]*entity.UserRole
+}
+
+func (f *fakeRoles) GetByName(ctx context.Context, name string) (*entity.Role, error) {
+	role, ok := f.roles[name]
+	if !ok {
+		return nil, errors.New("role not found")
+	}
+	return role, nil
+}
+
+func (f *fakeRoles) GetUserRoles(ctx context.Context, userID int) ([]*entity.UserRole, error) {
+	return f.assigned[userID], nil
+}
+
+func (f *fakeRoles) AssignToUser(ctx context.Context, userID, roleID int, categoryID *int64) error {
+	for _, role := range f.roles {
+		if role.ID == roleID {
+			f.assigned[userID] = append(f.assigned[userID], &entity.UserRole{
+				UserID:      userID,
+				Role:        role.Name,
+				CategoryID:  categoryID,
+				Permissions: role.Permissions,
+			})
+			return nil
+		}
+	}
+	return errors.New("role not found")
+}
+
+func (f *fakeRoles) RevokeFromUser(ctx context.Context, userID, roleID int, categoryID *int64) error {
+	for i, userRole := range f.assigned[userID] {
+		if f.roles[userRole.Role].ID == roleID && (userRole.CategoryID == nil) == (categoryID == nil) {
+			f.assigned[userID] = append(f.assigned[userID][:i], f.assigned[userID][i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("role is not assigned")
+}
+
+func newRolesUseCase() (*AuthUseCase, *fakeUsers, *fakeRoles) {
+	users := &fakeUsers{users: map[int]*entity.User{
+		1: {ID: 1, Username: "admin", IsAdmin: true},
+		2: {ID: 2, Username: "moderator"},
+		3: {ID: 3, Username: "user"},
+		4: {ID: 4, Username: "category moderator"},
+	}}
+	roles := &fakeRoles{
+		roles: map[string]*entity.Role{
+			rbac.RoleUser:      {ID: 1, Name: rbac.RoleUser, Permissions: []string{rbac.PostCreate}},
+			rbac.RoleAdmin:     {ID: 2, Name: rbac.RoleAdmin, Permissions: []string{rbac.All}},
+			rbac.RoleModerator: {ID: 3, Name: rbac.RoleModerator, Permissions: []string{rbac.PostEditAny, rbac.RoleAssign}},
+			"editor":           {ID: 4, Name: "editor", Permissions: []string{rbac.PostEditAny}},
+		},
+		assigned: map[int][]*entity.UserRole{},
+	}
+	sessions := &fakeSessions{revokedAt: map[int]time.Time{}}
+	uc := NewAuthUseCase(users, sessions, roles, nil, nil, nil, nil, nil, nil, &Config{})
+
+	category := int64(7)
+	roles.assigned[2] = []*entity.UserRole{{UserID: 2, Role: rbac.RoleModerator, Permissions: roles.roles[rbac.RoleModerator].Permissions}}
+	roles.assigned[4] = []*entity.UserRole{{UserID: 4, Role: rbac.RoleModerator, CategoryID: &category, Permissions: roles.roles[rbac.RoleModerator].Permissions}}
+	return uc, users, roles
+}
+
+func TestAssignRole_RequiresHeldPermissions(t *testing.T) {
+	ctx := context.Background()
+	uc, users, roles := newRolesUseCase()
+	moderator := users.users[2]
+
+	// Модератор с role.assign не может выдать admin и роли с разрешениями, которых у него нет
+	err := uc.AssignRole(ctx, moderator, 3, entity.AssignRoleInput{Role: rbac.RoleAdmin})
+	assert.ErrorIs(t, err, ErrPermissionNotHeld)
+	assert.False(t, users.users[3].IsAdmin)
+	assert.Empty(t, roles.assigned[3])
+
+	require.NoError(t, uc.AssignRole(ctx, moderator, 3, entity.AssignRoleInput{Role: "editor"}))
+	require.Len(t, roles.assigned[3], 1)
+	assert.Equal(t, "editor", roles.assigned[3][0].Role)
+
+	// Обладатель * может выдать admin
+	require.NoError(t, uc.AssignRole(ctx, users.users[1], 3, entity.AssignRoleInput{Role: rbac.RoleAdmin}))
+	assert.True(t, users.users[3].IsAdmin)
+}
+
+func TestAssignRole_ScopedPermissions(t *testing.T) {
+	ctx := context.Background()
+	uc, users, _ := newRolesUseCase()
+	categoryModerator := users.users[4]
+
+	// Разрешения в категории позволяют выдавать роли только в этой категории
+	category := int64(7)
+	require.NoError(t, uc.AssignRole(ctx, categoryModerator, 3, entity.AssignRoleInput{Role: "editor", CategoryID: &category}))
+
+	other := int64(8)
+	assert.ErrorIs(t, uc.AssignRole(ctx, categoryModerator, 3, entity.AssignRoleInput{Role: "editor", CategoryID: &other}), ErrPermissionNotHeld)
+	assert.ErrorIs(t, uc.AssignRole(ctx, categoryModerator, 3, entity.AssignRoleInput{Role: "editor"}), ErrPermissionNotHeld)
+}
+
+func TestAssignRole_Self(t *testing.T) {
+	ctx := context.Background()
+	uc, users, roles := newRolesUseCase()
+	moderator := users.users[2]
+
+	assert.ErrorIs(t, uc.AssignRole(ctx, moderator, moderator.ID, entity.AssignRoleInput{Role: "editor"}), ErrSelfAssign)
+	assert.ErrorIs(t, uc.UpdateUserRole(ctx, moderator, moderator.ID, entity.UpdateRoleInput{IsAdmin: true}), ErrSelfAssign)
+	assert.Len(t, roles.assigned[2], 1)
+}
+
+func TestUpdateUserRole_RefusedKeepsRoles(t *testing.T) {
+	ctx := context.Background()
+	uc, users, roles := newRolesUseCase()
+	require.NoError(t, uc.AssignRole(ctx, users.users[1], 3, entity.AssignRoleInput{Role: "editor"}))
+
+	// Проверка выполняется до снятия текущих ролей
+	err := uc.UpdateUserRole(ctx, users.users[2], 3, entity.UpdateRoleInput{Role: rbac.RoleAdmin})
+	assert.ErrorIs(t, err, ErrPermissionNotHeld)
+	require.Len(t, roles.assigned[3], 1)
+	assert.Equal(t, "editor", roles.assigned[3][0].Role)
+
+	err = uc.UpdateUserRole(ctx, users.users[2], 3, entity.UpdateRoleInput{IsAdmin: true})
+	assert.ErrorIs(t, err, ErrPermissionNotHeld)
+	assert.False(t, users.users[3].IsAdmin)
+}
+
+func TestRevokeRole_RevokesTokens(t *testing.T) {
+	ctx := context.Background()
+	uc, users, roles := newRolesUseCase()
+	sessions := uc.sessionRepo.(*fakeSessions)
+
+	// Выдача роли токены не отзывает, отказ в понижении тоже
+	require.NoError(t, uc.AssignRole(ctx, users.users[1], 3, entity.AssignRoleInput{Role: "editor"}))
+	assert.ErrorIs(t, uc.UpdateUserRole(ctx, users.users[2], 3, entity.UpdateRoleInput{Role: rbac.RoleAdmin}), ErrPermissionNotHeld)
+	assert.Empty(t, sessions.revokedAt)
+
+	// Снятая роль остается в выпущенных токенах, поэтому они отзываются
+	require.NoError(t, uc.RevokeRole(ctx, 2, rbac.RoleModerator, nil))
+	assert.Empty(t, roles.assigned[2])
+	assert.Contains(t, sessions.revokedAt, 2)
+
+	require.NoError(t, uc.UpdateUserRole(ctx, users.users[1], 3, entity.UpdateRoleInput{Role: rbac.RoleUser}))
+	assert.Empty(t, roles.assigned[3])
+	assert.Contains(t, sessions.revokedAt, 3)
+	assert.NotContains(t, sessions.revokedAt, 1)
+}
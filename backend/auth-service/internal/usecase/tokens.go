@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"context"
+	"errors"
+	"time"
+
+	sharedjwt "backend/pkg/jwt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ErrInvalidToken токен не подписан сервисом, истек или его сессия завершена
+var ErrInvalidToken = errors.New("invalid token")
+
+// ValidateToken проверяет токен для других сервисов: подпись и активную сессию.
+// Возвращает владельца с текущими ролями и разрешениями и срок действия токена.
+// Ошибки хранилища возвращаются как есть, чтобы их не приняли за недействительный токен.
+func (u *AuthUseCase) ValidateToken(ctx context.Context, token string) (*entity.User, time.Time, error) {
+	if _, err := u.tokenManager.Parse(token); err != nil {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+
+	session, err := u.sessionRepo.GetByAccessToken(ctx, token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if !session.IsActive || time.Now().After(session.AccessExpires) {
+		return nil, time.Time{}, ErrInvalidToken
+	}
+
+	user, err := u.userRepo.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if _, err := u.LoadAccess(ctx, user); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	return user, session.AccessExpires, nil
+}
+
+// revocationCutoff время отзыва токенов для записи в token_revocations. Берется по часам
+// сервиса, который подписывает токены, и округляется вниз до микросекунды, как время выпуска в токене
+func revocationCutoff() time.Time {
+	return sharedjwt.RevocationCutoff(time.Now())
+}
+
+// Revocations возвращает отзывы токенов после since. Отзывы старше срока жизни токена
+// не возвращаются: выпущенные до них токены уже истекли.
+func (u *AuthUseCase) Revocations(ctx context.Context, since time.Time) ([]entity.Revocation, error) {
+	if oldest := time.Now().Add(-u.config.AccessTokenTTL); since.Before(oldest) {
+		since = oldest
+	}
+	return u.sessionRepo.ListRevocations(ctx, since)
+}
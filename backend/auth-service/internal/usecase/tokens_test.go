@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"auth-service/internal/entity"
+	"auth-service/internal/repository"
+	"auth-service/pkg/jwt"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sharedjwt "backend/pkg/jwt"
+)
+
+// fakeSessions хранит сессии и время последнего отзыва токенов в памяти
+type fakeSessions struct {
+	repository.ISessionRepository
+	sessions  []entity.Session
+	revokedAt map[int]time.Time
+}
+
+func (f *fakeSessions) Create(ctx context.Context, session entity.Session) (int, error) {
+	f.sessions = append(f.sessions, session)
+	return len(f.sessions), nil
+}
+
+func (f *fakeSessions) DeleteAllUserSessions(ctx context.Context, userID int, revokedAt time.Time) error {
+	f.revokedAt[userID] = revokedAt
+	return nil
+}
+
+func TestCreateSession_RevokesBeforeSigning(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUsers{users: map[int]*entity.User{1: {ID: 1, Username: "alice"}}}
+	sessions := &fakeSessions{revokedAt: map[int]time.Time{}}
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	uc := NewAuthUseCase(users, sessions, nil, nil, nil, tokens, nil, nil, nil, &Config{AccessTokenTTL: time.Hour, RefreshTokenTTL: time.Hour})
+
+	manager, err := sharedjwt.NewManager("secret")
+	require.NoError(t, err)
+
+	// Повторные входы подряд: каждый новый токен выпущен не раньше записанного отзыва
+	for i := 0; i < 20; i++ {
+		result, err := uc.createSession(ctx, users.users[1], "test", "127.0.0.1")
+		require.NoError(t, err)
+
+		claims, err := manager.ParseClaims(result.AccessToken)
+		require.NoError(t, err)
+		cutoff := sessions.revokedAt[1]
+		assert.Equal(t, cutoff, sharedjwt.RevocationCutoff(cutoff), "cutoff has microsecond precision")
+		assert.False(t, claims.IssuedTime().Before(cutoff), "token issued at %s is revoked by %s", claims.IssuedTime(), cutoff)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestTerminateUserSessions_RevokesTokenOfSameSecond(t *testing.T) {
+	ctx := context.Background()
+	users := &fakeUsers{users: map[int]*entity.User{1: {ID: 1, Username: "alice"}}}
+	sessions := &fakeSessions{revokedAt: map[int]time.Time{}}
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	uc := NewAuthUseCase(users, sessions, nil, nil, nil, tokens, nil, nil, nil, &Config{AccessTokenTTL: time.Hour, RefreshTokenTTL: time.Hour})
+
+	manager, err := sharedjwt.NewManager("secret")
+	require.NoError(t, err)
+
+	result, err := uc.createSession(ctx, users.users[1], "test", "127.0.0.1")
+	require.NoError(t, err)
+	claims, err := manager.ParseClaims(result.AccessToken)
+	require.NoError(t, err)
+
+	// Завершение сессий сразу после входа, обычно в ту же секунду, отзывает выданный токен
+	time.Sleep(time.Millisecond)
+	require.NoError(t, uc.TerminateUserSessions(ctx, 1))
+	assert.True(t, claims.IssuedTime().Before(sessions.revokedAt[1]), "token issued at %s is not revoked by %s", claims.IssuedTime(), sessions.revokedAt[1])
+}
@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    permissions TEXT[] NOT NULL DEFAULT '{}',
+    is_system BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- category_id NULL означает глобальную роль, иначе роль действует только в категории форума
+CREATE TABLE IF NOT EXISTS user_roles (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    category_id BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_roles_unique_idx ON user_roles(user_id, role_id, COALESCE(category_id, 0));
+CREATE INDEX IF NOT EXISTS user_roles_user_id_idx ON user_roles(user_id);
+
+-- Встроенные роли. Роль user выдается всем пользователям неявно
+INSERT INTO roles (name, description, permissions, is_system) VALUES
+    ('user', 'Обычный пользователь', ARRAY['post.create', 'reply.create', 'chat.send'], true),
+    ('moderator', 'Модератор форума и чата', ARRAY[
+        'post.create', 'reply.create', 'chat.send',
+        'post.edit.any', 'post.delete.any', 'reply.delete.any',
+        'chat.mute', 'chat.kick', 'chat.ban', 'chat.message.delete.any', 'chat.slowmode',
+        'user.view', 'user.block'
+    ], true),
+    ('admin', 'Администратор', ARRAY['*'], true)
+ON CONFLICT (name) DO NOTHING;
+
+-- Переносим существующих администраторов
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM users u, roles r
+WHERE u.is_admin AND r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS password_history_user_id_idx ON password_history(user_id, created_at DESC);
+
+-- Текущие пароли становятся первой записью истории
+INSERT INTO password_history (user_id, password_hash, created_at)
+SELECT id, password_hash, updated_at FROM users;
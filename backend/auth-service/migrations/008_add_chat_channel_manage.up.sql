@@ -0,0 +1,4 @@
+-- Модераторы управляют участниками каналов чата
+UPDATE roles
+SET permissions = array_append(permissions, 'chat.channel.manage')
+WHERE name = 'moderator' AND NOT ('chat.channel.manage' = ANY(permissions));
@@ -0,0 +1,3 @@
+UPDATE roles
+SET permissions = array_remove(permissions, 'chat.message.edit.any')
+WHERE name = 'moderator';
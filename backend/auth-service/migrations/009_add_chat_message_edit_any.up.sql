@@ -0,0 +1,4 @@
+-- Модераторы могут редактировать чужие сообщения чата
+UPDATE roles
+SET permissions = array_append(permissions, 'chat.message.edit.any')
+WHERE name = 'moderator' AND NOT ('chat.message.edit.any' = ANY(permissions));
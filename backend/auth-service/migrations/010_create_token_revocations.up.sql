@@ -0,0 +1,8 @@
+-- Время последнего завершения сессий пользователя. Токены, выпущенные раньше,
+-- недействительны: сервисы, проверяющие подпись токена сами, узнают об этом отсюда.
+CREATE TABLE IF NOT EXISTS token_revocations (
+    user_id INTEGER PRIMARY KEY,
+    revoked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_token_revocations_revoked_at ON token_revocations(revoked_at);
@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"time"
 
+	sharedjwt "backend/pkg/jwt"
+	"backend/pkg/rbac"
+
 	"github.com/golang-jwt/jwt/v4"
 )
 
 type TokenManager interface {
 	NewJWT(userId string, ttl time.Duration) (string, error)
+	NewAccessToken(userId, username string, access rbac.Access, ttl time.Duration) (string, error)
 	Parse(accessToken string) (string, error)
 	NewRefreshToken() (string, error)
 }
@@ -27,10 +31,24 @@ func NewManager(signingKey string) (*Manager, error) {
 }
 
 func (m *Manager) NewJWT(userId string, ttl time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
-		Subject:   userId,
-	})
+	return m.NewAccessToken(userId, "", rbac.Access{}, ttl)
+}
+
+// NewAccessToken создает токен с именем пользователя, ролями и разрешениями,
+// чтобы форум и чат могли проверять права без запроса к auth-service
+func (m *Manager) NewAccessToken(userId, username string, access rbac.Access, ttl time.Duration) (string, error) {
+	// Время выпуска нужно сервисам, чтобы отбрасывать токены завершенных сессий
+	now := time.Now()
+	claims := sharedjwt.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			Subject:   userId,
+		},
+		Username: username,
+		Access:   access,
+	}
+	claims.SetIssuedTime(now)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(m.signingKey))
 }
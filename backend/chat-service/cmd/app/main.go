@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,11 +12,21 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 
+	"backend/chat-service/internal/auth"
+	"backend/chat-service/internal/broker"
 	"backend/chat-service/internal/config"
+	grpcdelivery "backend/chat-service/internal/delivery/grpc"
 	"backend/chat-service/internal/delivery/websocket"
 	"backend/chat-service/internal/repository"
 	"backend/chat-service/internal/usecase"
+	pb "backend/chat-service/proto"
+	"backend/pkg/contentfilter"
+	"backend/pkg/jwt"
+	"backend/pkg/ratelimit"
+	"backend/pkg/unfurl"
 )
 
 func main() {
@@ -37,7 +48,8 @@ func main() {
 		zap.String("db_port", cfg.Database.Port),
 		zap.String("db_name", cfg.Database.DBName),
 		zap.String("db_user", cfg.Database.User),
-		zap.String("http_port", cfg.Server.Port))
+		zap.String("http_port", cfg.Server.Port),
+		zap.String("grpc_port", cfg.Server.GRPCPort))
 
 	// Подключение к базе данных
 	ctx := context.Background()
@@ -71,8 +83,106 @@ func main() {
 
 	// Инициализация слоев
 	messageRepo := repository.NewMessageRepository(pool)
-	chatUseCase := usecase.NewChatUseCase(messageRepo, pool)
-	wsHandler := websocket.NewHandler(chatUseCase, logger)
+	rules, err := ratelimit.ParseRules(cfg.RateLimit.Rules)
+	if err != nil {
+		logger.Fatal("Invalid rate limit rules", zap.Error(err))
+	}
+	channelRepo := repository.NewChannelRepository(pool)
+
+	// Билеты WebSocket должны быть видны всем экземплярам, между которыми ходят события
+	var eventBroker broker.Broker
+	var tickets auth.Tickets
+	switch cfg.Broker.Type {
+	case "postgres":
+		eventBroker, err = broker.NewPostgres(ctx, pool, cfg.Broker.Channel, 256)
+		if err != nil {
+			logger.Fatal("Failed to start PostgreSQL broker", zap.Error(err))
+		}
+		tickets = auth.NewPostgresTickets(pool, cfg.Auth.TicketTTL)
+	case "memory", "":
+		eventBroker = broker.NewInProcess(256)
+		tickets = auth.NewInProcessTickets(cfg.Auth.TicketTTL)
+	default:
+		logger.Fatal("Unknown broker type", zap.String("broker", cfg.Broker.Type))
+	}
+	defer eventBroker.Close()
+	logger.Info("Using event broker", zap.String("broker", cfg.Broker.Type))
+
+	overflow, err := usecase.ParseOverflowPolicy(cfg.WebSocket.OverflowPolicy)
+	if err != nil {
+		logger.Fatal("Invalid WebSocket overflow policy", zap.Error(err))
+	}
+
+	moderationRepo := repository.NewModerationRepository(pool)
+	filter, err := contentfilter.Load(cfg.ContentFilter.Path, cfg.ContentFilter.DryRun, moderationRepo)
+	if err != nil {
+		logger.Fatal("Invalid content filter config", zap.Error(err))
+	}
+	if filter != nil {
+		logger.Info("Content filter enabled",
+			zap.String("config", cfg.ContentFilter.Path), zap.Bool("dry_run", filter.DryRun()))
+	}
+
+	var unfurler *unfurl.Unfurler
+	if cfg.LinkPreview.Enabled {
+		unfurler = unfurl.New(unfurl.NewPostgresCache(pool), unfurl.Options{
+			Timeout:     cfg.LinkPreview.Timeout,
+			MaxBodySize: cfg.LinkPreview.MaxBodySize,
+		})
+	}
+
+	// Токены проверяются локально, сервис авторизации нужен для списка отзывов
+	// и токенов, которые нельзя проверить без него
+	authConn, err := grpc.Dial(cfg.Auth.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logger.Fatal("Failed to connect to auth service", zap.Error(err))
+	}
+	defer authConn.Close()
+
+	var tokenManager *jwt.Manager
+	if cfg.Auth.JWTSecret != "" {
+		tokenManager, err = jwt.NewManager(cfg.Auth.JWTSecret)
+		if err != nil {
+			logger.Fatal("Failed to create token manager", zap.Error(err))
+		}
+	}
+	authClient := auth.NewVerifier(auth.VerifierConfig{
+		Tokens:          tokenManager,
+		Remote:          auth.NewGRPCClient(authConn),
+		Timeout:         cfg.Auth.Timeout,
+		CacheTTL:        cfg.Auth.CacheTTL,
+		PollInterval:    cfg.Auth.RevocationPoll,
+		BreakerFailures: cfg.Auth.BreakerFailures,
+		BreakerCooldown: cfg.Auth.BreakerCooldown,
+	})
+	revocationsCtx, stopRevocations := context.WithCancel(ctx)
+	defer stopRevocations()
+	go authClient.Run(revocationsCtx)
+
+	chatUseCase := usecase.NewChatUseCase(messageRepo, pool,
+		usecase.WithAuth(authClient),
+		usecase.WithRateLimiter(ratelimit.New(rules)),
+		usecase.WithChannelRepository(channelRepo),
+		usecase.WithBroker(eventBroker),
+		usecase.WithModeration(moderationRepo),
+		usecase.WithContentFilter(filter),
+		usecase.WithUnfurler(unfurler),
+		usecase.WithHubConfig(usecase.HubConfig{
+			QueueSize:      cfg.WebSocket.QueueSize,
+			Overflow:       overflow,
+			PongWait:       cfg.WebSocket.PongWait,
+			WriteWait:      cfg.WebSocket.WriteWait,
+			MaxMessageSize: cfg.WebSocket.MaxMessageSize,
+
+			Compression:          cfg.WebSocket.Compression,
+			CompressionLevel:     cfg.WebSocket.CompressionLevel,
+			CompressionThreshold: cfg.WebSocket.CompressionThreshold,
+		}),
+	)
+	wsHandler := websocket.NewHandler(chatUseCase, logger,
+		websocket.WithValidator(authClient),
+		websocket.WithTickets(tickets),
+	)
 
 	// Настройка маршрутизации
 	router := mux.NewRouter()
@@ -87,6 +197,19 @@ func main() {
 		Handler: router,
 	}
 
+	// Настройка gRPC сервера
+	grpcHandler := grpcdelivery.NewServer(chatUseCase, authClient, logger)
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(grpcHandler.UnaryInterceptor),
+		grpc.StreamInterceptor(grpcHandler.StreamInterceptor),
+	)
+	pb.RegisterChatServiceServer(grpcServer, grpcHandler)
+
+	grpcListener, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+	if err != nil {
+		logger.Fatal("Failed to listen gRPC port", zap.Error(err))
+	}
+
 	// Канал для graceful shutdown
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -98,6 +221,13 @@ func main() {
 		}
 	}()
 
+	go func() {
+		logger.Info("Starting gRPC server", zap.String("port", cfg.Server.GRPCPort))
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			logger.Fatal("Failed to start gRPC server", zap.Error(err))
+		}
+	}()
+
 	// Ожидание сигнала завершения
 	<-done
 	logger.Info("Server stopping")
@@ -110,5 +240,20 @@ func main() {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Потоки Subscribe не завершаются сами, поэтому по таймауту закрываем их принудительно
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
+	// Сохраняем указатели прочитанного, накопленные в памяти
+	chatUseCase.Close()
+
 	logger.Info("Server stopped gracefully")
 }
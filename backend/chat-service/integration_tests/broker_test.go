@@ -0,0 +1,99 @@
+package integration_tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/broker"
+)
+
+// TestPostgresBroker_TwoInstances проверяет, что события доходят до обоих экземпляров
+// сервиса, подключенных к одной базе, в одинаковом порядке
+func TestPostgresBroker_TwoInstances(t *testing.T) {
+	if os.Getenv("CI") != "" {
+		t.Skip("Skipping integration test in CI environment")
+	}
+
+	pool := connectBrokerDB(t)
+	defer pool.Close()
+
+	ctx := context.Background()
+	channel := fmt.Sprintf("chat_events_test_%d", time.Now().UnixNano())
+
+	first, err := broker.NewPostgres(ctx, pool, channel, 16)
+	require.NoError(t, err)
+	defer first.Close()
+
+	second, err := broker.NewPostgres(ctx, pool, channel, 16)
+	require.NoError(t, err)
+	defer second.Close()
+
+	// Публикуют оба экземпляра, одно событие не помещается в NOTIFY
+	large := json.RawMessage(`"` + strings.Repeat("x", 10000) + `"`)
+	require.NoError(t, first.Publish(ctx, broker.Event{Type: broker.EventDeliver, ChannelID: 1}))
+	require.NoError(t, second.Publish(ctx, broker.Event{Type: broker.EventDeliver, ChannelID: 2}))
+	require.NoError(t, first.Publish(ctx, broker.Event{Type: broker.EventDeliver, ChannelID: 3, Payload: large}))
+
+	for _, b := range []*broker.Postgres{first, second} {
+		for _, want := range []int64{1, 2, 3} {
+			select {
+			case event := <-b.Events():
+				assert.Equal(t, want, event.ChannelID)
+				if want == 3 {
+					assert.Equal(t, large, event.Payload)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("event %d was not delivered", want)
+			}
+		}
+	}
+}
+
+func connectBrokerDB(t *testing.T) *pgxpool.Pool {
+	host, port, name, user, password := testDBHost, testDBPort, testDBName, testDBUser, testDBPassword
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "5432"
+	}
+	if name == "" {
+		name = "chat_test"
+	}
+	if user == "" {
+		user = "postgres"
+	}
+	if password == "" {
+		password = "postgres"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pool, err := pgxpool.Connect(ctx, fmt.Sprintf("postgres://%s:%s@%s:%s/%s", user, password, host, port, name))
+	if err == nil {
+		err = pool.Ping(ctx)
+	}
+	if err != nil {
+		t.Skipf("Skipping broker test, database is unavailable: %v", err)
+	}
+
+	_, err = pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS chat_broker_events (
+			id BIGSERIAL PRIMARY KEY,
+			payload BYTEA NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`)
+	require.NoError(t, err)
+
+	return pool
+}
@@ -57,15 +57,29 @@ func setupTestDB(t *testing.T) (repository.MessageRepository, func()) {
 
 	// Create messages table
 	_, err = pool.Exec(context.Background(), `
+		CREATE TABLE IF NOT EXISTS channels (
+			id BIGSERIAL PRIMARY KEY,
+			name VARCHAR(64) NOT NULL,
+			last_seq BIGINT NOT NULL DEFAULT 0
+		);
+		INSERT INTO channels (id, name) VALUES (1, 'general') ON CONFLICT (id) DO NOTHING;
+
 		CREATE TABLE IF NOT EXISTS messages (
-			id TEXT PRIMARY KEY,
+			id BIGSERIAL PRIMARY KEY,
+			channel_id BIGINT NOT NULL DEFAULT 1,
+			seq BIGINT NOT NULL,
+			temp_id VARCHAR(64),
 			content TEXT NOT NULL,
 			user_id BIGINT NOT NULL,
 			username VARCHAR(255) NOT NULL,
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			edited_at TIMESTAMP WITH TIME ZONE,
+			deleted_at TIMESTAMP WITH TIME ZONE,
+			deleted_by BIGINT
 		);
-		
+
+		CREATE UNIQUE INDEX IF NOT EXISTS messages_user_id_temp_id_idx ON messages(user_id, temp_id) WHERE temp_id IS NOT NULL;
 		CREATE INDEX IF NOT EXISTS messages_created_at_idx ON messages(created_at DESC);
 		CREATE INDEX IF NOT EXISTS messages_user_id_idx ON messages(user_id);
 	`)
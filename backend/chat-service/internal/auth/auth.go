@@ -0,0 +1,103 @@
+// Package auth проверяет токены пользователей в сервисе авторизации
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"backend/pkg/jwt"
+	"backend/pkg/rbac"
+)
+
+// ErrInvalidToken сервис авторизации не принял токен
+var ErrInvalidToken = errors.New("invalid token")
+
+// User профиль, который возвращает эндпоинт /api/me сервиса авторизации
+type User struct {
+	ID          int64    `json:"id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	// Scoped разрешения в отдельных категориях, есть только у токенов, проверенных локально
+	Scoped map[string][]string `json:"scoped_permissions,omitempty"`
+	// ExpiresAt когда истекает проверенный токен, нулевое — срок неизвестен
+	ExpiresAt time.Time `json:"-"`
+	// IssuedAt когда выпущен проверенный токен, нужно для сверки с отзывами
+	IssuedAt time.Time `json:"-"`
+}
+
+// Access возвращает роли и разрешения пользователя
+func (u *User) Access() rbac.Access {
+	return rbac.Access{Roles: u.Roles, Permissions: u.Permissions, Scoped: u.Scoped}
+}
+
+// Validator проверяет токен и возвращает его владельца
+type Validator interface {
+	Validate(ctx context.Context, token string) (*User, error)
+}
+
+// Client проверяет токены через HTTP API сервиса авторизации. Каждая проверка — запрос
+// к сервису, поэтому для соединений чата используется Verifier.
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient создает клиент сервиса авторизации. Если baseURL пустой, адрес берется из
+// AUTH_SERVICE_URL при каждом запросе.
+func NewClient(baseURL string) *Client {
+	return &Client{baseURL: baseURL, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (c *Client) url() string {
+	if c.baseURL != "" {
+		return c.baseURL
+	}
+	if url := os.Getenv("AUTH_SERVICE_URL"); url != "" {
+		return url
+	}
+	return "http://localhost:8081"
+}
+
+// Validate проверяет токен в сервисе авторизации и возвращает пользователя
+func (c *Client) Validate(ctx context.Context, token string) (*User, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.url()+"/api/me", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, ErrInvalidToken
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth service returned status %d", resp.StatusCode)
+	}
+
+	var user User
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, ErrInvalidToken
+	}
+	// Токен уже проверен сервисом авторизации, из него нужны только сроки
+	if claims, err := jwt.ParseUnverified(token); err == nil {
+		user.ExpiresAt = claims.ExpiresTime()
+		user.IssuedAt = claims.IssuedTime()
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen сервис авторизации недавно не отвечал, запросы к нему временно не отправляются
+var ErrCircuitOpen = errors.New("auth service circuit is open")
+
+// Breaker размыкает цепь после failures ошибок подряд и cooldown не пропускает запросы.
+// Затем пропускает один пробный запрос: успех замыкает цепь, ошибка снова размыкает.
+type Breaker struct {
+	failures int
+	cooldown time.Duration
+
+	mu          sync.Mutex
+	consecutive int
+	openedAt    time.Time
+	probing     bool
+}
+
+// NewBreaker создает размыкатель цепи
+func NewBreaker(failures int, cooldown time.Duration) *Breaker {
+	if failures <= 0 {
+		failures = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 30 * time.Second
+	}
+	return &Breaker{failures: failures, cooldown: cooldown}
+}
+
+// Do выполняет fn, если цепь замкнута, и учитывает результат. При разомкнутой цепи
+// сразу возвращает ErrCircuitOpen.
+func (b *Breaker) Do(fn func() error) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// Open сообщает, что цепь разомкнута
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.consecutive >= b.failures
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch {
+	case b.consecutive < b.failures:
+		return true
+	case b.probing || time.Since(b.openedAt) < b.cooldown:
+		return false
+	default:
+		b.probing = true
+		return true
+	}
+}
+
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if err == nil {
+		b.consecutive = 0
+		return
+	}
+	b.consecutive++
+	if b.consecutive >= b.failures {
+		b.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCachedTokens ограничивает размер кэша проверенных токенов
+const maxCachedTokens = 10000
+
+// tokenCache результаты проверки токенов по хэшу токена. Пользователь nil — токен
+// недействителен.
+type tokenCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedToken
+}
+
+type cachedToken struct {
+	user      *User
+	expiresAt time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{entries: make(map[string]cachedToken)}
+}
+
+func (c *tokenCache) get(key string) (*User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.user, true
+}
+
+func (c *tokenCache) put(key string, user *User, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedTokens {
+		now := time.Now()
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		// Все записи еще действуют: начинаем заново, токены проверятся повторно
+		if len(c.entries) >= maxCachedTokens {
+			c.entries = make(map[string]cachedToken)
+		}
+	}
+	c.entries[key] = cachedToken{user: user, expiresAt: expiresAt}
+}
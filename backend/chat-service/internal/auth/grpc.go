@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"backend/pkg/authpb"
+	"backend/pkg/jwt"
+	"backend/pkg/revocation"
+)
+
+// Revocation отзыв токенов пользователя: выпущенные до RevokedAt токены недействительны
+type Revocation = revocation.Revocation
+
+// GRPCClient обращается к gRPC API сервиса авторизации. Отзывы токенов запрашивает
+// revocation.GRPCSource.
+type GRPCClient struct {
+	*revocation.GRPCSource
+	client authpb.AuthServiceClient
+}
+
+// NewGRPCClient создает клиент сервиса авторизации поверх соединения conn
+func NewGRPCClient(conn grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{
+		GRPCSource: revocation.NewGRPCSource(conn),
+		client:     authpb.NewAuthServiceClient(conn),
+	}
+}
+
+// Validate проверяет токен в сервисе авторизации с учетом завершенных сессий
+func (c *GRPCClient) Validate(ctx context.Context, token string) (*User, error) {
+	resp, err := c.client.ValidateToken(ctx, &authpb.ValidateTokenRequest{Token: token})
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate token: %w", err)
+	}
+	if !resp.GetIsValid() || resp.GetUserId() == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	user := &User{
+		ID:          resp.GetUserId(),
+		Username:    resp.GetUsername(),
+		Roles:       resp.GetRoles(),
+		Permissions: resp.GetPermissions(),
+	}
+	if resp.GetExpiresAt() != nil {
+		user.ExpiresAt = resp.GetExpiresAt().AsTime()
+	}
+	// Токен уже проверен, из него нужно только время выпуска для сверки с отзывами
+	if claims, err := jwt.ParseUnverified(token); err == nil {
+		user.IssuedAt = claims.IssuedTime()
+	}
+	return user, nil
+}
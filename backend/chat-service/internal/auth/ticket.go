@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// DefaultTicketTTL время, за которое клиент должен подключиться по билету
+const DefaultTicketTTL = 30 * time.Second
+
+// ErrInvalidTicket билет не выдавался, уже использован или истек
+var ErrInvalidTicket = errors.New("invalid or expired ticket")
+
+// Tickets выдает одноразовые билеты для подключения к WebSocket. Билет передается
+// в URL вместо токена: он живет несколько секунд и после подключения недействителен,
+// поэтому его попадание в журналы прокси ничего не дает.
+type Tickets interface {
+	// Issue выдает билет пользователю, проверенному по токену
+	Issue(ctx context.Context, user *User) (*Ticket, error)
+	// Redeem возвращает пользователя билета и делает билет недействительным
+	Redeem(ctx context.Context, ticket string) (*User, error)
+}
+
+// Ticket выданный билет
+type Ticket struct {
+	Value     string    `json:"ticket"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ticketUser пользователь билета вместе со сроком действия его токена
+type ticketUser struct {
+	User
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newTicket() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ticket: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken хранится вместо самого билета или токена
+func hashToken(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
+
+// InProcessTickets хранит билеты в памяти для одного экземпляра сервиса
+type InProcessTickets struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	tickets map[string]inProcessTicket
+}
+
+type inProcessTicket struct {
+	user      User
+	expiresAt time.Time
+}
+
+// NewInProcessTickets создает хранилище билетов в памяти процесса
+func NewInProcessTickets(ttl time.Duration) *InProcessTickets {
+	if ttl <= 0 {
+		ttl = DefaultTicketTTL
+	}
+	return &InProcessTickets{ttl: ttl, tickets: make(map[string]inProcessTicket)}
+}
+
+func (t *InProcessTickets) Issue(ctx context.Context, user *User) (*Ticket, error) {
+	ticket, err := newTicket()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for hash, stored := range t.tickets {
+		if !now.Before(stored.expiresAt) {
+			delete(t.tickets, hash)
+		}
+	}
+	expiresAt := now.Add(t.ttl)
+	t.tickets[hashToken(ticket)] = inProcessTicket{user: *user, expiresAt: expiresAt}
+	return &Ticket{Value: ticket, ExpiresAt: expiresAt}, nil
+}
+
+func (t *InProcessTickets) Redeem(ctx context.Context, ticket string) (*User, error) {
+	hash := hashToken(ticket)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stored, ok := t.tickets[hash]
+	if !ok {
+		return nil, ErrInvalidTicket
+	}
+	delete(t.tickets, hash)
+	if !time.Now().Before(stored.expiresAt) {
+		return nil, ErrInvalidTicket
+	}
+	return &stored.user, nil
+}
+
+// PostgresTickets хранит билеты в таблице ws_tickets, общей для всех экземпляров сервиса:
+// билет можно получить у одного экземпляра, а подключиться к другому
+type PostgresTickets struct {
+	pool *pgxpool.Pool
+	ttl  time.Duration
+}
+
+// NewPostgresTickets создает хранилище билетов в базе
+func NewPostgresTickets(pool *pgxpool.Pool, ttl time.Duration) *PostgresTickets {
+	if ttl <= 0 {
+		ttl = DefaultTicketTTL
+	}
+	return &PostgresTickets{pool: pool, ttl: ttl}
+}
+
+func (t *PostgresTickets) Issue(ctx context.Context, user *User) (*Ticket, error) {
+	ticket, err := newTicket()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(ticketUser{User: *user, ExpiresAt: user.ExpiresAt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ticket user: %w", err)
+	}
+
+	// Заодно удаляем неиспользованные билеты
+	if _, err := t.pool.Exec(ctx, `DELETE FROM ws_tickets WHERE expires_at <= NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to delete expired tickets: %w", err)
+	}
+	var expiresAt time.Time
+	err = t.pool.QueryRow(ctx, `
+		INSERT INTO ws_tickets (ticket_hash, user_data, expires_at)
+		VALUES ($1, $2, NOW() + $3 * INTERVAL '1 millisecond')
+		RETURNING expires_at
+	`, hashToken(ticket), data, t.ttl.Milliseconds()).Scan(&expiresAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save ticket: %w", err)
+	}
+	return &Ticket{Value: ticket, ExpiresAt: expiresAt}, nil
+}
+
+func (t *PostgresTickets) Redeem(ctx context.Context, ticket string) (*User, error) {
+	var data []byte
+	err := t.pool.QueryRow(ctx, `
+		DELETE FROM ws_tickets
+		WHERE ticket_hash = $1 AND expires_at > NOW()
+		RETURNING user_data
+	`, hashToken(ticket)).Scan(&data)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidTicket
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem ticket: %w", err)
+	}
+
+	var stored ticketUser
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ticket user: %w", err)
+	}
+	stored.User.ExpiresAt = stored.ExpiresAt
+	return &stored.User, nil
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInProcessTickets_SingleUse(t *testing.T) {
+	tickets := NewInProcessTickets(time.Minute)
+	expiresAt := time.Now().Add(time.Hour)
+
+	ticket, err := tickets.Issue(context.Background(), &User{ID: 7, Username: "alice", ExpiresAt: expiresAt})
+	require.NoError(t, err)
+	assert.NotEmpty(t, ticket.Value)
+
+	user, err := tickets.Redeem(context.Background(), ticket.Value)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), user.ID)
+	assert.Equal(t, expiresAt, user.ExpiresAt)
+
+	_, err = tickets.Redeem(context.Background(), ticket.Value)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+	_, err = tickets.Redeem(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+}
+
+func TestInProcessTickets_Expired(t *testing.T) {
+	tickets := NewInProcessTickets(time.Millisecond)
+
+	ticket, err := tickets.Issue(context.Background(), &User{ID: 7, Username: "alice"})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = tickets.Redeem(context.Background(), ticket.Value)
+	assert.ErrorIs(t, err, ErrInvalidTicket)
+}
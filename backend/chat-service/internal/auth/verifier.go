@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"backend/pkg/jwt"
+	"backend/pkg/revocation"
+)
+
+// Remote сервис авторизации: проверяет токены и сообщает об отзывах
+type Remote interface {
+	Validator
+	Revocations(ctx context.Context, since time.Time) ([]Revocation, time.Time, error)
+}
+
+// VerifierConfig настройки проверки токенов
+type VerifierConfig struct {
+	// Tokens проверяет подпись токенов локально, nil — все токены проверяет сервис авторизации
+	Tokens *jwt.Manager
+	// Remote проверяет токены, которые нельзя проверить локально, и сообщает об отзывах
+	Remote Remote
+	// Timeout ограничивает запрос к сервису авторизации
+	Timeout time.Duration
+	// CacheTTL сколько помнить результат проверки токена
+	CacheTTL time.Duration
+	// PollInterval как часто запрашивать отзывы токенов
+	PollInterval time.Duration
+	// BreakerFailures ошибок подряд, после которых запросы к сервису авторизации
+	// приостанавливаются на BreakerCooldown
+	BreakerFailures int
+	BreakerCooldown time.Duration
+}
+
+// Verifier проверяет токены без запроса к сервису авторизации: подпись и срок проверяются
+// локально, завершенные сессии — по списку отзывов, который обновляется в фоне (Run).
+// Токены, которые нельзя проверить локально, проверяет сервис авторизации через gRPC
+// с таймаутом и размыкателем цепи. Результаты кэшируются.
+type Verifier struct {
+	cfg         VerifierConfig
+	breaker     *Breaker
+	cache       *tokenCache
+	revocations *revocation.List
+}
+
+// NewVerifier создает проверку токенов
+func NewVerifier(cfg VerifierConfig) *Verifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 2 * time.Second
+	}
+	if cfg.CacheTTL <= 0 {
+		cfg.CacheTTL = time.Minute
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	return &Verifier{
+		cfg:         cfg,
+		breaker:     NewBreaker(cfg.BreakerFailures, cfg.BreakerCooldown),
+		cache:       newTokenCache(),
+		revocations: revocation.NewList(),
+	}
+}
+
+// Validate проверяет токен и возвращает его владельца
+func (v *Verifier) Validate(ctx context.Context, token string) (*User, error) {
+	key := hashToken(token)
+	user, ok := v.cache.get(key)
+	if !ok {
+		var err error
+		user, err = v.verify(ctx, token, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if user == nil || v.revocations.Revoked(user.ID, user.IssuedAt) {
+		return nil, ErrInvalidToken
+	}
+
+	copied := *user
+	return &copied, nil
+}
+
+func (v *Verifier) verify(ctx context.Context, token, key string) (*User, error) {
+	if v.cfg.Tokens != nil {
+		claims, err := v.cfg.Tokens.ParseClaims(token)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+		// Токены без имени выпущены до того, как права попали в токен, их проверяет сервис авторизации
+		if claims.Username != "" {
+			user, err := userFromClaims(claims)
+			if err != nil {
+				return nil, ErrInvalidToken
+			}
+			v.cache.put(key, user, v.cacheUntil(user.ExpiresAt))
+			return user, nil
+		}
+	}
+
+	user, err := v.validateRemote(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		// Отвергнутый сервисом токен не проверяем повторно при каждом подключении
+		v.cache.put(key, nil, v.cacheUntil(time.Time{}))
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	v.cache.put(key, user, v.cacheUntil(user.ExpiresAt))
+	return user, nil
+}
+
+func (v *Verifier) validateRemote(ctx context.Context, token string) (*User, error) {
+	if v.cfg.Remote == nil {
+		return nil, errors.New("auth service is not configured")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.cfg.Timeout)
+	defer cancel()
+
+	var user *User
+	var invalid bool
+	err := v.breaker.Do(func() error {
+		var err error
+		user, err = v.cfg.Remote.Validate(ctx, token)
+		// Недействительный токен — ответ сервиса, а не его сбой
+		if errors.Is(err, ErrInvalidToken) {
+			invalid = true
+			return nil
+		}
+		return err
+	})
+	if invalid {
+		return nil, ErrInvalidToken
+	}
+	return user, err
+}
+
+// cacheUntil время, до которого можно помнить результат проверки токена, истекающего в expiresAt
+func (v *Verifier) cacheUntil(expiresAt time.Time) time.Time {
+	until := time.Now().Add(v.cfg.CacheTTL)
+	if !expiresAt.IsZero() && expiresAt.Before(until) {
+		return expiresAt
+	}
+	return until
+}
+
+// Run обновляет список отзывов токенов каждые PollInterval, пока ctx не отменен.
+// Пока сервис авторизации недоступен, действует последний полученный список.
+func (v *Verifier) Run(ctx context.Context) {
+	if v.cfg.Remote == nil {
+		return
+	}
+	v.revocations.Run(ctx, remoteRevocations{v}, v.cfg.PollInterval, v.cfg.Timeout, func(err error) {
+		log.Printf("failed to load token revocations: %v", err)
+	})
+}
+
+// remoteRevocations запрашивает отзывы у сервиса авторизации через размыкатель цепи
+type remoteRevocations struct {
+	v *Verifier
+}
+
+func (r remoteRevocations) Revocations(ctx context.Context, since time.Time) ([]Revocation, time.Time, error) {
+	var revocations []Revocation
+	var now time.Time
+	err := r.v.breaker.Do(func() error {
+		var err error
+		revocations, now, err = r.v.cfg.Remote.Revocations(ctx, since)
+		return err
+	})
+	return revocations, now, err
+}
+
+func userFromClaims(claims *jwt.Claims) (*User, error) {
+	id, err := claims.UserID()
+	if err != nil {
+		return nil, err
+	}
+	return &User{
+		ID:          id,
+		Username:    claims.Username,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Scoped:      claims.Scoped,
+		ExpiresAt:   claims.ExpiresTime(),
+		IssuedAt:    claims.IssuedTime(),
+	}, nil
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/pkg/jwt"
+	"backend/pkg/rbac"
+)
+
+type fakeRemote struct {
+	mu          sync.Mutex
+	users       map[string]*User
+	err         error
+	calls       int
+	revocations []Revocation
+}
+
+func (r *fakeRemote) Validate(ctx context.Context, token string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	user, ok := r.users[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return user, nil
+}
+
+func (r *fakeRemote) Revocations(ctx context.Context, since time.Time) ([]Revocation, time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.revocations, time.Now(), r.err
+}
+
+func (r *fakeRemote) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func newTestManager(t *testing.T) *jwt.Manager {
+	manager, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	return manager
+}
+
+func TestVerifier_LocalToken(t *testing.T) {
+	manager := newTestManager(t)
+	remote := &fakeRemote{}
+	v := NewVerifier(VerifierConfig{Tokens: manager, Remote: remote})
+
+	token, err := manager.NewAccessToken(7, "alice", rbac.Access{Roles: []string{rbac.RoleModerator}}, time.Hour)
+	require.NoError(t, err)
+
+	user, err := v.Validate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), user.ID)
+	assert.Equal(t, "alice", user.Username)
+	assert.Equal(t, []string{rbac.RoleModerator}, user.Roles)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), user.ExpiresAt, 2*time.Second)
+	assert.Zero(t, remote.callCount())
+
+	other, err := jwt.NewManager("other")
+	require.NoError(t, err)
+	forged, err := other.NewAccessToken(7, "alice", rbac.Access{}, time.Hour)
+	require.NoError(t, err)
+	_, err = v.Validate(context.Background(), forged)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Zero(t, remote.callCount())
+}
+
+func TestVerifier_FallsBackToRemote(t *testing.T) {
+	manager := newTestManager(t)
+	// Старый токен без имени и прав можно проверить только в сервисе авторизации
+	token, err := manager.NewJWT(7, time.Hour)
+	require.NoError(t, err)
+
+	remote := &fakeRemote{users: map[string]*User{token: {ID: 7, Username: "alice"}}}
+	v := NewVerifier(VerifierConfig{Tokens: manager, Remote: remote})
+
+	user, err := v.Validate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+
+	// Повторная проверка берется из кэша
+	_, err = v.Validate(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, remote.callCount())
+}
+
+func TestVerifier_RejectsRevokedTokens(t *testing.T) {
+	manager := newTestManager(t)
+	remote := &fakeRemote{}
+	v := NewVerifier(VerifierConfig{Tokens: manager, Remote: remote})
+
+	issued := time.Now().Add(-time.Minute)
+	token, err := manager.NewAccessToken(7, "alice", rbac.Access{}, time.Hour)
+	require.NoError(t, err)
+	_, err = v.Validate(context.Background(), token)
+	require.NoError(t, err)
+
+	// Пользователь вышел: токен из кэша больше не принимается
+	remote.revocations = []Revocation{{UserID: 7, RevokedAt: time.Now().Add(2 * time.Second)}}
+	_, err = v.revocations.Poll(context.Background(), remoteRevocations{v}, time.Time{})
+	require.NoError(t, err)
+	_, err = v.Validate(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Токен, выпущенный после отзыва, действует
+	assert.False(t, v.revocations.Revoked(7, time.Now().Add(3*time.Second)))
+	assert.True(t, v.revocations.Revoked(7, issued))
+	assert.False(t, v.revocations.Revoked(8, issued))
+}
+
+// tokenIssuedAt подписывает токен newTestManager, выпущенный в issuedAt. Без precise
+// в токене только секунды iat, как у токенов, выпущенных до появления iat_us.
+func tokenIssuedAt(t *testing.T, userID int64, issuedAt time.Time, precise bool) string {
+	t.Helper()
+	claims := jwt.Claims{
+		RegisteredClaims: gojwt.RegisteredClaims{
+			ExpiresAt: gojwt.NewNumericDate(issuedAt.Add(time.Hour)),
+			IssuedAt:  gojwt.NewNumericDate(issuedAt),
+			Subject:   strconv.FormatInt(userID, 10),
+		},
+		Username: "alice",
+	}
+	if precise {
+		claims.SetIssuedTime(issuedAt)
+	}
+	signed, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestVerifier_LoginRightAfterLogout(t *testing.T) {
+	second := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name   string
+		issued time.Time
+		logout time.Time
+		login  time.Time
+		legacy bool
+	}{
+		{name: "earlier second", issued: second.Add(-500 * time.Millisecond), logout: second.Add(200 * time.Millisecond), login: second.Add(300 * time.Millisecond)},
+		{name: "same second", issued: second.Add(200 * time.Millisecond), logout: second.Add(700 * time.Millisecond), login: second.Add(800 * time.Millisecond)},
+		{name: "same millisecond", issued: second.Add(700 * time.Millisecond), logout: second.Add(700*time.Millisecond + 5*time.Microsecond), login: second.Add(700*time.Millisecond + 10*time.Microsecond)},
+		{name: "next second", issued: second.Add(100 * time.Millisecond), logout: second.Add(900 * time.Millisecond), login: second.Add(1100 * time.Millisecond)},
+		{name: "token without iat_us", issued: second.Add(200 * time.Millisecond), logout: second.Add(700 * time.Millisecond), login: second.Add(800 * time.Millisecond), legacy: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &fakeRemote{}
+			v := NewVerifier(VerifierConfig{Tokens: newTestManager(t), Remote: remote})
+
+			old := tokenIssuedAt(t, 7, tt.issued, !tt.legacy)
+			_, err := v.Validate(context.Background(), old)
+			require.NoError(t, err)
+
+			// Сервис авторизации записывает отзыв по своим часам до подписи нового токена
+			remote.revocations = []Revocation{{UserID: 7, RevokedAt: jwt.RevocationCutoff(tt.logout)}}
+			_, err = v.revocations.Poll(context.Background(), remoteRevocations{v}, time.Time{})
+			require.NoError(t, err)
+
+			// Токен, выпущенный до выхода, отвергается, даже если выход был в ту же секунду
+			_, err = v.Validate(context.Background(), old)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+
+			user, err := v.Validate(context.Background(), tokenIssuedAt(t, 7, tt.login, true))
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), user.ID)
+		})
+	}
+}
+
+func TestVerifier_BreakerStopsRemoteCalls(t *testing.T) {
+	remote := &fakeRemote{err: errors.New("connection refused")}
+	v := NewVerifier(VerifierConfig{Remote: remote, BreakerFailures: 2, BreakerCooldown: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		_, err := v.Validate(context.Background(), "token")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidToken)
+	}
+	_, err := v.Validate(context.Background(), "token")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, 2, remote.callCount())
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	b := NewBreaker(1, 10*time.Millisecond)
+	fail := errors.New("fail")
+
+	assert.Equal(t, fail, b.Do(func() error { return fail }))
+	assert.True(t, b.Open())
+	assert.ErrorIs(t, b.Do(func() error { return nil }), ErrCircuitOpen)
+
+	time.Sleep(15 * time.Millisecond)
+	// Пробный запрос прошел, цепь снова замкнута
+	assert.NoError(t, b.Do(func() error { return nil }))
+	assert.False(t, b.Open())
+}
@@ -0,0 +1,106 @@
+// Package broker доставляет события чата всем экземплярам сервиса.
+//
+// Хаб каждого экземпляра получает из брокера все события, включая опубликованные им самим,
+// поэтому порядок доставки одинаков на всех репликах.
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// Типы событий
+const (
+	// EventDeliver доставить Payload подписчикам канала или соединениям UserIDs
+	EventDeliver = "deliver"
+	// EventJoin подписать соединения UserIDs на канал и отправить им Payload
+	EventJoin = "join"
+	// EventLeave отписать соединения UserIDs от канала и отправить им Payload
+	EventLeave = "leave"
+	// EventPresence изменение статусов пользователей на экземпляре Instance
+	EventPresence = "presence"
+	// EventPresenceSync полный список пользователей экземпляра Instance, публикуется периодически
+	EventPresenceSync = "presence_sync"
+	// EventKick закрыть соединения UserIDs с кодом CloseCode и причиной Reason
+	EventKick = "kick"
+)
+
+// ErrClosed возвращается при публикации в закрытый брокер
+var ErrClosed = errors.New("broker is closed")
+
+// Event событие хаба
+type Event struct {
+	Type      string  `json:"type"`
+	ChannelID int64   `json:"channel_id"`
+	UserIDs   []int64 `json:"user_ids,omitempty"`
+	// Members участники личной переписки в событии EventJoin
+	Members []int64         `json:"members,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+	// Instance экземпляр, опубликовавший событие присутствия
+	Instance string     `json:"instance,omitempty"`
+	Presence []Presence `json:"presence,omitempty"`
+	// CloseCode и Reason кадра закрытия в событии EventKick
+	CloseCode int    `json:"close_code,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Presence статус пользователя на одном экземпляре сервиса
+type Presence struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Status   string `json:"status"`
+}
+
+// Broker рассылает события между экземплярами сервиса
+type Broker interface {
+	// Publish отправляет событие всем экземплярам, включая текущий
+	Publish(ctx context.Context, event Event) error
+	// Events возвращает поток событий всех экземпляров в едином порядке
+	Events() <-chan Event
+	Close() error
+}
+
+// InProcess брокер для одного экземпляра сервиса
+type InProcess struct {
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewInProcess создает брокер в памяти процесса
+func NewInProcess(buffer int) *InProcess {
+	return &InProcess{
+		events: make(chan Event, buffer),
+		done:   make(chan struct{}),
+	}
+}
+
+func (b *InProcess) Publish(ctx context.Context, event Event) error {
+	select {
+	case <-b.done:
+		return ErrClosed
+	default:
+	}
+
+	select {
+	case b.events <- event:
+		return nil
+	case <-b.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *InProcess) Events() <-chan Event {
+	return b.events
+}
+
+// Close прекращает прием событий. Канал событий не закрывается, чтобы не
+// конкурировать с публикациями, которые уже ждут места в буфере.
+func (b *InProcess) Close() error {
+	b.once.Do(func() { close(b.done) })
+	return nil
+}
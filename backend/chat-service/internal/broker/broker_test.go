@@ -0,0 +1,60 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInProcess_PreservesOrder(t *testing.T) {
+	b := NewInProcess(10)
+	defer b.Close()
+
+	for i := int64(1); i <= 3; i++ {
+		require.NoError(t, b.Publish(context.Background(), Event{Type: EventDeliver, ChannelID: i}))
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		event := <-b.Events()
+		assert.Equal(t, i, event.ChannelID)
+	}
+}
+
+func TestInProcess_PublishAfterClose(t *testing.T) {
+	b := NewInProcess(1)
+	require.NoError(t, b.Close())
+
+	assert.ErrorIs(t, b.Publish(context.Background(), Event{Type: EventDeliver}), ErrClosed)
+}
+
+func TestInProcess_PublishRespectsContext(t *testing.T) {
+	b := NewInProcess(0)
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(t, b.Publish(ctx, Event{Type: EventDeliver}), context.DeadlineExceeded)
+}
+
+func TestEvent_PayloadStaysJSON(t *testing.T) {
+	event := Event{
+		Type:      EventJoin,
+		ChannelID: 7,
+		UserIDs:   []int64{1, 2},
+		Members:   []int64{1, 2},
+		Payload:   json.RawMessage(`{"type":"joined","channel_id":7}`),
+	}
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"payload":{"type":"joined","channel_id":7}`)
+
+	var decoded Event
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, event, decoded)
+}
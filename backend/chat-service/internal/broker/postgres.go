@@ -0,0 +1,214 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+const (
+	// DefaultChannel канал LISTEN/NOTIFY для событий чата
+	DefaultChannel = "chat_events"
+
+	// PostgreSQL ограничивает payload NOTIFY 8000 байтами. Большие события
+	// сохраняются в таблицу chat_broker_events, а в уведомлении передается их ID.
+	maxNotifyPayload = 7900
+	spillPrefix      = "@"
+	// spillRetention время хранения больших событий, за которое их успевают прочитать все экземпляры
+	spillRetention = 5 * time.Minute
+
+	reconnectDelay    = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Postgres брокер на LISTEN/NOTIFY. Все экземпляры, подключенные к одной базе,
+// получают уведомления в порядке фиксации транзакций.
+//
+// Уведомления, отправленные пока соединение слушателя переподключается, теряются.
+type Postgres struct {
+	pool    *pgxpool.Pool
+	channel string
+	events  chan Event
+	cancel  context.CancelFunc
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewPostgres подключает слушателя к каналу. Соединение слушателя занимает одно соединение пула.
+func NewPostgres(ctx context.Context, pool *pgxpool.Pool, channel string, buffer int) (*Postgres, error) {
+	if channel == "" {
+		channel = DefaultChannel
+	}
+
+	conn, err := listen(ctx, pool, channel)
+	if err != nil {
+		return nil, err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	b := &Postgres{
+		pool:    pool,
+		channel: channel,
+		events:  make(chan Event, buffer),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go b.run(listenCtx, conn)
+
+	return b, nil
+}
+
+func listen(ctx context.Context, pool *pgxpool.Pool, channel string) (*pgxpool.Conn, error) {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire listener connection: %w", err)
+	}
+
+	if _, err := conn.Exec(ctx, "LISTEN "+quoteIdentifier(channel)); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+
+	return conn, nil
+}
+
+func (b *Postgres) Publish(ctx context.Context, event Event) error {
+	select {
+	case <-b.done:
+		return ErrClosed
+	default:
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	payload := string(data)
+	if len(payload) > maxNotifyPayload {
+		var id int64
+		err := b.pool.QueryRow(ctx, `
+            INSERT INTO chat_broker_events (payload) VALUES ($1)
+            RETURNING id`, data).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to store large event: %w", err)
+		}
+		payload = spillPrefix + strconv.FormatInt(id, 10)
+
+		// Заодно удаляем события, которые уже прочитаны всеми экземплярами
+		if _, err := b.pool.Exec(ctx, `
+            DELETE FROM chat_broker_events
+            WHERE created_at < NOW() - make_interval(secs => $1)`, spillRetention.Seconds()); err != nil {
+			log.Printf("broker: failed to clean up large events: %v", err)
+		}
+	}
+
+	if _, err := b.pool.Exec(ctx, "SELECT pg_notify($1, $2)", b.channel, payload); err != nil {
+		return fmt.Errorf("failed to notify: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Postgres) Events() <-chan Event {
+	return b.events
+}
+
+// Close останавливает слушателя и закрывает канал событий
+func (b *Postgres) Close() error {
+	b.once.Do(func() {
+		b.cancel()
+		<-b.done
+	})
+	return nil
+}
+
+// run читает уведомления и переподключается при обрыве соединения
+func (b *Postgres) run(ctx context.Context, conn *pgxpool.Conn) {
+	defer close(b.done)
+	defer close(b.events)
+
+	delay := reconnectDelay
+	for {
+		if conn == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			var err error
+			conn, err = listen(ctx, b.pool, b.channel)
+			if err != nil {
+				log.Printf("broker: reconnect failed: %v", err)
+				if delay *= 2; delay > maxReconnectDelay {
+					delay = maxReconnectDelay
+				}
+				continue
+			}
+			log.Printf("broker: listener reconnected")
+			delay = reconnectDelay
+		}
+
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				conn.Release()
+				return
+			}
+			log.Printf("broker: listener connection lost: %v", err)
+			// Соединение в неизвестном состоянии, не возвращаем его в пул
+			conn.Conn().Close(context.Background())
+			conn.Release()
+			conn = nil
+			continue
+		}
+
+		event, err := b.decode(ctx, notification.Payload)
+		if err != nil {
+			log.Printf("broker: skipping event: %v", err)
+			continue
+		}
+
+		select {
+		case b.events <- event:
+		case <-ctx.Done():
+			conn.Release()
+			return
+		}
+	}
+}
+
+func (b *Postgres) decode(ctx context.Context, payload string) (Event, error) {
+	data := []byte(payload)
+
+	if strings.HasPrefix(payload, spillPrefix) {
+		id, err := strconv.ParseInt(strings.TrimPrefix(payload, spillPrefix), 10, 64)
+		if err != nil {
+			return Event{}, fmt.Errorf("invalid event reference %q", payload)
+		}
+		if err := b.pool.QueryRow(ctx, `
+            SELECT payload FROM chat_broker_events WHERE id = $1`, id).Scan(&data); err != nil {
+			return Event{}, fmt.Errorf("failed to load large event %d: %w", id, err)
+		}
+	}
+
+	var event Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		return Event{}, fmt.Errorf("failed to unmarshal event: %w", err)
+	}
+	return event, nil
+}
+
+// quoteIdentifier экранирует имя канала для LISTEN, который не принимает параметры
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
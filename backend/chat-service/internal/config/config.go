@@ -10,14 +10,21 @@ import (
 
 // Config представляет конфигурацию приложения
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	RateLimit RateLimitConfig
+	Broker    BrokerConfig
+	WebSocket WebSocketConfig
+
+	ContentFilter ContentFilterConfig
+	LinkPreview   LinkPreviewConfig
 }
 
 // ServerConfig представляет конфигурацию сервера
 type ServerConfig struct {
 	Port            string
+	GRPCPort        string
 	ShutdownTimeout time.Duration
 }
 
@@ -34,6 +41,64 @@ type DatabaseConfig struct {
 // AuthConfig представляет конфигурацию аутентификации
 type AuthConfig struct {
 	AuthServiceURL string
+	// GRPCAddr адрес gRPC API сервиса авторизации: проверка токенов и список отзывов
+	GRPCAddr string
+	// JWTSecret ключ подписи токенов для локальной проверки, пусто — токены проверяет сервис авторизации
+	JWTSecret string
+	// Timeout ограничивает запрос к сервису авторизации
+	Timeout time.Duration
+	// CacheTTL сколько помнить результат проверки токена
+	CacheTTL time.Duration
+	// RevocationPoll как часто запрашивать отзывы токенов
+	RevocationPoll time.Duration
+	// BreakerFailures ошибок подряд, после которых запросы к сервису авторизации
+	// приостанавливаются на BreakerCooldown
+	BreakerFailures int
+	BreakerCooldown time.Duration
+	// TicketTTL время жизни одноразового билета для подключения к WebSocket
+	TicketTTL time.Duration
+}
+
+// RateLimitConfig представляет лимиты по типам WebSocket сообщений в формате ratelimit.ParseRules
+type RateLimitConfig struct {
+	Rules string
+}
+
+// BrokerConfig представляет конфигурацию доставки событий между экземплярами сервиса
+type BrokerConfig struct {
+	// Type "memory" для одного экземпляра или "postgres" для LISTEN/NOTIFY
+	Type    string
+	Channel string
+}
+
+// WebSocketConfig представляет настройки очередей клиентов, heartbeat и сжатия
+type WebSocketConfig struct {
+	QueueSize int
+	// OverflowPolicy "disconnect", "drop_newest" или "drop_oldest"
+	OverflowPolicy string
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
+	// Compression сжатие permessage-deflate для клиентов, которые его поддерживают
+	Compression          bool
+	CompressionLevel     int
+	CompressionThreshold int
+}
+
+// ContentFilterConfig представляет настройки фильтра содержимого сообщений
+type ContentFilterConfig struct {
+	// Path путь к JSON конфигурации contentfilter, пустой — фильтр выключен
+	Path string
+	// DryRun только записывает срабатывания в лог, не меняя сообщения
+	DryRun bool
+}
+
+// LinkPreviewConfig представляет настройки превью ссылок в сообщениях
+type LinkPreviewConfig struct {
+	Enabled bool
+	// Timeout ограничивает загрузку одной ссылки, MaxBodySize — число прочитанных байт страницы
+	Timeout     time.Duration
+	MaxBodySize int64
 }
 
 // Load загружает конфигурацию из переменных окружения
@@ -46,10 +111,25 @@ func Load() (*Config, error) {
 	}
 
 	shutdownTimeout, _ := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT_SECONDS", "5"))
+	queueSize, _ := strconv.Atoi(getEnv("WS_QUEUE_SIZE", "256"))
+	pongWait, _ := strconv.Atoi(getEnv("WS_PONG_WAIT_SECONDS", "60"))
+	writeWait, _ := strconv.Atoi(getEnv("WS_WRITE_WAIT_SECONDS", "10"))
+	maxMessageSize, _ := strconv.ParseInt(getEnv("WS_MAX_MESSAGE_SIZE", "65536"), 10, 64)
+	compressionLevel, _ := strconv.Atoi(getEnv("WS_COMPRESSION_LEVEL", "1"))
+	compressionThreshold, _ := strconv.Atoi(getEnv("WS_COMPRESSION_THRESHOLD", "512"))
+	ticketTTL, _ := strconv.Atoi(getEnv("WS_TICKET_TTL_SECONDS", "30"))
+	authTimeout, _ := strconv.Atoi(getEnv("AUTH_TIMEOUT_SECONDS", "2"))
+	authCacheTTL, _ := strconv.Atoi(getEnv("AUTH_CACHE_TTL_SECONDS", "60"))
+	revocationPoll, _ := strconv.Atoi(getEnv("AUTH_REVOCATION_POLL_SECONDS", "10"))
+	breakerFailures, _ := strconv.Atoi(getEnv("AUTH_BREAKER_FAILURES", "5"))
+	breakerCooldown, _ := strconv.Atoi(getEnv("AUTH_BREAKER_COOLDOWN_SECONDS", "30"))
+	previewTimeout, _ := strconv.Atoi(getEnv("LINK_PREVIEW_TIMEOUT_SECONDS", "5"))
+	previewMaxBytes, _ := strconv.ParseInt(getEnv("LINK_PREVIEW_MAX_BYTES", "1048576"), 10, 64)
 
 	return &Config{
 		Server: ServerConfig{
 			Port:            getEnv("HTTP_PORT", "8080"),
+			GRPCPort:        getEnv("GRPC_PORT", "50053"),
 			ShutdownTimeout: time.Duration(shutdownTimeout) * time.Second,
 		},
 		Database: DatabaseConfig{
@@ -61,7 +141,42 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		Auth: AuthConfig{
-			AuthServiceURL: getEnv("AUTH_SERVICE_URL", "http://localhost:8081"),
+			AuthServiceURL:  getEnv("AUTH_SERVICE_URL", "http://localhost:8081"),
+			GRPCAddr:        getEnv("AUTH_GRPC_ADDR", "localhost:50051"),
+			JWTSecret:       getEnv("JWT_SECRET", "your-secret-key"),
+			Timeout:         time.Duration(authTimeout) * time.Second,
+			CacheTTL:        time.Duration(authCacheTTL) * time.Second,
+			RevocationPoll:  time.Duration(revocationPoll) * time.Second,
+			BreakerFailures: breakerFailures,
+			BreakerCooldown: time.Duration(breakerCooldown) * time.Second,
+			TicketTTL:       time.Duration(ticketTTL) * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Rules: getEnv("RATE_LIMITS", "message=5/5s/10"),
+		},
+		Broker: BrokerConfig{
+			Type:    getEnv("BROKER", "memory"),
+			Channel: getEnv("BROKER_CHANNEL", "chat_events"),
+		},
+		ContentFilter: ContentFilterConfig{
+			Path:   getEnv("CONTENT_FILTER_CONFIG", ""),
+			DryRun: getEnv("CONTENT_FILTER_DRY_RUN", "false") == "true",
+		},
+		LinkPreview: LinkPreviewConfig{
+			Enabled:     getEnv("LINK_PREVIEWS", "true") == "true",
+			Timeout:     time.Duration(previewTimeout) * time.Second,
+			MaxBodySize: previewMaxBytes,
+		},
+		WebSocket: WebSocketConfig{
+			QueueSize:      queueSize,
+			OverflowPolicy: getEnv("WS_OVERFLOW_POLICY", "disconnect"),
+			PongWait:       time.Duration(pongWait) * time.Second,
+			WriteWait:      time.Duration(writeWait) * time.Second,
+			MaxMessageSize: maxMessageSize,
+
+			Compression:          getEnv("WS_COMPRESSION", "true") == "true",
+			CompressionLevel:     compressionLevel,
+			CompressionThreshold: compressionThreshold,
 		},
 	}, nil
 }
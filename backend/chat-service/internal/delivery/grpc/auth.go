@@ -0,0 +1,90 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"backend/chat-service/internal/auth"
+	"backend/chat-service/internal/usecase"
+)
+
+type userKey struct{}
+
+// UnaryInterceptor проверяет токен из метаданных authorization и кладет пользователя в контекст.
+// Запрос без токена выполняется анонимно, запрос с недействительным токеном отклоняется.
+func (s *Server) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamInterceptor то же, что UnaryInterceptor, для потоковых методов
+func (s *Server) StreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authStream{ServerStream: stream, ctx: ctx})
+}
+
+// authStream подменяет контекст потока контекстом с пользователем
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Server) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	token := strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "empty token")
+	}
+
+	user, err := s.auth.Validate(ctx, token)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	if err != nil {
+		s.logger.Error("Failed to validate token", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "auth service is unavailable")
+	}
+
+	return context.WithValue(ctx, userKey{}, user), nil
+}
+
+// userFromContext возвращает пользователя запроса или nil для анонимного запроса
+func userFromContext(ctx context.Context) *auth.User {
+	user, _ := ctx.Value(userKey{}).(*auth.User)
+	return user
+}
+
+// requireUser возвращает пользователя запроса или ошибку Unauthenticated
+func requireUser(ctx context.Context) (*auth.User, error) {
+	user := userFromContext(ctx)
+	if user == nil {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+	}
+	return user, nil
+}
+
+func actor(user *auth.User) usecase.Actor {
+	return usecase.Actor{UserID: user.ID, Access: user.Access()}
+}
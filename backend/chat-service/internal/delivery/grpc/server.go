@@ -0,0 +1,290 @@
+// Package grpc реализует gRPC API чата из proto/chat.proto
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"backend/chat-service/internal/auth"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/chat-service/internal/usecase"
+	pb "backend/chat-service/proto"
+	"backend/pkg/rbac"
+	"backend/pkg/unfurl"
+)
+
+// Server gRPC сервер чата
+type Server struct {
+	pb.UnimplementedChatServiceServer
+	useCase *usecase.ChatUseCase
+	auth    auth.Validator
+	logger  *zap.Logger
+}
+
+// NewServer создает gRPC сервер чата
+func NewServer(useCase *usecase.ChatUseCase, validator auth.Validator, logger *zap.Logger) *Server {
+	return &Server{
+		useCase: useCase,
+		auth:    validator,
+		logger:  logger,
+	}
+}
+
+func (s *Server) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
+	if req.Token == "" {
+		return &pb.ValidateTokenResponse{}, nil
+	}
+
+	user, err := s.auth.Validate(ctx, req.Token)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return &pb.ValidateTokenResponse{}, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to validate token", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "auth service is unavailable")
+	}
+
+	return &pb.ValidateTokenResponse{
+		IsValid:  true,
+		UserId:   user.ID,
+		Username: user.Username,
+	}, nil
+}
+
+func (s *Server) GetChatHistory(ctx context.Context, req *pb.GetChatHistoryRequest) (*pb.GetChatHistoryResponse, error) {
+	query := entity.HistoryQuery{
+		ChannelID: req.ChannelId,
+		Limit:     req.Limit,
+	}
+
+	var err error
+	if query.BeforeID, err = entity.DecodeCursor(req.Before); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if query.AfterID, err = entity.DecodeCursor(req.After); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if query.BeforeID == 0 {
+		// Устаревшее поле для старых клиентов
+		query.BeforeID = req.BeforeId
+	}
+
+	var userID int64
+	if user := userFromContext(ctx); user != nil {
+		userID = user.ID
+	}
+
+	page, err := s.useCase.History(ctx, userID, query)
+	if err != nil {
+		return nil, s.errorStatus(err)
+	}
+
+	messages := make([]*pb.ChatMessage, len(page.Messages))
+	for i, msg := range page.Messages {
+		messages[i] = messageToProto(msg)
+	}
+
+	return &pb.GetChatHistoryResponse{
+		Messages: messages,
+		Before:   page.Before,
+		After:    page.After,
+		HasMore:  page.HasMore,
+	}, nil
+}
+
+func (s *Server) DeleteOldMessages(ctx context.Context, req *pb.DeleteOldMessagesRequest) (*pb.DeleteOldMessagesResponse, error) {
+	user, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Access().Has(rbac.ChatDeleteAny) {
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	if req.BeforeTime == nil {
+		return nil, status.Error(codes.InvalidArgument, "before_time is required")
+	}
+
+	count, err := s.useCase.DeleteOldMessages(ctx, req.BeforeTime.AsTime())
+	if err != nil {
+		return nil, s.errorStatus(err)
+	}
+
+	s.logger.Info("Deleted old messages",
+		zap.Int64("user_id", user.ID),
+		zap.Time("before", req.BeforeTime.AsTime()),
+		zap.Int32("count", count))
+
+	return &pb.DeleteOldMessagesResponse{DeletedCount: count}, nil
+}
+
+// Subscribe передает события каналов, пока клиент не отменит поток. Клиент, который
+// не успевает читать события, отключается с кодом ResourceExhausted и может
+// догрузить пропущенное через GetChatHistory.
+func (s *Server) Subscribe(req *pb.SubscribeRequest, stream pb.ChatService_SubscribeServer) error {
+	ctx := stream.Context()
+	user, err := requireUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	listener, err := s.useCase.Listen(ctx, user.ID, req.ChannelIds)
+	if err != nil {
+		return s.errorStatus(err)
+	}
+	defer s.useCase.Unlisten(listener)
+
+	s.logger.Info("gRPC subscriber connected",
+		zap.Int64("user_id", user.ID),
+		zap.Int64s("channel_ids", req.ChannelIds))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-listener.Events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber is too slow or lost access to the channel")
+			}
+			if err := stream.Send(eventToProto(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) SendMessage(ctx context.Context, req *pb.SendMessageRequest) (*pb.SendMessageResponse, error) {
+	user, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var replyTo string
+	if req.ReplyTo != 0 {
+		replyTo = strconv.FormatInt(req.ReplyTo, 10)
+	}
+
+	msg, duplicate, err := s.useCase.SendMessage(ctx, actor(user), user.Username, req.ChannelId, req.Content, req.TempId, replyTo)
+	if err != nil {
+		return nil, s.errorStatus(err)
+	}
+
+	return &pb.SendMessageResponse{
+		Message:   messageToProto(msg),
+		Duplicate: duplicate,
+		Held:      msg.Held,
+	}, nil
+}
+
+// errorStatus переводит ошибку use case в статус gRPC
+func (s *Server) errorStatus(err error) error {
+	switch {
+	case errors.Is(err, usecase.ErrChannelNotFound), errors.Is(err, repository.ErrMessageNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, usecase.ErrChannelForbidden), errors.Is(err, usecase.ErrMuted),
+		errors.Is(err, usecase.ErrBanned):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, usecase.ErrSlowMode):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, usecase.ErrEmptyContent), errors.Is(err, usecase.ErrInvalidTempID),
+		errors.Is(err, usecase.ErrInvalidHistoryQuery), errors.Is(err, usecase.ErrContentRejected),
+		errors.Is(err, usecase.ErrInvalidReply):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		s.logger.Error("gRPC request failed", zap.Error(err))
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func messageToProto(msg *entity.Message) *pb.ChatMessage {
+	id, _ := strconv.ParseInt(msg.ID, 10, 64)
+	return &pb.ChatMessage{
+		Id:        id,
+		Content:   msg.Content,
+		UserId:    msg.UserID,
+		Username:  msg.Username,
+		CreatedAt: timestamppb.New(msg.CreatedAt),
+		ChannelId: msg.ChannelID,
+		Seq:       msg.Seq,
+		EditedAt:  timestampOrNil(msg.EditedAt),
+		Deleted:   msg.IsDeleted(),
+		ReplyTo:   parseID(msg.ReplyTo),
+		Reactions: reactionsToProto(msg.Reactions),
+		Previews:  previewsToProto(msg.Previews),
+	}
+}
+
+func eventToProto(event usecase.ChatMessage) *pb.ChatEvent {
+	id, _ := strconv.ParseInt(event.ID, 10, 64)
+	return &pb.ChatEvent{
+		Type:      event.Type,
+		ChannelId: event.ChannelID,
+		Message: &pb.ChatMessage{
+			Id:        id,
+			Content:   event.Content,
+			UserId:    event.UserID,
+			Username:  event.Username,
+			CreatedAt: timestampOrNil(nonZero(event.CreatedAt)),
+			ChannelId: event.ChannelID,
+			Seq:       event.Seq,
+			EditedAt:  timestampOrNil(event.EditedAt),
+			Deleted:   event.DeletedAt != nil,
+			ReplyTo:   parseID(event.ReplyTo),
+			Reactions: reactionsToProto(event.Reactions),
+			Previews:  previewsToProto(event.Previews),
+		},
+	}
+}
+
+func reactionsToProto(reactions []entity.Reaction) []*pb.Reaction {
+	result := make([]*pb.Reaction, 0, len(reactions))
+	for _, r := range reactions {
+		result = append(result, &pb.Reaction{Emoji: r.Emoji, Count: int32(r.Count), UserIds: r.UserIDs})
+	}
+	return result
+}
+
+func previewsToProto(previews []unfurl.Preview) []*pb.LinkPreview {
+	result := make([]*pb.LinkPreview, 0, len(previews))
+	for _, p := range previews {
+		result = append(result, &pb.LinkPreview{
+			Url:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			Image:       p.Image,
+			SiteName:    p.SiteName,
+		})
+	}
+	return result
+}
+
+// parseID возвращает ID сообщения из строки, 0 — если его нет
+func parseID(id string) int64 {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	return n
+}
+
+func nonZero(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timestampOrNil(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
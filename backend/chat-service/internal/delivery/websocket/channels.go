@@ -0,0 +1,303 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/chat-service/internal/usecase"
+)
+
+// @Summary Список каналов
+// @Description Возвращает публичные каналы и приватные каналы, в которых состоит пользователь
+// @Tags channels
+// @Produce  json
+// @Param   Authorization header string false "Bearer token"
+// @Success 200 {array}  entity.Channel
+// @Router /api/chat/channels [get]
+func (h *Handler) handleListChannels(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var userID int64
+	if user := h.authenticate(r); user != nil {
+		userID = user.ID
+	}
+
+	channels, err := h.useCase.ListChannels(ctx, userID)
+	if err != nil {
+		h.logger.Error("Failed to list channels", zap.Error(err))
+		http.Error(w, "Failed to list channels", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, channels)
+}
+
+// @Summary Создание канала
+// @Description Создает публичный или приватный канал, создатель становится его участником
+// @Tags channels
+// @Accept  json
+// @Produce  json
+// @Param   Authorization header string true "Bearer token"
+// @Param   input body entity.ChannelCreate true "Channel"
+// @Success 201 {object} entity.Channel
+// @Router /api/chat/channels [post]
+func (h *Handler) handleCreateChannel(w http.ResponseWriter, r *http.Request) {
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input entity.ChannelCreate
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	channel, err := h.useCase.CreateChannel(ctx, actor(user), input)
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, channel)
+}
+
+// @Summary История канала
+// @Description Возвращает историю сообщений канала. Приватный канал доступен только участникам.
+// @Tags channels
+// @Produce  json
+// @Param   id        path     int     true        "Channel ID"
+// @Param   limit     query    int     false       "Limit, 50 by default, at most 100"
+// @Param   before    query    string  false       "Cursor: newest messages before it"
+// @Param   after     query    string  false       "Cursor: oldest messages after it"
+// @Param   Authorization header string false "Bearer token"
+// @Success 200 {array}  entity.Message
+// @Header  200 {string} X-Cursor-Before "Cursor for older messages"
+// @Header  200 {string} X-Cursor-After  "Cursor for newer messages"
+// @Router /api/chat/channels/{id}/messages [get]
+func (h *Handler) handleGetChannelHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	h.writeChannelHistory(w, r, parseID(mux.Vars(r)["id"]))
+}
+
+func (h *Handler) writeChannelHistory(w http.ResponseWriter, r *http.Request, channelID int64) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	query, err := parseHistoryQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.ChannelID = channelID
+
+	var userID int64
+	if user := h.authenticate(r); user != nil {
+		userID = user.ID
+	}
+
+	page, err := h.useCase.History(ctx, userID, query)
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	// Тело ответа остается массивом сообщений, курсоры передаются в заголовках
+	w.Header().Set(headerCursorBefore, page.Before)
+	w.Header().Set(headerCursorAfter, page.After)
+	w.Header().Set(headerHasMore, strconv.FormatBool(page.HasMore))
+	writeJSON(w, http.StatusOK, page.Messages)
+}
+
+// Заголовки ответа с курсорами истории
+const (
+	headerCursorBefore = "X-Cursor-Before"
+	headerCursorAfter  = "X-Cursor-After"
+	headerHasMore      = "X-Has-More"
+
+	historyHeaders = headerCursorBefore + ", " + headerCursorAfter + ", " + headerHasMore
+)
+
+// parseHistoryQuery разбирает параметры limit, before, after и устаревший before_id
+func parseHistoryQuery(r *http.Request) (entity.HistoryQuery, error) {
+	values := r.URL.Query()
+	var query entity.HistoryQuery
+
+	if s := values.Get("limit"); s != "" {
+		limit, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || limit <= 0 {
+			return query, errors.New("invalid limit")
+		}
+		if limit > entity.MaxHistoryLimit {
+			limit = entity.MaxHistoryLimit
+		}
+		query.Limit = int32(limit)
+	}
+
+	var err error
+	if query.BeforeID, err = entity.DecodeCursor(values.Get("before")); err != nil {
+		return query, err
+	}
+	if query.AfterID, err = entity.DecodeCursor(values.Get("after")); err != nil {
+		return query, err
+	}
+	if s := values.Get("before_id"); s != "" && query.BeforeID == 0 {
+		if query.BeforeID = parseID(s); query.BeforeID <= 0 {
+			return query, errors.New("invalid before_id")
+		}
+	}
+	if query.BeforeID != 0 && query.AfterID != 0 {
+		return query, usecase.ErrInvalidHistoryQuery
+	}
+
+	return query, nil
+}
+
+// @Summary Добавление участника
+// @Description Добавляет пользователя в канал. Доступно создателю канала и модераторам.
+// @Tags channels
+// @Accept  json
+// @Param   id    path int true "Channel ID"
+// @Param   Authorization header string true "Bearer token"
+// @Param   input body entity.ChannelMemberAdd true "Member"
+// @Success 204
+// @Router /api/chat/channels/{id}/members [post]
+func (h *Handler) handleAddChannelMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input entity.ChannelMemberAdd
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.UserID == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.useCase.AddMember(ctx, actor(user), parseID(mux.Vars(r)["id"]), input.UserID); err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Удаление участника
+// @Description Удаляет пользователя из канала. Пользователь может выйти сам, удалить другого может создатель канала или модератор.
+// @Tags channels
+// @Param   id      path int true "Channel ID"
+// @Param   user_id path int true "User ID"
+// @Param   Authorization header string true "Bearer token"
+// @Success 204
+// @Router /api/chat/channels/{id}/members/{user_id} [delete]
+func (h *Handler) handleRemoveChannelMember(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.useCase.RemoveMember(ctx, actor(user), parseID(vars["id"]), parseID(vars["user_id"])); err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authenticate возвращает пользователя по токену из заголовка Authorization или параметра token.
+// Возвращает nil, если токена нет или он недействителен.
+func (h *Handler) authenticate(r *http.Request) *authUser {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return nil
+	}
+
+	user, err := h.validateToken(r.Context(), token)
+	if err != nil || user.ID == 0 {
+		h.logger.Debug("Failed to validate token", zap.Error(err))
+		return nil
+	}
+	return user
+}
+
+func actor(u *authUser) usecase.Actor {
+	return usecase.Actor{UserID: u.ID, Access: u.Access()}
+}
+
+// channelError отвечает статусом, соответствующим ошибке use case
+func (h *Handler) channelError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrChannelNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrChannelForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, repository.ErrChannelExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, usecase.ErrInvalidChannelName), errors.Is(err, usecase.ErrLeaveDefault),
+		errors.Is(err, usecase.ErrDirectMembers), errors.Is(err, usecase.ErrInvalidParticipants):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrChannelsDisabled):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		h.logger.Error("Channel request failed", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func parseID(s string) int64 {
+	id, _ := strconv.ParseInt(s, 10, 64)
+	return id
+}
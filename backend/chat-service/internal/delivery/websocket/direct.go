@@ -0,0 +1,186 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"backend/chat-service/internal/entity"
+)
+
+// @Summary Личные переписки
+// @Description Возвращает личные переписки пользователя с участниками и числом непрочитанных сообщений
+// @Tags direct
+// @Produce  json
+// @Param   Authorization header string true "Bearer token"
+// @Success 200 {array}  entity.Channel
+// @Router /api/chat/direct [get]
+func (h *Handler) handleListDirect(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	conversations, err := h.useCase.ListDirect(ctx, user.ID)
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+	if conversations == nil {
+		conversations = []*entity.Channel{}
+	}
+
+	writeJSON(w, http.StatusOK, conversations)
+}
+
+// @Summary Создание личной переписки
+// @Description Создает переписку с одним или несколькими пользователями. Для переписки один на один возвращается существующая.
+// @Tags direct
+// @Accept  json
+// @Produce  json
+// @Param   Authorization header string true "Bearer token"
+// @Param   input body entity.DirectCreate true "Participants"
+// @Success 200 {object} entity.Channel
+// @Success 201 {object} entity.Channel
+// @Router /api/chat/direct [post]
+func (h *Handler) handleCreateDirect(w http.ResponseWriter, r *http.Request) {
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input entity.DirectCreate
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	conversation, created, err := h.useCase.CreateDirect(ctx, actor(user), input)
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, conversation)
+}
+
+// @Summary Отметка о прочтении
+// @Description Отмечает сообщения канала или переписки до message_id прочитанными
+// @Tags direct
+// @Accept  json
+// @Param   id    path int true "Channel ID"
+// @Param   Authorization header string true "Bearer token"
+// @Param   input body entity.ReadInput true "Last read message"
+// @Success 204
+// @Router /api/chat/direct/{id}/read [post]
+func (h *Handler) handleMarkRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input entity.ReadInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.MessageID <= 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.useCase.MarkRead(ctx, user.ID, parseID(mux.Vars(r)["id"]), input.MessageID); err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Непрочитанные сообщения
+// @Description Возвращает число непрочитанных сообщений и последнее прочитанное сообщение во всех каналах и переписках пользователя
+// @Tags direct
+// @Produce  json
+// @Param   Authorization header string true "Bearer token"
+// @Success 200 {array}  entity.UnreadCount
+// @Router /api/chat/unread [get]
+func (h *Handler) handleUnread(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	counts, err := h.useCase.GetUnread(ctx, user.ID)
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, counts)
+}
+
+// @Summary Кто прочитал переписку
+// @Description Возвращает последнее прочитанное сообщение каждого участника личной переписки
+// @Tags direct
+// @Produce  json
+// @Param   id    path int true "Conversation ID"
+// @Param   Authorization header string true "Bearer token"
+// @Success 200 {array}  entity.ReadPointer
+// @Router /api/chat/direct/{id}/receipts [get]
+func (h *Handler) handleReceipts(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	receipts, err := h.useCase.GetReceipts(ctx, user.ID, parseID(mux.Vars(r)["id"]))
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, receipts)
+}
@@ -2,17 +2,19 @@ package websocket
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
+	"net"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 
+	"backend/chat-service/internal/auth"
 	"backend/chat-service/internal/usecase"
+	"backend/pkg/ratelimit"
 )
 
 // @title Chat Service WebSocket API
@@ -22,9 +24,12 @@ import (
 // @host localhost:8080
 // @BasePath /ws
 
+// upgrader всегда согласует permessage-deflate, если клиент его предлагает.
+// Сжимать ли исходящие кадры, решает настройка хаба.
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
 		origin := r.Header.Get("Origin")
 		return origin == "http://localhost:3000" || // React dev server
@@ -44,14 +49,41 @@ func SetUpgraderSettings(settings websocket.Upgrader) {
 type Handler struct {
 	useCase *usecase.ChatUseCase
 	logger  *zap.Logger
+	auth    auth.Validator
+	tickets auth.Tickets
+}
+
+// HandlerOption настраивает Handler
+type HandlerOption func(*Handler)
+
+// WithTickets задает хранилище билетов для подключения к WebSocket. По умолчанию билеты
+// хранятся в памяти процесса и действуют только на том экземпляре, который их выдал.
+func WithTickets(tickets auth.Tickets) HandlerOption {
+	return func(h *Handler) {
+		h.tickets = tickets
+	}
+}
+
+// WithValidator задает проверку токенов. По умолчанию каждый токен проверяется запросом
+// к HTTP API сервиса авторизации.
+func WithValidator(validator auth.Validator) HandlerOption {
+	return func(h *Handler) {
+		h.auth = validator
+	}
 }
 
 // NewHandler создает новый WebSocket обработчик
-func NewHandler(useCase *usecase.ChatUseCase, logger *zap.Logger) *Handler {
-	return &Handler{
+func NewHandler(useCase *usecase.ChatUseCase, logger *zap.Logger, opts ...HandlerOption) *Handler {
+	h := &Handler{
 		useCase: useCase,
 		logger:  logger,
+		auth:    auth.NewClient(""),
+		tickets: auth.NewInProcessTickets(auth.DefaultTicketTTL),
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // RegisterRoutes регистрирует маршруты
@@ -72,10 +104,11 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 
 			// Устанавливаем CORS заголовки
 			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Last-Event-ID")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Access-Control-Max-Age", "86400")
+			w.Header().Set("Access-Control-Expose-Headers", historyHeaders)
 
 			if r.Method == "OPTIONS" {
 				w.WriteHeader(http.StatusOK)
@@ -89,50 +122,63 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	// API endpoints
 	api := r.PathPrefix("/api/chat").Subrouter()
 	api.HandleFunc("/messages", h.handleGetHistory).Methods("GET", "OPTIONS")
-	api.HandleFunc("/ws", h.handleWebSocket).Methods("GET", "OPTIONS") // WebSocket endpoint
+	api.HandleFunc("/messages", h.handleSendMessage).Methods("POST")
+	api.HandleFunc("/messages/{id:[0-9]+}/thread", h.handleThread).Methods("GET", "OPTIONS")
+	api.HandleFunc("/ws", h.handleWebSocket).Methods("GET", "OPTIONS")  // WebSocket endpoint
+	api.HandleFunc("/events", h.handleEvents).Methods("GET", "OPTIONS") // SSE, если WebSocket недоступен
+	api.HandleFunc("/ws-ticket", h.handleIssueTicket).Methods("POST", "OPTIONS")
+	api.HandleFunc("/online", h.handleOnline).Methods("GET", "OPTIONS")
+	api.HandleFunc("/unread", h.handleUnread).Methods("GET", "OPTIONS")
+	api.HandleFunc("/channels", h.handleListChannels).Methods("GET", "OPTIONS")
+	api.HandleFunc("/channels", h.handleCreateChannel).Methods("POST")
+	api.HandleFunc("/channels/{id:[0-9]+}/messages", h.handleGetChannelHistory).Methods("GET", "OPTIONS")
+	api.HandleFunc("/channels/{id:[0-9]+}/members", h.handleAddChannelMember).Methods("POST", "OPTIONS")
+	api.HandleFunc("/channels/{id:[0-9]+}/members/{user_id:[0-9]+}", h.handleRemoveChannelMember).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/channels/{id:[0-9]+}/read", h.handleMarkRead).Methods("POST", "OPTIONS")
+	api.HandleFunc("/direct", h.handleListDirect).Methods("GET", "OPTIONS")
+	api.HandleFunc("/direct", h.handleCreateDirect).Methods("POST")
+	api.HandleFunc("/direct/{id:[0-9]+}/messages", h.handleGetChannelHistory).Methods("GET", "OPTIONS")
+	api.HandleFunc("/direct/{id:[0-9]+}/read", h.handleMarkRead).Methods("POST", "OPTIONS")
+	api.HandleFunc("/direct/{id:[0-9]+}/receipts", h.handleReceipts).Methods("GET", "OPTIONS")
+	api.HandleFunc("/moderation/log", h.handleModerationLog).Methods("GET", "OPTIONS")
+	api.HandleFunc("/moderation/held", h.handleHeldMessages).Methods("GET", "OPTIONS")
+	api.HandleFunc("/moderation/held/{id:[0-9]+}/{decision:approve|decline}", h.handleReviewHeld).Methods("POST", "OPTIONS")
+	api.HandleFunc("/moderation/{action:mute|unmute|kick|ban|unban|slowmode|purge}", h.handleModerate).Methods("POST", "OPTIONS")
 
 	// Добавляем обработчик для проверки здоровья сервиса
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	}).Methods("GET")
+	r.HandleFunc("/metrics", h.handleMetrics).Methods("GET")
 }
 
 // @Summary Подключение к WebSocket чату
-// @Description Устанавливает WebSocket соединение для обмена сообщениями
+// @Description Устанавливает WebSocket соединение для обмена сообщениями.
+// @Description Браузер не может передать заголовок Authorization при подключении, поэтому
+// @Description токен обменивается на одноразовый билет через POST /api/chat/ws-ticket.
+// @Description Без учетных данных клиент подключается анонимно и может авторизоваться
+// @Description сообщением {"type":"auth","token":"..."}. Недействительный токен или билет
+// @Description закрывает соединение с кодом 4401, истекший токен — с кодом 4402.
 // @Tags websocket
 // @Accept  json
 // @Produce  json
-// @Param   token     header    string     true        "Auth token"
+// @Param   Authorization header string false "Bearer token"
+// @Param   ticket    query     string     false       "Одноразовый билет из POST /api/chat/ws-ticket"
+// @Param   token     query     string     false       "Auth token, устарел: попадает в журналы прокси, используйте ticket"
+// @Param   Sec-WebSocket-Protocol header string false "Кодировка кадров: json, msgpack или protobuf"
 // @Success 101 {object} usecase.ChatMessage
-// @Router /chat [get]
+// @Router /api/chat/ws [get]
 func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("WebSocket connection attempt",
 		zap.String("remote_addr", r.RemoteAddr),
 		zap.String("origin", r.Header.Get("Origin")))
 
-	// Проверяем наличие токена
-	token := r.URL.Query().Get("token")
-	var authResp *struct {
-		ID       int64  `json:"id"`
-		Username string `json:"username"`
-	}
-	var err error
-
-	// Если токен предоставлен, пытаемся аутентифицировать пользователя
-	if token != "" {
-		authResp, err = h.validateToken(r.Context(), token)
-		if err != nil {
-			h.logger.Warn("Failed to validate token",
-				zap.Error(err),
-				zap.String("remote_addr", r.RemoteAddr))
-			// Продолжаем как анонимный пользователь
-			authResp = nil
-		}
-	}
+	authResp, authErr := h.connectionUser(r)
 
 	// Устанавливаем WebSocket соединение
-	conn, err := upgrader.Upgrade(w, r, nil)
+	encoding, responseHeader := negotiateEncoding(r)
+	conn, err := upgrader.Upgrade(w, r, responseHeader)
 	if err != nil {
 		h.logger.Error("Failed to upgrade connection",
 			zap.Error(err),
@@ -140,14 +186,31 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Ошибку авторизации сообщаем кодом закрытия: браузер не видит HTTP статус
+	// неудавшегося подключения и не отличил бы ее от обрыва сети
+	if authErr != nil {
+		code, reason := authCloseCode(authErr)
+		h.logger.Warn("WebSocket authentication failed",
+			zap.Error(authErr),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Int("close_code", code))
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+		conn.Close()
+		return
+	}
+
 	var client *usecase.Client
-	if authResp != nil && authResp.ID != 0 && authResp.Username != "" {
+	if authResp != nil {
 		// Создаем аутентифицированного клиента
 		client = usecase.NewClient(conn, authResp.ID, authResp.Username, true)
+		client.Access = authResp.Access()
+		client.Encoding = encoding
+		client.SetTokenExpiry(authResp.ExpiresAt)
 		h.logger.Info("Authenticated WebSocket connection established",
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.Int64("user_id", authResp.ID),
-			zap.String("username", authResp.Username))
+			zap.String("username", authResp.Username),
+			zap.Stringer("encoding", encoding))
 
 		// Отправляем подтверждение успешной аутентификации
 		authSuccess := usecase.ChatMessage{
@@ -155,8 +218,11 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			UserID:   authResp.ID,
 			Username: authResp.Username,
 		}
+		if !authResp.ExpiresAt.IsZero() {
+			authSuccess.ExpiresIn = ratelimit.Seconds(time.Until(authResp.ExpiresAt))
+		}
 
-		authSuccessJSON, err := json.Marshal(authSuccess)
+		authSuccessData, err := encoding.Marshal(authSuccess)
 		if err != nil {
 			h.logger.Error("Failed to marshal auth success message",
 				zap.Error(err),
@@ -165,7 +231,7 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := conn.WriteMessage(websocket.TextMessage, authSuccessJSON); err != nil {
+		if err := conn.WriteMessage(encoding.MessageType(), authSuccessData); err != nil {
 			h.logger.Error("Failed to send auth success message",
 				zap.Error(err),
 				zap.Any("auth_success", authSuccess))
@@ -175,8 +241,10 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	} else {
 		// Создаем анонимного клиента
 		client = usecase.NewClient(conn, 0, "anonymous", false)
+		client.Encoding = encoding
 		h.logger.Info("Anonymous WebSocket connection established",
-			zap.String("remote_addr", r.RemoteAddr))
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.Stringer("encoding", encoding))
 
 		// Отправляем информацию о статусе анонимного пользователя
 		anonInfo := usecase.ChatMessage{
@@ -184,7 +252,7 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			Error: "Вы подключены как анонимный пользователь. Для отправки сообщений необходима авторизация.",
 		}
 
-		anonInfoJSON, err := json.Marshal(anonInfo)
+		anonInfoData, err := encoding.Marshal(anonInfo)
 		if err != nil {
 			h.logger.Error("Failed to marshal anonymous info message",
 				zap.Error(err))
@@ -192,7 +260,7 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := conn.WriteMessage(websocket.TextMessage, anonInfoJSON); err != nil {
+		if err := conn.WriteMessage(encoding.MessageType(), anonInfoData); err != nil {
 			h.logger.Error("Failed to send anonymous info message",
 				zap.Error(err))
 			conn.Close()
@@ -200,53 +268,80 @@ func (h *Handler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// Регистрируем клиента
-	h.useCase.Register <- client
+	client.IP = clientIP(r)
 
-	// Запускаем горутины для чтения и записи сообщений
-	go client.WritePump()
-	go client.ReadPump(h.useCase)
+	// Регистрируем клиента и запускаем горутины для чтения и записи сообщений
+	h.useCase.Serve(client)
 }
 
-// validateToken validates the token with the auth service and returns user info
-func (h *Handler) validateToken(ctx context.Context, token string) (*struct {
-	ID       int64  `json:"id"`
-	Username string `json:"username"`
-}, error) {
-	// Get auth service URL from environment
-	authServiceURL := os.Getenv("AUTH_SERVICE_URL")
-	if authServiceURL == "" {
-		authServiceURL = "http://localhost:8081"
+// negotiateEncoding выбирает первый из предложенных клиентом подпротоколов, который
+// поддерживает сервер, и заголовок ответа с ним. Без подпротокола кадры передаются в JSON.
+func negotiateEncoding(r *http.Request) (usecase.Encoding, http.Header) {
+	for _, protocol := range websocket.Subprotocols(r) {
+		if encoding, ok := usecase.ParseEncoding(protocol); ok {
+			header := http.Header{}
+			header.Set("Sec-WebSocket-Protocol", protocol)
+			return encoding, header
+		}
 	}
+	return usecase.EncodingJSON, nil
+}
 
-	// Make request to auth service
-	req, err := http.NewRequestWithContext(ctx, "GET", authServiceURL+"/api/me", nil)
+// clientIP возвращает IP-адрес клиента без порта
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return r.RemoteAddr
 	}
+	return host
+}
 
-	req.Header.Set("Authorization", "Bearer "+token)
+// authUser is the profile returned by the auth service /api/me endpoint
+type authUser = auth.User
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
-	}
-	defer resp.Body.Close()
+// validateToken validates the token with the auth service and returns user info
+func (h *Handler) validateToken(ctx context.Context, token string) (*authUser, error) {
+	return h.auth.Validate(ctx, token)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("auth service returned status %d", resp.StatusCode)
+// connectionUser возвращает пользователя потокового соединения по заголовку Authorization,
+// одноразовому билету или устаревшему параметру token. Без учетных данных возвращает nil
+// без ошибки: клиент подключается анонимно.
+func (h *Handler) connectionUser(r *http.Request) (*authUser, error) {
+	var user *authUser
+	var err error
+	switch {
+	case strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "):
+		user, err = h.validateToken(r.Context(), strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	case r.URL.Query().Get("ticket") != "":
+		user, err = h.tickets.Redeem(r.Context(), r.URL.Query().Get("ticket"))
+	case r.URL.Query().Get("token") != "":
+		h.logger.Warn("Token passed in query string, clients should use a ticket",
+			zap.String("remote_addr", r.RemoteAddr))
+		user, err = h.validateToken(r.Context(), r.URL.Query().Get("token"))
+	default:
+		return nil, nil
 	}
-
-	var result struct {
-		ID       int64  `json:"id"`
-		Username string `json:"username"`
+	if err != nil {
+		return nil, err
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if user.ID == 0 || user.Username == "" {
+		return nil, auth.ErrInvalidToken
 	}
+	return user, nil
+}
 
-	return &result, nil
+// authCloseCode возвращает код и причину закрытия соединения для ошибки connectionUser
+func authCloseCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidTicket):
+		return usecase.CloseAuthFailed, "invalid ticket"
+	case errors.Is(err, auth.ErrInvalidToken):
+		return usecase.CloseAuthFailed, "invalid token"
+	default:
+		// Сервис авторизации недоступен, клиенту стоит переподключиться позже
+		return websocket.CloseTryAgainLater, "auth service unavailable"
+	}
 }
 
 // @Summary Получение истории сообщений
@@ -254,10 +349,15 @@ func (h *Handler) validateToken(ctx context.Context, token string) (*struct {
 // @Tags chat
 // @Accept  json
 // @Produce  json
-// @Param   limit     query    int     true        "Limit"
-// @Param   before_id query    int     false       "Before message ID"
-// @Success 200 {array}  usecase.ChatMessage
-// @Router /history [get]
+// @Param   limit     query    int     false       "Limit, 50 by default, at most 100"
+// @Param   before    query    string  false       "Cursor: newest messages before it (X-Cursor-Before of the previous page)"
+// @Param   after     query    string  false       "Cursor: oldest messages after it (X-Cursor-After of the previous page)"
+// @Param   before_id query    int     false       "Before message ID, deprecated in favour of before"
+// @Param   channel_id query   int     false       "Channel ID, general by default"
+// @Success 200 {array}  entity.Message
+// @Header  200 {string} X-Cursor-Before "Cursor for older messages"
+// @Header  200 {string} X-Cursor-After  "Cursor for newer messages"
+// @Router /api/chat/messages [get]
 func (h *Handler) handleGetHistory(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Getting chat history",
 		zap.String("remote_addr", r.RemoteAddr))
@@ -277,27 +377,5 @@ func (h *Handler) handleGetHistory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
-	defer cancel()
-
-	limit := int32(50) // Default limit
-	beforeID := int64(0)
-
-	messages, err := h.useCase.GetHistory(ctx, limit, beforeID)
-	if err != nil {
-		h.logger.Error("Failed to get chat history",
-			zap.Error(err),
-			zap.String("remote_addr", r.RemoteAddr))
-		http.Error(w, "Failed to get chat history", http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(messages); err != nil {
-		h.logger.Error("Failed to encode messages",
-			zap.Error(err),
-			zap.String("remote_addr", r.RemoteAddr))
-		http.Error(w, "Failed to encode messages", http.StatusInternalServerError)
-		return
-	}
+	h.writeChannelHistory(w, r, parseID(r.URL.Query().Get("channel_id")))
 }
@@ -0,0 +1,29 @@
+package websocket
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// @Summary Метрики хаба
+// @Description Возвращает счетчики хаба в текстовом формате Prometheus
+// @Tags metrics
+// @Produce  plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func (h *Handler) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	m := h.useCase.Metrics()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.WriteHeader(http.StatusOK)
+
+	writeMetric(w, "chat_ws_clients", "gauge", "Connected WebSocket clients.", uint64(m.Clients))
+	writeMetric(w, "chat_listeners", "gauge", "Connected subscribers outside WebSocket.", uint64(m.Listeners))
+	writeMetric(w, "chat_messages_dropped_total", "counter", "Messages dropped because a client send queue was full.", m.Dropped)
+	writeMetric(w, "chat_slow_consumers_disconnected_total", "counter", "Clients and subscribers disconnected because their queue was full.", m.SlowDisconnects)
+	writeMetric(w, "chat_dead_peers_total", "counter", "Connections closed by a read or write deadline.", m.DeadPeers)
+}
+
+func writeMetric(w http.ResponseWriter, name, kind, help string, value uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %d\n", name, help, name, kind, name, value)
+}
@@ -0,0 +1,185 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/usecase"
+)
+
+// @Summary Действие модератора
+// @Description Выполняет mute, unmute, kick, ban, unban, slowmode или purge и записывает его в журнал.
+// @Description channel_id 0 в mute, ban и purge означает все каналы; duration_seconds 0 в ban — бессрочно.
+// @Tags moderation
+// @Accept  json
+// @Produce  json
+// @Param   action path string true "mute, unmute, kick, ban, unban, slowmode или purge"
+// @Param   Authorization header string true "Bearer token"
+// @Param   input body entity.ModerationRequest true "Moderation request"
+// @Success 200 {object} entity.ModerationAction
+// @Router /api/chat/moderation/{action} [post]
+func (h *Handler) handleModerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input entity.ModerationRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	action, err := h.useCase.Moderate(ctx, actor(user), mux.Vars(r)["action"], input)
+	if err != nil {
+		h.moderationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, action)
+}
+
+// @Summary Журнал модерации
+// @Description Возвращает действия модераторов, последние первыми
+// @Tags moderation
+// @Produce  json
+// @Param   channel_id query int false "Channel ID, 0 for actions in all channels"
+// @Param   user_id    query int false "Target user ID"
+// @Param   before_id  query int false "Return entries older than this ID"
+// @Param   limit      query int false "Limit, 50 by default, at most 200"
+// @Param   Authorization header string true "Bearer token"
+// @Success 200 {array} entity.ModerationAction
+// @Router /api/chat/moderation/log [get]
+func (h *Handler) handleModerationLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	values := r.URL.Query()
+	query := entity.ModerationLogQuery{
+		UserID:   parseID(values.Get("user_id")),
+		BeforeID: parseID(values.Get("before_id")),
+		Limit:    int32(parseID(values.Get("limit"))),
+	}
+	if s := values.Get("channel_id"); s != "" {
+		channelID := parseID(s)
+		query.ChannelID = &channelID
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	actions, err := h.useCase.ModerationLog(ctx, actor(user), query)
+	if err != nil {
+		h.moderationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, actions)
+}
+
+// @Summary Сообщения на проверке
+// @Description Возвращает сообщения, задержанные фильтром содержимого, старые первыми
+// @Tags moderation
+// @Produce  json
+// @Param   channel_id query int false "Channel ID, all channels by default"
+// @Param   limit      query int false "Limit, 50 by default, at most 200"
+// @Param   Authorization header string true "Bearer token"
+// @Success 200 {array} entity.HeldMessage
+// @Router /api/chat/moderation/held [get]
+func (h *Handler) handleHeldMessages(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	values := r.URL.Query()
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	messages, err := h.useCase.HeldMessages(ctx, actor(user), parseID(values.Get("channel_id")), int32(parseID(values.Get("limit"))))
+	if err != nil {
+		h.moderationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, messages)
+}
+
+// @Summary Решение по сообщению на проверке
+// @Description approve публикует задержанное фильтром сообщение, decline удаляет его. Решение записывается в журнал.
+// @Tags moderation
+// @Produce  json
+// @Param   id       path int    true "Held message ID"
+// @Param   decision path string true "approve или decline"
+// @Param   Authorization header string true "Bearer token"
+// @Success 200 {object} entity.ModerationAction
+// @Router /api/chat/moderation/held/{id}/{decision} [post]
+func (h *Handler) handleReviewHeld(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	vars := mux.Vars(r)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	action, err := h.useCase.ReviewHeld(ctx, actor(user), parseID(vars["id"]), vars["decision"] == entity.ActionApprove)
+	if err != nil {
+		h.moderationError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, action)
+}
+
+// moderationError отвечает статусом, соответствующим ошибке модерации
+func (h *Handler) moderationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrModerationForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrInvalidModeration):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, usecase.ErrSanctionNotFound), errors.Is(err, usecase.ErrHeldNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrModerationDisabled):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	default:
+		h.channelError(w, err)
+	}
+}
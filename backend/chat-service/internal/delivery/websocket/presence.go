@@ -0,0 +1,20 @@
+package websocket
+
+import (
+	"net/http"
+)
+
+// @Summary Пользователи в сети
+// @Description Возвращает пользователей, подключенных к чату на любом экземпляре сервиса, со статусом online или away
+// @Tags presence
+// @Produce  json
+// @Success 200 {array}  entity.OnlineUser
+// @Router /api/chat/online [get]
+func (h *Handler) handleOnline(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.useCase.OnlineUsers())
+}
@@ -0,0 +1,291 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/usecase"
+	"backend/pkg/ratelimit"
+)
+
+const (
+	// sseRetry через сколько миллисекунд EventSource переподключается после обрыва
+	sseRetry = 3000
+	// maxEventIDChannels ограничивает число каналов в Last-Event-ID
+	maxEventIDChannels = 100
+)
+
+// @Summary Поток событий чата (SSE)
+// @Description Замена WebSocket для сетей, где он заблокирован: те же сообщения ChatMessage в поле data.
+// @Description id события — номера последних полученных сообщений по каналам; после переподключения
+// @Description с Last-Event-ID пропущенные сообщения досылаются, как в ответ на "resume".
+// @Description Перед отключением сервером приходит событие close с кодом и причиной.
+// @Tags websocket
+// @Produce  text/event-stream
+// @Param   ticket        query  string false "Одноразовый билет из POST /api/chat/ws-ticket, EventSource не умеет передавать заголовки"
+// @Param   token         query  string false "Auth token, устарел: используйте ticket"
+// @Param   Authorization header string false "Bearer token"
+// @Param   Last-Event-ID header string false "id последнего полученного события"
+// @Param   last_event_id query  string false "То же, что Last-Event-ID, для первого подключения"
+// @Success 200 {object} usecase.ChatMessage
+// @Failure 401 {string} string "Invalid token or ticket"
+// @Router /api/chat/events [get]
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	user, err := h.connectionUser(r)
+	if err != nil {
+		// EventSource не переподключается после ответа с ошибкой
+		h.logger.Warn("SSE authentication failed", zap.Error(err), zap.String("remote_addr", r.RemoteAddr))
+		if code, _ := authCloseCode(err); code == usecase.CloseAuthFailed {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		} else {
+			http.Error(w, "Auth service unavailable", http.StatusServiceUnavailable)
+		}
+		return
+	}
+
+	var client *usecase.Client
+	var hello usecase.ChatMessage
+	if user != nil {
+		client = usecase.NewClient(nil, user.ID, user.Username, true)
+		client.Access = user.Access()
+		client.SetTokenExpiry(user.ExpiresAt)
+		hello = usecase.ChatMessage{Type: "auth_success", UserID: user.ID, Username: user.Username}
+		if !user.ExpiresAt.IsZero() {
+			hello.ExpiresIn = ratelimit.Seconds(time.Until(user.ExpiresAt))
+		}
+	} else {
+		client = usecase.NewClient(nil, 0, "anonymous", false)
+		hello = usecase.ChatMessage{
+			Type:  "connection_info",
+			Error: "Вы подключены как анонимный пользователь. Для отправки сообщений необходима авторизация.",
+		}
+	}
+	client.IP = clientIP(r)
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	resume := parseEventID(lastEventID)
+
+	data, err := json.Marshal(hello)
+	if err != nil {
+		h.logger.Error("Failed to marshal stream greeting", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Буферизующие прокси (nginx) должны передавать события сразу
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := newSSEWriter(w, resume)
+	fmt.Fprintf(&stream.buf, "retry: %d\n\n", sseRetry)
+	if err := stream.WriteFrame(usecase.NewFrame(data)); err != nil {
+		return
+	}
+
+	h.logger.Info("SSE connection established",
+		zap.String("remote_addr", r.RemoteAddr),
+		zap.Int64("user_id", client.UserID),
+		zap.Int("resumed_channels", len(resume)))
+
+	h.useCase.Stream(r.Context(), client, stream, resume)
+}
+
+// @Summary Отправка сообщения
+// @Description Отправляет сообщение в канал вместо фрейма "message" WebSocket, для клиентов на SSE.
+// @Description Подписчики получают сообщение с tempId отправителя так же, как из WebSocket.
+// @Tags messages
+// @Accept  json
+// @Produce  json
+// @Param   Authorization header string true "Bearer token"
+// @Param   input body entity.MessageCreate true "Message"
+// @Success 201 {object} entity.Message
+// @Success 200 {object} entity.Message "Сообщение с этим tempId уже отправлено"
+// @Success 202 {object} entity.Message "Сообщение задержано фильтром до проверки модератором"
+// @Failure 429 {string} string "Лимит частоты или медленный режим, см. Retry-After"
+// @Router /api/chat/messages [post]
+func (h *Handler) handleSendMessage(w http.ResponseWriter, r *http.Request) {
+	user := h.authenticate(r)
+	if user == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var input entity.MessageCreate
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if result := h.useCase.AllowMessage("message", user.ID); !result.Allowed {
+		w.Header().Set(ratelimit.HeaderRetry, strconv.Itoa(ratelimit.Seconds(result.RetryAfter)))
+		http.Error(w, "Too many messages", http.StatusTooManyRequests)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	msg, duplicate, err := h.useCase.SendMessage(ctx, actor(user), user.Username,
+		input.ChannelID, input.Content, input.TempID, input.ReplyTo)
+	if err != nil {
+		h.sendError(w, err)
+		return
+	}
+
+	switch {
+	case msg.Held:
+		writeJSON(w, http.StatusAccepted, msg)
+	case duplicate:
+		writeJSON(w, http.StatusOK, msg)
+	default:
+		writeJSON(w, http.StatusCreated, msg)
+	}
+}
+
+// sendError отвечает статусом, соответствующим ошибке отправки сообщения
+func (h *Handler) sendError(w http.ResponseWriter, err error) {
+	var restriction *usecase.RestrictionError
+	if errors.As(err, &restriction) && restriction.RetryAfter > 0 {
+		w.Header().Set(ratelimit.HeaderRetry, strconv.Itoa(ratelimit.Seconds(restriction.RetryAfter)))
+	}
+
+	switch {
+	case errors.Is(err, usecase.ErrSlowMode):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, usecase.ErrMuted), errors.Is(err, usecase.ErrBanned):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, usecase.ErrEmptyContent), errors.Is(err, usecase.ErrInvalidTempID),
+		errors.Is(err, usecase.ErrInvalidReply), errors.Is(err, usecase.ErrContentRejected):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.channelError(w, err)
+	}
+}
+
+// sseWriter передает кадры клиента событиями SSE. id события — номера последних
+// сообщений по каналам, которые клиент получил.
+type sseWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	buf bytes.Buffer
+	// seqs номера последних отправленных сообщений по каналам
+	seqs map[int64]int64
+}
+
+func newSSEWriter(w http.ResponseWriter, resume map[int64]int64) *sseWriter {
+	seqs := make(map[int64]int64, len(resume))
+	for channelID, seq := range resume {
+		seqs[channelID] = seq
+	}
+	return &sseWriter{w: w, rc: http.NewResponseController(w), seqs: seqs}
+}
+
+func (s *sseWriter) SetWriteDeadline(t time.Time) error {
+	return s.rc.SetWriteDeadline(t)
+}
+
+func (s *sseWriter) WriteFrame(frame *usecase.Frame) error {
+	data, err := frame.Encode(usecase.EncodingJSON)
+	if err != nil {
+		// Кадр не удалось закодировать, остальные отправляются как обычно
+		return nil
+	}
+
+	// Номер есть у сообщений, в том числе досланных, и у "resumed"
+	if msg, err := frame.Message(); err == nil && msg.ChannelID != 0 && msg.Seq > s.seqs[msg.ChannelID] {
+		s.seqs[msg.ChannelID] = msg.Seq
+		fmt.Fprintf(&s.buf, "id: %s\n", formatEventID(s.seqs))
+	}
+	s.buf.WriteString("data: ")
+	s.buf.Write(data)
+	s.buf.WriteString("\n\n")
+	return s.flush()
+}
+
+func (s *sseWriter) Ping() error {
+	s.buf.WriteString(": ping\n\n")
+	return s.flush()
+}
+
+func (s *sseWriter) Close(code int, reason string) error {
+	data, _ := json.Marshal(struct {
+		Code   int    `json:"code"`
+		Reason string `json:"reason,omitempty"`
+	}{code, reason})
+	fmt.Fprintf(&s.buf, "event: close\ndata: %s\n\n", data)
+	return s.flush()
+}
+
+func (s *sseWriter) flush() error {
+	_, err := s.w.Write(s.buf.Bytes())
+	s.buf.Reset()
+	if err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// formatEventID записывает номера сообщений по каналам в виде "channel:seq,channel:seq"
+func formatEventID(seqs map[int64]int64) string {
+	channelIDs := make([]int64, 0, len(seqs))
+	for channelID := range seqs {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Slice(channelIDs, func(i, j int) bool { return channelIDs[i] < channelIDs[j] })
+
+	parts := make([]string, len(channelIDs))
+	for i, channelID := range channelIDs {
+		parts[i] = strconv.FormatInt(channelID, 10) + ":" + strconv.FormatInt(seqs[channelID], 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseEventID разбирает id события из formatEventID. Некорректные части пропускаются.
+func parseEventID(id string) map[int64]int64 {
+	if id == "" {
+		return nil
+	}
+
+	seqs := make(map[int64]int64)
+	for _, part := range strings.Split(id, ",") {
+		if len(seqs) == maxEventIDChannels {
+			break
+		}
+		channel, seq, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		channelID, err := strconv.ParseInt(channel, 10, 64)
+		if err != nil || channelID <= 0 {
+			continue
+		}
+		n, err := strconv.ParseInt(seq, 10, 64)
+		if err != nil || n < 0 {
+			continue
+		}
+		seqs[channelID] = n
+	}
+	return seqs
+}
@@ -0,0 +1,41 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/usecase"
+)
+
+func TestEventID(t *testing.T) {
+	assert.Equal(t, "1:15,7:3", formatEventID(map[int64]int64{7: 3, 1: 15}))
+	assert.Equal(t, map[int64]int64{1: 15, 7: 3}, parseEventID("1:15,7:3"))
+	assert.Equal(t, map[int64]int64{2: 4}, parseEventID("x,1:y,-3:1,2:4,5"))
+	assert.Nil(t, parseEventID(""))
+}
+
+func TestSSEWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := newSSEWriter(rec, map[int64]int64{1: 10})
+
+	require.NoError(t, stream.WriteFrame(usecase.NewFrame([]byte(`{"type":"message","channel_id":2,"seq":5}`))))
+	// Без номера сообщения id не меняется
+	require.NoError(t, stream.WriteFrame(usecase.NewFrame([]byte(`{"type":"typing_start","channel_id":2}`))))
+	// Уже полученный номер не отодвигает курсор назад
+	require.NoError(t, stream.WriteFrame(usecase.NewFrame([]byte(`{"type":"message","channel_id":1,"seq":9}`))))
+	require.NoError(t, stream.Ping())
+	require.NoError(t, stream.Close(4003, "banned"))
+
+	assert.Equal(t, "id: 1:10,2:5\n"+
+		`data: {"type":"message","channel_id":2,"seq":5}`+"\n\n"+
+		`data: {"type":"typing_start","channel_id":2}`+"\n\n"+
+		`data: {"type":"message","channel_id":1,"seq":9}`+"\n\n"+
+		": ping\n\n"+
+		"event: close\n"+`data: {"code":4003,"reason":"banned"}`+"\n\n", rec.Body.String())
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
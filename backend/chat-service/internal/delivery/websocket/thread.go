@@ -0,0 +1,69 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+)
+
+// @Summary Ветка ответов
+// @Description Возвращает сообщение и ответы на него от старых к новым. Сообщение приватного канала доступно только участникам.
+// @Tags messages
+// @Produce  json
+// @Param   id     path     int     true  "Message ID"
+// @Param   limit  query    int     false "Limit, 50 by default, at most 100"
+// @Param   after  query    string  false "Cursor: replies after it"
+// @Param   Authorization header string false "Bearer token"
+// @Success 200 {object} entity.Thread
+// @Router /api/chat/messages/{id}/thread [get]
+func (h *Handler) handleThread(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var limit int32
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		if n > entity.MaxHistoryLimit {
+			n = entity.MaxHistoryLimit
+		}
+		limit = int32(n)
+	}
+	afterID, err := entity.DecodeCursor(r.URL.Query().Get("after"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var userID int64
+	if user := h.authenticate(r); user != nil {
+		userID = user.ID
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	thread, err := h.useCase.Thread(ctx, userID, parseID(mux.Vars(r)["id"]), limit, afterID)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		h.channelError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, thread)
+}
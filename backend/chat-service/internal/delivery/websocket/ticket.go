@@ -0,0 +1,54 @@
+package websocket
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"backend/chat-service/internal/auth"
+)
+
+// @Summary Билет для подключения к WebSocket
+// @Description Обменивает токен из заголовка Authorization на одноразовый билет.
+// @Description Билет передается в параметре ticket при подключении к /api/chat/ws или /api/chat/events
+// @Description вместо токена и действует несколько секунд.
+// @Tags websocket
+// @Produce  json
+// @Param   Authorization header string true "Bearer token"
+// @Success 201 {object} auth.Ticket
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 503 {string} string "Auth service unavailable"
+// @Router /api/chat/ws-ticket [post]
+func (h *Handler) handleIssueTicket(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// Билет выдается только по заголовку, иначе токен снова оказался бы в URL
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if token == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	user, err := h.validateToken(r.Context(), token)
+	if errors.Is(err, auth.ErrInvalidToken) || (err == nil && user.ID == 0) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to validate token", zap.Error(err))
+		http.Error(w, "Auth service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	ticket, err := h.tickets.Issue(r.Context(), user)
+	if err != nil {
+		h.logger.Error("Failed to issue websocket ticket", zap.Error(err), zap.Int64("user_id", user.ID))
+		http.Error(w, "Failed to issue ticket", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ticket)
+}
@@ -0,0 +1,67 @@
+package entity
+
+import "time"
+
+// DefaultChannelID общий канал, к которому подключаются все клиенты
+const DefaultChannelID int64 = 1
+
+// Виды каналов
+const (
+	ChannelKindChannel = "channel"
+	// ChannelKindDirect личная переписка двух или нескольких пользователей
+	ChannelKindDirect = "direct"
+)
+
+// MaxDirectParticipants ограничивает размер групповой переписки
+const MaxDirectParticipants = 10
+
+// Channel представляет канал чата или личную переписку
+type Channel struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	IsPrivate bool      `json:"is_private"`
+	CreatedBy int64     `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// Members участники личной переписки
+	Members []int64 `json:"members,omitempty"`
+	// Unread число непрочитанных пользователем сообщений
+	Unread int `json:"unread"`
+}
+
+// IsDirect сообщает, что канал является личной перепиской
+func (c *Channel) IsDirect() bool {
+	return c.Kind == ChannelKindDirect
+}
+
+type ChannelCreate struct {
+	Name      string `json:"name" validate:"required,min=1,max=64"`
+	IsPrivate bool   `json:"is_private"`
+}
+
+type ChannelMemberAdd struct {
+	UserID int64 `json:"user_id" validate:"required"`
+}
+
+// DirectCreate участники новой личной переписки, не считая создателя
+type DirectCreate struct {
+	UserIDs []int64 `json:"user_ids" validate:"required,min=1"`
+}
+
+type ReadInput struct {
+	MessageID int64 `json:"message_id" validate:"required"`
+}
+
+// ReadPointer последнее прочитанное пользователем сообщение канала
+type ReadPointer struct {
+	ChannelID int64 `json:"channel_id"`
+	UserID    int64 `json:"user_id"`
+	MessageID int64 `json:"last_read_id"`
+}
+
+// UnreadCount число непрочитанных сообщений в канале
+type UnreadCount struct {
+	ChannelID  int64 `json:"channel_id"`
+	Unread     int   `json:"unread"`
+	LastReadID int64 `json:"last_read_id"`
+}
@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultHistoryLimit число сообщений в странице истории по умолчанию
+	DefaultHistoryLimit = 50
+	// MaxHistoryLimit наибольший размер страницы истории
+	MaxHistoryLimit = 100
+
+	cursorPrefix = "m1:"
+)
+
+// ErrInvalidCursor курсор поврежден или выдан не этим сервисом
+var ErrInvalidCursor = errors.New("invalid history cursor")
+
+// HistoryQuery запрос страницы истории канала.
+// Без курсоров возвращаются последние сообщения канала.
+type HistoryQuery struct {
+	ChannelID int64
+	Limit     int32
+	// BeforeID последние Limit сообщений до этого сообщения (прокрутка назад)
+	BeforeID int64
+	// AfterID первые Limit сообщений после этого сообщения (догрузка после переподключения)
+	AfterID int64
+}
+
+// HistoryPage страница истории. Сообщения всегда упорядочены от старых к новым.
+type HistoryPage struct {
+	Messages []*Message `json:"messages"`
+	// Before курсор для загрузки более старых сообщений, пустой, если их нет
+	Before string `json:"before,omitempty"`
+	// After курсор для загрузки более новых сообщений. Задан всегда, когда известна
+	// позиция, чтобы клиент мог догрузить сообщения, пришедшие позже.
+	After string `json:"after,omitempty"`
+	// HasMore сообщает, что в направлении запроса есть еще сообщения
+	HasMore bool `json:"has_more"`
+}
+
+// EncodeCursor возвращает непрозрачный курсор, указывающий на сообщение
+func EncodeCursor(messageID int64) string {
+	if messageID <= 0 {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(messageID, 10)))
+}
+
+// DecodeCursor возвращает ID сообщения из курсора. Пустой курсор означает 0.
+func DecodeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	value := string(raw)
+	if !strings.HasPrefix(value, cursorPrefix) {
+		return 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(value, cursorPrefix), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+// Thread сообщение и страница ответов на него, упорядоченных от старых к новым
+type Thread struct {
+	Parent  *Message   `json:"parent"`
+	Replies []*Message `json:"replies"`
+	// After курсор для загрузки следующих ответов
+	After   string `json:"after,omitempty"`
+	HasMore bool   `json:"has_more"`
+}
@@ -1,15 +1,50 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"backend/pkg/unfurl"
+)
 
 // Message представляет сообщение в чате
 type Message struct {
-	ID        string    `json:"id"`
+	ID        string `json:"id"`
+	ChannelID int64  `json:"channel_id"`
+	// Seq порядковый номер сообщения в канале, без пропусков
+	Seq int64 `json:"seq"`
+	// TempID идентификатор, присвоенный сообщению клиентом-отправителем
+	TempID    string    `json:"-"`
 	Content   string    `json:"content"`
 	UserID    int64     `json:"user_id"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// EditedAt время последнего редактирования
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// DeletedAt время удаления. У удаленного сообщения остается только надгробие без текста.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int64     `json:"deleted_by,omitempty"`
+	// ReplyTo ID сообщения того же канала, на которое это сообщение отвечает
+	ReplyTo string `json:"reply_to,omitempty"`
+	// Reactions сводка реакций в порядке появления
+	Reactions []Reaction `json:"reactions,omitempty"`
+	// Previews превью ссылок из текста, добавляются после рассылки сообщения
+	Previews []unfurl.Preview `json:"previews,omitempty"`
+	// Held сообщает, что фильтр задержал сообщение до проверки модератором:
+	// оно не сохранено в истории и никому не разослано
+	Held bool `json:"held,omitempty"`
+}
+
+// Reaction реакции на сообщение одним эмодзи
+type Reaction struct {
+	Emoji   string  `json:"emoji"`
+	Count   int     `json:"count"`
+	UserIDs []int64 `json:"user_ids"`
+}
+
+// IsDeleted сообщает, что сообщение удалено
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt != nil
 }
 
 // NewMessage создает новое сообщение
@@ -24,6 +59,12 @@ func NewMessage(content string, userID int64, username string) *Message {
 	}
 }
 
+// MessageCreate сообщение, отправляемое через HTTP вместо WebSocket
 type MessageCreate struct {
-	Content string `json:"content" validate:"required,min=1,max=1000"`
+	// ChannelID канал, по умолчанию общий
+	ChannelID int64  `json:"channel_id"`
+	Content   string `json:"content" validate:"required,min=1,max=1000"`
+	// TempID идентификатор отправителя, как в WebSocket: повторный запрос с ним не создает дубликат
+	TempID  string `json:"tempId"`
+	ReplyTo string `json:"reply_to,omitempty"`
 }
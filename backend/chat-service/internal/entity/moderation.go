@@ -0,0 +1,90 @@
+package entity
+
+import "time"
+
+// Виды ограничений пользователя
+const (
+	SanctionMute = "mute"
+	SanctionBan  = "ban"
+)
+
+// Действия модераторов в журнале
+const (
+	ActionMute     = "mute"
+	ActionUnmute   = "unmute"
+	ActionKick     = "kick"
+	ActionBan      = "ban"
+	ActionUnban    = "unban"
+	ActionSlowMode = "slowmode"
+	ActionPurge    = "purge"
+	// ActionApprove и ActionDecline решения по сообщениям, задержанным фильтром
+	ActionApprove = "approve"
+	ActionDecline = "decline"
+)
+
+// AllChannels вместо ID канала означает ограничение во всех каналах
+const AllChannels int64 = 0
+
+// Sanction ограничение пользователя в канале. ExpiresAt nil — бессрочное.
+type Sanction struct {
+	ChannelID int64      `json:"channel_id"`
+	UserID    int64      `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedBy int64      `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Restrictions действующие ограничения пользователя в канале
+type Restrictions struct {
+	Mute *Sanction
+	Ban  *Sanction
+	// SlowMode минимальный интервал между сообщениями в канале, 0 — без ограничения
+	SlowMode time.Duration
+	// LastMessageAt время последнего сообщения пользователя в канале в пределах SlowMode
+	LastMessageAt *time.Time
+}
+
+// ModerationRequest параметры действия модератора. Duration — длительность mute и ban
+// в секундах (0 для бессрочного бана), интервал медленного режима или период, за который
+// удаляются сообщения при очистке.
+type ModerationRequest struct {
+	ChannelID int64  `json:"channel_id"`
+	UserID    int64  `json:"user_id"`
+	Duration  int    `json:"duration_seconds"`
+	Reason    string `json:"reason"`
+}
+
+// ModerationAction запись журнала модерации. Affected — число сообщений, удаленных при очистке.
+type ModerationAction struct {
+	ID          int64     `json:"id"`
+	Action      string    `json:"action"`
+	ChannelID   int64     `json:"channel_id"`
+	ModeratorID int64     `json:"moderator_id"`
+	UserID      int64     `json:"user_id,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	Duration    int       `json:"duration_seconds,omitempty"`
+	Affected    int       `json:"affected,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ModerationLogQuery фильтр журнала модерации. Нулевые поля не ограничивают выборку.
+type ModerationLogQuery struct {
+	ChannelID *int64
+	UserID    int64
+	BeforeID  int64
+	Limit     int32
+}
+
+// HeldMessage сообщение, задержанное фильтром до проверки модератором.
+// Rules — правила фильтра, на которые оно сработало.
+type HeldMessage struct {
+	ID        int64     `json:"id"`
+	ChannelID int64     `json:"channel_id"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Content   string    `json:"content"`
+	Rules     []string  `json:"rules"`
+	CreatedAt time.Time `json:"created_at"`
+}
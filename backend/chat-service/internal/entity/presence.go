@@ -0,0 +1,15 @@
+package entity
+
+// Статусы присутствия пользователя
+const (
+	StatusOnline  = "online"
+	StatusAway    = "away"
+	StatusOffline = "offline"
+)
+
+// OnlineUser пользователь, подключенный к чату
+type OnlineUser struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
@@ -0,0 +1,383 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"backend/chat-service/internal/entity"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var (
+	ErrChannelNotFound = errors.New("channel not found")
+	ErrChannelExists   = errors.New("channel with this name already exists")
+)
+
+// ChannelRepository интерфейс для работы с каналами и их участниками
+type ChannelRepository interface {
+	Create(ctx context.Context, channel *entity.Channel) error
+	GetByID(ctx context.Context, id int64) (*entity.Channel, error)
+	// GetVisible возвращает публичные каналы и приватные, в которых состоит пользователь
+	GetVisible(ctx context.Context, userID int64) ([]*entity.Channel, error)
+	// GetUserChannels возвращает каналы и переписки пользователя с числом непрочитанных сообщений
+	GetUserChannels(ctx context.Context, userID int64) ([]*entity.Channel, error)
+	// GetDirect возвращает личные переписки пользователя, последние активные первыми
+	GetDirect(ctx context.Context, userID int64) ([]*entity.Channel, error)
+	// CreateDirect создает переписку с участниками. Переписка один на один с тем же
+	// собеседником не создается повторно: возвращается существующая и created = false.
+	CreateDirect(ctx context.Context, channel *entity.Channel) (created bool, err error)
+	GetMembers(ctx context.Context, channelID int64) ([]int64, error)
+	AddMember(ctx context.Context, channelID, userID int64) error
+	RemoveMember(ctx context.Context, channelID, userID int64) error
+	IsMember(ctx context.Context, channelID, userID int64) (bool, error)
+	// MarkRead сдвигает указатель прочитанного вперед. Возвращает false, если пользователь не участник.
+	MarkRead(ctx context.Context, channelID, userID, messageID int64) (bool, error)
+	// MarkReadBatch сохраняет несколько указателей прочитанного одним запросом
+	MarkReadBatch(ctx context.Context, pointers []entity.ReadPointer) error
+	// GetUnread возвращает число непрочитанных сообщений во всех каналах пользователя
+	GetUnread(ctx context.Context, userID int64) ([]entity.UnreadCount, error)
+	// GetReadPointers возвращает указатели прочитанного всех участников канала
+	GetReadPointers(ctx context.Context, channelID int64) ([]entity.ReadPointer, error)
+}
+
+type channelRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewChannelRepository создает новый репозиторий каналов
+func NewChannelRepository(pool *pgxpool.Pool) ChannelRepository {
+	return &channelRepository{pool: pool}
+}
+
+const channelColumns = `c.id, c.name, c.kind, c.is_private, c.created_by, c.created_at`
+
+// memberChannelColumns дополняет channelColumns участниками переписки и числом
+// непрочитанных сообщений участника m
+const memberChannelColumns = channelColumns + `,
+        CASE WHEN c.kind = 'direct' THEN ARRAY(
+            SELECT cm.user_id FROM channel_members cm WHERE cm.channel_id = c.id ORDER BY cm.user_id
+        ) END,
+        (SELECT COUNT(*) FROM messages msg
+         WHERE msg.channel_id = c.id AND msg.id > m.last_read_id
+           AND msg.user_id <> m.user_id AND msg.deleted_at IS NULL)`
+
+func scanChannel(row pgx.Row) (*entity.Channel, error) {
+	channel := &entity.Channel{}
+	err := row.Scan(
+		&channel.ID,
+		&channel.Name,
+		&channel.Kind,
+		&channel.IsPrivate,
+		&channel.CreatedBy,
+		&channel.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func scanMemberChannel(row pgx.Row) (*entity.Channel, error) {
+	channel := &entity.Channel{}
+	err := row.Scan(
+		&channel.ID,
+		&channel.Name,
+		&channel.Kind,
+		&channel.IsPrivate,
+		&channel.CreatedBy,
+		&channel.CreatedAt,
+		&channel.Members,
+		&channel.Unread,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Create создает канал и добавляет в него создателя
+func (r *channelRepository) Create(ctx context.Context, channel *entity.Channel) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	channel.Kind = entity.ChannelKindChannel
+	err = tx.QueryRow(ctx, `
+        INSERT INTO channels (name, kind, is_private, created_by)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, created_at`,
+		channel.Name, channel.Kind, channel.IsPrivate, channel.CreatedBy,
+	).Scan(&channel.ID, &channel.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrChannelExists
+		}
+		return fmt.Errorf("failed to create channel: %v", err)
+	}
+
+	if channel.CreatedBy != 0 {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO channel_members (channel_id, user_id)
+            VALUES ($1, $2)`, channel.ID, channel.CreatedBy)
+		if err != nil {
+			return fmt.Errorf("failed to add channel owner: %v", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *channelRepository) GetByID(ctx context.Context, id int64) (*entity.Channel, error) {
+	channel, err := scanChannel(r.pool.QueryRow(ctx, `
+        SELECT `+channelColumns+`
+        FROM channels c
+        WHERE c.id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel: %v", err)
+	}
+	return channel, nil
+}
+
+func (r *channelRepository) GetVisible(ctx context.Context, userID int64) ([]*entity.Channel, error) {
+	return r.query(ctx, scanChannel, `
+        SELECT `+channelColumns+`
+        FROM channels c
+        WHERE c.kind = 'channel'
+          AND (NOT c.is_private
+           OR EXISTS (SELECT 1 FROM channel_members m WHERE m.channel_id = c.id AND m.user_id = $1))
+        ORDER BY c.id`, userID)
+}
+
+func (r *channelRepository) GetUserChannels(ctx context.Context, userID int64) ([]*entity.Channel, error) {
+	return r.query(ctx, scanMemberChannel, `
+        SELECT `+memberChannelColumns+`
+        FROM channels c
+        JOIN channel_members m ON m.channel_id = c.id
+        WHERE m.user_id = $1
+        ORDER BY c.id`, userID)
+}
+
+func (r *channelRepository) GetDirect(ctx context.Context, userID int64) ([]*entity.Channel, error) {
+	return r.query(ctx, scanMemberChannel, `
+        SELECT `+memberChannelColumns+`
+        FROM channels c
+        JOIN channel_members m ON m.channel_id = c.id
+        WHERE m.user_id = $1 AND c.kind = 'direct'
+        ORDER BY (SELECT MAX(id) FROM messages WHERE channel_id = c.id) DESC NULLS LAST, c.id DESC`, userID)
+}
+
+func (r *channelRepository) CreateDirect(ctx context.Context, channel *entity.Channel) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Ключ есть только у переписки один на один
+	var directKey *string
+	if len(channel.Members) == 2 {
+		key := fmt.Sprintf("%d:%d", channel.Members[0], channel.Members[1])
+		directKey = &key
+	}
+
+	channel.Kind = entity.ChannelKindDirect
+	channel.IsPrivate = true
+	err = tx.QueryRow(ctx, `
+        INSERT INTO channels (name, kind, is_private, created_by, direct_key)
+        VALUES ($1, $2, TRUE, $3, $4)
+        ON CONFLICT (direct_key) DO NOTHING
+        RETURNING id, created_at`,
+		channel.Name, channel.Kind, channel.CreatedBy, directKey,
+	).Scan(&channel.ID, &channel.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Переписка уже есть
+		err = tx.QueryRow(ctx, `
+            SELECT id, name, created_by, created_at FROM channels WHERE direct_key = $1`, directKey,
+		).Scan(&channel.ID, &channel.Name, &channel.CreatedBy, &channel.CreatedAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to get direct conversation: %v", err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create direct conversation: %v", err)
+	}
+
+	for _, userID := range channel.Members {
+		_, err = tx.Exec(ctx, `
+            INSERT INTO channel_members (channel_id, user_id)
+            VALUES ($1, $2)`, channel.ID, userID)
+		if err != nil {
+			return false, fmt.Errorf("failed to add conversation member: %v", err)
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+func (r *channelRepository) GetMembers(ctx context.Context, channelID int64) ([]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT user_id FROM channel_members
+        WHERE channel_id = $1
+        ORDER BY user_id`, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channel members: %v", err)
+	}
+	defer rows.Close()
+
+	var members []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan channel member: %v", err)
+		}
+		members = append(members, userID)
+	}
+
+	return members, rows.Err()
+}
+
+func (r *channelRepository) query(ctx context.Context, scan func(pgx.Row) (*entity.Channel, error), query string, args ...interface{}) ([]*entity.Channel, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query channels: %v", err)
+	}
+	defer rows.Close()
+
+	var channels []*entity.Channel
+	for rows.Next() {
+		channel, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan channel: %v", err)
+		}
+		channels = append(channels, channel)
+	}
+
+	return channels, rows.Err()
+}
+
+func (r *channelRepository) AddMember(ctx context.Context, channelID, userID int64) error {
+	// Новый участник не видит старые сообщения непрочитанными
+	_, err := r.pool.Exec(ctx, `
+        INSERT INTO channel_members (channel_id, user_id, last_read_id)
+        VALUES ($1, $2, COALESCE((SELECT MAX(id) FROM messages WHERE channel_id = $1), 0))
+        ON CONFLICT DO NOTHING`, channelID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to add channel member: %v", err)
+	}
+	return nil
+}
+
+func (r *channelRepository) RemoveMember(ctx context.Context, channelID, userID int64) error {
+	_, err := r.pool.Exec(ctx, `
+        DELETE FROM channel_members
+        WHERE channel_id = $1 AND user_id = $2`, channelID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove channel member: %v", err)
+	}
+	return nil
+}
+
+func (r *channelRepository) IsMember(ctx context.Context, channelID, userID int64) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM channel_members
+            WHERE channel_id = $1 AND user_id = $2
+        )`, channelID, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check channel membership: %v", err)
+	}
+	return exists, nil
+}
+
+func (r *channelRepository) MarkRead(ctx context.Context, channelID, userID, messageID int64) (bool, error) {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE channel_members
+        SET last_read_id = GREATEST(last_read_id, $3)
+        WHERE channel_id = $1 AND user_id = $2`, channelID, userID, messageID)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark channel read: %v", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (r *channelRepository) MarkReadBatch(ctx context.Context, pointers []entity.ReadPointer) error {
+	batch := &pgx.Batch{}
+	for _, p := range pointers {
+		batch.Queue(`
+            UPDATE channel_members
+            SET last_read_id = GREATEST(last_read_id, $3)
+            WHERE channel_id = $1 AND user_id = $2`, p.ChannelID, p.UserID, p.MessageID)
+	}
+
+	results := r.pool.SendBatch(ctx, batch)
+	defer results.Close()
+
+	for range pointers {
+		if _, err := results.Exec(); err != nil {
+			return fmt.Errorf("failed to save read pointers: %v", err)
+		}
+	}
+	return nil
+}
+
+func (r *channelRepository) GetUnread(ctx context.Context, userID int64) ([]entity.UnreadCount, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT m.channel_id,
+            (SELECT COUNT(*) FROM messages msg
+             WHERE msg.channel_id = m.channel_id AND msg.id > m.last_read_id
+               AND msg.user_id <> m.user_id AND msg.deleted_at IS NULL),
+            m.last_read_id
+        FROM channel_members m
+        WHERE m.user_id = $1
+        ORDER BY m.channel_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread counts: %v", err)
+	}
+	defer rows.Close()
+
+	counts := []entity.UnreadCount{}
+	for rows.Next() {
+		var c entity.UnreadCount
+		if err := rows.Scan(&c.ChannelID, &c.Unread, &c.LastReadID); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %v", err)
+		}
+		counts = append(counts, c)
+	}
+
+	return counts, rows.Err()
+}
+
+func (r *channelRepository) GetReadPointers(ctx context.Context, channelID int64) ([]entity.ReadPointer, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT channel_id, user_id, last_read_id
+        FROM channel_members
+        WHERE channel_id = $1
+        ORDER BY user_id`, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query read pointers: %v", err)
+	}
+	defer rows.Close()
+
+	pointers := []entity.ReadPointer{}
+	for rows.Next() {
+		var p entity.ReadPointer
+		if err := rows.Scan(&p.ChannelID, &p.UserID, &p.MessageID); err != nil {
+			return nil, fmt.Errorf("failed to scan read pointer: %v", err)
+		}
+		pointers = append(pointers, p)
+	}
+
+	return pointers, rows.Err()
+}
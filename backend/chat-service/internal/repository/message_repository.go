@@ -2,23 +2,90 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"backend/chat-service/internal/entity"
+	"backend/pkg/unfurl"
 
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
 // MessageRepository интерфейс для работы с сообщениями
 type MessageRepository interface {
+	// Create сохраняет сообщение и присваивает ему ID и номер в канале.
+	// Повтор сообщения с тем же TempID возвращает ErrDuplicateMessage.
 	Create(ctx context.Context, message *entity.Message) error
+	// GetHistory возвращает историю общего канала
 	GetHistory(ctx context.Context, limit int32, beforeID int64) ([]*entity.Message, error)
+	// GetChannelHistory возвращает последние limit сообщений канала до beforeID (0 — последние в канале)
+	// в хронологическом порядке
+	GetChannelHistory(ctx context.Context, channelID int64, limit int32, beforeID int64) ([]*entity.Message, error)
+	// GetChannelHistoryAfter возвращает первые limit сообщений канала после afterID
+	GetChannelHistoryAfter(ctx context.Context, channelID int64, limit int32, afterID int64) ([]*entity.Message, error)
+	// GetSince возвращает первые limit сообщений канала с номером больше seq
+	GetSince(ctx context.Context, channelID, seq int64, limit int32) ([]*entity.Message, error)
 	DeleteOldMessages(ctx context.Context, before time.Time) (int32, error)
+	GetByID(ctx context.Context, id int64) (*entity.Message, error)
+	// Edit заменяет текст неудаленного сообщения
+	Edit(ctx context.Context, id int64, content string) (*entity.Message, error)
+	// SoftDelete стирает текст сообщения, оставляя надгробие
+	SoftDelete(ctx context.Context, id, deletedBy int64) (*entity.Message, error)
+	// GetReplies возвращает первые limit ответов на сообщение после afterID в хронологическом порядке
+	GetReplies(ctx context.Context, parentID int64, limit int32, afterID int64) ([]*entity.Message, error)
+	// ToggleReaction ставит реакцию пользователя на сообщение или снимает уже поставленную.
+	// added сообщает, что реакция поставлена.
+	ToggleReaction(ctx context.Context, messageID, userID int64, emoji string) (added bool, err error)
+	// GetReactions возвращает сводку реакций на сообщения
+	GetReactions(ctx context.Context, messageIDs []int64) (map[int64][]entity.Reaction, error)
+	// SetPreviews заменяет превью ссылок неудаленного сообщения, для удаленного возвращает ErrMessageNotFound
+	SetPreviews(ctx context.Context, messageID int64, previews []unfurl.Preview) error
 	QueryRow(ctx context.Context, query string, args ...interface{}) Row
 }
 
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrDuplicateMessage сообщение с таким TempID уже сохранено, Create заполняет его данными сохраненного
+	ErrDuplicateMessage = errors.New("message already saved")
+)
+
+const messageColumns = `id::text, channel_id, seq, COALESCE(temp_id, ''), content, user_id, username, created_at, updated_at, edited_at, deleted_at, deleted_by, COALESCE(reply_to::text, ''), previews`
+
+func scanMessage(row pgx.Row) (*entity.Message, error) {
+	msg := &entity.Message{}
+	var previews []byte
+	err := row.Scan(
+		&msg.ID,
+		&msg.ChannelID,
+		&msg.Seq,
+		&msg.TempID,
+		&msg.Content,
+		&msg.UserID,
+		&msg.Username,
+		&msg.CreatedAt,
+		&msg.UpdatedAt,
+		&msg.EditedAt,
+		&msg.DeletedAt,
+		&msg.DeletedBy,
+		&msg.ReplyTo,
+		&previews,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(previews) > 0 {
+		if err := json.Unmarshal(previews, &msg.Previews); err != nil {
+			return nil, fmt.Errorf("failed to decode message previews: %v", err)
+		}
+	}
+	return msg, nil
+}
+
 type Row interface {
 	Scan(dest ...interface{}) error
 }
@@ -33,55 +100,137 @@ func NewMessageRepository(pool *pgxpool.Pool) MessageRepository {
 }
 
 func (r *messageRepository) Create(ctx context.Context, message *entity.Message) error {
-	// Проверяем состояние соединения с БД
-	if err := r.pool.Ping(ctx); err != nil {
-		log.Printf("Database connection error: %v", err)
-		return fmt.Errorf("database connection error: %v", err)
+	if message.ChannelID == 0 {
+		message.ChannelID = entity.DefaultChannelID
 	}
 
-	query := `
-        INSERT INTO messages (content, user_id, username, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5)
-        RETURNING id::text`
+	err := r.create(ctx, message)
+	if err == nil || errors.Is(err, ErrDuplicateMessage) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	if message.TempID != "" && errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// То же сообщение одновременно сохранило другое соединение пользователя
+		if existing, findErr := r.getByTempID(ctx, message.UserID, message.TempID); findErr == nil {
+			*message = *existing
+			return ErrDuplicateMessage
+		}
+	}
+	if ctx.Err() != nil {
+		log.Printf("Context error during query execution: %v", ctx.Err())
+		return ctx.Err()
+	}
+	log.Printf("Error saving message: %v", err)
+	return fmt.Errorf("database error: %v", err)
+}
 
-	log.Printf("Executing query: %s with values: content=%s, user_id=%d, username=%s",
-		query, message.Content, message.UserID, message.Username)
+// create сохраняет сообщение, выделяя ему следующий номер канала. Блокировка строки канала
+// упорядочивает одновременные записи, поэтому номера идут без пропусков.
+func (r *messageRepository) create(ctx context.Context, message *entity.Message) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
-	// Используем контекст для выполнения запроса
-	row := r.pool.QueryRow(ctx, query,
+	if message.TempID != "" {
+		existing, err := scanMessage(tx.QueryRow(ctx, `
+            SELECT `+messageColumns+`
+            FROM messages
+            WHERE user_id = $1 AND temp_id = $2`, message.UserID, message.TempID))
+		if err == nil {
+			*message = *existing
+			return ErrDuplicateMessage
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+        UPDATE channels SET last_seq = last_seq + 1
+        WHERE id = $1
+        RETURNING last_seq`, message.ChannelID).Scan(&message.Seq)
+	if err != nil {
+		return err
+	}
+
+	var tempID *string
+	if message.TempID != "" {
+		tempID = &message.TempID
+	}
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO messages (channel_id, seq, temp_id, content, user_id, username, created_at, updated_at, reply_to)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::BIGINT)
+        RETURNING id::text`,
+		message.ChannelID,
+		message.Seq,
+		tempID,
 		message.Content,
 		message.UserID,
 		message.Username,
 		message.CreatedAt,
 		message.UpdatedAt,
-	)
-
-	err := row.Scan(&message.ID)
+		message.ReplyTo,
+	).Scan(&message.ID)
 	if err != nil {
-		if ctx.Err() != nil {
-			// Если ошибка связана с контекстом
-			log.Printf("Context error during query execution: %v", ctx.Err())
-			return ctx.Err()
-		}
-		log.Printf("Error executing query: %v", err)
-		return fmt.Errorf("database error: %v", err)
+		return err
 	}
 
-	log.Printf("Successfully created message with ID: %s", message.ID)
-	return nil
+	return tx.Commit(ctx)
+}
+
+func (r *messageRepository) getByTempID(ctx context.Context, userID int64, tempID string) (*entity.Message, error) {
+	return scanMessage(r.pool.QueryRow(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE user_id = $1 AND temp_id = $2`, userID, tempID))
 }
 
 func (r *messageRepository) GetHistory(ctx context.Context, limit int32, beforeID int64) ([]*entity.Message, error) {
-	query := `
-        SELECT id::text, content, user_id, username, created_at, updated_at
+	return r.GetChannelHistory(ctx, entity.DefaultChannelID, limit, beforeID)
+}
+
+func (r *messageRepository) GetChannelHistory(ctx context.Context, channelID int64, limit int32, beforeID int64) ([]*entity.Message, error) {
+	// Выбираем последние сообщения до курсора и разворачиваем их в хронологический порядок
+	messages, err := r.queryMessages(ctx, `
+        SELECT `+messageColumns+`
         FROM messages
-        WHERE ($2 = 0 OR id < $2)
-        ORDER BY created_at ASC
-        LIMIT $1`
+        WHERE channel_id = $3 AND ($2 = 0 OR id < $2)
+        ORDER BY id DESC
+        LIMIT $1`, limit, beforeID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
 
-	log.Printf("Executing query: %s with values: limit=%d, beforeID=%d", query, limit, beforeID)
+func (r *messageRepository) GetChannelHistoryAfter(ctx context.Context, channelID int64, limit int32, afterID int64) ([]*entity.Message, error) {
+	return r.queryMessages(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE channel_id = $3 AND id > $2
+        ORDER BY id ASC
+        LIMIT $1`, limit, afterID, channelID)
+}
 
-	rows, err := r.pool.Query(ctx, query, limit, beforeID)
+func (r *messageRepository) GetSince(ctx context.Context, channelID, seq int64, limit int32) ([]*entity.Message, error) {
+	return r.queryMessages(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE channel_id = $1 AND seq > $2
+        ORDER BY seq ASC
+        LIMIT $3`, channelID, seq, limit)
+}
+
+func (r *messageRepository) queryMessages(ctx context.Context, query string, args ...interface{}) ([]*entity.Message, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		log.Printf("Error executing query: %v", err)
 		return nil, fmt.Errorf("failed to query messages: %v", err)
@@ -90,15 +239,7 @@ func (r *messageRepository) GetHistory(ctx context.Context, limit int32, beforeI
 
 	var messages []*entity.Message
 	for rows.Next() {
-		msg := &entity.Message{}
-		err := rows.Scan(
-			&msg.ID,
-			&msg.Content,
-			&msg.UserID,
-			&msg.Username,
-			&msg.CreatedAt,
-			&msg.UpdatedAt,
-		)
+		msg, err := scanMessage(rows)
 		if err != nil {
 			log.Printf("Error scanning row: %v", err)
 			return nil, fmt.Errorf("failed to scan message: %v", err)
@@ -111,7 +252,9 @@ func (r *messageRepository) GetHistory(ctx context.Context, limit int32, beforeI
 		return nil, fmt.Errorf("failed to iterate messages: %v", err)
 	}
 
-	log.Printf("Successfully retrieved %d messages", len(messages))
+	if err := r.attachReactions(ctx, messages...); err != nil {
+		return nil, err
+	}
 	return messages, nil
 }
 
@@ -133,6 +276,74 @@ func (r *messageRepository) DeleteOldMessages(ctx context.Context, before time.T
 	return rowsAffected, nil
 }
 
+func (r *messageRepository) GetByID(ctx context.Context, id int64) (*entity.Message, error) {
+	msg, err := scanMessage(r.pool.QueryRow(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %v", err)
+	}
+	if err := r.attachReactions(ctx, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (r *messageRepository) Edit(ctx context.Context, id int64, content string) (*entity.Message, error) {
+	msg, err := scanMessage(r.pool.QueryRow(ctx, `
+        UPDATE messages
+        SET content = $2, edited_at = NOW(), updated_at = NOW()
+        WHERE id = $1 AND deleted_at IS NULL
+        RETURNING `+messageColumns, id, content))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to edit message: %v", err)
+	}
+	return msg, nil
+}
+
+func (r *messageRepository) SoftDelete(ctx context.Context, id, deletedBy int64) (*entity.Message, error) {
+	msg, err := scanMessage(r.pool.QueryRow(ctx, `
+        UPDATE messages
+        SET content = '', previews = NULL, deleted_at = NOW(), deleted_by = $2, updated_at = NOW()
+        WHERE id = $1 AND deleted_at IS NULL
+        RETURNING `+messageColumns, id, deletedBy))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete message: %v", err)
+	}
+	return msg, nil
+}
+
 func (r *messageRepository) QueryRow(ctx context.Context, query string, args ...interface{}) Row {
 	return r.pool.QueryRow(ctx, query, args...)
 }
+
+func (r *messageRepository) SetPreviews(ctx context.Context, messageID int64, previews []unfurl.Preview) error {
+	var data []byte
+	if len(previews) > 0 {
+		var err error
+		if data, err = json.Marshal(previews); err != nil {
+			return err
+		}
+	}
+
+	result, err := r.pool.Exec(ctx, `
+        UPDATE messages SET previews = $2
+        WHERE id = $1 AND deleted_at IS NULL`, messageID, data)
+	if err != nil {
+		return fmt.Errorf("failed to save message previews: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
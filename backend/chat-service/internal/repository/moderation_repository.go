@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/chat-service/internal/entity"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ModerationRepository интерфейс для работы с ограничениями пользователей и журналом модерации
+type ModerationRepository interface {
+	// SetSanction создает ограничение или заменяет действующее ограничение того же вида
+	SetSanction(ctx context.Context, sanction *entity.Sanction) error
+	// RemoveSanction снимает ограничение. Возвращает false, если его не было.
+	RemoveSanction(ctx context.Context, channelID, userID int64, kind string) (bool, error)
+	// Restrictions возвращает действующие ограничения пользователя в канале с учетом
+	// ограничений во всех каналах
+	Restrictions(ctx context.Context, channelID, userID int64) (*entity.Restrictions, error)
+	// IsBanned сообщает, что пользователь заблокирован в канале или во всех каналах
+	IsBanned(ctx context.Context, channelID, userID int64) (bool, error)
+	// SetSlowMode задает интервал медленного режима канала в секундах
+	SetSlowMode(ctx context.Context, channelID int64, seconds int) error
+	// Purge удаляет сообщения пользователя, отправленные после since. channelID 0 — во всех каналах.
+	Purge(ctx context.Context, channelID, userID, deletedBy int64, since time.Time) ([]*entity.Message, error)
+	LogAction(ctx context.Context, action *entity.ModerationAction) error
+	// GetLog возвращает записи журнала, последние первыми
+	GetLog(ctx context.Context, query entity.ModerationLogQuery) ([]*entity.ModerationAction, error)
+	// HoldMessage ставит сообщение, задержанное фильтром, в очередь на проверку
+	HoldMessage(ctx context.Context, held *entity.HeldMessage) error
+	// HeldMessages возвращает очередь на проверку, старые первыми. channelID 0 — все каналы.
+	HeldMessages(ctx context.Context, channelID int64, limit int32) ([]*entity.HeldMessage, error)
+	// TakeHeld удаляет сообщение из очереди и возвращает его
+	TakeHeld(ctx context.Context, id int64) (*entity.HeldMessage, error)
+	// Reputation возвращает число неудаленных сообщений пользователя, не больше maxReputation
+	Reputation(ctx context.Context, userID int64) (int, error)
+}
+
+// ErrHeldNotFound сообщения нет в очереди на проверку
+var ErrHeldNotFound = errors.New("held message not found")
+
+// maxReputation ограничивает подсчет сообщений для репутации
+const maxReputation = 1000
+
+type moderationRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewModerationRepository создает новый репозиторий модерации
+func NewModerationRepository(pool *pgxpool.Pool) ModerationRepository {
+	return &moderationRepository{pool: pool}
+}
+
+// activeSanction условие действующего ограничения
+const activeSanction = `(expires_at IS NULL OR expires_at > NOW())`
+
+func (r *moderationRepository) SetSanction(ctx context.Context, sanction *entity.Sanction) error {
+	err := r.pool.QueryRow(ctx, `
+        INSERT INTO chat_sanctions (channel_id, user_id, kind, reason, expires_at, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT (user_id, channel_id, kind) DO UPDATE
+        SET reason = EXCLUDED.reason,
+            expires_at = EXCLUDED.expires_at,
+            created_by = EXCLUDED.created_by,
+            created_at = NOW()
+        RETURNING created_at`,
+		sanction.ChannelID, sanction.UserID, sanction.Kind, sanction.Reason, sanction.ExpiresAt, sanction.CreatedBy,
+	).Scan(&sanction.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save sanction: %v", err)
+	}
+	return nil
+}
+
+func (r *moderationRepository) RemoveSanction(ctx context.Context, channelID, userID int64, kind string) (bool, error) {
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM chat_sanctions
+        WHERE user_id = $1 AND channel_id = $2 AND kind = $3 AND `+activeSanction,
+		userID, channelID, kind)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove sanction: %v", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+func (r *moderationRepository) Restrictions(ctx context.Context, channelID, userID int64) (*entity.Restrictions, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT channel_id, user_id, kind, reason, expires_at, created_by, created_at
+        FROM chat_sanctions
+        WHERE user_id = $1 AND channel_id IN ($2, 0) AND `+activeSanction,
+		userID, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sanctions: %v", err)
+	}
+	defer rows.Close()
+
+	restrictions := &entity.Restrictions{}
+	for rows.Next() {
+		s := &entity.Sanction{}
+		if err := rows.Scan(&s.ChannelID, &s.UserID, &s.Kind, &s.Reason, &s.ExpiresAt, &s.CreatedBy, &s.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sanction: %v", err)
+		}
+		switch s.Kind {
+		case entity.SanctionMute:
+			restrictions.Mute = longer(restrictions.Mute, s)
+		case entity.SanctionBan:
+			restrictions.Ban = longer(restrictions.Ban, s)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sanctions: %v", err)
+	}
+
+	var slowMode int
+	err = r.pool.QueryRow(ctx, `
+        SELECT c.slow_mode_seconds,
+               (SELECT MAX(m.created_at) FROM messages m
+                WHERE m.user_id = $2 AND m.channel_id = c.id AND c.slow_mode_seconds > 0
+                  AND m.created_at > NOW() - c.slow_mode_seconds * INTERVAL '1 second')
+        FROM channels c
+        WHERE c.id = $1`, channelID, userID).Scan(&slowMode, &restrictions.LastMessageAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get slow mode: %v", err)
+	}
+	restrictions.SlowMode = time.Duration(slowMode) * time.Second
+
+	return restrictions, nil
+}
+
+// longer возвращает ограничение, которое закончится позже
+func longer(a, b *entity.Sanction) *entity.Sanction {
+	switch {
+	case a == nil:
+		return b
+	case a.ExpiresAt == nil:
+		return a
+	case b.ExpiresAt == nil || b.ExpiresAt.After(*a.ExpiresAt):
+		return b
+	}
+	return a
+}
+
+func (r *moderationRepository) IsBanned(ctx context.Context, channelID, userID int64) (bool, error) {
+	var banned bool
+	err := r.pool.QueryRow(ctx, `
+        SELECT EXISTS (
+            SELECT 1 FROM chat_sanctions
+            WHERE user_id = $1 AND channel_id IN ($2, 0) AND kind = $3 AND `+activeSanction+`
+        )`, userID, channelID, entity.SanctionBan).Scan(&banned)
+	if err != nil {
+		return false, fmt.Errorf("failed to check ban: %v", err)
+	}
+	return banned, nil
+}
+
+func (r *moderationRepository) SetSlowMode(ctx context.Context, channelID int64, seconds int) error {
+	result, err := r.pool.Exec(ctx, `
+        UPDATE channels SET slow_mode_seconds = $2 WHERE id = $1`, channelID, seconds)
+	if err != nil {
+		return fmt.Errorf("failed to set slow mode: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrChannelNotFound
+	}
+	return nil
+}
+
+func (r *moderationRepository) Purge(ctx context.Context, channelID, userID, deletedBy int64, since time.Time) ([]*entity.Message, error) {
+	rows, err := r.pool.Query(ctx, `
+        UPDATE messages
+        SET content = '', previews = NULL, deleted_at = NOW(), deleted_by = $3, updated_at = NOW()
+        WHERE user_id = $1 AND ($2 = 0 OR channel_id = $2) AND created_at >= $4 AND deleted_at IS NULL
+        RETURNING `+messageColumns, userID, channelID, deletedBy, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge messages: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.Message
+	for rows.Next() {
+		msg, err := scanMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to purge messages: %v", err)
+	}
+	return messages, nil
+}
+
+func (r *moderationRepository) LogAction(ctx context.Context, action *entity.ModerationAction) error {
+	err := r.pool.QueryRow(ctx, `
+        INSERT INTO chat_moderation_log (action, channel_id, moderator_id, user_id, reason, duration_seconds, affected)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        RETURNING id, created_at`,
+		action.Action, action.ChannelID, action.ModeratorID, action.UserID, action.Reason, action.Duration, action.Affected,
+	).Scan(&action.ID, &action.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to log moderation action: %v", err)
+	}
+	return nil
+}
+
+func (r *moderationRepository) GetLog(ctx context.Context, query entity.ModerationLogQuery) ([]*entity.ModerationAction, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT id, action, channel_id, moderator_id, user_id, reason, duration_seconds, affected, created_at
+        FROM chat_moderation_log
+        WHERE ($1::BIGINT IS NULL OR channel_id = $1)
+          AND ($2 = 0 OR user_id = $2)
+          AND ($3 = 0 OR id < $3)
+        ORDER BY id DESC
+        LIMIT $4`, query.ChannelID, query.UserID, query.BeforeID, query.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query moderation log: %v", err)
+	}
+	defer rows.Close()
+
+	var actions []*entity.ModerationAction
+	for rows.Next() {
+		a := &entity.ModerationAction{}
+		err := rows.Scan(&a.ID, &a.Action, &a.ChannelID, &a.ModeratorID, &a.UserID,
+			&a.Reason, &a.Duration, &a.Affected, &a.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan moderation action: %v", err)
+		}
+		actions = append(actions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate moderation log: %v", err)
+	}
+	return actions, nil
+}
+
+func (r *moderationRepository) HoldMessage(ctx context.Context, held *entity.HeldMessage) error {
+	err := r.pool.QueryRow(ctx, `
+        INSERT INTO chat_held_messages (channel_id, user_id, username, content, rules)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, created_at`,
+		held.ChannelID, held.UserID, held.Username, held.Content, held.Rules,
+	).Scan(&held.ID, &held.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to hold message: %v", err)
+	}
+	return nil
+}
+
+const heldColumns = `id, channel_id, user_id, username, content, rules, created_at`
+
+func scanHeld(row pgx.Row) (*entity.HeldMessage, error) {
+	h := &entity.HeldMessage{}
+	err := row.Scan(&h.ID, &h.ChannelID, &h.UserID, &h.Username, &h.Content, &h.Rules, &h.CreatedAt)
+	return h, err
+}
+
+func (r *moderationRepository) HeldMessages(ctx context.Context, channelID int64, limit int32) ([]*entity.HeldMessage, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT `+heldColumns+`
+        FROM chat_held_messages
+        WHERE $1 = 0 OR channel_id = $1
+        ORDER BY id
+        LIMIT $2`, channelID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query held messages: %v", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.HeldMessage
+	for rows.Next() {
+		h, err := scanHeld(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan held message: %v", err)
+		}
+		messages = append(messages, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate held messages: %v", err)
+	}
+	return messages, nil
+}
+
+func (r *moderationRepository) TakeHeld(ctx context.Context, id int64) (*entity.HeldMessage, error) {
+	h, err := scanHeld(r.pool.QueryRow(ctx, `
+        DELETE FROM chat_held_messages WHERE id = $1
+        RETURNING `+heldColumns, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrHeldNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to take held message: %v", err)
+	}
+	return h, nil
+}
+
+func (r *moderationRepository) Reputation(ctx context.Context, userID int64) (int, error) {
+	var count int
+	err := r.pool.QueryRow(ctx, `
+        SELECT COUNT(*) FROM (
+            SELECT 1 FROM messages
+            WHERE user_id = $1 AND deleted_at IS NULL
+            LIMIT $2
+        ) m`, userID, maxReputation).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages of user %d: %v", userID, err)
+	}
+	return count, nil
+}
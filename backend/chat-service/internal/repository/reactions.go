@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"backend/chat-service/internal/entity"
+)
+
+func (r *messageRepository) GetReplies(ctx context.Context, parentID int64, limit int32, afterID int64) ([]*entity.Message, error) {
+	return r.queryMessages(ctx, `
+        SELECT `+messageColumns+`
+        FROM messages
+        WHERE reply_to = $1 AND id > $2
+        ORDER BY id ASC
+        LIMIT $3`, parentID, afterID, limit)
+}
+
+func (r *messageRepository) ToggleReaction(ctx context.Context, messageID, userID int64, emoji string) (bool, error) {
+	// Снимаем реакцию, если она уже стоит, иначе ставим. Одновременный повтор того же
+	// запроса не создает дубликат благодаря первичному ключу.
+	result, err := r.pool.Exec(ctx, `
+        DELETE FROM message_reactions
+        WHERE message_id = $1 AND user_id = $2 AND emoji = $3`, messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction: %v", err)
+	}
+	if result.RowsAffected() > 0 {
+		return false, nil
+	}
+
+	_, err = r.pool.Exec(ctx, `
+        INSERT INTO message_reactions (message_id, user_id, emoji)
+        VALUES ($1, $2, $3)
+        ON CONFLICT DO NOTHING`, messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to add reaction: %v", err)
+	}
+	return true, nil
+}
+
+func (r *messageRepository) GetReactions(ctx context.Context, messageIDs []int64) (map[int64][]entity.Reaction, error) {
+	reactions := make(map[int64][]entity.Reaction)
+	if len(messageIDs) == 0 {
+		return reactions, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `
+        SELECT message_id, emoji, COUNT(*), ARRAY_AGG(user_id ORDER BY created_at)
+        FROM message_reactions
+        WHERE message_id = ANY($1)
+        GROUP BY message_id, emoji
+        ORDER BY message_id, MIN(created_at)`, messageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reactions: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID int64
+		var reaction entity.Reaction
+		if err := rows.Scan(&messageID, &reaction.Emoji, &reaction.Count, &reaction.UserIDs); err != nil {
+			return nil, fmt.Errorf("failed to scan reaction: %v", err)
+		}
+		reactions[messageID] = append(reactions[messageID], reaction)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate reactions: %v", err)
+	}
+	return reactions, nil
+}
+
+// attachReactions заполняет реакции сообщений одним запросом
+func (r *messageRepository) attachReactions(ctx context.Context, messages ...*entity.Message) error {
+	ids := make([]int64, 0, len(messages))
+	for _, msg := range messages {
+		if id, err := strconv.ParseInt(msg.ID, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	reactions, err := r.GetReactions(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		id, _ := strconv.ParseInt(msg.ID, 10, 64)
+		msg.Reactions = reactions[id]
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"backend/chat-service/internal/auth"
+	"backend/pkg/ratelimit"
+)
+
+// Коды закрытия соединения при ошибках авторизации
+const (
+	// CloseAuthFailed токен или билет недействителен либо принадлежит другому пользователю
+	CloseAuthFailed = 4401
+	// CloseTokenExpired токен истек, а новый не прислали в "auth"
+	CloseTokenExpired = 4402
+)
+
+// ErrCodeAuthUnavailable сервис авторизации не ответил, "auth" можно повторить
+const ErrCodeAuthUnavailable = "auth_unavailable"
+
+const (
+	// authTimeout время на проверку токена из "auth"
+	authTimeout = 5 * time.Second
+	// reauthNotice за сколько до истечения токена клиенту приходит "reauth"
+	reauthNotice = 30 * time.Second
+)
+
+// WithAuth включает сообщения "auth": вход по токену после подключения и продление
+// авторизации, когда токен истекает
+func WithAuth(validator auth.Validator) Option {
+	return func(uc *ChatUseCase) {
+		uc.auth = validator
+	}
+}
+
+// SetTokenExpiry задает срок действия токена клиента. Незадолго до него клиенту придет
+// "reauth", а если он не пришлет новый токен, соединение закроется с CloseTokenExpired.
+// Нулевое время — срок неизвестен, соединение не ограничивается.
+// Токен с тем же или более ранним сроком не продлевает авторизацию, иначе повторно
+// присланный старый токен сразу вызывал бы новый "reauth".
+func (c *Client) SetTokenExpiry(expiresAt time.Time) {
+	var nanos int64
+	if !expiresAt.IsZero() {
+		nanos = expiresAt.UnixNano()
+	}
+	for {
+		current := c.tokenExpiry.Load()
+		if current != 0 && nanos != 0 && nanos <= current {
+			return
+		}
+		if c.tokenExpiry.CompareAndSwap(current, nanos) {
+			break
+		}
+	}
+
+	select {
+	case c.tokenRenewed <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Client) tokenExpiresAt() time.Time {
+	nanos := c.tokenExpiry.Load()
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+// watchToken предупреждает клиента об истечении токена и отключает его, если токен не продлен
+func (c *Client) watchToken() {
+	for {
+		expiresAt := c.tokenExpiresAt()
+		if expiresAt.IsZero() {
+			select {
+			case <-c.tokenRenewed:
+				continue
+			case <-c.ctx.Done():
+				return
+			}
+		}
+
+		if !c.waitToken(time.Until(expiresAt) - reauthNotice) {
+			continue
+		}
+		if c.ctx.Err() != nil {
+			return
+		}
+		c.reply(ChatMessage{Type: "reauth", ExpiresIn: ratelimit.Seconds(time.Until(expiresAt))})
+
+		if !c.waitToken(time.Until(expiresAt)) {
+			continue
+		}
+		c.shutdown(CloseTokenExpired, "token expired")
+		return
+	}
+}
+
+// waitToken ждет d и сообщает, что за это время токен не продлевали
+func (c *Client) waitToken(d time.Duration) bool {
+	timer := time.NewTimer(max(d, 0))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-c.tokenRenewed:
+		return false
+	case <-c.ctx.Done():
+		return true
+	}
+}
+
+// handleAuth обрабатывает сообщение типа "auth". Анонимный клиент становится авторизованным,
+// авторизованный продлевает токен: права обновляются, пользователь должен остаться тем же.
+// Недействительный токен закрывает соединение с CloseAuthFailed.
+func (c *Client) handleAuth(msg ChatMessage, uc *ChatUseCase) error {
+	if uc.auth == nil {
+		c.reply(ChatMessage{Type: "error", Code: ErrCodeAuthUnavailable, Error: "Авторизация через соединение недоступна"})
+		return nil
+	}
+	if msg.Token == "" {
+		c.shutdown(CloseAuthFailed, "token required")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, authTimeout)
+	defer cancel()
+
+	user, err := uc.auth.Validate(ctx, msg.Token)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		c.shutdown(CloseAuthFailed, "invalid token")
+		return nil
+	}
+	if err != nil {
+		log.Printf("failed to validate token of websocket client: %v", err)
+		c.reply(ChatMessage{Type: "error", Code: ErrCodeAuthUnavailable, Error: "Не удалось проверить токен, попробуйте позже"})
+		return nil
+	}
+	if c.IsAuth && user.ID != c.UserID {
+		c.shutdown(CloseAuthFailed, "token belongs to another user")
+		return nil
+	}
+
+	if c.IsAuth {
+		uc.mutex.Lock()
+		c.Access = user.Access()
+		uc.mutex.Unlock()
+	} else {
+		uc.promote(c, user)
+		// Подписываем на каналы пользователя, как при подключении с токеном
+		c.restoreChannels(uc)
+	}
+	c.SetTokenExpiry(user.ExpiresAt)
+
+	reply := ChatMessage{Type: "auth_success", UserID: c.UserID, Username: c.Username}
+	if !user.ExpiresAt.IsZero() {
+		reply.ExpiresIn = ratelimit.Seconds(time.Until(user.ExpiresAt))
+	}
+	c.reply(reply)
+	return nil
+}
+
+// promote делает анонимного клиента авторизованным
+func (uc *ChatUseCase) promote(c *Client, user *auth.User) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	c.UserID = user.ID
+	c.Username = user.Username
+	c.IsAuth = true
+	c.Role = "authenticated"
+	c.Access = user.Access()
+
+	// Еще не зарегистрированного клиента добавит в пользователи Run, уже отключенного — не нужно
+	if !uc.clients[c] {
+		return
+	}
+	if uc.users[c.UserID] == nil {
+		uc.users[c.UserID] = make(map[*Client]bool)
+	}
+	uc.users[c.UserID][c] = true
+	uc.updateLocalPresenceLocked(c.UserID, c.Username)
+}
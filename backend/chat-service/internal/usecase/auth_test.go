@@ -0,0 +1,158 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/auth"
+)
+
+type fakeValidator map[string]*auth.User
+
+func (v fakeValidator) Validate(ctx context.Context, token string) (*auth.User, error) {
+	user, ok := v[token]
+	if !ok {
+		return nil, auth.ErrInvalidToken
+	}
+	return user, nil
+}
+
+func sendAuth(t *testing.T, uc *ChatUseCase, c *Client, token string) {
+	data, err := json.Marshal(ChatMessage{Type: "auth", Token: token})
+	require.NoError(t, err)
+	require.NoError(t, c.HandleMessage(data, uc))
+}
+
+// nextFrame пропускает кадры присутствия и возвращает первый кадр типа msgType
+func nextFrame(t *testing.T, stream *fakeStream, msgType string) ChatMessage {
+	for {
+		select {
+		case msg := <-stream.frames:
+			if msg.Type == msgType {
+				return msg
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no %q frame", msgType)
+		}
+	}
+}
+
+func streamClient(t *testing.T, uc *ChatUseCase, c *Client) (*fakeStream, chan struct{}) {
+	stream := newFakeStream()
+	done := make(chan struct{})
+	go func() {
+		uc.Stream(context.Background(), c, stream, nil)
+		close(done)
+	}()
+	nextFrame(t, stream, "channels")
+	return stream, done
+}
+
+func TestHandleAuth_PromotesAnonymousClient(t *testing.T) {
+	validator := fakeValidator{"alice-token": {ID: 7, Username: "alice"}}
+	uc := NewChatUseCase(nil, nil, WithAuth(validator))
+	go uc.Run()
+
+	c := NewClient(nil, 0, "anonymous", false)
+	stream, _ := streamClient(t, uc, c)
+
+	sendAuth(t, uc, c, "alice-token")
+	reply := nextFrame(t, stream, "auth_success")
+	assert.Equal(t, int64(7), reply.UserID)
+	assert.True(t, c.IsAuth)
+	require.Eventually(t, func() bool { return len(uc.OnlineUsers()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "alice", uc.OnlineUsers()[0].Username)
+}
+
+func TestHandleAuth_RejectsAnotherUser(t *testing.T) {
+	validator := fakeValidator{"bob-token": {ID: 8, Username: "bob"}}
+	uc := NewChatUseCase(nil, nil, WithAuth(validator))
+	go uc.Run()
+
+	c := NewClient(nil, 7, "alice", true)
+	stream, done := streamClient(t, uc, c)
+
+	sendAuth(t, uc, c, "bob-token")
+	<-done
+	assert.Equal(t, CloseAuthFailed, stream.code())
+}
+
+func TestHandleAuth_InvalidToken(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithAuth(fakeValidator{}))
+	go uc.Run()
+
+	c := NewClient(nil, 0, "anonymous", false)
+	stream, done := streamClient(t, uc, c)
+
+	sendAuth(t, uc, c, "forged")
+	<-done
+	assert.Equal(t, CloseAuthFailed, stream.code())
+}
+
+func TestWatchToken_ExpiredTokenDisconnects(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+	go uc.Run()
+
+	c := NewClient(nil, 7, "alice", true)
+	stream, done := streamClient(t, uc, c)
+	c.SetTokenExpiry(time.Now().Add(100 * time.Millisecond))
+
+	// Токен истекает раньше, чем за reauthNotice: предупреждение приходит сразу
+	nextFrame(t, stream, "reauth")
+	<-done
+	assert.Equal(t, CloseTokenExpired, stream.code())
+}
+
+func TestWatchToken_RenewedTokenKeepsConnection(t *testing.T) {
+	validator := fakeValidator{"alice-token": {ID: 7, Username: "alice", ExpiresAt: time.Now().Add(time.Hour)}}
+	uc := NewChatUseCase(nil, nil, WithAuth(validator))
+	go uc.Run()
+
+	c := NewClient(nil, 7, "alice", true)
+	stream, done := streamClient(t, uc, c)
+	c.SetTokenExpiry(time.Now().Add(200 * time.Millisecond))
+
+	nextFrame(t, stream, "reauth")
+	sendAuth(t, uc, c, "alice-token")
+	reply := nextFrame(t, stream, "auth_success")
+	assert.Greater(t, reply.ExpiresIn, 3000)
+
+	select {
+	case <-done:
+		t.Fatal("client with renewed token was disconnected")
+	case <-time.After(400 * time.Millisecond):
+	}
+}
+
+func TestWatchToken_SameTokenDoesNotRenew(t *testing.T) {
+	expiresAt := time.Now().Add(300 * time.Millisecond)
+	validator := fakeValidator{"alice-token": {ID: 7, Username: "alice", ExpiresAt: expiresAt}}
+	uc := NewChatUseCase(nil, nil, WithAuth(validator))
+	go uc.Run()
+
+	c := NewClient(nil, 7, "alice", true)
+	stream, done := streamClient(t, uc, c)
+	c.SetTokenExpiry(expiresAt)
+
+	// Клиент в ответ на "reauth" прислал тот же токен: повторного "reauth" нет,
+	// соединение закрывается, когда токен истекает
+	nextFrame(t, stream, "reauth")
+	sendAuth(t, uc, c, "alice-token")
+	nextFrame(t, stream, "auth_success")
+
+	for {
+		select {
+		case msg := <-stream.frames:
+			require.NotEqual(t, "reauth", msg.Type, "reauth repeated for the same token")
+			continue
+		case <-done:
+		}
+		break
+	}
+	assert.Equal(t, CloseTokenExpired, stream.code())
+}
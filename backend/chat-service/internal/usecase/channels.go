@@ -0,0 +1,511 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/pkg/rbac"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	ErrChannelNotFound    = repository.ErrChannelNotFound
+	ErrChannelForbidden   = errors.New("no access to channel")
+	ErrChannelsDisabled   = errors.New("channels are not configured")
+	ErrLeaveDefault       = errors.New("cannot leave the general channel")
+	ErrInvalidChannelName = errors.New("channel name must be 1-64 characters long")
+)
+
+// channelLoadTimeout ограничивает загрузку каналов клиента при подключении
+const channelLoadTimeout = 5 * time.Second
+
+// Actor пользователь, от имени которого выполняется действие через REST
+type Actor struct {
+	UserID int64
+	Access rbac.Access
+}
+
+// defaultChannel возвращается, когда репозиторий каналов не настроен
+var defaultChannel = &entity.Channel{ID: entity.DefaultChannelID, Name: "general"}
+
+// getChannel возвращает канал по ID
+func (uc *ChatUseCase) getChannel(ctx context.Context, channelID int64) (*entity.Channel, error) {
+	if uc.channelRepo == nil {
+		if channelID == entity.DefaultChannelID {
+			return defaultChannel, nil
+		}
+		return nil, ErrChannelNotFound
+	}
+	return uc.channelRepo.GetByID(ctx, channelID)
+}
+
+// canRead проверяет, что пользователь может читать канал: публичный канал доступен всем,
+// кроме заблокированных в нем, приватный — только участникам
+func (uc *ChatUseCase) canRead(ctx context.Context, channel *entity.Channel, userID int64) (bool, error) {
+	if banned, err := uc.isBanned(ctx, channel.ID, userID); err != nil || banned {
+		return false, err
+	}
+	if !channel.IsPrivate {
+		return true, nil
+	}
+	if userID == 0 || uc.channelRepo == nil {
+		return false, nil
+	}
+	return uc.channelRepo.IsMember(ctx, channel.ID, userID)
+}
+
+// canManage проверяет, что пользователь может управлять участниками канала
+func canManage(channel *entity.Channel, actor Actor) bool {
+	return (actor.UserID != 0 && channel.CreatedBy == actor.UserID) || actor.Access.Has(rbac.ChatChannelManage)
+}
+
+// ListChannels возвращает каналы, доступные пользователю. userID 0 — анонимный пользователь.
+func (uc *ChatUseCase) ListChannels(ctx context.Context, userID int64) ([]*entity.Channel, error) {
+	if uc.channelRepo == nil {
+		return []*entity.Channel{defaultChannel}, nil
+	}
+	return uc.channelRepo.GetVisible(ctx, userID)
+}
+
+// CreateChannel создает канал, создатель становится его участником
+func (uc *ChatUseCase) CreateChannel(ctx context.Context, actor Actor, input entity.ChannelCreate) (*entity.Channel, error) {
+	if uc.channelRepo == nil {
+		return nil, ErrChannelsDisabled
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len([]rune(name)) > 64 {
+		return nil, ErrInvalidChannelName
+	}
+
+	channel := &entity.Channel{
+		Name:      name,
+		IsPrivate: input.IsPrivate,
+		CreatedBy: actor.UserID,
+	}
+	if err := uc.channelRepo.Create(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	// Подключаем к новому каналу открытые соединения создателя
+	uc.subscribeUsers(ctx, channel, actor.UserID)
+
+	return channel, nil
+}
+
+// AddMember добавляет пользователя в канал. Доступно создателю канала и модераторам.
+func (uc *ChatUseCase) AddMember(ctx context.Context, actor Actor, channelID, userID int64) error {
+	channel, err := uc.getChannel(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if uc.channelRepo == nil {
+		return ErrChannelsDisabled
+	}
+	if channel.IsDirect() {
+		return ErrDirectMembers
+	}
+	if !canManage(channel, actor) {
+		return ErrChannelForbidden
+	}
+
+	if err := uc.channelRepo.AddMember(ctx, channelID, userID); err != nil {
+		return err
+	}
+
+	uc.subscribeUsers(ctx, channel, userID)
+	return nil
+}
+
+// RemoveMember удаляет пользователя из канала. Пользователь может выйти сам,
+// удалить другого может создатель канала или модератор.
+func (uc *ChatUseCase) RemoveMember(ctx context.Context, actor Actor, channelID, userID int64) error {
+	channel, err := uc.getChannel(ctx, channelID)
+	if err != nil {
+		return err
+	}
+	if uc.channelRepo == nil {
+		return ErrChannelsDisabled
+	}
+	if channelID == entity.DefaultChannelID {
+		return ErrLeaveDefault
+	}
+	if channel.IsDirect() {
+		return ErrDirectMembers
+	}
+	if actor.UserID != userID && !canManage(channel, actor) {
+		return ErrChannelForbidden
+	}
+
+	if err := uc.channelRepo.RemoveMember(ctx, channelID, userID); err != nil {
+		return err
+	}
+
+	uc.unsubscribeUsers(ctx, channel, userID)
+	return nil
+}
+
+// restoreChannels подписывает клиента на каналы, в которых он состоит, и отправляет ему их список
+func (c *Client) restoreChannels(uc *ChatUseCase) {
+	if !c.applyBans(uc) {
+		return
+	}
+	channels := []*entity.Channel{defaultChannel}
+
+	if uc.channelRepo != nil && c.IsAuth {
+		ctx, cancel := context.WithTimeout(c.ctx, channelLoadTimeout)
+		defer cancel()
+
+		general, err := uc.channelRepo.GetByID(ctx, entity.DefaultChannelID)
+		if err == nil {
+			channels[0] = general
+		}
+
+		// Число непрочитанных должно учитывать указатели, которые еще не сохранены
+		if uc.reads != nil {
+			uc.reads.Flush(ctx, c.UserID)
+		}
+
+		joined, err := uc.channelRepo.GetUserChannels(ctx, c.UserID)
+		if err != nil {
+			log.Printf("failed to load channels of user %d: %v", c.UserID, err)
+		}
+
+		uc.mutex.Lock()
+		for _, channel := range joined {
+			if channel.ID == entity.DefaultChannelID {
+				// У участника общего канала есть счетчик непрочитанных
+				channels[0] = channel
+				continue
+			}
+			if channel.IsDirect() {
+				uc.direct[channel.ID] = channel.Members
+			}
+			if uc.subscribeLocked(c, channel.ID) {
+				channels = append(channels, channel)
+			}
+		}
+		uc.mutex.Unlock()
+	}
+
+	c.reply(ChatMessage{
+		Type:      "channels",
+		ChannelID: uc.activeChannel(c),
+		Channels:  channels,
+	})
+}
+
+// handleJoin подписывает клиента на канал. Авторизованный пользователь становится участником
+// публичного канала, в приватный канал его должен добавить создатель или модератор.
+func (c *Client) handleJoin(msg ChatMessage, uc *ChatUseCase) error {
+	channel, ok := c.openChannel(msg, uc)
+	if !ok {
+		return nil
+	}
+
+	if err := c.joinChannel(channel, uc); err != nil {
+		return err
+	}
+
+	c.reply(ChatMessage{Type: "joined", ChannelID: channel.ID, Channel: channel.Name})
+	return nil
+}
+
+// joinChannel подписывает клиента на канал, к которому у него есть доступ
+func (c *Client) joinChannel(channel *entity.Channel, uc *ChatUseCase) error {
+	if c.IsAuth && uc.channelRepo != nil && !channel.IsPrivate && channel.ID != entity.DefaultChannelID {
+		if err := uc.channelRepo.AddMember(c.ctx, channel.ID, c.UserID); err != nil {
+			return err
+		}
+	}
+	if channel.IsDirect() {
+		if err := uc.loadDirectMembers(c.ctx, channel.ID); err != nil {
+			return err
+		}
+	}
+
+	uc.mutex.Lock()
+	uc.subscribeLocked(c, channel.ID)
+	uc.mutex.Unlock()
+
+	return nil
+}
+
+// handleLeave отписывает клиента от канала и удаляет его из участников
+func (c *Client) handleLeave(msg ChatMessage, uc *ChatUseCase) error {
+	if msg.ChannelID == entity.DefaultChannelID || msg.ChannelID == 0 {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeChannelForbidden,
+			Error:     "Нельзя покинуть общий канал",
+			ChannelID: entity.DefaultChannelID,
+		})
+		return nil
+	}
+	if uc.directMembers(msg.ChannelID) != nil {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeChannelForbidden,
+			Error:     "Нельзя покинуть личную переписку",
+			ChannelID: msg.ChannelID,
+		})
+		return nil
+	}
+
+	if c.IsAuth && uc.channelRepo != nil {
+		if err := uc.channelRepo.RemoveMember(c.ctx, msg.ChannelID, c.UserID); err != nil {
+			return err
+		}
+	}
+
+	uc.mutex.Lock()
+	uc.unsubscribeLocked(c, msg.ChannelID)
+	uc.mutex.Unlock()
+
+	c.reply(ChatMessage{Type: "left", ChannelID: msg.ChannelID})
+	return nil
+}
+
+// handleSwitch делает канал активным, при необходимости подписывая на него клиента
+func (c *Client) handleSwitch(msg ChatMessage, uc *ChatUseCase) error {
+	if !uc.isSubscribed(c, msg.ChannelID) {
+		channel, ok := c.openChannel(msg, uc)
+		if !ok {
+			return nil
+		}
+
+		if err := c.joinChannel(channel, uc); err != nil {
+			return err
+		}
+	}
+
+	uc.mutex.Lock()
+	if c.channels[msg.ChannelID] {
+		c.active = msg.ChannelID
+	}
+	uc.mutex.Unlock()
+
+	c.reply(ChatMessage{Type: "switched", ChannelID: msg.ChannelID})
+	return nil
+}
+
+// openChannel находит канал из сообщения и проверяет доступ клиента к нему.
+// При ошибке отправляет клиенту сообщение и возвращает false.
+func (c *Client) openChannel(msg ChatMessage, uc *ChatUseCase) (*entity.Channel, bool) {
+	channel, err := uc.getChannel(c.ctx, msg.ChannelID)
+	if errors.Is(err, ErrChannelNotFound) {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeChannelNotFound,
+			Error:     "Канал не найден",
+			ChannelID: msg.ChannelID,
+		})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("failed to get channel %d: %v", msg.ChannelID, err)
+		c.reply(ChatMessage{Type: "error", Error: "Failed to process message"})
+		return nil, false
+	}
+
+	ok, err := uc.canRead(c.ctx, channel, c.UserID)
+	if err != nil {
+		log.Printf("failed to check access to channel %d: %v", channel.ID, err)
+	}
+	if !ok {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeChannelForbidden,
+			Error:     "Нет доступа к каналу",
+			ChannelID: channel.ID,
+		})
+		return nil, false
+	}
+
+	return channel, true
+}
+
+// subscribeLocked подписывает зарегистрированного клиента на канал. Вызывается под uc.mutex.
+func (uc *ChatUseCase) subscribeLocked(c *Client, channelID int64) bool {
+	if !uc.clients[c] {
+		return false
+	}
+
+	subscribers, ok := uc.subscribers[channelID]
+	if !ok {
+		subscribers = make(map[*Client]bool)
+		uc.subscribers[channelID] = subscribers
+	}
+	subscribers[c] = true
+	c.channels[channelID] = true
+	return true
+}
+
+// unsubscribeLocked отписывает клиента от канала. Вызывается под uc.mutex.
+func (uc *ChatUseCase) unsubscribeLocked(c *Client, channelID int64) {
+	delete(c.channels, channelID)
+	if subscribers, ok := uc.subscribers[channelID]; ok {
+		delete(subscribers, c)
+		if len(subscribers) == 0 {
+			delete(uc.subscribers, channelID)
+		}
+	}
+	if c.active == channelID {
+		c.active = entity.DefaultChannelID
+	}
+}
+
+// removeClientLocked отключает клиента от всех каналов. Вызывается под uc.mutex.
+func (uc *ChatUseCase) removeClientLocked(c *Client) {
+	if _, ok := uc.clients[c]; !ok {
+		return
+	}
+	for channelID := range c.channels {
+		uc.unsubscribeLocked(c, channelID)
+	}
+	if c.IsAuth {
+		delete(uc.users[c.UserID], c)
+		if len(uc.users[c.UserID]) == 0 {
+			delete(uc.users, c.UserID)
+		}
+		uc.updateLocalPresenceLocked(c.UserID, c.Username)
+	}
+	delete(uc.clients, c)
+	c.shutdown(websocket.CloseNormalClosure, "")
+}
+
+// recipientsLocked возвращает клиентов, которым предназначено сообщение канала.
+// Если указаны userIDs, сообщение получают все соединения этих пользователей. Вызывается под uc.mutex.
+func (uc *ChatUseCase) recipientsLocked(channelID int64, userIDs []int64) []*Client {
+	var clients []*Client
+	if userIDs != nil {
+		for _, userID := range userIDs {
+			for client := range uc.users[userID] {
+				clients = append(clients, client)
+			}
+		}
+		return clients
+	}
+
+	for client := range uc.subscribers[channelID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func (uc *ChatUseCase) isSubscribed(c *Client, channelID int64) bool {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+	return c.channels[channelID]
+}
+
+func (uc *ChatUseCase) activeChannel(c *Client) int64 {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+	return c.active
+}
+
+// userClients возвращает открытые соединения пользователя
+func (uc *ChatUseCase) userClients(userID int64) []*Client {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+
+	clients := make([]*Client, 0, len(uc.users[userID]))
+	for client := range uc.users[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// subscribeUsers подписывает соединения пользователей на всех экземплярах сервиса на канал
+func (uc *ChatUseCase) subscribeUsers(ctx context.Context, channel *entity.Channel, userIDs ...int64) {
+	event := broker.Event{Type: broker.EventJoin, ChannelID: channel.ID, UserIDs: userIDs}
+	if channel.IsDirect() {
+		event.Members = channel.Members
+	}
+	uc.publishMembership(ctx, event, ChatMessage{Type: "joined", ChannelID: channel.ID, Channel: channel.Name})
+}
+
+// unsubscribeUsers отписывает соединения пользователей на всех экземплярах сервиса от канала
+func (uc *ChatUseCase) unsubscribeUsers(ctx context.Context, channel *entity.Channel, userIDs ...int64) {
+	event := broker.Event{Type: broker.EventLeave, ChannelID: channel.ID, UserIDs: userIDs}
+	uc.publishMembership(ctx, event, ChatMessage{Type: "left", ChannelID: channel.ID, Channel: channel.Name})
+}
+
+// publishMembership публикует изменение подписок. Участие уже сохранено в БД,
+// поэтому при ошибке клиенты получат канал при следующем подключении.
+func (uc *ChatUseCase) publishMembership(ctx context.Context, event broker.Event, notice ChatMessage) {
+	payload, err := json.Marshal(notice)
+	if err != nil {
+		log.Printf("failed to marshal %s message: %v", notice.Type, err)
+		return
+	}
+	event.Payload = payload
+
+	if err := uc.broker.Publish(ctx, event); err != nil {
+		log.Printf("failed to publish %s event for channel %d: %v", event.Type, event.ChannelID, err)
+	}
+}
+
+// dispatch применяет событие брокера к клиентам этого экземпляра
+func (uc *ChatUseCase) dispatch(event broker.Event) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	// Один кадр на всех получателей: каждая кодировка вычисляется один раз
+	frame := NewFrame(event.Payload)
+
+	switch event.Type {
+	case broker.EventDeliver:
+		for _, client := range uc.recipientsLocked(event.ChannelID, event.UserIDs) {
+			uc.sendLocked(client, frame)
+		}
+		uc.notifyListenersLocked(event.ChannelID, frame)
+
+	case broker.EventJoin:
+		if event.Members != nil {
+			uc.direct[event.ChannelID] = event.Members
+		}
+		for _, client := range uc.recipientsLocked(event.ChannelID, event.UserIDs) {
+			if client.channels[event.ChannelID] {
+				continue
+			}
+			if uc.subscribeLocked(client, event.ChannelID) {
+				uc.sendLocked(client, frame)
+			}
+		}
+
+	case broker.EventLeave:
+		for _, client := range uc.recipientsLocked(event.ChannelID, event.UserIDs) {
+			if !client.channels[event.ChannelID] {
+				continue
+			}
+			uc.unsubscribeLocked(client, event.ChannelID)
+			uc.sendLocked(client, frame)
+		}
+		uc.removeUsersListenersLocked(event.ChannelID, event.UserIDs)
+
+	case broker.EventPresence, broker.EventPresenceSync:
+		uc.applyPresenceLocked(event)
+
+	case broker.EventKick:
+		for _, client := range uc.recipientsLocked(0, event.UserIDs) {
+			client.shutdown(event.CloseCode, event.Reason)
+			uc.removeClientLocked(client)
+		}
+		for channelID := range uc.listeners {
+			uc.removeUsersListenersLocked(channelID, event.UserIDs)
+		}
+
+	default:
+		log.Printf("unknown broker event type %q", event.Type)
+	}
+}
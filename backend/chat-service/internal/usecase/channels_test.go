@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/pkg/rbac"
+)
+
+// fakeChannels хранит каналы, участников и указатели прочитанного в памяти.
+// latest — ID последнего сообщения канала для подсчета непрочитанных.
+type fakeChannels struct {
+	repository.ChannelRepository
+
+	mu       sync.Mutex
+	channels map[int64]*entity.Channel
+	members  map[int64]map[int64]bool
+	read     map[readKey]int64
+	latest   map[int64]int64
+	batches  [][]entity.ReadPointer
+	batchErr error
+}
+
+func newFakeChannels(channels ...*entity.Channel) *fakeChannels {
+	f := &fakeChannels{
+		channels: make(map[int64]*entity.Channel),
+		members:  make(map[int64]map[int64]bool),
+		read:     make(map[readKey]int64),
+		latest:   make(map[int64]int64),
+	}
+	for _, channel := range channels {
+		f.channels[channel.ID] = channel
+		f.members[channel.ID] = make(map[int64]bool)
+		for _, userID := range channel.Members {
+			f.members[channel.ID][userID] = true
+		}
+	}
+	return f
+}
+
+func (f *fakeChannels) GetByID(ctx context.Context, id int64) (*entity.Channel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	channel, ok := f.channels[id]
+	if !ok {
+		return nil, repository.ErrChannelNotFound
+	}
+	return channel, nil
+}
+
+func (f *fakeChannels) GetMembers(ctx context.Context, channelID int64) ([]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var members []int64
+	for userID := range f.members[channelID] {
+		members = append(members, userID)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+	return members, nil
+}
+
+func (f *fakeChannels) AddMember(ctx context.Context, channelID, userID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.members[channelID][userID] = true
+	return nil
+}
+
+func (f *fakeChannels) RemoveMember(ctx context.Context, channelID, userID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.members[channelID], userID)
+	return nil
+}
+
+func (f *fakeChannels) IsMember(ctx context.Context, channelID, userID int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.members[channelID][userID], nil
+}
+
+func (f *fakeChannels) isMember(channelID, userID int64) bool {
+	ok, _ := f.IsMember(context.Background(), channelID, userID)
+	return ok
+}
+
+// newChannelsUseCase создает use case с публичным каналом 2 и приватным каналом 3,
+// созданными пользователем 1. В приватном канале состоит только создатель.
+func newChannelsUseCase(opts ...Option) (*ChatUseCase, *fakeChannels) {
+	channels := newFakeChannels(
+		&entity.Channel{ID: entity.DefaultChannelID, Name: "general"},
+		&entity.Channel{ID: 2, Name: "public", CreatedBy: 1, Members: []int64{1}},
+		&entity.Channel{ID: 3, Name: "private", IsPrivate: true, CreatedBy: 1, Members: []int64{1}},
+		&entity.Channel{ID: 4, Kind: entity.ChannelKindDirect, IsPrivate: true, CreatedBy: 1, Members: []int64{1, 2}},
+	)
+	uc := NewChatUseCase(nil, nil, append([]Option{WithChannelRepository(channels)}, opts...)...)
+	return uc, channels
+}
+
+var channelManager = Actor{UserID: 100, Access: rbac.Access{Permissions: []string{rbac.ChatChannelManage}}}
+
+func TestCanRead(t *testing.T) {
+	moderation := newMemoryModeration()
+	uc, channels := newChannelsUseCase(WithModeration(moderation))
+	defer uc.Close()
+	ctx := context.Background()
+
+	public, private := channels.channels[2], channels.channels[3]
+	for _, tc := range []struct {
+		channel *entity.Channel
+		userID  int64
+		want    bool
+	}{
+		{public, 0, true},
+		{public, 2, true},
+		{private, 0, false},
+		{private, 2, false},
+		{private, 1, true},
+	} {
+		ok, err := uc.canRead(ctx, tc.channel, tc.userID)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, ok, "user %d, channel %s", tc.userID, tc.channel.Name)
+	}
+
+	// Заблокированный в канале не читает даже публичный канал, в остальных каналах доступ остается
+	require.NoError(t, moderation.SetSanction(ctx, &entity.Sanction{ChannelID: 2, UserID: 2, Kind: entity.SanctionBan}))
+	ok, err := uc.canRead(ctx, public, 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = uc.canRead(ctx, channels.channels[entity.DefaultChannelID], 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Блокировка во всех каналах закрывает и приватный канал участнику
+	require.NoError(t, moderation.SetSanction(ctx, &entity.Sanction{ChannelID: entity.AllChannels, UserID: 1, Kind: entity.SanctionBan}))
+	ok, err = uc.canRead(ctx, private, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAddMember(t *testing.T) {
+	uc, channels := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+
+	// Добавлять участников может только создатель канала или модератор
+	assert.ErrorIs(t, uc.AddMember(ctx, Actor{UserID: 2}, 3, 5), ErrChannelForbidden)
+	assert.ErrorIs(t, uc.AddMember(ctx, Actor{}, 3, 5), ErrChannelForbidden)
+	assert.False(t, channels.isMember(3, 5))
+
+	require.NoError(t, uc.AddMember(ctx, Actor{UserID: 1}, 3, 5))
+	assert.True(t, channels.isMember(3, 5))
+
+	// Открытые соединения нового участника подписываются на канал
+	event := <-uc.broker.Events()
+	assert.Equal(t, broker.EventJoin, event.Type)
+	assert.Equal(t, int64(3), event.ChannelID)
+	assert.Equal(t, []int64{5}, event.UserIDs)
+
+	require.NoError(t, uc.AddMember(ctx, channelManager, 3, 6))
+	assert.True(t, channels.isMember(3, 6))
+
+	// Состав личной переписки не меняется
+	assert.ErrorIs(t, uc.AddMember(ctx, Actor{UserID: 1}, 4, 5), ErrDirectMembers)
+	assert.ErrorIs(t, uc.AddMember(ctx, channelManager, 4, 5), ErrDirectMembers)
+	assert.ErrorIs(t, uc.AddMember(ctx, channelManager, 9, 5), ErrChannelNotFound)
+}
+
+func TestRemoveMember(t *testing.T) {
+	uc, channels := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+	for _, userID := range []int64{2, 3} {
+		require.NoError(t, channels.AddMember(ctx, 3, userID))
+	}
+
+	// Участник может выйти сам, но не удалить другого
+	assert.ErrorIs(t, uc.RemoveMember(ctx, Actor{UserID: 2}, 3, 3), ErrChannelForbidden)
+	assert.True(t, channels.isMember(3, 3))
+	require.NoError(t, uc.RemoveMember(ctx, Actor{UserID: 2}, 3, 2))
+	assert.False(t, channels.isMember(3, 2))
+
+	event := <-uc.broker.Events()
+	assert.Equal(t, broker.EventLeave, event.Type)
+	assert.Equal(t, []int64{2}, event.UserIDs)
+
+	require.NoError(t, uc.RemoveMember(ctx, Actor{UserID: 1}, 3, 3))
+	assert.False(t, channels.isMember(3, 3))
+
+	assert.ErrorIs(t, uc.RemoveMember(ctx, channelManager, entity.DefaultChannelID, 2), ErrLeaveDefault)
+	assert.ErrorIs(t, uc.RemoveMember(ctx, Actor{UserID: 2}, 4, 2), ErrDirectMembers)
+	assert.True(t, channels.isMember(4, 2))
+}
+
+func TestChannels_Disabled(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+	ctx := context.Background()
+
+	// Без репозитория каналов есть только общий канал
+	assert.ErrorIs(t, uc.AddMember(ctx, channelManager, entity.DefaultChannelID, 2), ErrChannelsDisabled)
+	assert.ErrorIs(t, uc.RemoveMember(ctx, channelManager, 2, 2), ErrChannelNotFound)
+
+	ok, err := uc.canRead(ctx, &entity.Channel{ID: 2, IsPrivate: true}, 1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -2,15 +2,20 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"backend/chat-service/internal/auth"
+	"backend/chat-service/internal/broker"
 	"backend/chat-service/internal/entity"
 	"backend/chat-service/internal/repository"
+	"backend/pkg/contentfilter"
+	"backend/pkg/ratelimit"
+	"backend/pkg/rbac"
+	"backend/pkg/unfurl"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -19,50 +24,193 @@ import (
 
 // ChatMessage представляет сообщение для WebSocket
 type ChatMessage struct {
-	Type      string    `json:"type"`
-	Content   string    `json:"content,omitempty"`
-	UserID    int64     `json:"user_id,omitempty"`
-	Username  string    `json:"username,omitempty"`
-	Error     string    `json:"error,omitempty"`
-	Token     string    `json:"token,omitempty"`
-	ID        string    `json:"id,omitempty"`
-	TempID    string    `json:"tempId,omitempty"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
+	Type       string    `json:"type"`
+	Content    string    `json:"content,omitempty"`
+	UserID     int64     `json:"user_id,omitempty"`
+	Username   string    `json:"username,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Token      string    `json:"token,omitempty"`
+	ID         string    `json:"id,omitempty"`
+	TempID     string    `json:"tempId,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+	Code       string    `json:"code,omitempty"`
+	RetryAfter int       `json:"retry_after,omitempty"`
+	ChannelID  int64     `json:"channel_id,omitempty"`
+	Channel    string    `json:"channel,omitempty"`
+	// Channels список каналов клиента в сообщении типа "channels"
+	Channels  []*entity.Channel `json:"channels,omitempty"`
+	EditedAt  *time.Time        `json:"edited_at,omitempty"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
+	// Status статус присутствия в сообщениях типа "presence"
+	Status string `json:"status,omitempty"`
+	// ExpiresIn через сколько секунд индикатор typing_start нужно скрыть, если не придет новый.
+	// В "auth_success" и "reauth" — через сколько секунд истекает токен.
+	ExpiresIn int `json:"expires_in,omitempty"`
+	// Seq номер сообщения в канале. В "resume" — последний полученный клиентом,
+	// в "resumed" — последний отправленный сервером.
+	Seq int64 `json:"seq,omitempty"`
+	// More в "resumed" сообщает, что пропущенных сообщений больше, чем отправлено
+	More bool `json:"more,omitempty"`
+	// Action, Duration и Reason команды модератора, Count — число удаленных ею сообщений.
+	// Duration в "slowmode" — интервал медленного режима в секундах, 0 — выключен.
+	Action    string     `json:"action,omitempty"`
+	Duration  int        `json:"duration,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+	Count     int        `json:"count,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// ReplyTo ID сообщения, на которое отвечают
+	ReplyTo string `json:"reply_to,omitempty"`
+	// Emoji реакция в "react" и "reactions", Reactions — сводка реакций сообщения,
+	// Previews — превью ссылок. Если в "reactions" или "previews" поля нет, их не осталось.
+	Emoji     string            `json:"emoji,omitempty"`
+	Reactions []entity.Reaction `json:"reactions,omitempty"`
+	Previews  []unfurl.Preview  `json:"previews,omitempty"`
 }
 
+// Коды ошибок в сообщениях типа "error"
+const (
+	ErrCodeRateLimited      = "rate_limited"
+	ErrCodeChannelNotFound  = "channel_not_found"
+	ErrCodeChannelForbidden = "channel_forbidden"
+	ErrCodeNotSubscribed    = "not_subscribed"
+)
+
 // Client представляет подключенного клиента
 type Client struct {
-	Conn     *websocket.Conn
-	Send     chan []byte
+	Conn *websocket.Conn
+	Send chan *Frame
+	// Encoding формат кадров, согласованный при подключении
+	Encoding Encoding
 	UserID   int64
 	Username string
 	IsAuth   bool
 	Role     string // "anonymous" или "authenticated"
+	IP       string
+	Access   rbac.Access
 	ctx      context.Context
 	cancel   context.CancelFunc
+	// closeCode и closeReason причина отключения, которую WritePump или Stream
+	// передадут клиенту после отмены ctx
+	closeCode   int
+	closeReason string
+	closeOnce   sync.Once
+	// tokenExpiry срок действия токена в наносекундах Unix, 0 — не ограничен.
+	// tokenRenewed сообщает watchToken о новом сроке.
+	tokenExpiry  atomic.Int64
+	tokenRenewed chan struct{}
+
+	// channels каналы, на которые подписан клиент, active — канал по умолчанию для отправки.
+	// Защищены мьютексом ChatUseCase.
+	channels map[int64]bool
+	active   int64
+	// status online или away, защищен мьютексом ChatUseCase
+	status string
+	typing typingTracker
 }
 
 // ChatUseCase представляет use case для чата
 type ChatUseCase struct {
-	repo       repository.MessageRepository
-	db         *pgxpool.Pool
-	clients    map[*Client]bool
-	broadcast  chan []byte
+	repo        repository.MessageRepository
+	channelRepo repository.ChannelRepository
+	db          *pgxpool.Pool
+	clients     map[*Client]bool
+	// subscribers подписчики каждого канала
+	subscribers map[int64]map[*Client]bool
+	// users соединения каждого авторизованного пользователя
+	users map[int64]map[*Client]bool
+	// direct участники личных переписок, известных хабу
+	direct map[int64][]int64
+	// listeners слушатели каждого канала вне WebSocket
+	listeners map[int64]map[*Listener]bool
+	// broker доставляет события хабам всех экземпляров сервиса
+	broker broker.Broker
+	// instance идентификатор экземпляра в событиях присутствия
+	instance string
+	// presence статусы пользователей на всех экземплярах, localPresence — на этом
+	presence        map[int64]*userPresence
+	localPresence   map[int64]string
+	instances       map[string]time.Time
+	presenceUpdates chan broker.Presence
+	syncRequests    chan struct{}
+	// reads сохраняет указатели прочитанного пачками, nil без репозитория каналов
+	reads *readBatcher
+	// moderation ограничения пользователей и журнал модерации, nil — модерация выключена
+	moderation repository.ModerationRepository
+	// filter проверяет текст сообщений, nil — без проверки
+	filter *contentfilter.Pipeline
+	// unfurler строит превью ссылок, nil — без превью. unfurlSlots ограничивает число фоновых загрузок.
+	unfurler    *unfurl.Unfurler
+	unfurlSlots chan struct{}
+	// auth проверяет токены из сообщений "auth", nil — вход через соединение выключен
+	auth auth.Validator
+
 	Register   chan *Client
 	unregister chan *Client
 	mutex      sync.RWMutex
+	limiter    *ratelimit.Limiter
+	hub        HubConfig
+	counters   hubCounters
+}
+
+// Option настраивает ChatUseCase
+type Option func(*ChatUseCase)
+
+// WithRateLimiter включает ограничение частоты сообщений по их типу
+func WithRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(uc *ChatUseCase) {
+		uc.limiter = limiter
+	}
+}
+
+// WithChannelRepository включает каналы с участниками. Без него доступен только общий канал.
+func WithChannelRepository(channelRepo repository.ChannelRepository) Option {
+	return func(uc *ChatUseCase) {
+		uc.channelRepo = channelRepo
+	}
+}
+
+// WithBroker задает брокер событий для работы нескольких экземпляров сервиса.
+// По умолчанию события доставляются только клиентам этого процесса.
+func WithBroker(b broker.Broker) Option {
+	return func(uc *ChatUseCase) {
+		uc.broker = b
+	}
 }
 
 // NewChatUseCase создает новый use case для чата
-func NewChatUseCase(repo repository.MessageRepository, db *pgxpool.Pool) *ChatUseCase {
-	return &ChatUseCase{
-		repo:       repo,
-		db:         db,
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		Register:   make(chan *Client),
-		unregister: make(chan *Client),
+func NewChatUseCase(repo repository.MessageRepository, db *pgxpool.Pool, opts ...Option) *ChatUseCase {
+	uc := &ChatUseCase{
+		repo:            repo,
+		db:              db,
+		clients:         make(map[*Client]bool),
+		subscribers:     make(map[int64]map[*Client]bool),
+		users:           make(map[int64]map[*Client]bool),
+		direct:          make(map[int64][]int64),
+		listeners:       make(map[int64]map[*Listener]bool),
+		instance:        uuid.New().String(),
+		presence:        make(map[int64]*userPresence),
+		localPresence:   make(map[int64]string),
+		instances:       make(map[string]time.Time),
+		presenceUpdates: make(chan broker.Presence, presenceQueueSize),
+		syncRequests:    make(chan struct{}, 1),
+		Register:        make(chan *Client),
+		unregister:      make(chan *Client),
+		hub:             DefaultHubConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(uc)
+	}
+
+	if uc.broker == nil {
+		uc.broker = broker.NewInProcess(256)
 	}
+	if uc.channelRepo != nil {
+		uc.reads = newReadBatcher(uc.channelRepo)
+		go uc.reads.run()
+	}
+
+	return uc
 }
 
 // NewClient создает нового клиента
@@ -74,111 +222,219 @@ func NewClient(conn *websocket.Conn, userID int64, username string, isAuth bool)
 	}
 	return &Client{
 		Conn:     conn,
-		Send:     make(chan []byte, 256),
+		Send:     make(chan *Frame, DefaultHubConfig().QueueSize),
 		UserID:   userID,
 		Username: username,
 		IsAuth:   isAuth,
 		Role:     role,
 		ctx:      ctx,
 		cancel:   cancel,
+		channels: make(map[int64]bool),
+		status:   entity.StatusOnline,
+		typing:   typingTracker{channels: make(map[int64]*typingState)},
+
+		tokenRenewed: make(chan struct{}, 1),
 	}
 }
 
+// HasPermission проверяет разрешение пользователя из его ролей
+func (c *Client) HasPermission(perm string) bool {
+	return c.IsAuth && c.Access.Has(perm)
+}
+
 // Close закрывает клиента
 func (c *Client) Close() {
-	c.cancel()
-	c.Conn.Close()
+	c.shutdown(websocket.CloseNormalClosure, "")
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
 }
 
 // Run запускает обработку WebSocket соединений
 func (uc *ChatUseCase) Run() {
+	events := uc.broker.Events()
+	go uc.presenceLoop()
+
 	for {
 		select {
 		case client := <-uc.Register:
-			uc.mutex.Lock()
-			uc.clients[client] = true
-			uc.mutex.Unlock()
+			uc.register(client)
 
 		case client := <-uc.unregister:
 			uc.mutex.Lock()
-			if _, ok := uc.clients[client]; ok {
-				delete(uc.clients, client)
-				close(client.Send)
-			}
+			uc.removeClientLocked(client)
 			uc.mutex.Unlock()
 
-		case message := <-uc.broadcast:
-			uc.mutex.RLock()
-			for client := range uc.clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(uc.clients, client)
-				}
+		case event, ok := <-events:
+			if !ok {
+				// Брокер закрыт, новых событий не будет
+				events = nil
+				continue
 			}
-			uc.mutex.RUnlock()
+			uc.dispatch(event)
 		}
 	}
 }
 
+// register добавляет клиента в хаб и подписывает на общий канал. Serve и Stream
+// вызывают его до восстановления каналов клиента: подписка возможна только после регистрации.
+func (uc *ChatUseCase) register(c *Client) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	uc.clients[c] = true
+	if c.IsAuth {
+		if uc.users[c.UserID] == nil {
+			uc.users[c.UserID] = make(map[*Client]bool)
+		}
+		uc.users[c.UserID][c] = true
+		uc.updateLocalPresenceLocked(c.UserID, c.Username)
+	}
+	// Все клиенты подключаются к общему каналу
+	uc.subscribeLocked(c, entity.DefaultChannelID)
+	c.active = entity.DefaultChannelID
+}
+
 // HandleMessage обрабатывает входящее сообщение
 func (c *Client) HandleMessage(message []byte, uc *ChatUseCase) error {
 	var msg ChatMessage
-	if err := json.Unmarshal(message, &msg); err != nil {
-		return fmt.Errorf("failed to unmarshal message: %v", err)
+	if err := c.Encoding.Unmarshal(message, &msg); err != nil {
+		return fmt.Errorf("failed to unmarshal %s message: %v", c.Encoding, err)
 	}
 
 	// Проверяем права на отправку сообщений
-	if msg.Type == "message" && !c.IsAuth {
+	if requiresAuth(msg.Type) && !c.IsAuth {
 		errorMsg := ChatMessage{
 			Type:  "error",
 			Error: "Только авторизованные пользователи могут отправлять сообщения",
 		}
-		errorJSON, _ := json.Marshal(errorMsg)
-		c.Send <- errorJSON
+		c.reply(errorMsg)
 		return errors.New("unauthorized to send messages")
 	}
 
+	// Проверяем лимит частоты для данного типа сообщений
+	if uc.limiter != nil {
+		result := uc.limiter.Allow(msg.Type, c.rateLimitKey())
+		if !result.Allowed {
+			errorMsg := ChatMessage{
+				Type:       "error",
+				Code:       ErrCodeRateLimited,
+				Error:      "Слишком много сообщений, попробуйте позже",
+				TempID:     msg.TempID,
+				RetryAfter: ratelimit.Seconds(result.RetryAfter),
+			}
+			c.reply(errorMsg)
+			return nil
+		}
+	}
+
 	switch msg.Type {
+	case "auth":
+		return c.handleAuth(msg, uc)
+	case "join":
+		return c.handleJoin(msg, uc)
+	case "leave":
+		return c.handleLeave(msg, uc)
+	case "switch":
+		return c.handleSwitch(msg, uc)
+	case "edit":
+		return c.handleEdit(msg, uc)
+	case "delete":
+		return c.handleDelete(msg, uc)
+	case "typing_start":
+		return c.handleTypingStart(msg, uc)
+	case "typing_stop":
+		return c.handleTypingStop(msg, uc)
+	case "presence":
+		return c.setStatus(msg, uc)
+	case "read":
+		return c.handleRead(msg, uc)
+	case "resume":
+		return c.handleResume(msg, uc)
+	case "react":
+		return c.handleReact(msg, uc)
+	case entity.ActionMute, entity.ActionUnmute, entity.ActionKick, entity.ActionBan,
+		entity.ActionUnban, entity.ActionSlowMode, entity.ActionPurge:
+		return c.handleModeration(msg, uc)
 	case "message":
+		channelID := msg.ChannelID
+		if channelID == 0 {
+			channelID = uc.activeChannel(c)
+		}
+		if !uc.isSubscribed(c, channelID) {
+			c.reply(ChatMessage{
+				Type:      "error",
+				Code:      ErrCodeNotSubscribed,
+				Error:     "Вы не подключены к этому каналу",
+				ChannelID: channelID,
+				TempID:    msg.TempID,
+			})
+			return nil
+		}
+		if len(msg.TempID) > maxTempIDLength {
+			c.reply(ChatMessage{Type: "error", Code: ErrCodeInvalidMessage, Error: "Слишком длинный tempId"})
+			return nil
+		}
+		if err := uc.checkRestrictions(c.ctx, c.actor(), channelID, true); err != nil {
+			if c.replyRestriction(msg, channelID, err) {
+				return nil
+			}
+			return err
+		}
+		if err := uc.validateReply(c.ctx, channelID, msg.ReplyTo); err != nil {
+			return c.replyMessageError(msg, err)
+		}
+
 		// Создаем новое сообщение
 		newMsg := entity.Message{
-			ID:        uuid.New().String(),
+			ChannelID: channelID,
+			TempID:    msg.TempID,
 			Content:   msg.Content,
 			UserID:    c.UserID,
 			Username:  c.Username,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			ReplyTo:   msg.ReplyTo,
 		}
 
-		// Сохраняем сообщение в БД
-		if err := uc.repo.Create(c.ctx, &newMsg); err != nil {
-			return fmt.Errorf("failed to save message: %v", err)
+		held, err := uc.screenMessage(c.ctx, &newMsg)
+		if errors.Is(err, ErrContentRejected) {
+			return c.replyMessageError(msg, err)
 		}
-
-		// Отправляем сообщение всем клиентам
-		response := ChatMessage{
-			Type:      "message",
-			ID:        newMsg.ID,
-			Content:   newMsg.Content,
-			UserID:    newMsg.UserID,
-			Username:  newMsg.Username,
-			CreatedAt: newMsg.CreatedAt,
-			TempID:    msg.TempID,
+		if err != nil {
+			return err
+		}
+		if held {
+			c.reply(ChatMessage{Type: "held", ChannelID: channelID, TempID: msg.TempID})
+			return nil
 		}
 
-		responseJSON, err := json.Marshal(response)
+		duplicate, err := uc.postMessage(c.ctx, &newMsg)
 		if err != nil {
-			return fmt.Errorf("failed to marshal response: %v", err)
+			return err
+		}
+		if duplicate {
+			// Клиент повторил отправку, не получив подтверждения: сообщение уже разослано,
+			// достаточно подтвердить его отправителю
+			response := messageFrame(&newMsg)
+			response.TempID = msg.TempID
+			c.reply(response)
+			return nil
 		}
 
-		uc.broadcast <- responseJSON
+		uc.markOwnMessageRead(c, &newMsg)
+		c.stopTyping(uc, newMsg.ChannelID)
 	}
 
 	return nil
 }
 
+// rateLimitKey возвращает ключ лимита: пользователь для авторизованных, иначе IP
+func (c *Client) rateLimitKey() string {
+	if c.IsAuth {
+		return ratelimit.UserKey(c.UserID)
+	}
+	return "ip:" + c.IP
+}
+
 // GetHistory возвращает историю сообщений
 func (uc *ChatUseCase) GetHistory(ctx context.Context, limit int32, beforeID int64) ([]*entity.Message, error) {
 	return uc.repo.GetHistory(ctx, limit, beforeID)
@@ -188,65 +444,3 @@ func (uc *ChatUseCase) GetHistory(ctx context.Context, limit int32, beforeID int
 func (uc *ChatUseCase) DeleteOldMessages(ctx context.Context, before time.Time) (int32, error) {
 	return uc.repo.DeleteOldMessages(ctx, before)
 }
-
-// WritePump отправляет сообщения клиенту
-func (c *Client) WritePump() {
-	defer func() {
-		c.Close()
-	}()
-
-	for {
-		select {
-		case message, ok := <-c.Send:
-			if !ok {
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
-				return
-			}
-
-			w, err := c.Conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			if err := w.Close(); err != nil {
-				return
-			}
-		case <-c.ctx.Done():
-			return
-		}
-	}
-}
-
-// ReadPump читает сообщения от клиента
-func (c *Client) ReadPump(uc *ChatUseCase) {
-	defer func() {
-		uc.unregister <- c
-		c.Close()
-	}()
-
-	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		default:
-			_, message, err := c.Conn.ReadMessage()
-			if err != nil {
-				if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-					log.Printf("error: %v", err)
-				}
-				return
-			}
-
-			if err := c.HandleMessage(message, uc); err != nil {
-				log.Printf("error handling message: %v", err)
-				errorMsg := ChatMessage{
-					Type:  "error",
-					Error: "Failed to process message",
-				}
-				errorData, _ := json.Marshal(errorMsg)
-				c.Send <- errorData
-			}
-		}
-	}
-}
@@ -0,0 +1,326 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"backend/chat-service/internal/entity"
+	pb "backend/chat-service/proto"
+	"backend/pkg/unfurl"
+)
+
+// Encoding формат кадров WebSocket, согласованный с клиентом через Sec-WebSocket-Protocol
+type Encoding int
+
+const (
+	// EncodingJSON текстовые кадры JSON, используется, если клиент не запросил подпротокол
+	EncodingJSON Encoding = iota
+	// EncodingMsgpack бинарные кадры MessagePack с теми же именами полей, что в JSON
+	EncodingMsgpack
+	// EncodingProtobuf бинарные кадры chat.Frame из proto/chat.proto
+	EncodingProtobuf
+
+	encodingCount
+)
+
+// Subprotocols имена подпротоколов WebSocket для каждой кодировки
+var Subprotocols = []string{"json", "msgpack", "protobuf"}
+
+// ParseEncoding возвращает кодировку по имени подпротокола
+func ParseEncoding(subprotocol string) (Encoding, bool) {
+	for i, name := range Subprotocols {
+		if name == subprotocol {
+			return Encoding(i), true
+		}
+	}
+	return EncodingJSON, false
+}
+
+func (e Encoding) String() string {
+	if e < 0 || e >= encodingCount {
+		return fmt.Sprintf("encoding(%d)", int(e))
+	}
+	return Subprotocols[e]
+}
+
+// MessageType возвращает тип кадра WebSocket: текстовый для JSON, бинарный для остальных
+func (e Encoding) MessageType() int {
+	if e == EncodingJSON {
+		return websocket.TextMessage
+	}
+	return websocket.BinaryMessage
+}
+
+// msgpackHandle кодирует сообщения по json-тегам, время — расширением timestamp из спецификации MessagePack
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{WriteExt: true}
+	h.TypeInfos = codec.NewTypeInfos([]string{"json"})
+	return h
+}()
+
+// Marshal кодирует сообщение
+func (e Encoding) Marshal(msg ChatMessage) ([]byte, error) {
+	switch e {
+	case EncodingJSON:
+		return json.Marshal(msg)
+	case EncodingMsgpack:
+		var data []byte
+		err := codec.NewEncoderBytes(&data, msgpackHandle).Encode(msg)
+		return data, err
+	case EncodingProtobuf:
+		return proto.Marshal(frameToProto(msg))
+	default:
+		return nil, fmt.Errorf("unknown encoding %d", int(e))
+	}
+}
+
+// Unmarshal декодирует сообщение клиента
+func (e Encoding) Unmarshal(data []byte, msg *ChatMessage) error {
+	switch e {
+	case EncodingJSON:
+		return json.Unmarshal(data, msg)
+	case EncodingMsgpack:
+		return codec.NewDecoderBytes(data, msgpackHandle).Decode(msg)
+	case EncodingProtobuf:
+		var frame pb.Frame
+		if err := proto.Unmarshal(data, &frame); err != nil {
+			return err
+		}
+		*msg = frameFromProto(&frame)
+		return nil
+	default:
+		return fmt.Errorf("unknown encoding %d", int(e))
+	}
+}
+
+// Frame исходящий кадр. Кадр рассылки один на всех получателей и кодируется не больше
+// одного раза для каждой кодировки. Закодированные данные хранятся в websocket.PreparedMessage,
+// поэтому и сжатие permessage-deflate выполняется один раз, а не для каждого соединения.
+type Frame struct {
+	// payload JSON из события брокера, nil у кадров, созданных из сообщения
+	payload []byte
+
+	decodeOnce sync.Once
+	msg        ChatMessage
+	decodeErr  error
+
+	encoded [encodingCount]encodedFrame
+}
+
+type encodedFrame struct {
+	once sync.Once
+	data []byte
+	err  error
+
+	prepareOnce sync.Once
+	prepared    *websocket.PreparedMessage
+	prepareErr  error
+}
+
+// NewFrame создает кадр из JSON-сообщения. JSON-клиентам данные уходят без перекодирования.
+func NewFrame(payload []byte) *Frame {
+	return &Frame{payload: payload}
+}
+
+// newMessageFrame создает кадр из сообщения
+func newMessageFrame(msg ChatMessage) *Frame {
+	f := &Frame{msg: msg}
+	f.decodeOnce.Do(func() {})
+	return f
+}
+
+// Message возвращает сообщение кадра. JSON разбирается один раз при первом вызове.
+// Срезы сообщения общие для всех вызовов и не должны изменяться.
+func (f *Frame) Message() (ChatMessage, error) {
+	f.decodeOnce.Do(func() {
+		f.decodeErr = json.Unmarshal(f.payload, &f.msg)
+	})
+	return f.msg, f.decodeErr
+}
+
+// Encode возвращает кадр в кодировке, кодируя его при первом обращении
+func (f *Frame) Encode(e Encoding) ([]byte, error) {
+	if e < 0 || e >= encodingCount {
+		return nil, fmt.Errorf("unknown encoding %d", int(e))
+	}
+
+	encoded := &f.encoded[e]
+	encoded.once.Do(func() {
+		encoded.data, encoded.err = f.encode(e)
+	})
+	return encoded.data, encoded.err
+}
+
+// prepared возвращает кадр в кодировке, готовый к записи в WebSocket соединение, и его размер
+func (f *Frame) prepared(e Encoding) (*websocket.PreparedMessage, int, error) {
+	data, err := f.Encode(e)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	encoded := &f.encoded[e]
+	encoded.prepareOnce.Do(func() {
+		encoded.prepared, encoded.prepareErr = websocket.NewPreparedMessage(e.MessageType(), data)
+	})
+	return encoded.prepared, len(data), encoded.prepareErr
+}
+
+func (f *Frame) encode(e Encoding) ([]byte, error) {
+	if e == EncodingJSON && f.payload != nil {
+		return f.payload, nil
+	}
+	msg, err := f.Message()
+	if err != nil {
+		return nil, err
+	}
+	return e.Marshal(msg)
+}
+
+func frameToProto(msg ChatMessage) *pb.Frame {
+	frame := &pb.Frame{
+		Type:       msg.Type,
+		Content:    msg.Content,
+		UserId:     msg.UserID,
+		Username:   msg.Username,
+		Error:      msg.Error,
+		Token:      msg.Token,
+		Id:         msg.ID,
+		TempId:     msg.TempID,
+		CreatedAt:  timeToProto(msg.CreatedAt),
+		Code:       msg.Code,
+		RetryAfter: int32(msg.RetryAfter),
+		ChannelId:  msg.ChannelID,
+		Channel:    msg.Channel,
+		EditedAt:   timePtrToProto(msg.EditedAt),
+		DeletedAt:  timePtrToProto(msg.DeletedAt),
+		Status:     msg.Status,
+		ExpiresIn:  int32(msg.ExpiresIn),
+		Seq:        msg.Seq,
+		More:       msg.More,
+		Action:     msg.Action,
+		Duration:   int32(msg.Duration),
+		Reason:     msg.Reason,
+		Count:      int32(msg.Count),
+		ExpiresAt:  timePtrToProto(msg.ExpiresAt),
+		ReplyTo:    msg.ReplyTo,
+		Emoji:      msg.Emoji,
+	}
+	for _, ch := range msg.Channels {
+		frame.Channels = append(frame.Channels, &pb.Channel{
+			Id:        ch.ID,
+			Name:      ch.Name,
+			Kind:      ch.Kind,
+			IsPrivate: ch.IsPrivate,
+			CreatedBy: ch.CreatedBy,
+			CreatedAt: timeToProto(ch.CreatedAt),
+			Members:   ch.Members,
+			Unread:    int32(ch.Unread),
+		})
+	}
+	for _, r := range msg.Reactions {
+		frame.Reactions = append(frame.Reactions, &pb.Reaction{Emoji: r.Emoji, Count: int32(r.Count), UserIds: r.UserIDs})
+	}
+	for _, p := range msg.Previews {
+		frame.Previews = append(frame.Previews, &pb.LinkPreview{
+			Url:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			Image:       p.Image,
+			SiteName:    p.SiteName,
+		})
+	}
+	return frame
+}
+
+func frameFromProto(frame *pb.Frame) ChatMessage {
+	msg := ChatMessage{
+		Type:       frame.Type,
+		Content:    frame.Content,
+		UserID:     frame.UserId,
+		Username:   frame.Username,
+		Error:      frame.Error,
+		Token:      frame.Token,
+		ID:         frame.Id,
+		TempID:     frame.TempId,
+		CreatedAt:  timeFromProto(frame.CreatedAt),
+		Code:       frame.Code,
+		RetryAfter: int(frame.RetryAfter),
+		ChannelID:  frame.ChannelId,
+		Channel:    frame.Channel,
+		EditedAt:   timePtrFromProto(frame.EditedAt),
+		DeletedAt:  timePtrFromProto(frame.DeletedAt),
+		Status:     frame.Status,
+		ExpiresIn:  int(frame.ExpiresIn),
+		Seq:        frame.Seq,
+		More:       frame.More,
+		Action:     frame.Action,
+		Duration:   int(frame.Duration),
+		Reason:     frame.Reason,
+		Count:      int(frame.Count),
+		ExpiresAt:  timePtrFromProto(frame.ExpiresAt),
+		ReplyTo:    frame.ReplyTo,
+		Emoji:      frame.Emoji,
+	}
+	for _, ch := range frame.Channels {
+		msg.Channels = append(msg.Channels, &entity.Channel{
+			ID:        ch.Id,
+			Name:      ch.Name,
+			Kind:      ch.Kind,
+			IsPrivate: ch.IsPrivate,
+			CreatedBy: ch.CreatedBy,
+			CreatedAt: timeFromProto(ch.CreatedAt),
+			Members:   ch.Members,
+			Unread:    int(ch.Unread),
+		})
+	}
+	for _, r := range frame.Reactions {
+		msg.Reactions = append(msg.Reactions, entity.Reaction{Emoji: r.Emoji, Count: int(r.Count), UserIDs: r.UserIds})
+	}
+	for _, p := range frame.Previews {
+		msg.Previews = append(msg.Previews, unfurl.Preview{
+			URL:         p.Url,
+			Title:       p.Title,
+			Description: p.Description,
+			Image:       p.Image,
+			SiteName:    p.SiteName,
+		})
+	}
+	return msg
+}
+
+// timeToProto не передает нулевое время, как omitempty в JSON для указателей
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timePtrToProto(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func timeFromProto(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+func timePtrFromProto(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ugorji/go/codec"
+
+	"backend/chat-service/internal/entity"
+	"backend/pkg/unfurl"
+)
+
+func TestEncoding_RoundTrip(t *testing.T) {
+	created := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	edited := created.Add(time.Minute)
+	msg := ChatMessage{
+		Type:      "message",
+		ID:        "42",
+		TempID:    "tmp-1",
+		ChannelID: 7,
+		Content:   "привет https://go.dev",
+		UserID:    3,
+		Username:  "alice",
+		CreatedAt: created,
+		EditedAt:  &edited,
+		Seq:       12,
+		ReplyTo:   "41",
+		Channels:  []*entity.Channel{{ID: 7, Name: "general", Kind: entity.ChannelKindChannel, CreatedAt: created, Unread: 2}},
+		Reactions: []entity.Reaction{{Emoji: "👍", Count: 2, UserIDs: []int64{3, 4}}},
+		Previews:  []unfurl.Preview{{URL: "https://go.dev", Title: "Go", SiteName: "go.dev"}},
+	}
+
+	for _, enc := range []Encoding{EncodingJSON, EncodingMsgpack, EncodingProtobuf} {
+		t.Run(enc.String(), func(t *testing.T) {
+			data, err := enc.Marshal(msg)
+			require.NoError(t, err)
+
+			var decoded ChatMessage
+			require.NoError(t, enc.Unmarshal(data, &decoded))
+			assert.True(t, msg.CreatedAt.Equal(decoded.CreatedAt))
+			assert.True(t, msg.EditedAt.Equal(*decoded.EditedAt))
+			assert.True(t, msg.Channels[0].CreatedAt.Equal(decoded.Channels[0].CreatedAt))
+
+			decoded.CreatedAt, decoded.EditedAt, decoded.Channels[0].CreatedAt = msg.CreatedAt, msg.EditedAt, created
+			assert.Equal(t, msg, decoded)
+		})
+	}
+}
+
+func TestEncoding_MsgpackUsesJSONNames(t *testing.T) {
+	data, err := EncodingMsgpack.Marshal(ChatMessage{Type: "typing_start", UserID: 3, ChannelID: 1})
+	require.NoError(t, err)
+
+	var fields map[string]interface{}
+	require.NoError(t, codec.NewDecoderBytes(data, msgpackHandle).Decode(&fields))
+	assert.Contains(t, fields, "user_id")
+	assert.Contains(t, fields, "channel_id")
+	// Пустые поля опускаются так же, как в JSON
+	assert.NotContains(t, fields, "content")
+}
+
+func TestParseEncoding(t *testing.T) {
+	enc, ok := ParseEncoding("msgpack")
+	assert.True(t, ok)
+	assert.Equal(t, EncodingMsgpack, enc)
+	assert.Equal(t, websocket.BinaryMessage, enc.MessageType())
+	assert.Equal(t, websocket.TextMessage, EncodingJSON.MessageType())
+
+	_, ok = ParseEncoding("xml")
+	assert.False(t, ok)
+}
+
+func TestFrame_EncodesOncePerEncoding(t *testing.T) {
+	payload, err := json.Marshal(ChatMessage{Type: "presence", UserID: 3, Status: entity.StatusOnline})
+	require.NoError(t, err)
+	frame := NewFrame(payload)
+
+	// JSON из брокера уходит клиентам без перекодирования
+	data, err := frame.encode(EncodingJSON)
+	require.NoError(t, err)
+	assert.Same(t, &payload[0], &data[0])
+
+	for _, enc := range []Encoding{EncodingJSON, EncodingMsgpack, EncodingProtobuf} {
+		first, size, err := frame.prepared(enc)
+		require.NoError(t, err)
+		assert.Positive(t, size)
+
+		second, _, err := frame.prepared(enc)
+		require.NoError(t, err)
+		assert.Same(t, first, second, enc.String())
+	}
+
+	msg, err := frame.Message()
+	require.NoError(t, err)
+	assert.Equal(t, entity.StatusOnline, msg.Status)
+}
+
+func TestFrame_InvalidPayload(t *testing.T) {
+	frame := NewFrame([]byte("{"))
+
+	// JSON-клиентам данные передаются как есть, перекодировать их нельзя
+	_, _, err := frame.prepared(EncodingJSON)
+	assert.NoError(t, err)
+	_, _, err = frame.prepared(EncodingMsgpack)
+	assert.Error(t, err)
+}
@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"backend/chat-service/internal/entity"
+)
+
+var (
+	ErrDirectMembers       = errors.New("members of a direct conversation cannot be changed")
+	ErrInvalidParticipants = fmt.Errorf("a direct conversation needs 2-%d participants", entity.MaxDirectParticipants)
+)
+
+// CreateDirect создает личную переписку создателя с указанными пользователями.
+// Для переписки один на один возвращается уже существующая; created сообщает, что переписка новая.
+func (uc *ChatUseCase) CreateDirect(ctx context.Context, actor Actor, input entity.DirectCreate) (channel *entity.Channel, created bool, err error) {
+	if uc.channelRepo == nil {
+		return nil, false, ErrChannelsDisabled
+	}
+
+	members := participants(actor.UserID, input.UserIDs)
+	if len(members) < 2 || len(members) > entity.MaxDirectParticipants {
+		return nil, false, ErrInvalidParticipants
+	}
+
+	channel = &entity.Channel{
+		CreatedBy: actor.UserID,
+		Members:   members,
+	}
+	created, err = uc.channelRepo.CreateDirect(ctx, channel)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		// Открытые соединения участников сразу получают новую переписку
+		uc.subscribeUsers(ctx, channel, members...)
+	}
+
+	return channel, created, nil
+}
+
+// ListDirect возвращает личные переписки пользователя с числом непрочитанных сообщений
+func (uc *ChatUseCase) ListDirect(ctx context.Context, userID int64) ([]*entity.Channel, error) {
+	if uc.channelRepo == nil {
+		return nil, ErrChannelsDisabled
+	}
+	return uc.channelRepo.GetDirect(ctx, userID)
+}
+
+// MarkRead отмечает сообщения канала до messageID прочитанными
+func (uc *ChatUseCase) MarkRead(ctx context.Context, userID, channelID, messageID int64) error {
+	if uc.channelRepo == nil {
+		return ErrChannelsDisabled
+	}
+
+	ok, err := uc.channelRepo.MarkRead(ctx, channelID, userID, messageID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrChannelForbidden
+	}
+	return nil
+}
+
+// directMembers возвращает участников личной переписки или nil для обычного канала
+func (uc *ChatUseCase) directMembers(channelID int64) []int64 {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+	return uc.direct[channelID]
+}
+
+// loadDirectMembers запоминает участников переписки. Состав переписки не меняется,
+// поэтому загруженный список не нужно обновлять.
+func (uc *ChatUseCase) loadDirectMembers(ctx context.Context, channelID int64) error {
+	if uc.directMembers(channelID) != nil {
+		return nil
+	}
+
+	members, err := uc.channelRepo.GetMembers(ctx, channelID)
+	if err != nil {
+		return err
+	}
+
+	uc.mutex.Lock()
+	uc.direct[channelID] = members
+	uc.mutex.Unlock()
+	return nil
+}
+
+// participants возвращает отсортированный список участников без повторов, включая создателя
+func participants(creator int64, userIDs []int64) []int64 {
+	seen := map[int64]bool{creator: true}
+	members := []int64{creator}
+	for _, id := range userIDs {
+		if id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		members = append(members, id)
+	}
+
+	sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+	return members
+}
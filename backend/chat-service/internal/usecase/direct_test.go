@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+)
+
+func (f *fakeChannels) CreateDirect(ctx context.Context, channel *entity.Channel) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(channel.Members) == 2 {
+		for _, existing := range f.channels {
+			if existing.IsDirect() && len(existing.Members) == 2 &&
+				existing.Members[0] == channel.Members[0] && existing.Members[1] == channel.Members[1] {
+				*channel = *existing
+				return false, nil
+			}
+		}
+	}
+
+	channel.ID = int64(len(f.channels) + 1)
+	channel.Kind = entity.ChannelKindDirect
+	channel.IsPrivate = true
+	f.channels[channel.ID] = channel
+	f.members[channel.ID] = make(map[int64]bool)
+	for _, userID := range channel.Members {
+		f.members[channel.ID][userID] = true
+	}
+	return true, nil
+}
+
+func (f *fakeChannels) MarkRead(ctx context.Context, channelID, userID, messageID int64) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.members[channelID][userID] {
+		return false, nil
+	}
+	key := readKey{channelID: channelID, userID: userID}
+	if f.read[key] < messageID {
+		f.read[key] = messageID
+	}
+	return true, nil
+}
+
+func TestCreateDirect(t *testing.T) {
+	uc, channels := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+
+	// Создатель входит в участников, повторы и некорректные ID отбрасываются
+	channel, created, err := uc.CreateDirect(ctx, Actor{UserID: 5}, entity.DirectCreate{UserIDs: []int64{7, 3, 7, 0, 5}})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.True(t, channel.IsDirect())
+	assert.Equal(t, []int64{3, 5, 7}, channel.Members)
+	assert.Equal(t, int64(5), channel.CreatedBy)
+
+	// Открытые соединения всех участников сразу подписываются на переписку
+	event := <-uc.broker.Events()
+	assert.Equal(t, broker.EventJoin, event.Type)
+	assert.Equal(t, channel.ID, event.ChannelID)
+	assert.Equal(t, []int64{3, 5, 7}, event.UserIDs)
+	assert.Equal(t, []int64{3, 5, 7}, event.Members)
+
+	// Группа с теми же участниками — новая переписка
+	group, created, err := uc.CreateDirect(ctx, Actor{UserID: 3}, entity.DirectCreate{UserIDs: []int64{5, 7}})
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, channel.ID, group.ID)
+	<-uc.broker.Events()
+
+	// Переписка один на один с тем же собеседником возвращается существующая
+	first, created, err := uc.CreateDirect(ctx, Actor{UserID: 9}, entity.DirectCreate{UserIDs: []int64{8}})
+	require.NoError(t, err)
+	assert.True(t, created)
+	<-uc.broker.Events()
+
+	again, created, err := uc.CreateDirect(ctx, Actor{UserID: 8}, entity.DirectCreate{UserIDs: []int64{9}})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Len(t, uc.broker.Events(), 0)
+	assert.Len(t, channels.channels, 7)
+}
+
+func TestCreateDirect_Participants(t *testing.T) {
+	uc, _ := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+
+	_, _, err := uc.CreateDirect(ctx, Actor{UserID: 1}, entity.DirectCreate{UserIDs: []int64{1}})
+	assert.ErrorIs(t, err, ErrInvalidParticipants)
+
+	var userIDs []int64
+	for id := int64(2); id <= entity.MaxDirectParticipants+1; id++ {
+		userIDs = append(userIDs, id)
+	}
+	_, _, err = uc.CreateDirect(ctx, Actor{UserID: 1}, entity.DirectCreate{UserIDs: userIDs})
+	assert.ErrorIs(t, err, ErrInvalidParticipants)
+
+	_, created, err := uc.CreateDirect(ctx, Actor{UserID: 1}, entity.DirectCreate{UserIDs: userIDs[:len(userIDs)-1]})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	_, _, err = NewChatUseCase(nil, nil).CreateDirect(ctx, Actor{UserID: 1}, entity.DirectCreate{UserIDs: []int64{2}})
+	assert.ErrorIs(t, err, ErrChannelsDisabled)
+}
+
+func TestMarkRead(t *testing.T) {
+	uc, channels := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+
+	require.NoError(t, uc.MarkRead(ctx, 2, 4, 10))
+	assert.Equal(t, int64(10), channels.read[readKey{channelID: 4, userID: 2}])
+
+	// Указатель только сдвигается вперед
+	require.NoError(t, uc.MarkRead(ctx, 2, 4, 5))
+	assert.Equal(t, int64(10), channels.read[readKey{channelID: 4, userID: 2}])
+
+	// Не участник переписки не может отмечать ее прочитанной
+	assert.ErrorIs(t, uc.MarkRead(ctx, 3, 4, 10), ErrChannelForbidden)
+}
+
+func TestLoadDirectMembers(t *testing.T) {
+	uc, channels := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+
+	assert.Nil(t, uc.directMembers(4))
+	require.NoError(t, uc.loadDirectMembers(ctx, 4))
+	assert.Equal(t, []int64{1, 2}, uc.directMembers(4))
+
+	// Загруженный состав не перечитывается
+	channels.members[4][3] = true
+	require.NoError(t, uc.loadDirectMembers(ctx, 4))
+	assert.Equal(t, []int64{1, 2}, uc.directMembers(4))
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/pkg/contentfilter"
+	"backend/pkg/rbac"
+)
+
+var (
+	ErrContentRejected = errors.New("message rejected by content filter")
+	ErrHeldNotFound    = repository.ErrHeldNotFound
+)
+
+// Коды ответов фильтра содержимого
+const (
+	ErrCodeContentRejected = "content_rejected"
+	ErrCodeHeldNotFound    = "held_not_found"
+)
+
+// WithContentFilter проверяет текст сообщений фильтром перед сохранением.
+// Без модерации сообщения, которые фильтр задержал бы, отклоняются.
+func WithContentFilter(filter *contentfilter.Pipeline) Option {
+	return func(uc *ChatUseCase) {
+		uc.filter = filter
+	}
+}
+
+// filterContent прогоняет текст через фильтр и записывает в лог сработавшие правила
+func (uc *ChatUseCase) filterContent(ctx context.Context, userID int64, content string) contentfilter.Verdict {
+	verdict := uc.filter.Check(ctx, contentfilter.Content{Kind: contentfilter.KindChat, UserID: userID, Text: content})
+	if verdict.Flagged() {
+		log.Printf("content filter: message of user %d matched %s, action %s (dry run %t)",
+			userID, strings.Join(verdict.Rules(), ","), verdict.Intended, verdict.DryRun)
+	}
+	return verdict
+}
+
+// screenMessage применяет решение фильтра к новому сообщению: маскирует текст,
+// отклоняет сообщение или ставит его в очередь на проверку. held сообщает,
+// что сообщение задержано и сохранять его не нужно.
+func (uc *ChatUseCase) screenMessage(ctx context.Context, msg *entity.Message) (held bool, err error) {
+	verdict := uc.filterContent(ctx, msg.UserID, msg.Content)
+	switch verdict.Action {
+	case contentfilter.Mask:
+		msg.Content = verdict.Text
+	case contentfilter.Hold:
+		if uc.moderation == nil {
+			return false, ErrContentRejected
+		}
+		err := uc.moderation.HoldMessage(ctx, &entity.HeldMessage{
+			ChannelID: msg.ChannelID,
+			UserID:    msg.UserID,
+			Username:  msg.Username,
+			Content:   msg.Content,
+			Rules:     verdict.Rules(),
+		})
+		if err != nil {
+			return false, err
+		}
+		msg.Held = true
+		return true, nil
+	case contentfilter.Reject:
+		return false, ErrContentRejected
+	}
+	return false, nil
+}
+
+// screenEdit применяет решение фильтра к новому тексту сообщения. Правку,
+// которую фильтр задержал бы, отклоняем: старый текст уже виден участникам.
+func (uc *ChatUseCase) screenEdit(ctx context.Context, userID int64, content string) (string, error) {
+	verdict := uc.filterContent(ctx, userID, content)
+	switch verdict.Action {
+	case contentfilter.Hold, contentfilter.Reject:
+		return "", ErrContentRejected
+	}
+	return verdict.Text, nil
+}
+
+// HeldMessages возвращает сообщения, задержанные фильтром, старые первыми.
+// channelID 0 — во всех каналах.
+func (uc *ChatUseCase) HeldMessages(ctx context.Context, actor Actor, channelID int64, limit int32) ([]*entity.HeldMessage, error) {
+	if uc.moderation == nil {
+		return nil, ErrModerationDisabled
+	}
+	if actor.UserID == 0 || !actor.Access.Has(rbac.ChatDeleteAny) {
+		return nil, ErrModerationForbidden
+	}
+	if limit <= 0 || limit > maxModerationLogLimit {
+		limit = defaultModerationLogLimit
+	}
+	return uc.moderation.HeldMessages(ctx, channelID, limit)
+}
+
+// ReviewHeld публикует задержанное сообщение или отклоняет его и записывает решение в журнал
+func (uc *ChatUseCase) ReviewHeld(ctx context.Context, actor Actor, id int64, approve bool) (*entity.ModerationAction, error) {
+	if uc.moderation == nil {
+		return nil, ErrModerationDisabled
+	}
+	if actor.UserID == 0 || !actor.Access.Has(rbac.ChatDeleteAny) {
+		return nil, ErrModerationForbidden
+	}
+
+	held, err := uc.moderation.TakeHeld(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &entity.ModerationAction{
+		Action:      entity.ActionDecline,
+		ChannelID:   held.ChannelID,
+		ModeratorID: actor.UserID,
+		UserID:      held.UserID,
+		Reason:      strings.Join(held.Rules, ","),
+	}
+	if approve {
+		record.Action = entity.ActionApprove
+		if err := uc.publishHeld(ctx, held); err != nil {
+			// Возвращаем сообщение в очередь, чтобы его можно было проверить снова
+			if holdErr := uc.moderation.HoldMessage(ctx, held); holdErr != nil {
+				log.Printf("failed to return held message of user %d: %v", held.UserID, holdErr)
+			}
+			return nil, err
+		}
+	}
+
+	log.Printf("moderator %d: %s held message %d of user %d in channel %d",
+		actor.UserID, record.Action, held.ID, held.UserID, held.ChannelID)
+	if err := uc.moderation.LogAction(ctx, record); err != nil {
+		log.Printf("failed to save moderation action: %v", err)
+	}
+	return record, nil
+}
+
+// publishHeld сохраняет одобренное сообщение и рассылает его участникам канала
+func (uc *ChatUseCase) publishHeld(ctx context.Context, held *entity.HeldMessage) error {
+	channel, err := uc.getChannel(ctx, held.ChannelID)
+	if err != nil {
+		return err
+	}
+	if channel.IsDirect() {
+		if err := uc.loadDirectMembers(ctx, held.ChannelID); err != nil {
+			return err
+		}
+	}
+
+	_, err = uc.postMessage(ctx, &entity.Message{
+		ChannelID: held.ChannelID,
+		Content:   held.Content,
+		UserID:    held.UserID,
+		Username:  held.Username,
+	})
+	return err
+}
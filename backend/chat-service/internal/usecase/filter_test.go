@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/entity"
+	"backend/pkg/contentfilter"
+	"backend/pkg/rbac"
+)
+
+func newTestFilter() *contentfilter.Pipeline {
+	filter := contentfilter.NewPipeline(nil, false)
+	filter.Use(contentfilter.NewWordRule("profanity", contentfilter.Mask, []string{"дурак"}), 0)
+	filter.Use(contentfilter.NewWordRule("scam", contentfilter.Reject, []string{"casino"}), 0)
+	filter.Use(contentfilter.NewLinkRule("links", contentfilter.Hold, 0), 0)
+	return filter
+}
+
+func TestScreenMessage(t *testing.T) {
+	repo := newMemoryModeration()
+	uc := NewChatUseCase(nil, nil, WithModeration(repo), WithContentFilter(newTestFilter()))
+	ctx := context.Background()
+
+	msg := &entity.Message{ChannelID: entity.DefaultChannelID, UserID: 1, Username: "alice", Content: "сам дурак"}
+	held, err := uc.screenMessage(ctx, msg)
+	require.NoError(t, err)
+	assert.False(t, held)
+	assert.Equal(t, "сам *****", msg.Content)
+
+	_, err = uc.screenMessage(ctx, &entity.Message{UserID: 1, Content: "best casino"})
+	assert.ErrorIs(t, err, ErrContentRejected)
+
+	msg = &entity.Message{ChannelID: entity.DefaultChannelID, UserID: 1, Username: "alice", Content: "see https://example.com"}
+	held, err = uc.screenMessage(ctx, msg)
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.True(t, msg.Held)
+	require.Len(t, repo.held, 1)
+	assert.Equal(t, []string{"links"}, repo.held[0].Rules)
+
+	_, err = uc.screenEdit(ctx, 1, "see https://example.com")
+	assert.ErrorIs(t, err, ErrContentRejected)
+}
+
+func TestScreenMessage_RejectsHeldWithoutModeration(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithContentFilter(newTestFilter()))
+
+	_, err := uc.screenMessage(context.Background(), &entity.Message{UserID: 1, Content: "https://example.com"})
+	assert.ErrorIs(t, err, ErrContentRejected)
+}
+
+func TestReviewHeld_Decline(t *testing.T) {
+	repo := newMemoryModeration()
+	uc := NewChatUseCase(nil, nil, WithModeration(repo))
+	ctx := context.Background()
+	require.NoError(t, repo.HoldMessage(ctx, &entity.HeldMessage{ChannelID: entity.DefaultChannelID, UserID: 1, Rules: []string{"links"}}))
+
+	_, err := uc.ReviewHeld(ctx, moderator, 1, false)
+	assert.ErrorIs(t, err, ErrModerationForbidden)
+
+	reviewer := Actor{UserID: 100, Access: rbac.Access{Permissions: []string{rbac.ChatDeleteAny}}}
+	record, err := uc.ReviewHeld(ctx, reviewer, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, entity.ActionDecline, record.Action)
+	assert.Equal(t, "links", record.Reason)
+	assert.Empty(t, repo.held)
+
+	_, err = uc.ReviewHeld(ctx, reviewer, 1, false)
+	assert.ErrorIs(t, err, ErrHeldNotFound)
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"backend/chat-service/internal/entity"
+)
+
+// ErrInvalidHistoryQuery запрошена история одновременно до и после курсора
+var ErrInvalidHistoryQuery = errors.New("before and after cursors are mutually exclusive")
+
+// History возвращает страницу истории канала, если пользователь может его читать.
+// userID 0 — анонимный пользователь.
+func (uc *ChatUseCase) History(ctx context.Context, userID int64, query entity.HistoryQuery) (*entity.HistoryPage, error) {
+	if query.BeforeID != 0 && query.AfterID != 0 {
+		return nil, ErrInvalidHistoryQuery
+	}
+	if query.ChannelID == 0 {
+		query.ChannelID = entity.DefaultChannelID
+	}
+	if query.Limit <= 0 {
+		query.Limit = entity.DefaultHistoryLimit
+	}
+	if query.Limit > entity.MaxHistoryLimit {
+		query.Limit = entity.MaxHistoryLimit
+	}
+
+	channel, err := uc.getChannel(ctx, query.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := uc.canRead(ctx, channel, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrChannelForbidden
+	}
+
+	var messages []*entity.Message
+	switch {
+	case query.AfterID != 0:
+		messages, err = uc.repo.GetChannelHistoryAfter(ctx, query.ChannelID, query.Limit, query.AfterID)
+	case query.ChannelID == entity.DefaultChannelID:
+		messages, err = uc.repo.GetHistory(ctx, query.Limit, query.BeforeID)
+	default:
+		messages, err = uc.repo.GetChannelHistory(ctx, query.ChannelID, query.Limit, query.BeforeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return historyPage(query, messages), nil
+}
+
+// historyPage вычисляет курсоры страницы. Полная страница считается признаком того,
+// что в направлении запроса есть еще сообщения: в худшем случае следующая страница будет пустой.
+func historyPage(query entity.HistoryQuery, messages []*entity.Message) *entity.HistoryPage {
+	if messages == nil {
+		messages = []*entity.Message{}
+	}
+
+	page := &entity.HistoryPage{
+		Messages: messages,
+		HasMore:  len(messages) == int(query.Limit),
+	}
+
+	if len(messages) == 0 {
+		// Позиция не сдвинулась: после курсора сообщений пока нет
+		page.After = entity.EncodeCursor(query.AfterID)
+		return page
+	}
+
+	first, _ := strconv.ParseInt(messages[0].ID, 10, 64)
+	last, _ := strconv.ParseInt(messages[len(messages)-1].ID, 10, 64)
+
+	page.After = entity.EncodeCursor(last)
+	if query.AfterID != 0 || page.HasMore {
+		page.Before = entity.EncodeCursor(first)
+	}
+	return page
+}
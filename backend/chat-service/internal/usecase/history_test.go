@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/entity"
+)
+
+func messagesFrom(first, last int64) []*entity.Message {
+	var messages []*entity.Message
+	for id := first; id <= last; id++ {
+		messages = append(messages, &entity.Message{ID: strconv.FormatInt(id, 10)})
+	}
+	return messages
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	id, err := entity.DecodeCursor(entity.EncodeCursor(42))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	id, err = entity.DecodeCursor("")
+	require.NoError(t, err)
+	assert.Zero(t, id)
+
+	for _, cursor := range []string{"42", "!!!", entity.EncodeCursor(42)[1:]} {
+		_, err := entity.DecodeCursor(cursor)
+		assert.ErrorIs(t, err, entity.ErrInvalidCursor, cursor)
+	}
+}
+
+func TestHistoryPage_Before(t *testing.T) {
+	// Полная страница: старые сообщения еще есть
+	page := historyPage(entity.HistoryQuery{Limit: 3}, messagesFrom(8, 10))
+	assert.True(t, page.HasMore)
+	assert.Equal(t, entity.EncodeCursor(8), page.Before)
+	assert.Equal(t, entity.EncodeCursor(10), page.After)
+
+	// Начало канала
+	page = historyPage(entity.HistoryQuery{Limit: 3, BeforeID: 3}, messagesFrom(1, 2))
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.Before)
+	assert.Equal(t, entity.EncodeCursor(2), page.After)
+}
+
+func TestHistoryPage_After(t *testing.T) {
+	page := historyPage(entity.HistoryQuery{Limit: 3, AfterID: 5}, messagesFrom(6, 7))
+	assert.False(t, page.HasMore)
+	assert.Equal(t, entity.EncodeCursor(6), page.Before)
+	assert.Equal(t, entity.EncodeCursor(7), page.After)
+
+	// Новых сообщений нет: курсор остается прежним
+	page = historyPage(entity.HistoryQuery{Limit: 3, AfterID: 7}, nil)
+	assert.NotNil(t, page.Messages)
+	assert.Empty(t, page.Before)
+	assert.Equal(t, entity.EncodeCursor(7), page.After)
+}
+
+func TestHistory_RejectsBothCursors(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+	_, err := uc.History(context.Background(), 0, entity.HistoryQuery{BeforeID: 1, AfterID: 2})
+	assert.ErrorIs(t, err, ErrInvalidHistoryQuery)
+}
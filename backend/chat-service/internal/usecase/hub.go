@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OverflowPolicy определяет, что делать с сообщением для клиента, чья очередь заполнена
+type OverflowPolicy int
+
+const (
+	// OverflowDisconnect отключает медленного клиента. После переподключения он
+	// догружает пропущенное через "resume".
+	OverflowDisconnect OverflowPolicy = iota
+	// OverflowDropNewest отбрасывает новое сообщение
+	OverflowDropNewest
+	// OverflowDropOldest вытесняет самое старое сообщение из очереди
+	OverflowDropOldest
+)
+
+// ParseOverflowPolicy разбирает политику из конфигурации: "disconnect", "drop_newest" или "drop_oldest"
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "disconnect", "":
+		return OverflowDisconnect, nil
+	case "drop_newest":
+		return OverflowDropNewest, nil
+	case "drop_oldest":
+		return OverflowDropOldest, nil
+	default:
+		return 0, fmt.Errorf("unknown overflow policy %q", s)
+	}
+}
+
+// HubConfig настраивает очереди клиентов и проверку живости соединений
+type HubConfig struct {
+	// QueueSize размер очереди исходящих сообщений клиента
+	QueueSize int
+	// Overflow что делать, когда очередь клиента заполнена
+	Overflow OverflowPolicy
+	// PongWait сколько ждать ответа на ping. Ping отправляется каждые 9/10 этого времени.
+	PongWait time.Duration
+	// WriteWait таймаут записи одного кадра
+	WriteWait time.Duration
+	// MaxMessageSize максимальный размер входящего сообщения в байтах
+	MaxMessageSize int64
+	// Compression сжимать исходящие кадры, если клиент согласовал permessage-deflate
+	Compression bool
+	// CompressionLevel уровень сжатия flate от 1 до 9
+	CompressionLevel int
+	// CompressionThreshold кадры меньше этого размера в байтах отправляются без сжатия
+	CompressionThreshold int
+}
+
+// DefaultHubConfig возвращает настройки хаба по умолчанию
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		QueueSize:            256,
+		Overflow:             OverflowDisconnect,
+		PongWait:             60 * time.Second,
+		WriteWait:            10 * time.Second,
+		MaxMessageSize:       64 * 1024,
+		Compression:          true,
+		CompressionLevel:     1,
+		CompressionThreshold: 512,
+	}
+}
+
+// WithHubConfig задает настройки очередей, heartbeat и сжатия. Нулевые поля, кроме Compression,
+// берутся из DefaultHubConfig.
+func WithHubConfig(cfg HubConfig) Option {
+	return func(uc *ChatUseCase) {
+		def := DefaultHubConfig()
+		if cfg.QueueSize <= 0 {
+			cfg.QueueSize = def.QueueSize
+		}
+		if cfg.PongWait <= 0 {
+			cfg.PongWait = def.PongWait
+		}
+		if cfg.WriteWait <= 0 {
+			cfg.WriteWait = def.WriteWait
+		}
+		if cfg.MaxMessageSize <= 0 {
+			cfg.MaxMessageSize = def.MaxMessageSize
+		}
+		if cfg.CompressionLevel <= 0 || cfg.CompressionLevel > 9 {
+			cfg.CompressionLevel = def.CompressionLevel
+		}
+		if cfg.CompressionThreshold <= 0 {
+			cfg.CompressionThreshold = def.CompressionThreshold
+		}
+		uc.hub = cfg
+	}
+}
+
+func (cfg HubConfig) pingPeriod() time.Duration {
+	return cfg.PongWait * 9 / 10
+}
+
+// HubMetrics счетчики хаба с момента запуска сервиса
+type HubMetrics struct {
+	// Clients подключенные WebSocket клиенты
+	Clients int `json:"clients"`
+	// Listeners подключенные слушатели вне WebSocket
+	Listeners int `json:"listeners"`
+	// Dropped сообщения, отброшенные из-за переполненной очереди клиента
+	Dropped uint64 `json:"dropped"`
+	// SlowDisconnects клиенты и слушатели, отключенные из-за переполненной очереди
+	SlowDisconnects uint64 `json:"slow_disconnects"`
+	// DeadPeers соединения, закрытые по таймауту чтения или записи
+	DeadPeers uint64 `json:"dead_peers"`
+}
+
+type hubCounters struct {
+	dropped         atomic.Uint64
+	slowDisconnects atomic.Uint64
+	deadPeers       atomic.Uint64
+}
+
+// Metrics возвращает текущие счетчики хаба
+func (uc *ChatUseCase) Metrics() HubMetrics {
+	uc.mutex.RLock()
+	clients := len(uc.clients)
+	listeners := make(map[*Listener]bool)
+	for _, channelListeners := range uc.listeners {
+		for l := range channelListeners {
+			listeners[l] = true
+		}
+	}
+	uc.mutex.RUnlock()
+
+	return HubMetrics{
+		Clients:         clients,
+		Listeners:       len(listeners),
+		Dropped:         uc.counters.dropped.Load(),
+		SlowDisconnects: uc.counters.slowDisconnects.Load(),
+		DeadPeers:       uc.counters.deadPeers.Load(),
+	}
+}
+
+// Serve регистрирует клиента в хабе и запускает чтение и запись его соединения
+func (uc *ChatUseCase) Serve(c *Client) {
+	if cap(c.Send) != uc.hub.QueueSize {
+		c.Send = make(chan *Frame, uc.hub.QueueSize)
+	}
+	c.Conn.SetCompressionLevel(uc.hub.CompressionLevel)
+
+	uc.register(c)
+
+	go c.WritePump(uc)
+	go c.ReadPump(uc)
+	go c.watchToken()
+}
+
+// sendLocked ставит кадр в очередь клиента, не дожидаясь места в ней. При переполнении
+// применяется политика хаба. Вызывается под uc.mutex.
+func (uc *ChatUseCase) sendLocked(c *Client, frame *Frame) {
+	if c.ctx.Err() != nil {
+		return
+	}
+
+	select {
+	case c.Send <- frame:
+		return
+	default:
+	}
+
+	switch uc.hub.Overflow {
+	case OverflowDropNewest:
+		uc.counters.dropped.Add(1)
+	case OverflowDropOldest:
+		// Очередь разбирает и WritePump, поэтому место могло освободиться само
+		select {
+		case <-c.Send:
+			uc.counters.dropped.Add(1)
+		default:
+		}
+		select {
+		case c.Send <- frame:
+		default:
+			uc.counters.dropped.Add(1)
+		}
+	default:
+		uc.counters.slowDisconnects.Add(1)
+		log.Printf("client of user %d is too slow, disconnecting", c.UserID)
+		c.shutdown(websocket.CloseTryAgainLater, "send queue overflow")
+		uc.removeClientLocked(c)
+	}
+}
+
+// reply отправляет сообщение только этому клиенту. В отличие от рассылки ждет места
+// в очереди: так медленный клиент притормаживает обработку собственных запросов.
+func (c *Client) reply(msg ChatMessage) {
+	select {
+	case c.Send <- newMessageFrame(msg):
+	case <-c.ctx.Done():
+	}
+}
+
+// shutdown останавливает клиента. Код и причина уходят клиенту в кадре закрытия.
+// Повторные вызовы ничего не делают.
+func (c *Client) shutdown(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode, c.closeReason = code, reason
+		c.cancel()
+	})
+}
+
+// WritePump отправляет клиенту сообщения из очереди и ping для проверки соединения
+func (c *Client) WritePump(uc *ChatUseCase) {
+	ticker := time.NewTicker(uc.hub.pingPeriod())
+	defer func() {
+		ticker.Stop()
+		c.Close()
+	}()
+
+	for {
+		select {
+		case frame := <-c.Send:
+			message, size, err := frame.prepared(c.Encoding)
+			if err != nil {
+				log.Printf("failed to encode message for %s client: %v", c.Encoding, err)
+				continue
+			}
+			c.Conn.EnableWriteCompression(uc.hub.Compression && size >= uc.hub.CompressionThreshold)
+			c.Conn.SetWriteDeadline(time.Now().Add(uc.hub.WriteWait))
+			if err := c.Conn.WritePreparedMessage(message); err != nil {
+				uc.countDeadPeer(err)
+				return
+			}
+		case <-ticker.C:
+			c.Conn.SetWriteDeadline(time.Now().Add(uc.hub.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				uc.countDeadPeer(err)
+				return
+			}
+		case <-c.ctx.Done():
+			c.Conn.SetWriteDeadline(time.Now().Add(uc.hub.WriteWait))
+			c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeReason))
+			return
+		}
+	}
+}
+
+// ReadPump читает сообщения от клиента. Соединение, по которому за PongWait не пришло
+// ни одного кадра, считается мертвым.
+func (c *Client) ReadPump(uc *ChatUseCase) {
+	defer func() {
+		c.stopAllTyping(uc)
+		uc.unregister <- c
+		c.Close()
+	}()
+
+	c.Conn.SetReadLimit(uc.hub.MaxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(uc.hub.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(uc.hub.PongWait))
+	})
+
+	c.restoreChannels(uc)
+
+	for {
+		_, message, err := c.Conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("error: %v", err)
+			}
+			uc.countDeadPeer(err)
+			return
+		}
+		c.Conn.SetReadDeadline(time.Now().Add(uc.hub.PongWait))
+
+		if err := c.HandleMessage(message, uc); err != nil {
+			log.Printf("error handling message: %v", err)
+			c.reply(ChatMessage{
+				Type:  "error",
+				Error: "Failed to process message",
+			})
+		}
+	}
+}
+
+// countDeadPeer учитывает соединение, закрытое по таймауту чтения или записи
+func (uc *ChatUseCase) countDeadPeer(err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		uc.counters.deadPeers.Add(1)
+	}
+}
@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueuedClient(uc *ChatUseCase, size int) *Client {
+	c := NewClient(nil, 1, "alice", true)
+	c.Send = make(chan *Frame, size)
+	uc.clients[c] = true
+	return c
+}
+
+func TestHub_DropNewest(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithHubConfig(HubConfig{Overflow: OverflowDropNewest}))
+	c := newQueuedClient(uc, 1)
+
+	first := NewFrame([]byte("1"))
+	uc.sendLocked(c, first)
+	uc.sendLocked(c, NewFrame([]byte("2")))
+
+	assert.Same(t, first, <-c.Send)
+	assert.True(t, uc.clients[c])
+	assert.Equal(t, uint64(1), uc.Metrics().Dropped)
+}
+
+func TestHub_DropOldest(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithHubConfig(HubConfig{Overflow: OverflowDropOldest}))
+	c := newQueuedClient(uc, 2)
+
+	second, third := NewFrame([]byte("2")), NewFrame([]byte("3"))
+	uc.sendLocked(c, NewFrame([]byte("1")))
+	uc.sendLocked(c, second)
+	uc.sendLocked(c, third)
+
+	assert.Same(t, second, <-c.Send)
+	assert.Same(t, third, <-c.Send)
+	assert.Equal(t, uint64(1), uc.Metrics().Dropped)
+}
+
+func TestHub_DisconnectSlowClient(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+	c := newQueuedClient(uc, 1)
+
+	uc.sendLocked(c, NewFrame([]byte("1")))
+	uc.sendLocked(c, NewFrame([]byte("2")))
+
+	assert.False(t, uc.clients[c])
+	require.Error(t, c.ctx.Err())
+	assert.Equal(t, uint64(1), uc.Metrics().SlowDisconnects)
+
+	// Отключенному клиенту больше ничего не ставится в очередь, и отправка не паникует
+	uc.sendLocked(c, NewFrame([]byte("3")))
+	c.reply(ChatMessage{Type: "error"})
+	assert.Len(t, c.Send, 1)
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	policy, err := ParseOverflowPolicy("drop_oldest")
+	require.NoError(t, err)
+	assert.Equal(t, OverflowDropOldest, policy)
+
+	_, err = ParseOverflowPolicy("block")
+	assert.Error(t, err)
+}
@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"log"
+
+	"backend/chat-service/internal/entity"
+)
+
+// listenerQueueSize очередь событий слушателя. Слушатель, который не успевает
+// их забирать, отключается так же, как медленный WebSocket клиент.
+const listenerQueueSize = 256
+
+// listenerEvents события, которые получают слушатели
+var listenerEvents = map[string]bool{
+	"message":   true,
+	"edited":    true,
+	"deleted":   true,
+	"reactions": true,
+	"previews":  true,
+}
+
+// Listener получает сообщения каналов вне WebSocket соединения, например в потоке gRPC
+type Listener struct {
+	UserID int64
+	// Events закрывается, когда слушатель отключен
+	Events <-chan ChatMessage
+
+	events   chan ChatMessage
+	channels map[int64]bool
+}
+
+// Listen подписывает пользователя на новые, отредактированные и удаленные сообщения каналов.
+// Без каналов подписывает на общий. Слушателя нужно отключить через Unlisten.
+func (uc *ChatUseCase) Listen(ctx context.Context, userID int64, channelIDs []int64) (*Listener, error) {
+	if len(channelIDs) == 0 {
+		channelIDs = []int64{entity.DefaultChannelID}
+	}
+
+	for _, channelID := range channelIDs {
+		channel, err := uc.getChannel(ctx, channelID)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := uc.canRead(ctx, channel, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrChannelForbidden
+		}
+	}
+
+	events := make(chan ChatMessage, listenerQueueSize)
+	l := &Listener{
+		UserID:   userID,
+		Events:   events,
+		events:   events,
+		channels: make(map[int64]bool, len(channelIDs)),
+	}
+
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	for _, channelID := range channelIDs {
+		l.channels[channelID] = true
+		if uc.listeners[channelID] == nil {
+			uc.listeners[channelID] = make(map[*Listener]bool)
+		}
+		uc.listeners[channelID][l] = true
+	}
+	return l, nil
+}
+
+// Unlisten отключает слушателя
+func (uc *ChatUseCase) Unlisten(l *Listener) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+	uc.removeListenerLocked(l)
+}
+
+// removeListenerLocked отписывает слушателя от всех каналов и закрывает его очередь.
+// Вызывается под uc.mutex.
+func (uc *ChatUseCase) removeListenerLocked(l *Listener) {
+	if l.channels == nil {
+		return
+	}
+	for channelID := range l.channels {
+		uc.removeListenerChannelLocked(l, channelID)
+	}
+	l.channels = nil
+	close(l.events)
+}
+
+func (uc *ChatUseCase) removeListenerChannelLocked(l *Listener, channelID int64) {
+	delete(l.channels, channelID)
+	if listeners, ok := uc.listeners[channelID]; ok {
+		delete(listeners, l)
+		if len(listeners) == 0 {
+			delete(uc.listeners, channelID)
+		}
+	}
+}
+
+// notifyListenersLocked передает слушателям канала событие из брокера. Вызывается под uc.mutex.
+func (uc *ChatUseCase) notifyListenersLocked(channelID int64, frame *Frame) {
+	listeners := uc.listeners[channelID]
+	if len(listeners) == 0 {
+		return
+	}
+
+	msg, err := frame.Message()
+	if err != nil {
+		log.Printf("failed to decode event for listeners: %v", err)
+		return
+	}
+	if !listenerEvents[msg.Type] {
+		return
+	}
+
+	for l := range listeners {
+		select {
+		case l.events <- msg:
+		default:
+			uc.counters.slowDisconnects.Add(1)
+			log.Printf("listener of user %d is too slow, disconnecting", l.UserID)
+			uc.removeListenerLocked(l)
+		}
+	}
+}
+
+// removeUsersListenersLocked отписывает слушателей пользователей, исключенных из канала.
+// Вызывается под uc.mutex.
+func (uc *ChatUseCase) removeUsersListenersLocked(channelID int64, userIDs []int64) {
+	removed := make(map[int64]bool, len(userIDs))
+	for _, userID := range userIDs {
+		removed[userID] = true
+	}
+
+	for l := range uc.listeners[channelID] {
+		if !removed[l.UserID] {
+			continue
+		}
+		uc.removeListenerChannelLocked(l, channelID)
+		if len(l.channels) == 0 {
+			uc.removeListenerLocked(l)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/pkg/rbac"
+)
+
+var (
+	ErrMessageNotFound  = repository.ErrMessageNotFound
+	ErrMessageForbidden = errors.New("only the author or a moderator can change this message")
+	ErrMessageDeleted   = errors.New("message is deleted")
+	ErrEmptyContent     = errors.New("message content is empty")
+)
+
+// Коды ошибок редактирования и удаления
+const (
+	ErrCodeMessageNotFound  = "message_not_found"
+	ErrCodeMessageForbidden = "message_forbidden"
+	ErrCodeMessageDeleted   = "message_deleted"
+	ErrCodeInvalidMessage   = "invalid_message"
+)
+
+// EditMessage заменяет текст сообщения. Доступно автору и модераторам.
+func (uc *ChatUseCase) EditMessage(ctx context.Context, actor Actor, messageID int64, content string) (*entity.Message, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyContent
+	}
+
+	msg, err := uc.changeableMessage(ctx, actor, messageID, rbac.ChatEditAny)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.checkRestrictions(ctx, actor, msg.ChannelID, false); err != nil {
+		return nil, err
+	}
+	content, err = uc.screenEdit(ctx, actor.UserID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := uc.repo.Edit(ctx, messageID, content)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		// Сообщение удалили между проверкой и редактированием
+		return nil, ErrMessageDeleted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.publishToChannel(ctx, msg.ChannelID, ChatMessage{
+		Type:      "edited",
+		ID:        edited.ID,
+		ChannelID: edited.ChannelID,
+		Content:   edited.Content,
+		UserID:    edited.UserID,
+		Username:  edited.Username,
+		EditedAt:  edited.EditedAt,
+	})
+	if err == nil {
+		uc.attachPreviews(edited)
+	}
+	return edited, err
+}
+
+// DeleteMessage мягко удаляет сообщение. Доступно автору и модераторам.
+func (uc *ChatUseCase) DeleteMessage(ctx context.Context, actor Actor, messageID int64) (*entity.Message, error) {
+	msg, err := uc.changeableMessage(ctx, actor, messageID, rbac.ChatDeleteAny)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := uc.repo.SoftDelete(ctx, messageID, actor.UserID)
+	if errors.Is(err, repository.ErrMessageNotFound) {
+		return nil, ErrMessageDeleted
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = uc.publishToChannel(ctx, msg.ChannelID, ChatMessage{
+		Type:      "deleted",
+		ID:        deleted.ID,
+		ChannelID: deleted.ChannelID,
+		UserID:    deleted.UserID,
+		DeletedAt: deleted.DeletedAt,
+	})
+	return deleted, err
+}
+
+// changeableMessage возвращает неудаленное сообщение, которое пользователь может изменить:
+// свое или любое при наличии разрешения модератора
+func (uc *ChatUseCase) changeableMessage(ctx context.Context, actor Actor, messageID int64, anyPerm string) (*entity.Message, error) {
+	msg, err := uc.repo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.IsDeleted() {
+		return nil, ErrMessageDeleted
+	}
+	if actor.UserID == 0 || (msg.UserID != actor.UserID && !actor.Access.Has(anyPerm)) {
+		return nil, ErrMessageForbidden
+	}
+	return msg, nil
+}
+
+// publishToChannel рассылает сообщение участникам канала на всех экземплярах сервиса
+func (uc *ChatUseCase) publishToChannel(ctx context.Context, channelID int64, msg ChatMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %v", msg.Type, err)
+	}
+
+	err = uc.broker.Publish(ctx, broker.Event{
+		Type:      broker.EventDeliver,
+		ChannelID: channelID,
+		UserIDs:   uc.directMembers(channelID),
+		Payload:   payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s message: %v", msg.Type, err)
+	}
+	return nil
+}
+
+// handleEdit обрабатывает сообщение типа "edit"
+func (c *Client) handleEdit(msg ChatMessage, uc *ChatUseCase) error {
+	id, ok := c.messageID(msg)
+	if !ok {
+		return nil
+	}
+
+	_, err := uc.EditMessage(c.ctx, c.actor(), id, msg.Content)
+	return c.replyMessageError(msg, err)
+}
+
+// handleDelete обрабатывает сообщение типа "delete"
+func (c *Client) handleDelete(msg ChatMessage, uc *ChatUseCase) error {
+	id, ok := c.messageID(msg)
+	if !ok {
+		return nil
+	}
+
+	_, err := uc.DeleteMessage(c.ctx, c.actor(), id)
+	return c.replyMessageError(msg, err)
+}
+
+// messageID разбирает ID сообщения из кадра, при ошибке отвечает клиенту
+func (c *Client) messageID(msg ChatMessage) (int64, bool) {
+	id, err := strconv.ParseInt(msg.ID, 10, 64)
+	if err != nil || id <= 0 {
+		c.reply(ChatMessage{
+			Type:   "error",
+			Code:   ErrCodeInvalidMessage,
+			Error:  "Некорректный ID сообщения",
+			ID:     msg.ID,
+			TempID: msg.TempID,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// replyMessageError сообщает клиенту об ошибке отправки, редактирования или удаления.
+// Ошибки, не связанные с запросом клиента, возвращаются вызывающему.
+func (c *Client) replyMessageError(msg ChatMessage, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if c.replyRestriction(msg, msg.ChannelID, err) {
+		return nil
+	}
+
+	reply := ChatMessage{Type: "error", ID: msg.ID, TempID: msg.TempID}
+	switch {
+	case errors.Is(err, ErrMessageNotFound):
+		reply.Code, reply.Error = ErrCodeMessageNotFound, "Сообщение не найдено"
+	case errors.Is(err, ErrMessageForbidden):
+		reply.Code, reply.Error = ErrCodeMessageForbidden, "Изменять сообщение может только автор или модератор"
+	case errors.Is(err, ErrMessageDeleted):
+		reply.Code, reply.Error = ErrCodeMessageDeleted, "Сообщение удалено"
+	case errors.Is(err, ErrEmptyContent):
+		reply.Code, reply.Error = ErrCodeInvalidMessage, "Сообщение не может быть пустым"
+	case errors.Is(err, ErrContentRejected):
+		reply.Code, reply.Error = ErrCodeContentRejected, "Сообщение отклонено фильтром"
+	case errors.Is(err, ErrInvalidReaction):
+		reply.Code, reply.Error = ErrCodeInvalidReaction, "Реакцией может быть только один эмодзи"
+	case errors.Is(err, ErrTooManyReactions):
+		reply.Code, reply.Error = ErrCodeTooManyReactions, "У сообщения слишком много разных реакций"
+	case errors.Is(err, ErrInvalidReply):
+		reply.Code, reply.Error = ErrCodeInvalidReply, "Можно ответить только на сообщение этого канала"
+	case errors.Is(err, ErrChannelForbidden):
+		reply.Code, reply.Error = ErrCodeChannelForbidden, "Нет доступа к каналу"
+	default:
+		return err
+	}
+
+	c.reply(reply)
+	return nil
+}
+
+// actor возвращает пользователя клиента для проверок прав в use case
+func (c *Client) actor() Actor {
+	if !c.IsAuth {
+		return Actor{}
+	}
+	return Actor{UserID: c.UserID, Access: c.Access}
+}
+
+// requiresAuth сообщает, что тип сообщения доступен только авторизованным пользователям
+func requiresAuth(msgType string) bool {
+	switch msgType {
+	case "message", "edit", "delete", "typing_start", "typing_stop", "presence", "read", "react",
+		entity.ActionMute, entity.ActionUnmute, entity.ActionKick, entity.ActionBan,
+		entity.ActionUnban, entity.ActionSlowMode, entity.ActionPurge:
+		return true
+	}
+	return false
+}
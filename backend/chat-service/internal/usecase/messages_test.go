@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/pkg/rbac"
+)
+
+func (r *fakeMessages) find(id int64) *entity.Message {
+	for _, m := range r.messages {
+		if m.ID == strconv.FormatInt(id, 10) {
+			return m
+		}
+	}
+	return nil
+}
+
+func (r *fakeMessages) GetByID(ctx context.Context, id int64) (*entity.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.find(id)
+	if m == nil {
+		return nil, repository.ErrMessageNotFound
+	}
+	copied := *m
+	copied.Reactions = append([]entity.Reaction(nil), m.Reactions...)
+	return &copied, nil
+}
+
+func (r *fakeMessages) Edit(ctx context.Context, id int64, content string) (*entity.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.find(id)
+	if m == nil || m.IsDeleted() {
+		return nil, repository.ErrMessageNotFound
+	}
+	now := time.Now()
+	m.Content, m.EditedAt = content, &now
+	copied := *m
+	return &copied, nil
+}
+
+func (r *fakeMessages) SoftDelete(ctx context.Context, id, deletedBy int64) (*entity.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.find(id)
+	if m == nil || m.IsDeleted() {
+		return nil, repository.ErrMessageNotFound
+	}
+	now := time.Now()
+	m.Content, m.DeletedAt, m.DeletedBy = "", &now, &deletedBy
+	copied := *m
+	return &copied, nil
+}
+
+// newMessageRepo создает хранилище с сообщениями 1 (автор 1) и 2 (автор 2) общего канала
+func newMessageRepo() *fakeMessages {
+	return &fakeMessages{messages: []*entity.Message{
+		{ID: "1", ChannelID: entity.DefaultChannelID, Seq: 1, Content: "first", UserID: 1, Username: "alice"},
+		{ID: "2", ChannelID: entity.DefaultChannelID, Seq: 2, Content: "second", UserID: 2, Username: "bob"},
+	}}
+}
+
+// published возвращает сообщение, опубликованное в брокер
+func published(t *testing.T, uc *ChatUseCase) (broker.Event, ChatMessage) {
+	t.Helper()
+	select {
+	case event := <-uc.broker.Events():
+		var msg ChatMessage
+		require.NoError(t, json.Unmarshal(event.Payload, &msg))
+		return event, msg
+	case <-time.After(time.Second):
+		t.Fatal("nothing was published")
+		return broker.Event{}, ChatMessage{}
+	}
+}
+
+var messageModerator = Actor{UserID: 100, Access: rbac.Access{Permissions: []string{rbac.ChatEditAny, rbac.ChatDeleteAny}}}
+
+func TestEditMessage_AuthorOrModerator(t *testing.T) {
+	repo := newMessageRepo()
+	uc := NewChatUseCase(repo, nil)
+	ctx := context.Background()
+
+	// Чужое сообщение без разрешения модератора и от анонимного пользователя не меняется
+	_, err := uc.EditMessage(ctx, Actor{UserID: 2}, 1, "changed")
+	assert.ErrorIs(t, err, ErrMessageForbidden)
+	_, err = uc.EditMessage(ctx, Actor{}, 1, "changed")
+	assert.ErrorIs(t, err, ErrMessageForbidden)
+	// Разрешение на удаление не дает права редактировать
+	_, err = uc.EditMessage(ctx, Actor{UserID: 2, Access: rbac.Access{Permissions: []string{rbac.ChatDeleteAny}}}, 1, "changed")
+	assert.ErrorIs(t, err, ErrMessageForbidden)
+	assert.Equal(t, "first", repo.messages[0].Content)
+
+	edited, err := uc.EditMessage(ctx, Actor{UserID: 1}, 1, "  edited  ")
+	require.NoError(t, err)
+	assert.Equal(t, "edited", edited.Content)
+	assert.NotNil(t, edited.EditedAt)
+
+	event, msg := published(t, uc)
+	assert.Equal(t, entity.DefaultChannelID, event.ChannelID)
+	assert.Equal(t, "edited", msg.Type)
+	assert.Equal(t, "1", msg.ID)
+	assert.Equal(t, "edited", msg.Content)
+
+	_, err = uc.EditMessage(ctx, messageModerator, 2, "moderated")
+	require.NoError(t, err)
+	assert.Equal(t, "moderated", repo.messages[1].Content)
+
+	_, err = uc.EditMessage(ctx, Actor{UserID: 1}, 1, "   ")
+	assert.ErrorIs(t, err, ErrEmptyContent)
+	_, err = uc.EditMessage(ctx, Actor{UserID: 1}, 3, "missing")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
+
+func TestDeleteMessage_LeavesTombstone(t *testing.T) {
+	repo := newMessageRepo()
+	uc := NewChatUseCase(repo, nil)
+	ctx := context.Background()
+
+	_, err := uc.DeleteMessage(ctx, Actor{UserID: 1}, 2)
+	assert.ErrorIs(t, err, ErrMessageForbidden)
+	// Разрешение на редактирование не дает права удалять
+	_, err = uc.DeleteMessage(ctx, Actor{UserID: 1, Access: rbac.Access{Permissions: []string{rbac.ChatEditAny}}}, 2)
+	assert.ErrorIs(t, err, ErrMessageForbidden)
+
+	deleted, err := uc.DeleteMessage(ctx, messageModerator, 2)
+	require.NoError(t, err)
+	assert.True(t, deleted.IsDeleted())
+	assert.Empty(t, deleted.Content)
+	require.NotNil(t, deleted.DeletedBy)
+	assert.Equal(t, messageModerator.UserID, *deleted.DeletedBy)
+
+	// Надгробие рассылается без текста и остается в истории
+	_, msg := published(t, uc)
+	assert.Equal(t, "deleted", msg.Type)
+	assert.Equal(t, "2", msg.ID)
+	assert.Empty(t, msg.Content)
+	assert.NotNil(t, msg.DeletedAt)
+	assert.Len(t, repo.messages, 2)
+
+	// Надгробие нельзя ни изменить, ни удалить повторно
+	_, err = uc.EditMessage(ctx, Actor{UserID: 2}, 2, "again")
+	assert.ErrorIs(t, err, ErrMessageDeleted)
+	_, err = uc.DeleteMessage(ctx, Actor{UserID: 2}, 2)
+	assert.ErrorIs(t, err, ErrMessageDeleted)
+
+	_, err = uc.DeleteMessage(ctx, Actor{UserID: 1}, 1)
+	require.NoError(t, err)
+}
+
+// deletingMessages удаляет сообщение сразу после того, как use case его прочитал
+type deletingMessages struct {
+	*fakeMessages
+}
+
+func (r deletingMessages) GetByID(ctx context.Context, id int64) (*entity.Message, error) {
+	msg, err := r.fakeMessages.GetByID(ctx, id)
+	if err == nil {
+		_, err = r.fakeMessages.SoftDelete(ctx, id, 2)
+	}
+	return msg, err
+}
+
+func TestEditMessage_DeletedMeanwhile(t *testing.T) {
+	repo := newMessageRepo()
+	uc := NewChatUseCase(deletingMessages{repo}, nil)
+	ctx := context.Background()
+
+	// Проверка прав прошла, но сообщение удалили до записи правки
+	_, err := uc.EditMessage(ctx, Actor{UserID: 1}, 1, "edited")
+	assert.ErrorIs(t, err, ErrMessageDeleted)
+	assert.Empty(t, repo.messages[0].Content)
+
+	_, err = uc.DeleteMessage(ctx, Actor{UserID: 2}, 2)
+	assert.ErrorIs(t, err, ErrMessageDeleted)
+
+	// Ни правка, ни повторное удаление не рассылаются
+	select {
+	case event := <-uc.broker.Events():
+		t.Fatalf("unexpected event %s", event.Payload)
+	default:
+	}
+}
+
+func TestEditMessage_Muted(t *testing.T) {
+	moderation := newMemoryModeration()
+	uc := NewChatUseCase(newMessageRepo(), nil, WithModeration(moderation))
+	ctx := context.Background()
+
+	require.NoError(t, moderation.SetSanction(ctx, &entity.Sanction{
+		ChannelID: entity.AllChannels, UserID: 1, Kind: entity.SanctionMute,
+	}))
+
+	_, err := uc.EditMessage(ctx, Actor{UserID: 1}, 1, "edited")
+	assert.ErrorIs(t, err, ErrMuted)
+}
@@ -0,0 +1,484 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+	"backend/pkg/ratelimit"
+	"backend/pkg/rbac"
+)
+
+var (
+	ErrModerationDisabled  = errors.New("moderation is not configured")
+	ErrModerationForbidden = errors.New("moderation permission required")
+	ErrInvalidModeration   = errors.New("invalid moderation request")
+	ErrSanctionNotFound    = errors.New("user has no such sanction")
+
+	ErrMuted    = errors.New("user is muted")
+	ErrBanned   = errors.New("user is banned")
+	ErrSlowMode = errors.New("slow mode is enabled in the channel")
+)
+
+// Коды ошибок модерации
+const (
+	ErrCodeMuted               = "muted"
+	ErrCodeBanned              = "banned"
+	ErrCodeSlowMode            = "slow_mode"
+	ErrCodeModerationForbidden = "moderation_forbidden"
+	ErrCodeInvalidModeration   = "invalid_moderation"
+	ErrCodeSanctionNotFound    = "sanction_not_found"
+)
+
+// Коды закрытия соединений, закрытых модератором
+const (
+	CloseKicked = 4001
+	CloseBanned = 4003
+)
+
+// Пределы параметров модерации
+const (
+	maxSanctionDuration = 365 * 24 * time.Hour
+	maxSlowMode         = time.Hour
+	defaultPurgePeriod  = time.Hour
+	maxPurgePeriod      = 7 * 24 * time.Hour
+
+	defaultModerationLogLimit = 50
+	maxModerationLogLimit     = 200
+)
+
+// moderationPermissions разрешение, необходимое для каждого действия
+var moderationPermissions = map[string]string{
+	entity.ActionMute:     rbac.ChatMute,
+	entity.ActionUnmute:   rbac.ChatMute,
+	entity.ActionKick:     rbac.ChatKick,
+	entity.ActionBan:      rbac.ChatBan,
+	entity.ActionUnban:    rbac.ChatBan,
+	entity.ActionSlowMode: rbac.ChatSlowMode,
+	entity.ActionPurge:    rbac.ChatDeleteAny,
+}
+
+// RestrictionError запрещает пользователю писать в канал. RetryAfter — через сколько
+// запрет снимется, ноль для бессрочного.
+type RestrictionError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RestrictionError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%v, retry after %v", e.Err, e.RetryAfter.Round(time.Second))
+	}
+	return e.Err.Error()
+}
+
+func (e *RestrictionError) Unwrap() error {
+	return e.Err
+}
+
+// WithModeration включает mute, ban, медленный режим и журнал модерации
+func WithModeration(moderation repository.ModerationRepository) Option {
+	return func(uc *ChatUseCase) {
+		uc.moderation = moderation
+	}
+}
+
+// IsModerator сообщает, что пользователь может выполнять хотя бы одно действие модератора
+func IsModerator(actor Actor) bool {
+	for _, perm := range moderationPermissions {
+		if actor.Access.Has(perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Moderate выполняет действие модератора и записывает его в журнал.
+// ChannelID 0 в mute, ban и purge означает все каналы.
+func (uc *ChatUseCase) Moderate(ctx context.Context, actor Actor, action string, req entity.ModerationRequest) (*entity.ModerationAction, error) {
+	if uc.moderation == nil {
+		return nil, ErrModerationDisabled
+	}
+	perm, ok := moderationPermissions[action]
+	if !ok {
+		return nil, ErrInvalidModeration
+	}
+	if actor.UserID == 0 || !actor.Access.Has(perm) {
+		return nil, ErrModerationForbidden
+	}
+	if err := uc.validateModeration(ctx, action, req); err != nil {
+		return nil, err
+	}
+
+	record := &entity.ModerationAction{
+		Action:      action,
+		ChannelID:   req.ChannelID,
+		ModeratorID: actor.UserID,
+		UserID:      req.UserID,
+		Reason:      req.Reason,
+		Duration:    req.Duration,
+	}
+
+	var err error
+	switch action {
+	case entity.ActionMute, entity.ActionBan:
+		err = uc.sanction(ctx, actor, action, req)
+	case entity.ActionUnmute:
+		err = uc.liftSanction(ctx, req, entity.SanctionMute, "unmuted")
+	case entity.ActionUnban:
+		err = uc.liftSanction(ctx, req, entity.SanctionBan, "unbanned")
+	case entity.ActionKick:
+		err = uc.kick(ctx, req.UserID, CloseKicked, "kicked")
+	case entity.ActionSlowMode:
+		err = uc.setSlowMode(ctx, req)
+	case entity.ActionPurge:
+		record.Affected, err = uc.purge(ctx, actor, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("moderator %d: %s user %d in channel %d (duration %ds, affected %d): %s",
+		actor.UserID, action, req.UserID, req.ChannelID, req.Duration, record.Affected, req.Reason)
+	if err := uc.moderation.LogAction(ctx, record); err != nil {
+		// Действие уже выполнено, запись о нем осталась в логе сервиса
+		log.Printf("failed to save moderation action: %v", err)
+	}
+	return record, nil
+}
+
+// validateModeration проверяет параметры действия и существование канала
+func (uc *ChatUseCase) validateModeration(ctx context.Context, action string, req entity.ModerationRequest) error {
+	duration := time.Duration(req.Duration) * time.Second
+	if req.Duration < 0 || duration > maxSanctionDuration {
+		return ErrInvalidModeration
+	}
+
+	switch action {
+	case entity.ActionSlowMode:
+		if req.ChannelID == entity.AllChannels || duration > maxSlowMode {
+			return ErrInvalidModeration
+		}
+	case entity.ActionMute:
+		if req.UserID <= 0 || req.Duration == 0 {
+			return ErrInvalidModeration
+		}
+	case entity.ActionPurge:
+		if req.UserID <= 0 || duration > maxPurgePeriod {
+			return ErrInvalidModeration
+		}
+	default:
+		if req.UserID <= 0 {
+			return ErrInvalidModeration
+		}
+	}
+
+	if req.ChannelID < 0 {
+		return ErrInvalidModeration
+	}
+	if req.ChannelID != entity.AllChannels && action != entity.ActionKick {
+		channel, err := uc.getChannel(ctx, req.ChannelID)
+		if err != nil {
+			return err
+		}
+		if channel.IsDirect() && (action == entity.ActionBan || action == entity.ActionSlowMode) {
+			return ErrDirectMembers
+		}
+	}
+	return nil
+}
+
+// sanction выдает mute или ban и сообщает о нем пользователю
+func (uc *ChatUseCase) sanction(ctx context.Context, actor Actor, action string, req entity.ModerationRequest) error {
+	kind := entity.SanctionBan
+	if action == entity.ActionMute {
+		kind = entity.SanctionMute
+	}
+
+	s := &entity.Sanction{
+		ChannelID: req.ChannelID,
+		UserID:    req.UserID,
+		Kind:      kind,
+		Reason:    req.Reason,
+		CreatedBy: actor.UserID,
+	}
+	if req.Duration > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.Duration) * time.Second)
+		s.ExpiresAt = &expiresAt
+	}
+	if err := uc.moderation.SetSanction(ctx, s); err != nil {
+		return err
+	}
+
+	notice := ChatMessage{
+		Type:      "muted",
+		ChannelID: req.ChannelID,
+		Reason:    req.Reason,
+		ExpiresAt: s.ExpiresAt,
+	}
+	if s.Kind == entity.SanctionMute {
+		return uc.notifyUser(ctx, req.UserID, notice)
+	}
+
+	// Заблокированный во всех каналах пользователь отключается, в одном канале — исключается из него
+	if req.ChannelID == entity.AllChannels {
+		return uc.kick(ctx, req.UserID, CloseBanned, "banned")
+	}
+	if uc.channelRepo != nil && req.ChannelID != entity.DefaultChannelID {
+		if err := uc.channelRepo.RemoveMember(ctx, req.ChannelID, req.UserID); err != nil {
+			return err
+		}
+	}
+	notice.Type = "banned"
+	uc.publishMembership(ctx, broker.Event{
+		Type:      broker.EventLeave,
+		ChannelID: req.ChannelID,
+		UserIDs:   []int64{req.UserID},
+	}, notice)
+	return nil
+}
+
+// liftSanction снимает mute или ban
+func (uc *ChatUseCase) liftSanction(ctx context.Context, req entity.ModerationRequest, kind, notice string) error {
+	removed, err := uc.moderation.RemoveSanction(ctx, req.ChannelID, req.UserID, kind)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrSanctionNotFound
+	}
+	return uc.notifyUser(ctx, req.UserID, ChatMessage{Type: notice, ChannelID: req.ChannelID})
+}
+
+// kick закрывает соединения пользователя на всех экземплярах сервиса
+func (uc *ChatUseCase) kick(ctx context.Context, userID int64, code int, reason string) error {
+	err := uc.broker.Publish(ctx, broker.Event{
+		Type:      broker.EventKick,
+		UserIDs:   []int64{userID},
+		CloseCode: code,
+		Reason:    reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish kick event: %v", err)
+	}
+	return nil
+}
+
+func (uc *ChatUseCase) setSlowMode(ctx context.Context, req entity.ModerationRequest) error {
+	if err := uc.moderation.SetSlowMode(ctx, req.ChannelID, req.Duration); err != nil {
+		return err
+	}
+	return uc.publishToChannel(ctx, req.ChannelID, ChatMessage{
+		Type:      "slowmode",
+		ChannelID: req.ChannelID,
+		Duration:  req.Duration,
+	})
+}
+
+// purge удаляет недавние сообщения пользователя и возвращает их число
+func (uc *ChatUseCase) purge(ctx context.Context, actor Actor, req entity.ModerationRequest) (int, error) {
+	period := defaultPurgePeriod
+	if req.Duration > 0 {
+		period = time.Duration(req.Duration) * time.Second
+	}
+
+	messages, err := uc.moderation.Purge(ctx, req.ChannelID, req.UserID, actor.UserID, time.Now().Add(-period))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, msg := range messages {
+		err := uc.publishToChannel(ctx, msg.ChannelID, ChatMessage{
+			Type:      "deleted",
+			ID:        msg.ID,
+			ChannelID: msg.ChannelID,
+			UserID:    msg.UserID,
+			DeletedAt: msg.DeletedAt,
+		})
+		if err != nil {
+			return len(messages), err
+		}
+	}
+	return len(messages), nil
+}
+
+// notifyUser отправляет сообщение всем соединениям пользователя
+func (uc *ChatUseCase) notifyUser(ctx context.Context, userID int64, msg ChatMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s message: %v", msg.Type, err)
+	}
+	err = uc.broker.Publish(ctx, broker.Event{
+		Type:      broker.EventDeliver,
+		ChannelID: msg.ChannelID,
+		UserIDs:   []int64{userID},
+		Payload:   payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s message: %v", msg.Type, err)
+	}
+	return nil
+}
+
+// ModerationLog возвращает журнал модерации, последние действия первыми
+func (uc *ChatUseCase) ModerationLog(ctx context.Context, actor Actor, query entity.ModerationLogQuery) ([]*entity.ModerationAction, error) {
+	if uc.moderation == nil {
+		return nil, ErrModerationDisabled
+	}
+	if actor.UserID == 0 || !IsModerator(actor) {
+		return nil, ErrModerationForbidden
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = defaultModerationLogLimit
+	}
+	if query.Limit > maxModerationLogLimit {
+		query.Limit = maxModerationLogLimit
+	}
+
+	actions, err := uc.moderation.GetLog(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if actions == nil {
+		actions = []*entity.ModerationAction{}
+	}
+	return actions, nil
+}
+
+// checkRestrictions проверяет, что пользователь может писать в канал: он не заблокирован,
+// у него нет mute и, если slowMode, не нарушен медленный режим канала.
+// Модераторы с правом на медленный режим его не соблюдают.
+func (uc *ChatUseCase) checkRestrictions(ctx context.Context, actor Actor, channelID int64, slowMode bool) error {
+	if uc.moderation == nil || actor.UserID == 0 {
+		return nil
+	}
+
+	r, err := uc.moderation.Restrictions(ctx, channelID, actor.UserID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if r.Ban != nil {
+		return &RestrictionError{Err: ErrBanned, RetryAfter: remaining(r.Ban, now)}
+	}
+	if r.Mute != nil {
+		return &RestrictionError{Err: ErrMuted, RetryAfter: remaining(r.Mute, now)}
+	}
+	if slowMode && r.SlowMode > 0 && r.LastMessageAt != nil && !actor.Access.Has(rbac.ChatSlowMode) {
+		if wait := r.LastMessageAt.Add(r.SlowMode).Sub(now); wait > 0 {
+			return &RestrictionError{Err: ErrSlowMode, RetryAfter: wait}
+		}
+	}
+	return nil
+}
+
+func remaining(s *entity.Sanction, now time.Time) time.Duration {
+	if s.ExpiresAt == nil {
+		return 0
+	}
+	return s.ExpiresAt.Sub(now)
+}
+
+// isBanned сообщает, что пользователь заблокирован в канале или во всех каналах
+func (uc *ChatUseCase) isBanned(ctx context.Context, channelID, userID int64) (bool, error) {
+	if uc.moderation == nil || userID == 0 {
+		return false, nil
+	}
+	return uc.moderation.IsBanned(ctx, channelID, userID)
+}
+
+// applyBans отключает заблокированного во всех каналах клиента и отписывает от общего
+// канала заблокированного в нем. Возвращает false, если клиент отключен.
+func (c *Client) applyBans(uc *ChatUseCase) bool {
+	if uc.moderation == nil || !c.IsAuth {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, channelLoadTimeout)
+	defer cancel()
+
+	r, err := uc.moderation.Restrictions(ctx, entity.DefaultChannelID, c.UserID)
+	if err != nil {
+		log.Printf("failed to load restrictions of user %d: %v", c.UserID, err)
+		return true
+	}
+	if r.Ban == nil {
+		return true
+	}
+	if r.Ban.ChannelID == entity.AllChannels {
+		c.shutdown(CloseBanned, "banned")
+		return false
+	}
+
+	uc.mutex.Lock()
+	uc.unsubscribeLocked(c, entity.DefaultChannelID)
+	uc.mutex.Unlock()
+	return true
+}
+
+// handleModeration обрабатывает команды модератора: mute, unmute, kick, ban, unban, slowmode и purge
+func (c *Client) handleModeration(msg ChatMessage, uc *ChatUseCase) error {
+	record, err := uc.Moderate(c.ctx, c.actor(), msg.Type, entity.ModerationRequest{
+		ChannelID: msg.ChannelID,
+		UserID:    msg.UserID,
+		Duration:  msg.Duration,
+		Reason:    msg.Reason,
+	})
+
+	reply := ChatMessage{Type: "error", Action: msg.Type, UserID: msg.UserID, ChannelID: msg.ChannelID}
+	switch {
+	case err == nil:
+		reply.Type = "moderated"
+		reply.Duration = record.Duration
+		reply.Count = record.Affected
+	case errors.Is(err, ErrModerationForbidden), errors.Is(err, ErrModerationDisabled):
+		reply.Code, reply.Error = ErrCodeModerationForbidden, "Недостаточно прав для модерации"
+	case errors.Is(err, ErrInvalidModeration), errors.Is(err, ErrDirectMembers):
+		reply.Code, reply.Error = ErrCodeInvalidModeration, "Некорректные параметры модерации"
+	case errors.Is(err, ErrSanctionNotFound):
+		reply.Code, reply.Error = ErrCodeSanctionNotFound, "У пользователя нет такого ограничения"
+	case errors.Is(err, ErrChannelNotFound):
+		reply.Code, reply.Error = ErrCodeChannelNotFound, "Канал не найден"
+	default:
+		return err
+	}
+
+	c.reply(reply)
+	return nil
+}
+
+// replyRestriction сообщает клиенту, что ему запрещено писать в канал.
+// Возвращает false, если err не является запретом.
+func (c *Client) replyRestriction(msg ChatMessage, channelID int64, err error) bool {
+	var restriction *RestrictionError
+	if !errors.As(err, &restriction) {
+		return false
+	}
+
+	reply := ChatMessage{
+		Type:       "error",
+		ID:         msg.ID,
+		TempID:     msg.TempID,
+		ChannelID:  channelID,
+		RetryAfter: ratelimit.Seconds(restriction.RetryAfter),
+	}
+	switch {
+	case errors.Is(err, ErrBanned):
+		reply.Code, reply.Error = ErrCodeBanned, "Вы заблокированы в этом канале"
+	case errors.Is(err, ErrMuted):
+		reply.Code, reply.Error = ErrCodeMuted, "Вам временно запрещено писать сообщения"
+	default:
+		reply.Code, reply.Error = ErrCodeSlowMode, "В канале включен медленный режим"
+	}
+
+	c.reply(reply)
+	return true
+}
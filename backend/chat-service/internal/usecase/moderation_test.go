@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/entity"
+	"backend/pkg/rbac"
+)
+
+// memoryModeration хранит ограничения в памяти
+type memoryModeration struct {
+	sanctions     map[string]*entity.Sanction
+	slowMode      time.Duration
+	lastMessageAt *time.Time
+	log           []*entity.ModerationAction
+	held          []*entity.HeldMessage
+}
+
+func newMemoryModeration() *memoryModeration {
+	return &memoryModeration{sanctions: make(map[string]*entity.Sanction)}
+}
+
+func sanctionKey(channelID, userID int64, kind string) string {
+	return fmt.Sprintf("%s:%d:%d", kind, channelID, userID)
+}
+
+func (m *memoryModeration) SetSanction(ctx context.Context, s *entity.Sanction) error {
+	s.CreatedAt = time.Now()
+	m.sanctions[sanctionKey(s.ChannelID, s.UserID, s.Kind)] = s
+	return nil
+}
+
+func (m *memoryModeration) RemoveSanction(ctx context.Context, channelID, userID int64, kind string) (bool, error) {
+	key := sanctionKey(channelID, userID, kind)
+	_, ok := m.sanctions[key]
+	delete(m.sanctions, key)
+	return ok, nil
+}
+
+func (m *memoryModeration) Restrictions(ctx context.Context, channelID, userID int64) (*entity.Restrictions, error) {
+	r := &entity.Restrictions{SlowMode: m.slowMode, LastMessageAt: m.lastMessageAt}
+	for _, id := range []int64{channelID, entity.AllChannels} {
+		if s, ok := m.sanctions[sanctionKey(id, userID, entity.SanctionMute)]; ok && r.Mute == nil {
+			r.Mute = s
+		}
+		if s, ok := m.sanctions[sanctionKey(id, userID, entity.SanctionBan)]; ok && r.Ban == nil {
+			r.Ban = s
+		}
+	}
+	return r, nil
+}
+
+func (m *memoryModeration) IsBanned(ctx context.Context, channelID, userID int64) (bool, error) {
+	r, _ := m.Restrictions(ctx, channelID, userID)
+	return r.Ban != nil, nil
+}
+
+func (m *memoryModeration) SetSlowMode(ctx context.Context, channelID int64, seconds int) error {
+	m.slowMode = time.Duration(seconds) * time.Second
+	return nil
+}
+
+func (m *memoryModeration) Purge(ctx context.Context, channelID, userID, deletedBy int64, since time.Time) ([]*entity.Message, error) {
+	return nil, nil
+}
+
+func (m *memoryModeration) LogAction(ctx context.Context, action *entity.ModerationAction) error {
+	action.ID = int64(len(m.log) + 1)
+	m.log = append(m.log, action)
+	return nil
+}
+
+func (m *memoryModeration) GetLog(ctx context.Context, query entity.ModerationLogQuery) ([]*entity.ModerationAction, error) {
+	return m.log, nil
+}
+
+func (m *memoryModeration) HoldMessage(ctx context.Context, held *entity.HeldMessage) error {
+	held.ID = int64(len(m.held) + 1)
+	m.held = append(m.held, held)
+	return nil
+}
+
+func (m *memoryModeration) HeldMessages(ctx context.Context, channelID int64, limit int32) ([]*entity.HeldMessage, error) {
+	return m.held, nil
+}
+
+func (m *memoryModeration) TakeHeld(ctx context.Context, id int64) (*entity.HeldMessage, error) {
+	for i, h := range m.held {
+		if h.ID == id {
+			m.held = append(m.held[:i], m.held[i+1:]...)
+			return h, nil
+		}
+	}
+	return nil, ErrHeldNotFound
+}
+
+func (m *memoryModeration) Reputation(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+var (
+	moderator = Actor{UserID: 100, Access: rbac.Access{Permissions: []string{rbac.ChatMute, rbac.ChatKick, rbac.ChatSlowMode}}}
+	member    = Actor{UserID: 1}
+)
+
+func TestModeration_RequiresPermission(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithModeration(newMemoryModeration()))
+
+	_, err := uc.Moderate(context.Background(), member, entity.ActionMute,
+		entity.ModerationRequest{UserID: 2, Duration: 60})
+	assert.ErrorIs(t, err, ErrModerationForbidden)
+
+	_, err = uc.Moderate(context.Background(), moderator, entity.ActionBan,
+		entity.ModerationRequest{UserID: 2})
+	assert.ErrorIs(t, err, ErrModerationForbidden)
+}
+
+func TestModeration_MuteBlocksMessages(t *testing.T) {
+	repo := newMemoryModeration()
+	uc := NewChatUseCase(nil, nil, WithModeration(repo))
+	ctx := context.Background()
+
+	_, err := uc.Moderate(ctx, moderator, entity.ActionMute,
+		entity.ModerationRequest{UserID: 0, Duration: 60})
+	assert.ErrorIs(t, err, ErrInvalidModeration)
+
+	record, err := uc.Moderate(ctx, moderator, entity.ActionMute,
+		entity.ModerationRequest{ChannelID: entity.AllChannels, UserID: member.UserID, Duration: 60, Reason: "spam"})
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.ModerationAction{record}, repo.log)
+
+	_, _, err = uc.SendMessage(ctx, member, "alice", entity.DefaultChannelID, "hello", "", "")
+	var restriction *RestrictionError
+	require.ErrorAs(t, err, &restriction)
+	assert.ErrorIs(t, err, ErrMuted)
+	assert.InDelta(t, 60, restriction.RetryAfter.Seconds(), 1)
+
+	_, err = uc.Moderate(ctx, moderator, entity.ActionUnmute,
+		entity.ModerationRequest{ChannelID: entity.AllChannels, UserID: member.UserID})
+	require.NoError(t, err)
+	assert.NoError(t, uc.checkRestrictions(ctx, member, entity.DefaultChannelID, true))
+
+	_, err = uc.Moderate(ctx, moderator, entity.ActionUnmute,
+		entity.ModerationRequest{ChannelID: entity.AllChannels, UserID: member.UserID})
+	assert.ErrorIs(t, err, ErrSanctionNotFound)
+}
+
+func TestModeration_SlowMode(t *testing.T) {
+	repo := newMemoryModeration()
+	uc := NewChatUseCase(nil, nil, WithModeration(repo))
+	ctx := context.Background()
+
+	_, err := uc.Moderate(ctx, moderator, entity.ActionSlowMode,
+		entity.ModerationRequest{ChannelID: entity.DefaultChannelID, Duration: 30})
+	require.NoError(t, err)
+
+	lastMessageAt := time.Now().Add(-10 * time.Second)
+	repo.lastMessageAt = &lastMessageAt
+
+	err = uc.checkRestrictions(ctx, member, entity.DefaultChannelID, true)
+	var restriction *RestrictionError
+	require.ErrorAs(t, err, &restriction)
+	assert.ErrorIs(t, err, ErrSlowMode)
+	assert.InDelta(t, 20, restriction.RetryAfter.Seconds(), 1)
+
+	// Редактирование и сообщения модераторов медленный режим не ограничивает
+	assert.NoError(t, uc.checkRestrictions(ctx, member, entity.DefaultChannelID, false))
+	assert.NoError(t, uc.checkRestrictions(ctx, moderator, entity.DefaultChannelID, true))
+}
+
+func TestModeration_KickClosesConnections(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithModeration(newMemoryModeration()))
+	client := newQueuedClient(uc, 1)
+	uc.users[client.UserID] = map[*Client]bool{client: true}
+
+	_, err := uc.Moderate(context.Background(), moderator, entity.ActionKick,
+		entity.ModerationRequest{UserID: client.UserID})
+	require.NoError(t, err)
+
+	uc.dispatch(<-uc.broker.Events())
+
+	assert.False(t, uc.clients[client])
+	assert.Error(t, client.ctx.Err())
+}
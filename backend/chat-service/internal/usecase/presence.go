@@ -0,0 +1,272 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"time"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+)
+
+const (
+	// presenceHeartbeat период публикации полного списка пользователей экземпляра
+	presenceHeartbeat = 30 * time.Second
+	// presenceExpiry время, после которого пользователи молчащего экземпляра считаются отключенными
+	presenceExpiry = 3 * presenceHeartbeat
+	// presenceQueueSize очередь изменений статусов на публикацию
+	presenceQueueSize = 256
+)
+
+// userPresence статусы пользователя на всех экземплярах сервиса
+type userPresence struct {
+	username  string
+	instances map[string]string
+}
+
+// status возвращает общий статус: online, если пользователь активен хотя бы на одном
+// экземпляре, away, если все его соединения неактивны
+func (p *userPresence) status() string {
+	if p == nil || len(p.instances) == 0 {
+		return entity.StatusOffline
+	}
+	for _, status := range p.instances {
+		if status == entity.StatusOnline {
+			return entity.StatusOnline
+		}
+	}
+	return entity.StatusAway
+}
+
+// OnlineUsers возвращает пользователей со статусом online или away
+func (uc *ChatUseCase) OnlineUsers() []entity.OnlineUser {
+	uc.mutex.RLock()
+	defer uc.mutex.RUnlock()
+
+	users := make([]entity.OnlineUser, 0, len(uc.presence))
+	for userID, p := range uc.presence {
+		if status := p.status(); status != entity.StatusOffline {
+			users = append(users, entity.OnlineUser{UserID: userID, Username: p.username, Status: status})
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Username != users[j].Username {
+			return users[i].Username < users[j].Username
+		}
+		return users[i].UserID < users[j].UserID
+	})
+	return users
+}
+
+// localStatusLocked возвращает статус пользователя по его соединениям с этим экземпляром.
+// Вызывается под uc.mutex.
+func (uc *ChatUseCase) localStatusLocked(userID int64) string {
+	clients := uc.users[userID]
+	if len(clients) == 0 {
+		return entity.StatusOffline
+	}
+	for client := range clients {
+		if client.status != entity.StatusAway {
+			return entity.StatusOnline
+		}
+	}
+	return entity.StatusAway
+}
+
+// updateLocalPresenceLocked ставит в очередь публикацию статуса пользователя, если он изменился.
+// Вызывается под uc.mutex, поэтому не блокируется на брокере.
+func (uc *ChatUseCase) updateLocalPresenceLocked(userID int64, username string) {
+	status := uc.localStatusLocked(userID)
+	if uc.localPresence[userID] == status {
+		return
+	}
+
+	if status == entity.StatusOffline {
+		delete(uc.localPresence, userID)
+	} else {
+		uc.localPresence[userID] = status
+	}
+
+	select {
+	case uc.presenceUpdates <- broker.Presence{UserID: userID, Username: username, Status: status}:
+	default:
+		// Состояние восстановится при следующей синхронизации
+		log.Printf("presence queue is full, dropping update for user %d", userID)
+	}
+}
+
+// setStatus меняет статус соединения клиента (online или away)
+func (c *Client) setStatus(msg ChatMessage, uc *ChatUseCase) error {
+	status := msg.Status
+	if status != entity.StatusOnline && status != entity.StatusAway {
+		c.reply(ChatMessage{Type: "error", Code: ErrCodeInvalidMessage, Error: "Неизвестный статус"})
+		return nil
+	}
+
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	if !uc.clients[c] {
+		return nil
+	}
+	c.status = status
+	uc.updateLocalPresenceLocked(c.UserID, c.Username)
+	return nil
+}
+
+// presenceLoop публикует изменения статусов и периодически — полный список пользователей
+// экземпляра, а также забывает пользователей экземпляров, которые перестали его присылать
+func (uc *ChatUseCase) presenceLoop() {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	uc.publishPresenceSync()
+
+	for {
+		select {
+		case update := <-uc.presenceUpdates:
+			uc.publishPresence(broker.Event{
+				Type:     broker.EventPresence,
+				Instance: uc.instance,
+				Presence: []broker.Presence{update},
+			})
+
+		case <-uc.syncRequests:
+			uc.publishPresenceSync()
+
+		case <-ticker.C:
+			uc.publishPresenceSync()
+			uc.expireInstances(time.Now().Add(-presenceExpiry))
+		}
+	}
+}
+
+func (uc *ChatUseCase) publishPresenceSync() {
+	uc.mutex.RLock()
+	snapshot := make([]broker.Presence, 0, len(uc.localPresence))
+	for userID, status := range uc.localPresence {
+		var username string
+		for client := range uc.users[userID] {
+			username = client.Username
+			break
+		}
+		snapshot = append(snapshot, broker.Presence{UserID: userID, Username: username, Status: status})
+	}
+	uc.mutex.RUnlock()
+
+	uc.publishPresence(broker.Event{
+		Type:     broker.EventPresenceSync,
+		Instance: uc.instance,
+		Presence: snapshot,
+	})
+}
+
+func (uc *ChatUseCase) publishPresence(event broker.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := uc.broker.Publish(ctx, event); err != nil {
+		log.Printf("failed to publish %s event: %v", event.Type, err)
+	}
+}
+
+// requestSync просит presenceLoop опубликовать список пользователей вне очереди
+func (uc *ChatUseCase) requestSync() {
+	select {
+	case uc.syncRequests <- struct{}{}:
+	default:
+	}
+}
+
+// applyPresenceLocked применяет событие присутствия другого или этого экземпляра.
+// Вызывается под uc.mutex.
+func (uc *ChatUseCase) applyPresenceLocked(event broker.Event) {
+	if _, known := uc.instances[event.Instance]; !known && event.Instance != uc.instance {
+		// Новый экземпляр еще не знает о наших пользователях
+		uc.requestSync()
+	}
+	uc.instances[event.Instance] = time.Now()
+
+	if event.Type == broker.EventPresenceSync {
+		listed := make(map[int64]bool, len(event.Presence))
+		for _, p := range event.Presence {
+			listed[p.UserID] = true
+		}
+		for userID, p := range uc.presence {
+			if _, ok := p.instances[event.Instance]; ok && !listed[userID] {
+				uc.setPresenceLocked(event.Instance, broker.Presence{UserID: userID, Status: entity.StatusOffline})
+			}
+		}
+	}
+
+	for _, p := range event.Presence {
+		uc.setPresenceLocked(event.Instance, p)
+	}
+}
+
+// expireInstances забывает пользователей экземпляров, от которых давно не было событий
+func (uc *ChatUseCase) expireInstances(before time.Time) {
+	uc.mutex.Lock()
+	defer uc.mutex.Unlock()
+
+	for instance, seen := range uc.instances {
+		if instance == uc.instance || seen.After(before) {
+			continue
+		}
+		log.Printf("chat instance %s stopped reporting presence", instance)
+		delete(uc.instances, instance)
+		for userID, p := range uc.presence {
+			if _, ok := p.instances[instance]; ok {
+				uc.setPresenceLocked(instance, broker.Presence{UserID: userID, Status: entity.StatusOffline})
+			}
+		}
+	}
+}
+
+// setPresenceLocked обновляет статус пользователя на экземпляре и сообщает клиентам,
+// если изменился общий статус. Вызывается под uc.mutex.
+func (uc *ChatUseCase) setPresenceLocked(instance string, update broker.Presence) {
+	p := uc.presence[update.UserID]
+	before := p.status()
+
+	if update.Status == entity.StatusOffline {
+		if p == nil {
+			return
+		}
+		delete(p.instances, instance)
+		if len(p.instances) == 0 {
+			delete(uc.presence, update.UserID)
+		}
+	} else {
+		if p == nil {
+			p = &userPresence{instances: make(map[string]string)}
+			uc.presence[update.UserID] = p
+		}
+		p.instances[instance] = update.Status
+		if update.Username != "" {
+			p.username = update.Username
+		}
+	}
+
+	after := uc.presence[update.UserID].status()
+	if after == before {
+		return
+	}
+
+	data, err := json.Marshal(ChatMessage{
+		Type:     "presence",
+		UserID:   update.UserID,
+		Username: p.username,
+		Status:   after,
+	})
+	if err != nil {
+		return
+	}
+	frame := NewFrame(data)
+	for client := range uc.clients {
+		uc.sendLocked(client, frame)
+	}
+}
@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+)
+
+func presenceEvent(eventType, instance string, presence ...broker.Presence) broker.Event {
+	return broker.Event{Type: eventType, Instance: instance, Presence: presence}
+}
+
+func TestPresence_AggregatesInstances(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresence, "a",
+		broker.Presence{UserID: 1, Username: "alice", Status: entity.StatusAway}))
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresence, "b",
+		broker.Presence{UserID: 1, Username: "alice", Status: entity.StatusOnline}))
+	assert.Equal(t, []entity.OnlineUser{{UserID: 1, Username: "alice", Status: entity.StatusOnline}}, uc.OnlineUsers())
+
+	// Активное соединение отключилось, осталось неактивное
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresence, "b",
+		broker.Presence{UserID: 1, Status: entity.StatusOffline}))
+	assert.Equal(t, entity.StatusAway, uc.OnlineUsers()[0].Status)
+
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresence, "a",
+		broker.Presence{UserID: 1, Status: entity.StatusOffline}))
+	assert.Empty(t, uc.OnlineUsers())
+}
+
+func TestPresence_SyncReplacesInstanceUsers(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresence, "a",
+		broker.Presence{UserID: 1, Username: "alice", Status: entity.StatusOnline}))
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresenceSync, "a",
+		broker.Presence{UserID: 2, Username: "bob", Status: entity.StatusOnline}))
+
+	assert.Equal(t, []entity.OnlineUser{{UserID: 2, Username: "bob", Status: entity.StatusOnline}}, uc.OnlineUsers())
+}
+
+func TestPresence_ExpiresSilentInstances(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+
+	uc.applyPresenceLocked(presenceEvent(broker.EventPresence, "a",
+		broker.Presence{UserID: 1, Username: "alice", Status: entity.StatusOnline}))
+	uc.instances["a"] = time.Now().Add(-2 * presenceExpiry)
+
+	uc.expireInstances(time.Now().Add(-presenceExpiry))
+
+	assert.Empty(t, uc.OnlineUsers())
+}
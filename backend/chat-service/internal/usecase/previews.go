@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"backend/chat-service/internal/entity"
+	"backend/pkg/unfurl"
+)
+
+const (
+	// maxPreviews число ссылок сообщения, для которых строятся превью
+	maxPreviews = 3
+	// maxConcurrentUnfurls число сообщений, ссылки которых загружаются одновременно
+	maxConcurrentUnfurls = 8
+	// unfurlTimeout время на превью всех ссылок сообщения, включая ожидание очереди
+	unfurlTimeout = 20 * time.Second
+)
+
+// WithUnfurler включает превью ссылок в сообщениях. Превью строятся в фоне после
+// рассылки сообщения и приходят отдельным сообщением типа "previews".
+func WithUnfurler(unfurler *unfurl.Unfurler) Option {
+	return func(uc *ChatUseCase) {
+		uc.unfurler = unfurler
+		uc.unfurlSlots = make(chan struct{}, maxConcurrentUnfurls)
+	}
+}
+
+// attachPreviews в фоне строит превью ссылок сообщения, сохраняет их и рассылает подписчикам канала.
+// После редактирования превью заменяются, а если ссылок не осталось — убираются.
+func (uc *ChatUseCase) attachPreviews(msg *entity.Message) {
+	if uc.unfurler == nil {
+		return
+	}
+	urls := unfurl.ExtractURLs(msg.Content, maxPreviews)
+	if len(urls) == 0 && len(msg.Previews) == 0 {
+		return
+	}
+	id, err := strconv.ParseInt(msg.ID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), unfurlTimeout)
+		defer cancel()
+
+		select {
+		case uc.unfurlSlots <- struct{}{}:
+			defer func() { <-uc.unfurlSlots }()
+		case <-ctx.Done():
+			log.Printf("link previews of message %d skipped: too many messages in the queue", id)
+			return
+		}
+
+		previews := uc.unfurlAll(ctx, urls)
+		if len(previews) == 0 && len(msg.Previews) == 0 {
+			return
+		}
+		err := uc.repo.SetPreviews(ctx, id, previews)
+		if errors.Is(err, ErrMessageNotFound) {
+			// Сообщение удалили, пока загружались превью
+			return
+		}
+		if err != nil {
+			log.Printf("failed to save link previews of message %d: %v", id, err)
+			return
+		}
+
+		err = uc.publishToChannel(ctx, msg.ChannelID, ChatMessage{
+			Type:      "previews",
+			ID:        msg.ID,
+			ChannelID: msg.ChannelID,
+			Previews:  previews,
+		})
+		if err != nil {
+			log.Printf("failed to publish link previews of message %d: %v", id, err)
+		}
+	}()
+}
+
+// unfurlAll возвращает превью ссылок, для которых они есть, в порядке ссылок
+func (uc *ChatUseCase) unfurlAll(ctx context.Context, urls []string) []unfurl.Preview {
+	var previews []unfurl.Preview
+	for _, url := range urls {
+		preview, err := uc.unfurler.Unfurl(ctx, url)
+		if err != nil {
+			if !errors.Is(err, unfurl.ErrNoPreview) {
+				log.Printf("failed to unfurl %s: %v", url, err)
+			}
+			continue
+		}
+		previews = append(previews, *preview)
+	}
+	return previews
+}
@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"unicode"
+
+	"backend/chat-service/internal/entity"
+)
+
+var (
+	ErrInvalidReaction  = errors.New("reaction must be a single emoji")
+	ErrTooManyReactions = errors.New("message has too many different reactions")
+	ErrInvalidReply     = errors.New("reply must refer to a message in the same channel")
+)
+
+// Коды ошибок реакций и ответов
+const (
+	ErrCodeInvalidReaction  = "invalid_reaction"
+	ErrCodeTooManyReactions = "too_many_reactions"
+	ErrCodeInvalidReply     = "invalid_reply"
+)
+
+// Действия в сообщениях типа "reactions"
+const (
+	ReactionAdded   = "add"
+	ReactionRemoved = "remove"
+)
+
+const (
+	// maxEmojiLength ограничивает эмодзи с модификаторами и соединителями, в байтах
+	maxEmojiLength = 32
+	// maxReactionKinds число разных эмодзи на одном сообщении
+	maxReactionKinds = 20
+)
+
+// React ставит реакцию пользователя на сообщение или снимает уже поставленную
+// и рассылает участникам канала новую сводку реакций
+func (uc *ChatUseCase) React(ctx context.Context, actor Actor, messageID int64, emoji string) (*entity.Message, error) {
+	if actor.UserID == 0 {
+		return nil, ErrMessageForbidden
+	}
+	if !isEmoji(emoji) {
+		return nil, ErrInvalidReaction
+	}
+
+	msg, err := uc.readableMessage(ctx, actor.UserID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if msg.IsDeleted() {
+		return nil, ErrMessageDeleted
+	}
+	if err := uc.checkRestrictions(ctx, actor, msg.ChannelID, false); err != nil {
+		return nil, err
+	}
+	if !hasReaction(msg.Reactions, emoji) && len(msg.Reactions) >= maxReactionKinds {
+		return nil, ErrTooManyReactions
+	}
+
+	added, err := uc.repo.ToggleReaction(ctx, messageID, actor.UserID, emoji)
+	if err != nil {
+		return nil, err
+	}
+	reactions, err := uc.repo.GetReactions(ctx, []int64{messageID})
+	if err != nil {
+		return nil, err
+	}
+	msg.Reactions = reactions[messageID]
+
+	action := ReactionRemoved
+	if added {
+		action = ReactionAdded
+	}
+	err = uc.publishToChannel(ctx, msg.ChannelID, ChatMessage{
+		Type:      "reactions",
+		ID:        msg.ID,
+		ChannelID: msg.ChannelID,
+		UserID:    actor.UserID,
+		Emoji:     emoji,
+		Action:    action,
+		Reactions: msg.Reactions,
+	})
+	return msg, err
+}
+
+// Thread возвращает сообщение и ответы на него после курсора after, если пользователь
+// может читать канал. userID 0 — анонимный пользователь.
+func (uc *ChatUseCase) Thread(ctx context.Context, userID, messageID int64, limit int32, afterID int64) (*entity.Thread, error) {
+	if limit <= 0 {
+		limit = entity.DefaultHistoryLimit
+	}
+	if limit > entity.MaxHistoryLimit {
+		limit = entity.MaxHistoryLimit
+	}
+
+	parent, err := uc.readableMessage(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := uc.repo.GetReplies(ctx, messageID, limit, afterID)
+	if err != nil {
+		return nil, err
+	}
+	if replies == nil {
+		replies = []*entity.Message{}
+	}
+
+	thread := &entity.Thread{
+		Parent:  parent,
+		Replies: replies,
+		After:   entity.EncodeCursor(afterID),
+		HasMore: len(replies) == int(limit),
+	}
+	if len(replies) > 0 {
+		last, _ := strconv.ParseInt(replies[len(replies)-1].ID, 10, 64)
+		thread.After = entity.EncodeCursor(last)
+	}
+	return thread, nil
+}
+
+// readableMessage возвращает сообщение из канала, который пользователь может читать
+func (uc *ChatUseCase) readableMessage(ctx context.Context, userID, messageID int64) (*entity.Message, error) {
+	msg, err := uc.repo.GetByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := uc.getChannel(ctx, msg.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := uc.canRead(ctx, channel, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrChannelForbidden
+	}
+	return msg, nil
+}
+
+// validateReply проверяет, что сообщение, на которое отвечают, есть в том же канале и не удалено
+func (uc *ChatUseCase) validateReply(ctx context.Context, channelID int64, replyTo string) error {
+	if replyTo == "" {
+		return nil
+	}
+	parentID, err := strconv.ParseInt(replyTo, 10, 64)
+	if err != nil || parentID <= 0 {
+		return ErrInvalidReply
+	}
+
+	parent, err := uc.repo.GetByID(ctx, parentID)
+	if errors.Is(err, ErrMessageNotFound) {
+		return ErrInvalidReply
+	}
+	if err != nil {
+		return err
+	}
+	if parent.ChannelID != channelID || parent.IsDeleted() {
+		return ErrInvalidReply
+	}
+	return nil
+}
+
+// handleReact обрабатывает сообщение типа "react"
+func (c *Client) handleReact(msg ChatMessage, uc *ChatUseCase) error {
+	id, ok := c.messageID(msg)
+	if !ok {
+		return nil
+	}
+
+	_, err := uc.React(c.ctx, c.actor(), id, msg.Emoji)
+	return c.replyMessageError(msg, err)
+}
+
+// isEmoji сообщает, что строка — один эмодзи, возможно составной: с модификаторами
+// цвета кожи, вариантами начертания, соединителями и флагами
+func isEmoji(s string) bool {
+	if s == "" || len(s) > maxEmojiLength {
+		return false
+	}
+
+	symbols := 0
+	for _, r := range s {
+		switch {
+		case unicode.Is(unicode.So, r):
+			symbols++
+		case unicode.Is(unicode.Sk, r), unicode.Is(unicode.Me, r),
+			r == 0x200D, // соединитель нулевой ширины
+			r >= 0xFE00 && r <= 0xFE0F,
+			r >= 0xE0020 && r <= 0xE007F: // теги флагов регионов
+		default:
+			return false
+		}
+	}
+	return symbols > 0
+}
+
+func hasReaction(reactions []entity.Reaction, emoji string) bool {
+	for _, r := range reactions {
+		if r.Emoji == emoji {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+)
+
+func (r *fakeMessages) ToggleReaction(ctx context.Context, messageID, userID int64, emoji string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.find(messageID)
+	if m == nil {
+		return false, repository.ErrMessageNotFound
+	}
+	for i, reaction := range m.Reactions {
+		if reaction.Emoji != emoji {
+			continue
+		}
+		for j, id := range reaction.UserIDs {
+			if id == userID {
+				reaction.UserIDs = append(reaction.UserIDs[:j:j], reaction.UserIDs[j+1:]...)
+				reaction.Count--
+				if reaction.Count == 0 {
+					m.Reactions = append(m.Reactions[:i:i], m.Reactions[i+1:]...)
+				} else {
+					m.Reactions[i] = reaction
+				}
+				return false, nil
+			}
+		}
+		reaction.UserIDs = append(reaction.UserIDs[:len(reaction.UserIDs):len(reaction.UserIDs)], userID)
+		reaction.Count++
+		m.Reactions[i] = reaction
+		return true, nil
+	}
+	m.Reactions = append(m.Reactions, entity.Reaction{Emoji: emoji, Count: 1, UserIDs: []int64{userID}})
+	return true, nil
+}
+
+func (r *fakeMessages) GetReactions(ctx context.Context, messageIDs []int64) (map[int64][]entity.Reaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reactions := make(map[int64][]entity.Reaction)
+	for _, id := range messageIDs {
+		if m := r.find(id); m != nil && len(m.Reactions) > 0 {
+			reactions[id] = append([]entity.Reaction(nil), m.Reactions...)
+		}
+	}
+	return reactions, nil
+}
+
+func TestIsEmoji(t *testing.T) {
+	for _, emoji := range []string{"👍", "❤️", "👍🏽", "👨‍👩‍👧", "🏳️‍🌈", "🏴󠁧󠁢󠁳󠁣󠁴󠁿"} {
+		assert.True(t, isEmoji(emoji), emoji)
+	}
+	for _, text := range []string{"", "ok", "👍 ", "+1", "👍a", "‍"} {
+		assert.False(t, isEmoji(text), text)
+	}
+}
+
+func TestReact_TogglesPerUser(t *testing.T) {
+	uc := NewChatUseCase(newMessageRepo(), nil)
+	ctx := context.Background()
+
+	msg, err := uc.React(ctx, Actor{UserID: 1}, 2, "👍")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Reaction{{Emoji: "👍", Count: 1, UserIDs: []int64{1}}}, msg.Reactions)
+
+	_, frame := published(t, uc)
+	assert.Equal(t, "reactions", frame.Type)
+	assert.Equal(t, "2", frame.ID)
+	assert.Equal(t, ReactionAdded, frame.Action)
+	assert.Equal(t, int64(1), frame.UserID)
+	assert.Equal(t, msg.Reactions, frame.Reactions)
+
+	// Реакции разных пользователей одним эмодзи складываются, разные эмодзи идут в порядке появления
+	_, err = uc.React(ctx, Actor{UserID: 2}, 2, "👍")
+	require.NoError(t, err)
+	msg, err = uc.React(ctx, Actor{UserID: 2}, 2, "🎉")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Reaction{
+		{Emoji: "👍", Count: 2, UserIDs: []int64{1, 2}},
+		{Emoji: "🎉", Count: 1, UserIDs: []int64{2}},
+	}, msg.Reactions)
+	published(t, uc)
+	published(t, uc)
+
+	// Повторная реакция снимает только реакцию этого пользователя
+	msg, err = uc.React(ctx, Actor{UserID: 1}, 2, "👍")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Reaction{
+		{Emoji: "👍", Count: 1, UserIDs: []int64{2}},
+		{Emoji: "🎉", Count: 1, UserIDs: []int64{2}},
+	}, msg.Reactions)
+	_, frame = published(t, uc)
+	assert.Equal(t, ReactionRemoved, frame.Action)
+
+	// Снятая последняя реакция пропадает из сводки
+	_, err = uc.React(ctx, Actor{UserID: 2}, 2, "🎉")
+	require.NoError(t, err)
+	msg, err = uc.React(ctx, Actor{UserID: 2}, 2, "👍")
+	require.NoError(t, err)
+	assert.Empty(t, msg.Reactions)
+	published(t, uc)
+	_, frame = published(t, uc)
+	assert.Empty(t, frame.Reactions)
+}
+
+func TestReact_Rejected(t *testing.T) {
+	repo := newMessageRepo()
+	uc := NewChatUseCase(repo, nil)
+	ctx := context.Background()
+
+	_, err := uc.React(ctx, Actor{}, 1, "👍")
+	assert.ErrorIs(t, err, ErrMessageForbidden)
+	_, err = uc.React(ctx, Actor{UserID: 1}, 1, "+1")
+	assert.ErrorIs(t, err, ErrInvalidReaction)
+	_, err = uc.React(ctx, Actor{UserID: 1}, 3, "👍")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+
+	_, err = uc.DeleteMessage(ctx, Actor{UserID: 1}, 1)
+	require.NoError(t, err)
+	published(t, uc)
+	_, err = uc.React(ctx, Actor{UserID: 2}, 1, "👍")
+	assert.ErrorIs(t, err, ErrMessageDeleted)
+	assert.Empty(t, repo.messages[0].Reactions)
+}
+
+func TestReact_DistinctLimit(t *testing.T) {
+	repo := newMessageRepo()
+	for i := 0; i < maxReactionKinds; i++ {
+		repo.messages[0].Reactions = append(repo.messages[0].Reactions, entity.Reaction{
+			Emoji: string(rune(0x1F600 + i)), Count: 1, UserIDs: []int64{2},
+		})
+	}
+	uc := NewChatUseCase(repo, nil)
+	ctx := context.Background()
+
+	// Новый эмодзи сверх лимита не добавляется
+	_, err := uc.React(ctx, Actor{UserID: 3}, 1, "👍")
+	assert.ErrorIs(t, err, ErrTooManyReactions)
+	assert.Len(t, repo.messages[0].Reactions, maxReactionKinds)
+
+	// К уже поставленным можно присоединиться и снять их
+	msg, err := uc.React(ctx, Actor{UserID: 3}, 1, "😀")
+	require.NoError(t, err)
+	assert.Equal(t, 2, msg.Reactions[0].Count)
+
+	_, err = uc.React(ctx, Actor{UserID: 2}, 1, "😁")
+	require.NoError(t, err)
+	assert.Len(t, repo.messages[0].Reactions, maxReactionKinds-1)
+
+	// После снятия освобождается место для нового эмодзи
+	_, err = uc.React(ctx, Actor{UserID: 3}, 1, "👍")
+	require.NoError(t, err)
+	assert.Len(t, repo.messages[0].Reactions, maxReactionKinds)
+}
+
+func TestReact_PrivateChannel(t *testing.T) {
+	repo := newMessageRepo()
+	repo.messages = append(repo.messages, &entity.Message{ID: "3", ChannelID: 3, Seq: 1, Content: "secret", UserID: 1})
+	uc := NewChatUseCase(repo, nil, WithChannelRepository(newFakeChannels(
+		&entity.Channel{ID: 3, Name: "private", IsPrivate: true, CreatedBy: 1, Members: []int64{1}},
+	)))
+	defer uc.Close()
+	ctx := context.Background()
+
+	// Реагировать можно только на сообщения каналов, которые пользователь читает
+	_, err := uc.React(ctx, Actor{UserID: 2}, 3, "👍")
+	assert.ErrorIs(t, err, ErrChannelForbidden)
+	assert.Empty(t, repo.messages[2].Reactions)
+
+	_, err = uc.React(ctx, Actor{UserID: 1}, 3, "👍")
+	require.NoError(t, err)
+}
+
+func TestValidateReply(t *testing.T) {
+	repo := newMessageRepo()
+	repo.messages = append(repo.messages,
+		&entity.Message{ID: "3", ChannelID: 2, Seq: 1, Content: "other channel", UserID: 1},
+		&entity.Message{ID: "4", ChannelID: entity.DefaultChannelID, Seq: 3, Content: "deleted", UserID: 1},
+	)
+	uc := NewChatUseCase(repo, nil)
+	ctx := context.Background()
+	_, err := uc.DeleteMessage(ctx, Actor{UserID: 1}, 4)
+	require.NoError(t, err)
+
+	assert.NoError(t, uc.validateReply(ctx, entity.DefaultChannelID, ""))
+	assert.NoError(t, uc.validateReply(ctx, entity.DefaultChannelID, "1"))
+	assert.NoError(t, uc.validateReply(ctx, 2, "3"))
+
+	// Ответ возможен только на неудаленное сообщение того же канала
+	for _, replyTo := range []string{"3", "4", "5", "0", "-1", "abc"} {
+		assert.ErrorIs(t, uc.validateReply(ctx, entity.DefaultChannelID, replyTo), ErrInvalidReply, replyTo)
+	}
+	assert.ErrorIs(t, uc.validateReply(ctx, 2, "1"), ErrInvalidReply)
+	assert.ErrorIs(t, uc.validateReply(ctx, 2, "2"), ErrInvalidReply)
+}
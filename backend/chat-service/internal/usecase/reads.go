@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+)
+
+const (
+	// readFlushInterval период сохранения указателей прочитанного
+	readFlushInterval = 2 * time.Second
+	// readFlushSize сохранение начинается раньше, если накопилось столько указателей
+	readFlushSize = 500
+)
+
+type readKey struct {
+	channelID int64
+	userID    int64
+}
+
+// readBatcher накапливает указатели прочитанного и сохраняет их пачками
+type readBatcher struct {
+	repo    repository.ChannelRepository
+	mu      sync.Mutex
+	pending map[readKey]int64
+	flush   chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+func newReadBatcher(repo repository.ChannelRepository) *readBatcher {
+	return &readBatcher{
+		repo:    repo,
+		pending: make(map[readKey]int64),
+		flush:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// Add запоминает указатель. Возвращает false, если он не сдвигает уже ожидающий сохранения.
+func (b *readBatcher) Add(channelID, userID, messageID int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := readKey{channelID: channelID, userID: userID}
+	if b.pending[key] >= messageID {
+		return false
+	}
+	b.pending[key] = messageID
+
+	if len(b.pending) >= readFlushSize {
+		select {
+		case b.flush <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// run сохраняет указатели по таймеру, при переполнении и перед остановкой
+func (b *readBatcher) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(readFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-b.flush:
+		case <-b.done:
+			b.Flush(context.Background(), 0)
+			return
+		}
+		b.Flush(context.Background(), 0)
+	}
+}
+
+// Flush сохраняет ожидающие указатели. Если userID не 0, только указатели этого пользователя.
+func (b *readBatcher) Flush(ctx context.Context, userID int64) {
+	b.mu.Lock()
+	pointers := make([]entity.ReadPointer, 0, len(b.pending))
+	for key, messageID := range b.pending {
+		if userID != 0 && key.userID != userID {
+			continue
+		}
+		pointers = append(pointers, entity.ReadPointer{ChannelID: key.channelID, UserID: key.userID, MessageID: messageID})
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+
+	if len(pointers) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if err := b.repo.MarkReadBatch(ctx, pointers); err != nil {
+		log.Printf("failed to save %d read pointers: %v", len(pointers), err)
+		b.restore(pointers)
+	}
+}
+
+// restore возвращает несохраненные указатели в очередь, не затирая более новые
+func (b *readBatcher) restore(pointers []entity.ReadPointer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, p := range pointers {
+		key := readKey{channelID: p.ChannelID, userID: p.UserID}
+		if b.pending[key] < p.MessageID {
+			b.pending[key] = p.MessageID
+		}
+	}
+}
+
+// Close сохраняет оставшиеся указатели и останавливает фоновую запись
+func (b *readBatcher) Close() {
+	b.once.Do(func() {
+		close(b.done)
+		<-b.stopped
+	})
+}
+
+// GetUnread возвращает число непрочитанных сообщений во всех каналах пользователя
+func (uc *ChatUseCase) GetUnread(ctx context.Context, userID int64) ([]entity.UnreadCount, error) {
+	if uc.channelRepo == nil {
+		return nil, ErrChannelsDisabled
+	}
+
+	// Учитываем прочитанное, которое еще не сохранено
+	uc.reads.Flush(ctx, userID)
+	return uc.channelRepo.GetUnread(ctx, userID)
+}
+
+// GetReceipts возвращает, до какого сообщения прочитали переписку ее участники.
+// Доступно только участникам личной переписки.
+func (uc *ChatUseCase) GetReceipts(ctx context.Context, userID, channelID int64) ([]entity.ReadPointer, error) {
+	channel, err := uc.getChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if !channel.IsDirect() {
+		return nil, ErrChannelNotFound
+	}
+
+	ok, err := uc.canRead(ctx, channel, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrChannelForbidden
+	}
+
+	uc.reads.Flush(ctx, 0)
+	return uc.channelRepo.GetReadPointers(ctx, channelID)
+}
+
+// Close сохраняет накопленные указатели прочитанного. Вызывается при остановке сервиса.
+func (uc *ChatUseCase) Close() {
+	if uc.reads != nil {
+		uc.reads.Close()
+	}
+}
+
+// handleRead обрабатывает сообщение типа "read": сдвигает указатель прочитанного,
+// а в личной переписке сообщает участникам, что сообщение просмотрено
+func (c *Client) handleRead(msg ChatMessage, uc *ChatUseCase) error {
+	id, ok := c.messageID(msg)
+	if !ok {
+		return nil
+	}
+
+	channelID := msg.ChannelID
+	if channelID == 0 {
+		channelID = uc.activeChannel(c)
+	}
+	if !uc.isSubscribed(c, channelID) {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeNotSubscribed,
+			Error:     "Вы не подключены к этому каналу",
+			ChannelID: channelID,
+		})
+		return nil
+	}
+
+	if uc.reads == nil || !uc.reads.Add(channelID, c.UserID, id) {
+		return nil
+	}
+
+	if uc.directMembers(channelID) == nil {
+		return nil
+	}
+
+	return uc.publishToChannel(c.ctx, channelID, ChatMessage{
+		Type:      "read",
+		ID:        msg.ID,
+		ChannelID: channelID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+	})
+}
+
+// markOwnMessageRead сдвигает указатель прочитанного отправителя, чтобы его сообщения не считались непрочитанными
+func (uc *ChatUseCase) markOwnMessageRead(c *Client, msg *entity.Message) {
+	if uc.reads == nil {
+		return
+	}
+
+	id, err := strconv.ParseInt(msg.ID, 10, 64)
+	if err != nil {
+		return
+	}
+
+	uc.reads.Add(msg.ChannelID, c.UserID, id)
+}
@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/entity"
+)
+
+func (f *fakeChannels) MarkReadBatch(ctx context.Context, pointers []entity.ReadPointer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.batchErr != nil {
+		return f.batchErr
+	}
+	f.batches = append(f.batches, pointers)
+	for _, p := range pointers {
+		key := readKey{channelID: p.ChannelID, userID: p.UserID}
+		if f.read[key] < p.MessageID {
+			f.read[key] = p.MessageID
+		}
+	}
+	return nil
+}
+
+func (f *fakeChannels) GetUnread(ctx context.Context, userID int64) ([]entity.UnreadCount, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var counts []entity.UnreadCount
+	for channelID, members := range f.members {
+		if !members[userID] {
+			continue
+		}
+		lastRead := f.read[readKey{channelID: channelID, userID: userID}]
+		counts = append(counts, entity.UnreadCount{
+			ChannelID:  channelID,
+			Unread:     int(f.latest[channelID] - lastRead),
+			LastReadID: lastRead,
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].ChannelID < counts[j].ChannelID })
+	return counts, nil
+}
+
+func (f *fakeChannels) GetReadPointers(ctx context.Context, channelID int64) ([]entity.ReadPointer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var pointers []entity.ReadPointer
+	for userID := range f.members[channelID] {
+		pointers = append(pointers, entity.ReadPointer{
+			ChannelID: channelID,
+			UserID:    userID,
+			MessageID: f.read[readKey{channelID: channelID, userID: userID}],
+		})
+	}
+	sort.Slice(pointers, func(i, j int) bool { return pointers[i].UserID < pointers[j].UserID })
+	return pointers, nil
+}
+
+func (f *fakeChannels) savedBatches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.batches)
+}
+
+// pendingPointer возвращает ожидающий сохранения указатель
+func (b *readBatcher) pendingPointer(channelID, userID int64) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pending[readKey{channelID: channelID, userID: userID}]
+}
+
+func TestReadBatcher_Add(t *testing.T) {
+	b := newReadBatcher(newFakeChannels())
+
+	assert.True(t, b.Add(1, 1, 10))
+	// Указатель, не сдвигающий ожидающий сохранения, не запоминается
+	assert.False(t, b.Add(1, 1, 10))
+	assert.False(t, b.Add(1, 1, 5))
+	assert.True(t, b.Add(1, 1, 11))
+	assert.True(t, b.Add(1, 2, 3))
+	assert.True(t, b.Add(2, 1, 3))
+
+	assert.Equal(t, map[readKey]int64{
+		{channelID: 1, userID: 1}: 11,
+		{channelID: 1, userID: 2}: 3,
+		{channelID: 2, userID: 1}: 3,
+	}, b.pending)
+}
+
+func TestReadBatcher_FlushUser(t *testing.T) {
+	channels := newFakeChannels()
+	b := newReadBatcher(channels)
+	ctx := context.Background()
+
+	b.Add(1, 1, 10)
+	b.Add(2, 1, 20)
+	b.Add(1, 2, 30)
+
+	// Сохраняются только указатели пользователя, остальные ждут своей пачки
+	b.Flush(ctx, 1)
+	require.Len(t, channels.batches, 1)
+	assert.ElementsMatch(t, []entity.ReadPointer{
+		{ChannelID: 1, UserID: 1, MessageID: 10},
+		{ChannelID: 2, UserID: 1, MessageID: 20},
+	}, channels.batches[0])
+	assert.Equal(t, map[readKey]int64{{channelID: 1, userID: 2}: 30}, b.pending)
+
+	b.Flush(ctx, 0)
+	require.Len(t, channels.batches, 2)
+	assert.Equal(t, []entity.ReadPointer{{ChannelID: 1, UserID: 2, MessageID: 30}}, channels.batches[1])
+	assert.Empty(t, b.pending)
+
+	// Пустая очередь не сохраняется
+	b.Flush(ctx, 0)
+	assert.Len(t, channels.batches, 2)
+}
+
+func TestReadBatcher_FailedFlushRestores(t *testing.T) {
+	channels := newFakeChannels()
+	channels.batchErr = errors.New("connection refused")
+	b := newReadBatcher(channels)
+	ctx := context.Background()
+
+	b.Add(1, 1, 10)
+	b.Flush(ctx, 0)
+	assert.Equal(t, int64(10), b.pending[readKey{channelID: 1, userID: 1}])
+
+	// Указатель, сдвинутый во время неудачной записи, не откатывается
+	b.Add(1, 1, 20)
+	b.restore([]entity.ReadPointer{{ChannelID: 1, UserID: 1, MessageID: 10}})
+	assert.Equal(t, int64(20), b.pending[readKey{channelID: 1, userID: 1}])
+
+	channels.batchErr = nil
+	b.Flush(ctx, 0)
+	assert.Equal(t, int64(20), channels.read[readKey{channelID: 1, userID: 1}])
+	assert.Empty(t, b.pending)
+}
+
+func TestReadBatcher_Run(t *testing.T) {
+	channels := newFakeChannels()
+	b := newReadBatcher(channels)
+	go b.run()
+
+	// Переполнение очереди сохраняет указатели, не дожидаясь таймера
+	for userID := int64(1); userID <= readFlushSize; userID++ {
+		b.Add(1, userID, 1)
+	}
+	require.Eventually(t, func() bool { return channels.savedBatches() == 1 }, readFlushInterval/2, 10*time.Millisecond)
+
+	// Остановка сохраняет оставшиеся указатели
+	b.Add(1, 1, 2)
+	b.Close()
+	assert.Equal(t, 2, channels.savedBatches())
+	assert.Equal(t, int64(2), channels.read[readKey{channelID: 1, userID: 1}])
+	b.Close()
+}
+
+func TestGetUnread_IncludesPending(t *testing.T) {
+	uc, channels := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+	channels.latest[2] = 10
+	channels.latest[4] = 8
+
+	uc.reads.Add(4, 2, 5)
+	uc.reads.Add(2, 1, 7)
+
+	// Непрочитанные учитывают указатели, которые еще ждут сохранения
+	unread, err := uc.GetUnread(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.UnreadCount{{ChannelID: 4, Unread: 3, LastReadID: 5}}, unread)
+
+	// Сохраняются только указатели запросившего пользователя
+	assert.Equal(t, int64(7), uc.reads.pendingPointer(2, 1))
+
+	_, err = NewChatUseCase(nil, nil).GetUnread(ctx, 2)
+	assert.ErrorIs(t, err, ErrChannelsDisabled)
+}
+
+func TestGetReceipts(t *testing.T) {
+	uc, _ := newChannelsUseCase()
+	defer uc.Close()
+	ctx := context.Background()
+
+	uc.reads.Add(4, 1, 6)
+	receipts, err := uc.GetReceipts(ctx, 2, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []entity.ReadPointer{
+		{ChannelID: 4, UserID: 1, MessageID: 6},
+		{ChannelID: 4, UserID: 2},
+	}, receipts)
+
+	// Отметки о прочтении есть только у личных переписок и видны только их участникам
+	_, err = uc.GetReceipts(ctx, 3, 4)
+	assert.ErrorIs(t, err, ErrChannelForbidden)
+	_, err = uc.GetReceipts(ctx, 1, 2)
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+}
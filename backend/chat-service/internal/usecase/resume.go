@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"backend/chat-service/internal/entity"
+)
+
+const (
+	// resumeLimit наибольшее число сообщений, отправляемых в ответ на один resume.
+	// Если пропущено больше, клиент повторяет resume с номером последнего полученного.
+	resumeLimit = 500
+	// maxTempIDLength соответствует размеру колонки temp_id
+	maxTempIDLength = 64
+)
+
+// messageFrame возвращает сообщение типа "message" для отправки клиенту
+func messageFrame(msg *entity.Message) ChatMessage {
+	return ChatMessage{
+		Type:      "message",
+		ID:        msg.ID,
+		Seq:       msg.Seq,
+		ChannelID: msg.ChannelID,
+		Content:   msg.Content,
+		UserID:    msg.UserID,
+		Username:  msg.Username,
+		CreatedAt: msg.CreatedAt,
+		EditedAt:  msg.EditedAt,
+		DeletedAt: msg.DeletedAt,
+		ReplyTo:   msg.ReplyTo,
+		Reactions: msg.Reactions,
+		Previews:  msg.Previews,
+	}
+}
+
+// handleResume обрабатывает сообщение типа "resume": отправляет сообщения канала с номером
+// больше msg.Seq, которые клиент пропустил, пока был отключен, и завершает их сообщением
+// "resumed". Сообщения, разосланные во время восстановления, могут прийти повторно
+// или раньше восстановленных, поэтому клиент отбрасывает номера, которые уже видел.
+func (c *Client) handleResume(msg ChatMessage, uc *ChatUseCase) error {
+	_, err := c.resume(msg, uc)
+	return err
+}
+
+// resume отправляет до resumeLimit пропущенных сообщений и возвращает завершающее их
+// сообщение "resumed". Его More сообщает, что пропущенных сообщений осталось еще.
+func (c *Client) resume(msg ChatMessage, uc *ChatUseCase) (ChatMessage, error) {
+	channelID := msg.ChannelID
+	if channelID == 0 {
+		channelID = uc.activeChannel(c)
+	}
+	if !uc.isSubscribed(c, channelID) {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeNotSubscribed,
+			Error:     "Вы не подключены к этому каналу",
+			ChannelID: channelID,
+		})
+		return ChatMessage{}, nil
+	}
+
+	missed, err := uc.repo.GetSince(c.ctx, channelID, msg.Seq, resumeLimit)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	last := msg.Seq
+	for _, m := range missed {
+		frame := messageFrame(m)
+		if m.UserID == c.UserID && c.IsAuth {
+			// Отправитель сопоставит сохраненное сообщение с неподтвержденным
+			frame.TempID = m.TempID
+		}
+		c.reply(frame)
+		last = m.Seq
+	}
+
+	resumed := ChatMessage{
+		Type:      "resumed",
+		ChannelID: channelID,
+		Seq:       last,
+		More:      len(missed) == resumeLimit,
+	}
+	c.reply(resumed)
+	return resumed, nil
+}
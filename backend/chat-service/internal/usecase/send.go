@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+)
+
+// ErrInvalidTempID идентификатор отправителя длиннее допустимого
+var ErrInvalidTempID = fmt.Errorf("temp id is longer than %d characters", maxTempIDLength)
+
+// SendMessage сохраняет сообщение пользователя в канале, доступном ему для чтения,
+// и рассылает его подписчикам. duplicate сообщает, что сообщение с таким TempID
+// уже было сохранено и повторно не рассылалось. Сообщение, задержанное фильтром
+// до проверки модератором, возвращается с Held. replyTo — ID сообщения того же канала,
+// на которое отвечает пользователь, или пустая строка.
+func (uc *ChatUseCase) SendMessage(ctx context.Context, actor Actor, username string, channelID int64, content, tempID, replyTo string) (msg *entity.Message, duplicate bool, err error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, false, ErrEmptyContent
+	}
+	if len(tempID) > maxTempIDLength {
+		return nil, false, ErrInvalidTempID
+	}
+	if channelID == 0 {
+		channelID = entity.DefaultChannelID
+	}
+
+	channel, err := uc.getChannel(ctx, channelID)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := uc.canRead(ctx, channel, actor.UserID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, ErrChannelForbidden
+	}
+	if err := uc.checkRestrictions(ctx, actor, channelID, true); err != nil {
+		return nil, false, err
+	}
+	if channel.IsDirect() {
+		// Сообщение переписки доставляется ее участникам, даже если хаб ее еще не видел
+		if err := uc.loadDirectMembers(ctx, channelID); err != nil {
+			return nil, false, err
+		}
+	}
+	if err := uc.validateReply(ctx, channelID, replyTo); err != nil {
+		return nil, false, err
+	}
+
+	msg = &entity.Message{
+		ChannelID: channelID,
+		TempID:    tempID,
+		Content:   content,
+		UserID:    actor.UserID,
+		Username:  username,
+		ReplyTo:   replyTo,
+	}
+	held, err := uc.screenMessage(ctx, msg)
+	if err != nil || held {
+		return msg, false, err
+	}
+	duplicate, err = uc.postMessage(ctx, msg)
+	if err != nil {
+		return nil, false, err
+	}
+	return msg, duplicate, nil
+}
+
+// postMessage сохраняет сообщение и рассылает его подписчикам канала
+func (uc *ChatUseCase) postMessage(ctx context.Context, msg *entity.Message) (duplicate bool, err error) {
+	now := time.Now()
+	msg.CreatedAt = now
+	msg.UpdatedAt = now
+
+	err = uc.repo.Create(ctx, msg)
+	if errors.Is(err, repository.ErrDuplicateMessage) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save message: %v", err)
+	}
+
+	response := messageFrame(msg)
+	response.TempID = msg.TempID
+	if err := uc.publishToChannel(ctx, msg.ChannelID, response); err != nil {
+		return false, err
+	}
+	uc.attachPreviews(msg)
+	return false, nil
+}
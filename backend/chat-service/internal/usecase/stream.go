@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"backend/pkg/ratelimit"
+)
+
+// StreamWriter передает кадры клиенту, подключенному без WebSocket, например через SSE
+type StreamWriter interface {
+	// SetWriteDeadline ограничивает время следующей записи
+	SetWriteDeadline(t time.Time) error
+	// WriteFrame отправляет кадр клиенту
+	WriteFrame(frame *Frame) error
+	// Ping поддерживает соединение через прокси и проверяет, что клиент еще читает
+	Ping() error
+	// Close сообщает клиенту, почему сервер его отключает
+	Close(code int, reason string) error
+}
+
+// Stream регистрирует клиента без WebSocket соединения и передает ему кадры, пока клиент
+// не отключится (ctx отменен) или сервер его не отключит. Очередь, политика переполнения,
+// подписки, присутствие и модерация у такого клиента те же, что у WebSocket клиента.
+// resume — последние полученные клиентом номера сообщений по каналам: пропущенные
+// сообщения досылаются так же, как в ответ на "resume".
+func (uc *ChatUseCase) Stream(ctx context.Context, c *Client, w StreamWriter, resume map[int64]int64) {
+	if cap(c.Send) != uc.hub.QueueSize {
+		c.Send = make(chan *Frame, uc.hub.QueueSize)
+	}
+
+	uc.register(c)
+	defer func() {
+		c.stopAllTyping(uc)
+		uc.unregister <- c
+		c.Close()
+	}()
+
+	// Ответы ждут места в очереди, которую разбирает цикл ниже
+	go c.restoreStream(uc, resume)
+	// Продлить токен в потоке нельзя: после "reauth" клиент переподключается с новым
+	go c.watchToken()
+
+	ticker := time.NewTicker(uc.hub.pingPeriod())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case frame := <-c.Send:
+			w.SetWriteDeadline(time.Now().Add(uc.hub.WriteWait))
+			if err := w.WriteFrame(frame); err != nil {
+				uc.countDeadPeer(err)
+				return
+			}
+		case <-ticker.C:
+			w.SetWriteDeadline(time.Now().Add(uc.hub.WriteWait))
+			if err := w.Ping(); err != nil {
+				uc.countDeadPeer(err)
+				return
+			}
+		case <-ctx.Done():
+			return
+		case <-c.ctx.Done():
+			w.SetWriteDeadline(time.Now().Add(uc.hub.WriteWait))
+			w.Close(c.closeCode, c.closeReason)
+			return
+		}
+	}
+}
+
+// restoreStream подписывает клиента на его каналы и досылает сообщения, пропущенные
+// в тех из них, на которые он подписан. Клиент потока не может повторить resume сам,
+// поэтому пропущенные сообщения досылаются частями по resumeLimit, пока не закончатся
+func (c *Client) restoreStream(uc *ChatUseCase, resume map[int64]int64) {
+	c.restoreChannels(uc)
+
+	channelIDs := make([]int64, 0, len(resume))
+	for channelID := range resume {
+		channelIDs = append(channelIDs, channelID)
+	}
+	sort.Slice(channelIDs, func(i, j int) bool { return channelIDs[i] < channelIDs[j] })
+
+	for _, channelID := range channelIDs {
+		if c.ctx.Err() != nil {
+			return
+		}
+		if !uc.isSubscribed(c, channelID) {
+			// Из канала исключили, пока клиент был отключен
+			continue
+		}
+		seq := resume[channelID]
+		for c.ctx.Err() == nil {
+			resumed, err := c.resume(ChatMessage{Type: "resume", ChannelID: channelID, Seq: seq}, uc)
+			if err != nil {
+				log.Printf("failed to resume channel %d for user %d: %v", channelID, c.UserID, err)
+				break
+			}
+			if !resumed.More {
+				break
+			}
+			seq = resumed.Seq
+		}
+	}
+}
+
+// AllowMessage проверяет лимит частоты сообщений типа msgType, отправленных пользователем
+// вне WebSocket. Лимит общий с WebSocket соединениями пользователя.
+func (uc *ChatUseCase) AllowMessage(msgType string, userID int64) ratelimit.Result {
+	if uc.limiter == nil {
+		return ratelimit.Result{Allowed: true}
+	}
+	return uc.limiter.Allow(msgType, ratelimit.UserKey(userID))
+}
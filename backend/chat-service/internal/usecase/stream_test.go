@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"backend/chat-service/internal/broker"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/repository"
+)
+
+// fakeMessages хранит сообщения в памяти
+type fakeMessages struct {
+	repository.MessageRepository
+
+	mu       sync.Mutex
+	messages []*entity.Message
+}
+
+func (r *fakeMessages) GetSince(ctx context.Context, channelID, seq int64, limit int32) ([]*entity.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []*entity.Message
+	for _, m := range r.messages {
+		if m.ChannelID == channelID && m.Seq > seq && len(result) < int(limit) {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+type fakeStream struct {
+	frames  chan ChatMessage
+	release chan struct{}
+
+	mu        sync.Mutex
+	closeCode int
+}
+
+func newFakeStream() *fakeStream {
+	return &fakeStream{frames: make(chan ChatMessage, 16)}
+}
+
+func (s *fakeStream) SetWriteDeadline(time.Time) error { return nil }
+
+func (s *fakeStream) WriteFrame(frame *Frame) error {
+	if s.release != nil {
+		<-s.release
+	}
+	msg, err := frame.Message()
+	if err != nil {
+		return err
+	}
+	s.frames <- msg
+	return nil
+}
+
+func (s *fakeStream) Ping() error { return nil }
+
+func (s *fakeStream) Close(code int, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeCode = code
+	return nil
+}
+
+func (s *fakeStream) code() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeCode
+}
+
+func deliver(t *testing.T, uc *ChatUseCase, msg ChatMessage) {
+	payload, err := json.Marshal(msg)
+	require.NoError(t, err)
+	uc.dispatch(broker.Event{Type: broker.EventDeliver, ChannelID: msg.ChannelID, Payload: payload})
+}
+
+func TestStream_ReceivesBroadcasts(t *testing.T) {
+	uc := NewChatUseCase(nil, nil)
+	go uc.Run()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := newFakeStream()
+	done := make(chan struct{})
+	go func() {
+		uc.Stream(ctx, NewClient(nil, 0, "anonymous", false), stream, nil)
+		close(done)
+	}()
+
+	// Клиент подписан на общий канал и получил список каналов
+	assert.Equal(t, "channels", (<-stream.frames).Type)
+	assert.Equal(t, 1, uc.Metrics().Clients)
+
+	deliver(t, uc, ChatMessage{Type: "message", ID: "1", ChannelID: entity.DefaultChannelID, Seq: 1, Content: "hi"})
+	msg := <-stream.frames
+	assert.Equal(t, "hi", msg.Content)
+
+	cancel()
+	<-done
+	require.Eventually(t, func() bool { return uc.Metrics().Clients == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestStream_DisconnectsSlowClient(t *testing.T) {
+	uc := NewChatUseCase(nil, nil, WithHubConfig(HubConfig{QueueSize: 1}))
+	go uc.Run()
+
+	stream := newFakeStream()
+	stream.release = make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		uc.Stream(context.Background(), NewClient(nil, 0, "anonymous", false), stream, nil)
+		close(done)
+	}()
+
+	// Первый кадр ("channels") застрял в записи, очередь из одного места переполняется
+	require.Eventually(t, func() bool { return uc.Metrics().Clients == 1 }, time.Second, 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		deliver(t, uc, ChatMessage{Type: "message", ChannelID: entity.DefaultChannelID, Content: "spam"})
+	}
+	close(stream.release)
+
+	<-done
+	assert.Equal(t, websocket.CloseTryAgainLater, stream.code())
+	assert.Equal(t, uint64(1), uc.Metrics().SlowDisconnects)
+}
+
+func TestStream_ResumesMoreThanLimit(t *testing.T) {
+	const missed = 2*resumeLimit + 200
+	repo := &fakeMessages{}
+	for seq := int64(1); seq <= missed+10; seq++ {
+		repo.messages = append(repo.messages, &entity.Message{ID: strconv.FormatInt(seq, 10), ChannelID: entity.DefaultChannelID, Seq: seq})
+	}
+	uc := NewChatUseCase(repo, nil)
+	go uc.Run()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := newFakeStream()
+	go uc.Stream(ctx, NewClient(nil, 0, "anonymous", false), stream, map[int64]int64{entity.DefaultChannelID: 10})
+
+	// Пропущенные сообщения приходят частями до последней, без повторного resume от клиента
+	var seqs []int64
+	var resumed []ChatMessage
+	for {
+		var msg ChatMessage
+		select {
+		case msg = <-stream.frames:
+		case <-time.After(time.Second):
+			t.Fatalf("resume stopped after %d messages", len(seqs))
+		}
+		if msg.Type == "message" {
+			seqs = append(seqs, msg.Seq)
+		}
+		if msg.Type == "resumed" {
+			resumed = append(resumed, msg)
+			if !msg.More {
+				break
+			}
+		}
+	}
+
+	require.Len(t, seqs, missed)
+	for i, seq := range seqs {
+		require.Equal(t, int64(11+i), seq)
+	}
+	require.Len(t, resumed, 3)
+	assert.True(t, resumed[0].More)
+	assert.Equal(t, int64(10+resumeLimit), resumed[0].Seq)
+	assert.Equal(t, int64(10+missed), resumed[2].Seq)
+}
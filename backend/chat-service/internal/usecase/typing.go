@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// typingThrottle не чаще этого интервала typing_start клиента рассылается повторно
+	typingThrottle = 3 * time.Second
+	// typingTimeout через это время без повторного typing_start набор текста считается завершенным
+	typingTimeout = 6 * time.Second
+)
+
+// typingState набор текста клиентом в одном канале
+type typingState struct {
+	sentAt time.Time
+	timer  *time.Timer
+	// gen отличает текущий таймер от уже сработавшего предыдущего
+	gen int
+}
+
+// typingTracker каналы, в которых клиент набирает текст
+type typingTracker struct {
+	mu       sync.Mutex
+	channels map[int64]*typingState
+}
+
+// handleTypingStart рассылает typing_start не чаще typingThrottle и продлевает индикатор.
+// Если клиент не повторит typing_start, через typingTimeout будет разослан typing_stop.
+func (c *Client) handleTypingStart(msg ChatMessage, uc *ChatUseCase) error {
+	channelID := c.typingChannel(msg, uc)
+	if channelID == 0 {
+		return nil
+	}
+
+	now := time.Now()
+
+	c.typing.mu.Lock()
+	state, ok := c.typing.channels[channelID]
+	if !ok {
+		state = &typingState{}
+		c.typing.channels[channelID] = state
+	}
+	send := now.Sub(state.sentAt) >= typingThrottle
+	if send {
+		state.sentAt = now
+	}
+	if state.timer != nil {
+		state.timer.Stop()
+	}
+	state.gen++
+	gen := state.gen
+	state.timer = time.AfterFunc(typingTimeout, func() {
+		c.expireTyping(uc, channelID, gen)
+	})
+	c.typing.mu.Unlock()
+
+	if !send {
+		return nil
+	}
+
+	return uc.publishToChannel(c.ctx, channelID, ChatMessage{
+		Type:      "typing_start",
+		ChannelID: channelID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+		ExpiresIn: int(typingTimeout / time.Second),
+	})
+}
+
+// handleTypingStop завершает индикатор набора текста
+func (c *Client) handleTypingStop(msg ChatMessage, uc *ChatUseCase) error {
+	channelID := c.typingChannel(msg, uc)
+	if channelID == 0 {
+		return nil
+	}
+
+	c.stopTyping(uc, channelID)
+	return nil
+}
+
+// expireTyping завершает индикатор по таймеру, если его не продлили
+func (c *Client) expireTyping(uc *ChatUseCase, channelID int64, gen int) {
+	c.typing.mu.Lock()
+	state, ok := c.typing.channels[channelID]
+	current := ok && state.gen == gen
+	c.typing.mu.Unlock()
+
+	if current {
+		c.stopTyping(uc, channelID)
+	}
+}
+
+// stopTyping рассылает typing_stop, если клиент набирал текст в канале
+func (c *Client) stopTyping(uc *ChatUseCase, channelID int64) {
+	c.typing.mu.Lock()
+	state, ok := c.typing.channels[channelID]
+	if ok {
+		state.timer.Stop()
+		delete(c.typing.channels, channelID)
+	}
+	c.typing.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	// Клиент мог уже отключиться, поэтому не используем его контекст
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := uc.publishToChannel(ctx, channelID, ChatMessage{
+		Type:      "typing_stop",
+		ChannelID: channelID,
+		UserID:    c.UserID,
+		Username:  c.Username,
+	})
+	if err != nil {
+		log.Printf("failed to publish typing_stop for user %d: %v", c.UserID, err)
+	}
+}
+
+// stopAllTyping завершает все индикаторы клиента при отключении
+func (c *Client) stopAllTyping(uc *ChatUseCase) {
+	c.typing.mu.Lock()
+	channels := make([]int64, 0, len(c.typing.channels))
+	for channelID := range c.typing.channels {
+		channels = append(channels, channelID)
+	}
+	c.typing.mu.Unlock()
+
+	for _, channelID := range channels {
+		c.stopTyping(uc, channelID)
+	}
+}
+
+// typingChannel возвращает канал индикатора или 0, если клиент на него не подписан
+func (c *Client) typingChannel(msg ChatMessage, uc *ChatUseCase) int64 {
+	channelID := msg.ChannelID
+	if channelID == 0 {
+		channelID = uc.activeChannel(c)
+	}
+	if !uc.isSubscribed(c, channelID) {
+		c.reply(ChatMessage{
+			Type:      "error",
+			Code:      ErrCodeNotSubscribed,
+			Error:     "Вы не подключены к этому каналу",
+			ChannelID: channelID,
+		})
+		return 0
+	}
+	return channelID
+}
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS messages_channel_id_id_idx;
+ALTER TABLE messages DROP COLUMN IF EXISTS channel_id;
+DROP TABLE IF EXISTS channel_members;
+DROP TABLE IF EXISTS channels;
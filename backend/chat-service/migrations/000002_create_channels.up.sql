@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS channels (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL UNIQUE,
+    is_private BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS channel_members (
+    channel_id BIGINT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS channel_members_user_id_idx ON channel_members(user_id);
+
+-- Общий канал, в котором оказываются все существующие сообщения
+INSERT INTO channels (id, name, is_private) VALUES (1, 'general', FALSE)
+ON CONFLICT (id) DO NOTHING;
+SELECT setval('channels_id_seq', GREATEST((SELECT MAX(id) FROM channels), 1));
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS channel_id BIGINT NOT NULL DEFAULT 1 REFERENCES channels(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS messages_channel_id_id_idx ON messages(channel_id, id DESC);
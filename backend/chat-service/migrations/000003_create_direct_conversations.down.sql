@@ -0,0 +1,6 @@
+DELETE FROM channels WHERE kind = 'direct';
+ALTER TABLE channel_members DROP COLUMN IF EXISTS last_read_id;
+DROP INDEX IF EXISTS channels_name_idx;
+ALTER TABLE channels ADD CONSTRAINT channels_name_key UNIQUE (name);
+ALTER TABLE channels DROP COLUMN IF EXISTS direct_key;
+ALTER TABLE channels DROP COLUMN IF EXISTS kind;
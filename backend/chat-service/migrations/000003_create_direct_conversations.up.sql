@@ -0,0 +1,14 @@
+-- Личные переписки хранятся как каналы вида 'direct'
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS kind VARCHAR(16) NOT NULL DEFAULT 'channel';
+-- Ключ переписки один на один ("1:2"), чтобы не создавать ее повторно
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS direct_key VARCHAR(64) UNIQUE;
+
+-- У переписок нет имени, уникальность нужна только обычным каналам
+ALTER TABLE channels DROP CONSTRAINT IF EXISTS channels_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS channels_name_idx ON channels(name) WHERE kind = 'channel';
+
+-- Последнее прочитанное сообщение участника, по нему считаются непрочитанные
+ALTER TABLE channel_members ADD COLUMN IF NOT EXISTS last_read_id BIGINT NOT NULL DEFAULT 0;
+
+UPDATE channel_members m
+SET last_read_id = COALESCE((SELECT MAX(id) FROM messages WHERE channel_id = m.channel_id), 0);
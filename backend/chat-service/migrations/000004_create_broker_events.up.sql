@@ -0,0 +1,8 @@
+-- События брокера, не помещающиеся в payload NOTIFY
+CREATE TABLE IF NOT EXISTS chat_broker_events (
+    id BIGSERIAL PRIMARY KEY,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS chat_broker_events_created_at_idx ON chat_broker_events(created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
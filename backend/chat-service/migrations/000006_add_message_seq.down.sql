@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS messages_user_id_temp_id_idx;
+DROP INDEX IF EXISTS messages_channel_id_seq_idx;
+ALTER TABLE messages DROP COLUMN IF EXISTS temp_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS seq;
+ALTER TABLE channels DROP COLUMN IF EXISTS last_seq;
@@ -0,0 +1,16 @@
+-- Порядковые номера сообщений внутри канала для восстановления после переподключения
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS last_seq BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT;
+-- Идентификатор, присвоенный сообщению клиентом, для защиты от повторной отправки
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS temp_id VARCHAR(64);
+
+UPDATE messages m SET seq = n.seq
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY channel_id ORDER BY id) AS seq FROM messages) n
+WHERE m.id = n.id AND m.seq IS NULL;
+
+UPDATE channels c SET last_seq = COALESCE((SELECT MAX(seq) FROM messages WHERE channel_id = c.id), 0);
+
+ALTER TABLE messages ALTER COLUMN seq SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS messages_channel_id_seq_idx ON messages(channel_id, seq);
+CREATE UNIQUE INDEX IF NOT EXISTS messages_user_id_temp_id_idx ON messages(user_id, temp_id) WHERE temp_id IS NOT NULL;
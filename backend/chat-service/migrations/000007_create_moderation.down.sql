@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS messages_user_id_created_at_idx;
+DROP TABLE IF EXISTS chat_moderation_log;
+ALTER TABLE channels DROP COLUMN IF EXISTS slow_mode_seconds;
+DROP TABLE IF EXISTS chat_sanctions;
@@ -0,0 +1,33 @@
+-- Ограничения пользователей: mute и ban. channel_id 0 — во всех каналах, expires_at NULL — бессрочно.
+CREATE TABLE IF NOT EXISTS chat_sanctions (
+    channel_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, channel_id, kind)
+);
+
+-- Медленный режим: минимальный интервал между сообщениями пользователя в канале
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS slow_mode_seconds INTEGER NOT NULL DEFAULT 0;
+
+-- Журнал действий модераторов
+CREATE TABLE IF NOT EXISTS chat_moderation_log (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(16) NOT NULL,
+    channel_id BIGINT NOT NULL DEFAULT 0,
+    moderator_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    reason TEXT NOT NULL DEFAULT '',
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    affected INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS chat_moderation_log_user_id_idx ON chat_moderation_log(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS chat_moderation_log_channel_id_idx ON chat_moderation_log(channel_id, id DESC);
+
+-- Последние сообщения пользователя для медленного режима и очистки
+CREATE INDEX IF NOT EXISTS messages_user_id_created_at_idx ON messages(user_id, created_at DESC);
@@ -0,0 +1,13 @@
+-- Сообщения, задержанные фильтром содержимого до проверки модератором.
+-- rules — правила фильтра, на которые сработало сообщение.
+CREATE TABLE IF NOT EXISTS chat_held_messages (
+    id BIGSERIAL PRIMARY KEY,
+    channel_id BIGINT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    content TEXT NOT NULL,
+    rules TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS chat_held_messages_channel_id_idx ON chat_held_messages(channel_id, id);
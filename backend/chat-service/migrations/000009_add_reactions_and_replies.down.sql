@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS message_reactions;
+DROP INDEX IF EXISTS messages_reply_to_idx;
+ALTER TABLE messages DROP COLUMN IF EXISTS reply_to;
@@ -0,0 +1,13 @@
+-- Ответ на сообщение: ссылка на родительское сообщение того же канала
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_to BIGINT REFERENCES messages(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS messages_reply_to_idx ON messages(reply_to, id) WHERE reply_to IS NOT NULL;
+
+-- Реакции пользователей на сообщения: не больше одной реакции каждым эмодзи
+CREATE TABLE IF NOT EXISTS message_reactions (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL,
+    emoji VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (message_id, user_id, emoji)
+);
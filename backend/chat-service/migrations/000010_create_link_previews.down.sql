@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS previews;
+DROP TABLE IF EXISTS link_previews;
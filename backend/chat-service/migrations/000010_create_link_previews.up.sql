@@ -0,0 +1,13 @@
+-- Кэш превью ссылок (backend/pkg/unfurl). Неудачные загрузки хранятся с пустыми полями.
+CREATE TABLE IF NOT EXISTS link_previews (
+    url TEXT PRIMARY KEY,
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Превью ссылок сообщения на момент отправки или последнего редактирования
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS previews JSONB;
@@ -0,0 +1,8 @@
+-- Одноразовые билеты для подключения к WebSocket без токена в URL. Хранится хэш билета.
+CREATE TABLE IF NOT EXISTS ws_tickets (
+    ticket_hash TEXT PRIMARY KEY,
+    user_data JSONB NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_tickets_expires_at ON ws_tickets (expires_at);
@@ -0,0 +1,138 @@
+package mocks
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"backend/chat-service/internal/auth"
+	grpcdelivery "backend/chat-service/internal/delivery/grpc"
+	"backend/chat-service/internal/entity"
+	"backend/chat-service/internal/usecase"
+	pb "backend/chat-service/proto"
+)
+
+// staticValidator принимает только токен "valid"
+type staticValidator struct{}
+
+func (staticValidator) Validate(ctx context.Context, token string) (*auth.User, error) {
+	if token != "valid" {
+		return nil, auth.ErrInvalidToken
+	}
+	return &auth.User{ID: 1, Username: "test_user"}, nil
+}
+
+func startGRPCServer(t *testing.T, repo *MockMessageRepository) pb.ChatServiceClient {
+	logger, _ := zap.NewDevelopment()
+	chatUseCase := usecase.NewChatUseCase(repo, nil)
+	go chatUseCase.Run()
+
+	handler := grpcdelivery.NewServer(chatUseCase, staticValidator{}, logger)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(handler.UnaryInterceptor),
+		grpc.StreamInterceptor(handler.StreamInterceptor),
+	)
+	pb.RegisterChatServiceServer(server, handler)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewChatServiceClient(conn)
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestGRPC_SendMessageRequiresAuth(t *testing.T) {
+	client := startGRPCServer(t, new(MockMessageRepository))
+	ctx := context.Background()
+
+	_, err := client.SendMessage(ctx, &pb.SendMessageRequest{Content: "hello"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.SendMessage(withToken(ctx, "expired"), &pb.SendMessageRequest{Content: "hello"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestGRPC_ValidateToken(t *testing.T) {
+	client := startGRPCServer(t, new(MockMessageRepository))
+
+	resp, err := client.ValidateToken(context.Background(), &pb.ValidateTokenRequest{Token: "valid"})
+	require.NoError(t, err)
+	assert.True(t, resp.IsValid)
+	assert.Equal(t, int64(1), resp.UserId)
+
+	resp, err = client.ValidateToken(context.Background(), &pb.ValidateTokenRequest{Token: "expired"})
+	require.NoError(t, err)
+	assert.False(t, resp.IsValid)
+}
+
+func TestGRPC_SubscribeReceivesSentMessage(t *testing.T) {
+	repo := new(MockMessageRepository)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*entity.Message")).
+		Run(func(args mock.Arguments) {
+			msg := args.Get(1).(*entity.Message)
+			msg.ID = "7"
+			msg.Seq = 3
+		}).
+		Return(nil)
+
+	client := startGRPCServer(t, repo)
+	ctx, cancel := context.WithTimeout(withToken(context.Background(), "valid"), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Subscribe(ctx, &pb.SubscribeRequest{})
+	require.NoError(t, err)
+
+	// Подписка регистрируется асинхронно, отправляем сообщение, пока оно не придет в поток
+	events := make(chan *pb.ChatEvent, 1)
+	go func() {
+		if event, err := stream.Recv(); err == nil {
+			events <- event
+		}
+	}()
+
+	var sent *pb.SendMessageResponse
+	for sent == nil {
+		resp, err := client.SendMessage(ctx, &pb.SendMessageRequest{Content: "hello", TempId: "t1"})
+		require.NoError(t, err)
+
+		select {
+		case event := <-events:
+			assert.Equal(t, "message", event.Type)
+			assert.Equal(t, "hello", event.Message.Content)
+			assert.Equal(t, int64(7), event.Message.Id)
+			assert.Equal(t, int64(3), event.Message.Seq)
+			sent = resp
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no event received")
+		}
+	}
+
+	assert.Equal(t, int64(7), sent.Message.Id)
+	assert.False(t, sent.Duplicate)
+}
@@ -19,6 +19,7 @@ import (
 	"backend/chat-service/internal/entity"
 	"backend/chat-service/internal/repository"
 	"backend/chat-service/internal/usecase"
+	"backend/pkg/unfurl"
 )
 
 // MockMessageRepository is a mock implementation of MessageRepository
@@ -36,11 +37,61 @@ func (m *MockMessageRepository) GetHistory(ctx context.Context, limit int32, bef
 	return args.Get(0).([]*entity.Message), args.Error(1)
 }
 
+func (m *MockMessageRepository) GetChannelHistory(ctx context.Context, channelID int64, limit int32, beforeID int64) ([]*entity.Message, error) {
+	args := m.Called(ctx, channelID, limit, beforeID)
+	return args.Get(0).([]*entity.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) GetSince(ctx context.Context, channelID, seq int64, limit int32) ([]*entity.Message, error) {
+	args := m.Called(ctx, channelID, seq, limit)
+	return args.Get(0).([]*entity.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) GetChannelHistoryAfter(ctx context.Context, channelID int64, limit int32, afterID int64) ([]*entity.Message, error) {
+	args := m.Called(ctx, channelID, limit, afterID)
+	return args.Get(0).([]*entity.Message), args.Error(1)
+}
+
 func (m *MockMessageRepository) DeleteOldMessages(ctx context.Context, before time.Time) (int32, error) {
 	args := m.Called(ctx, before)
 	return args.Get(0).(int32), args.Error(1)
 }
 
+func (m *MockMessageRepository) GetByID(ctx context.Context, id int64) (*entity.Message, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*entity.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) Edit(ctx context.Context, id int64, content string) (*entity.Message, error) {
+	args := m.Called(ctx, id, content)
+	return args.Get(0).(*entity.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) SoftDelete(ctx context.Context, id, deletedBy int64) (*entity.Message, error) {
+	args := m.Called(ctx, id, deletedBy)
+	return args.Get(0).(*entity.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) GetReplies(ctx context.Context, parentID int64, limit int32, afterID int64) ([]*entity.Message, error) {
+	args := m.Called(ctx, parentID, limit, afterID)
+	return args.Get(0).([]*entity.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) ToggleReaction(ctx context.Context, messageID, userID int64, emoji string) (bool, error) {
+	args := m.Called(ctx, messageID, userID, emoji)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepository) GetReactions(ctx context.Context, messageIDs []int64) (map[int64][]entity.Reaction, error) {
+	args := m.Called(ctx, messageIDs)
+	return args.Get(0).(map[int64][]entity.Reaction), args.Error(1)
+}
+
+func (m *MockMessageRepository) SetPreviews(ctx context.Context, messageID int64, previews []unfurl.Preview) error {
+	args := m.Called(ctx, messageID, previews)
+	return args.Error(0)
+}
+
 func (m *MockMessageRepository) QueryRow(ctx context.Context, query string, args ...interface{}) repository.Row {
 	mockArgs := m.Called(ctx, query, args)
 	return mockArgs.Get(0).(repository.Row)
@@ -8,14 +8,23 @@ import (
 	"forum-service/internal/logger"
 	"forum-service/internal/repository"
 	"forum-service/internal/usecase"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"backend/pkg/contentfilter"
+	"backend/pkg/jwt"
+	"backend/pkg/ratelimit"
+	"backend/pkg/revocation"
+	"backend/pkg/unfurl"
+
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
@@ -55,16 +64,70 @@ func main() {
 	// Repository and use case initialization
 	postRepo := repository.NewPostRepository(dbpool)
 	userRepo := repository.NewUserRepository(dbpool)
-	postUseCase := usecase.NewPostUseCase(postRepo, userRepo)
+	moderationRepo := repository.NewModerationRepository(dbpool)
+	filter, err := contentfilter.Load(cfg.ContentFilter.Path, cfg.ContentFilter.DryRun, moderationRepo)
+	if err != nil {
+		logger.Fatal("Invalid content filter config", zap.Error(err))
+	}
+	if filter != nil {
+		logger.Info("Content filter enabled",
+			zap.String("config", cfg.ContentFilter.Path), zap.Bool("dry_run", filter.DryRun()))
+	}
+	var unfurler *unfurl.Unfurler
+	if cfg.LinkPreview.Enabled {
+		unfurler = unfurl.New(unfurl.NewPostgresCache(dbpool), unfurl.Options{
+			Timeout:     cfg.LinkPreview.Timeout,
+			MaxBodySize: cfg.LinkPreview.MaxBodySize,
+		})
+	}
+	postUseCase := usecase.NewPostUseCase(postRepo, userRepo,
+		usecase.WithContentFilter(filter),
+		usecase.WithModeration(moderationRepo),
+		usecase.WithUnfurler(unfurler, repository.NewPreviewRepository(dbpool)),
+	)
 	logger.Info("Initialized repository and use case")
 
-	// Create and start HTTP server
-	server := app.NewServer(postUseCase)
+	// Rate limits for write endpoints
+	rules, err := ratelimit.ParseRules(cfg.RateLimit.Rules)
+	if err != nil {
+		logger.Fatal("Invalid rate limit rules", zap.Error(err))
+	}
+
+	// Access tokens are issued by auth-service with the same secret
+	tokenManager, err := jwt.NewManager(cfg.JWT.Secret)
+	if err != nil {
+		logger.Fatal("Failed to create token manager", zap.Error(err))
+	}
+
+	// Tokens of ended sessions are rejected by the revocation list of auth-service
+	authConn, err := grpc.Dial(cfg.Auth.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		logger.Fatal("Failed to connect to auth service", zap.Error(err))
+	}
+	defer authConn.Close()
+
+	revocations := revocation.NewList()
+	revocationsCtx, stopRevocations := context.WithCancel(context.Background())
+	defer stopRevocations()
+	go revocations.Run(revocationsCtx, revocation.NewGRPCSource(authConn), cfg.Auth.RevocationPoll, cfg.Auth.Timeout, func(err error) {
+		logger.Warn("Failed to load token revocations", zap.Error(err))
+	})
+
+	// Create and start HTTP and gRPC servers
+	server, err := app.NewServer(postUseCase, ratelimit.New(rules), tokenManager, revocations, fmt.Sprintf(":%d", cfg.GRPC.Port))
+	if err != nil {
+		logger.Fatal("Failed to create server", zap.Error(err))
+	}
 	go func() {
-		if err := server.Start(fmt.Sprintf(":%s", cfg.HTTP.Port)); err != nil {
+		if err := server.Start(fmt.Sprintf(":%s", cfg.HTTP.Port)); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Server error", zap.Error(err))
 		}
 	}()
+	go func() {
+		if err := server.StartGRPC(); err != nil {
+			logger.Fatal("gRPC server error", zap.Error(err))
+		}
+	}()
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
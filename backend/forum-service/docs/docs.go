@@ -0,0 +1,10 @@
+// Package docs contains the OpenAPI spec of the REST API. It is generated from
+// proto/forum.proto by protoc-gen-openapiv2, run make proto after changing the proto file.
+package docs
+
+import _ "embed"
+
+// SwaggerJSON is the OpenAPI 2.0 spec of the REST API
+//
+//go:embed forum.swagger.json
+var SwaggerJSON []byte
@@ -2,41 +2,74 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"forum-service/internal/config"
 	delivery "forum-service/internal/delivery/http"
+	grpcdelivery "forum-service/internal/grpc"
 	"forum-service/internal/logger"
 	"forum-service/internal/repository"
 	"forum-service/internal/usecase"
+	pb "forum-service/proto"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"backend/pkg/contentfilter"
+	"backend/pkg/jwt"
+	"backend/pkg/ratelimit"
+	"backend/pkg/revocation"
+	"backend/pkg/unfurl"
 )
 
 type Server struct {
-	httpServer *http.Server
-	logger     *zap.Logger
+	httpServer  *http.Server
+	grpcServer  *grpc.Server
+	grpcAddr    string
+	gatewayConn *grpc.ClientConn
+	health      *health.Server
+	logger      *zap.Logger
 }
 
-func NewServer(postUC usecase.PostUseCase) *Server {
-	handler := delivery.NewHandler(postUC)
+// NewServer creates the HTTP and gRPC servers. The REST API of the HTTP server
+// is a gateway to the gRPC server listening on grpcAddr. Tokens issued before
+// a revocation in revocations are rejected.
+func NewServer(postUC usecase.PostUseCase, limiter *ratelimit.Limiter, tokenManager *jwt.Manager, revocations *revocation.List, grpcAddr string) (*Server, error) {
+	logger, _ := logger.NewLogger("debug")
+
+	metrics := grpcdelivery.NewMetrics()
+	grpcServer, healthServer := newGRPCServer(postUC, tokenManager, revocations, limiter, metrics, logger)
+
+	router, conn, err := newRouter(grpcAddr, metrics)
+	if err != nil {
+		return nil, err
+	}
 
 	srv := &http.Server{
-		Handler:      handler.Init(),
+		Handler:      router,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
 
-	logger, _ := logger.NewLogger("debug")
-
 	return &Server{
-		httpServer: srv,
-		logger:     logger,
-	}
+		httpServer:  srv,
+		grpcServer:  grpcServer,
+		grpcAddr:    grpcAddr,
+		gatewayConn: conn,
+		health:      healthServer,
+		logger:      logger,
+	}, nil
 }
 
 func (s *Server) Start(addr string) error {
@@ -45,15 +78,90 @@ func (s *Server) Start(addr string) error {
 	return s.httpServer.ListenAndServe()
 }
 
+// StartGRPC serves the gRPC API on the address passed to NewServer until Shutdown
+func (s *Server) StartGRPC() error {
+	listener, err := net.Listen("tcp", s.grpcAddr)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Starting gRPC server", zap.String("address", s.grpcAddr))
+	return s.grpcServer.Serve(listener)
+}
+
+// Shutdown stops the HTTP server first, so that the gateway can finish its calls to the gRPC server
 func (s *Server) Shutdown(ctx context.Context) error {
-	return s.httpServer.Shutdown(ctx)
+	err := s.httpServer.Shutdown(ctx)
+	s.health.Shutdown()
+	stopGRPC(ctx, s.grpcServer)
+	s.gatewayConn.Close()
+	return err
+}
+
+// newRouter returns the HTTP API: the gateway to the gRPC server on grpcAddr and gRPC metrics on /metrics.
+// The returned connection is used by the gateway until it is closed.
+func newRouter(grpcAddr string, metrics *grpcdelivery.Metrics) (*gin.Engine, *grpc.ClientConn, error) {
+	conn, err := grpc.Dial(gatewayTarget(grpcAddr), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gateway, err := delivery.NewGateway(context.Background(), conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return delivery.NewHandler(gateway, metrics).Init(), conn, nil
+}
+
+// gatewayTarget turns a listen address like ":50054" into an address the gateway can dial
+func gatewayTarget(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || (host != "" && host != "0.0.0.0" && host != "::") {
+		return addr
+	}
+	return net.JoinHostPort("localhost", port)
+}
+
+// newGRPCServer registers the forum service with its interceptors, health checks and reflection
+func newGRPCServer(postUC usecase.PostUseCase, tokenManager *jwt.Manager, revocations *revocation.List, limiter *ratelimit.Limiter, metrics *grpcdelivery.Metrics, logger *zap.Logger) (*grpc.Server, *health.Server) {
+	handler := grpcdelivery.NewServer(postUC, tokenManager, revocations, limiter, metrics, logger)
+	grpcServer := grpc.NewServer(handler.ServerOptions()...)
+	pb.RegisterForumServiceServer(grpcServer, handler)
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.ForumService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+
+	return grpcServer, healthServer
+}
+
+// stopGRPC waits for running calls until ctx is done and then closes the connections
+func stopGRPC(ctx context.Context, grpcServer *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 }
 
 // App represents the application
 type App struct {
-	config     *config.Config
-	logger     *zap.Logger
-	httpServer *http.Server
+	config      *config.Config
+	logger      *zap.Logger
+	httpServer  *http.Server
+	grpcServer  *grpc.Server
+	gatewayConn *grpc.ClientConn
+	authConn    *grpc.ClientConn
+	revocations *revocation.List
+	health      *health.Server
 }
 
 // New creates a new application instance
@@ -81,21 +189,66 @@ func New(configPath string) (*App, error) {
 	userRepo := repository.NewUserRepository(pool)
 
 	// Initialize use cases
-	uc := usecase.NewPostUseCase(repo, userRepo)
+	moderationRepo := repository.NewModerationRepository(pool)
+	filter, err := contentfilter.Load(cfg.ContentFilter.Path, cfg.ContentFilter.DryRun, moderationRepo)
+	if err != nil {
+		return nil, err
+	}
+	var unfurler *unfurl.Unfurler
+	if cfg.LinkPreview.Enabled {
+		unfurler = unfurl.New(unfurl.NewPostgresCache(pool), unfurl.Options{
+			Timeout:     cfg.LinkPreview.Timeout,
+			MaxBodySize: cfg.LinkPreview.MaxBodySize,
+		})
+	}
+	uc := usecase.NewPostUseCase(repo, userRepo,
+		usecase.WithContentFilter(filter),
+		usecase.WithModeration(moderationRepo),
+		usecase.WithUnfurler(unfurler, repository.NewPreviewRepository(pool)),
+	)
+
+	rules, err := ratelimit.ParseRules(cfg.RateLimit.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenManager, err := jwt.NewManager(cfg.JWT.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens are verified locally, auth-service lists the revocations of ended sessions
+	authConn, err := grpc.Dial(cfg.Auth.GRPCAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+	revocations := revocation.NewList()
+
+	// Initialize gRPC and HTTP servers
+	metrics := grpcdelivery.NewMetrics()
+	grpcServer, healthServer := newGRPCServer(uc, tokenManager, revocations, ratelimit.New(rules), metrics, log)
+
+	router, conn, err := newRouter(fmt.Sprintf(":%d", cfg.GRPC.Port), metrics)
+	if err != nil {
+		return nil, err
+	}
 
-	// Initialize HTTP server
-	handler := delivery.NewHandler(uc)
 	srv := &http.Server{
 		Addr:         ":" + cfg.Server.Port,
-		Handler:      handler.Init(),
+		Handler:      router,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 	}
 
 	return &App{
-		config:     cfg,
-		logger:     log,
-		httpServer: srv,
+		config:      cfg,
+		logger:      log,
+		httpServer:  srv,
+		grpcServer:  grpcServer,
+		gatewayConn: conn,
+		authConn:    authConn,
+		revocations: revocations,
+		health:      healthServer,
 	}, nil
 }
 
@@ -105,6 +258,25 @@ func (a *App) Run() error {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
+	// Keep the token revocations up to date while the servers run
+	revocationsCtx, stopRevocations := context.WithCancel(context.Background())
+	defer stopRevocations()
+	go a.revocations.Run(revocationsCtx, revocation.NewGRPCSource(a.authConn), a.config.Auth.RevocationPoll, a.config.Auth.Timeout, func(err error) {
+		a.logger.Warn("Failed to load token revocations", zap.Error(err))
+	})
+
+	// Start gRPC server
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", a.config.GRPC.Port))
+	if err != nil {
+		return err
+	}
+	go func() {
+		a.logger.Info("Starting gRPC server", zap.Int("port", a.config.GRPC.Port))
+		if err := a.grpcServer.Serve(listener); err != nil {
+			a.logger.Fatal("Failed to start gRPC server", zap.Error(err))
+		}
+	}()
+
 	// Start HTTP server
 	go func() {
 		a.logger.Info("Starting HTTP server", zap.String("port", a.config.Server.Port))
@@ -121,8 +293,17 @@ func (a *App) Run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Shutdown HTTP server
-	if err := a.httpServer.Shutdown(ctx); err != nil {
+	// Shutdown HTTP server first, its REST API calls the gRPC server
+	err = a.httpServer.Shutdown(ctx)
+
+	// Stop gRPC server: health checks report NOT_SERVING while running calls finish
+	a.health.Shutdown()
+	stopGRPC(ctx, a.grpcServer)
+	a.gatewayConn.Close()
+	stopRevocations()
+	a.authConn.Close()
+
+	if err != nil {
 		a.logger.Error("Server forced to shutdown", zap.Error(err))
 		return err
 	}
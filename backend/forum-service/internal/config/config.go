@@ -7,11 +7,17 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig
-	DB     DBConfig
-	Logger LoggerConfig
-	HTTP   HTTPConfig
-	GRPC   GRPCConfig
+	Server    ServerConfig
+	DB        DBConfig
+	Logger    LoggerConfig
+	HTTP      HTTPConfig
+	GRPC      GRPCConfig
+	RateLimit RateLimitConfig
+	JWT       JWTConfig
+	Auth      AuthConfig
+
+	ContentFilter ContentFilterConfig
+	LinkPreview   LinkPreviewConfig
 }
 
 type ServerConfig struct {
@@ -36,6 +42,38 @@ type GRPCConfig struct {
 	Port int
 }
 
+// RateLimitConfig holds per-route limits in the ratelimit.ParseRules format
+type RateLimitConfig struct {
+	Rules string
+}
+
+// JWTConfig holds the secret shared with auth-service to verify access tokens
+type JWTConfig struct {
+	Secret string
+}
+
+// AuthConfig locates the auth-service gRPC API, polled every RevocationPoll for the
+// revocations of access tokens with requests limited by Timeout
+type AuthConfig struct {
+	GRPCAddr       string
+	RevocationPoll time.Duration
+	Timeout        time.Duration
+}
+
+// ContentFilterConfig points to the contentfilter JSON config, an empty path disables filtering.
+// DryRun only logs matches without changing or holding content.
+type ContentFilterConfig struct {
+	Path   string
+	DryRun bool
+}
+
+// LinkPreviewConfig turns link previews of posts on and limits the fetch of each link
+type LinkPreviewConfig struct {
+	Enabled     bool
+	Timeout     time.Duration
+	MaxBodySize int64
+}
+
 func LoadConfig() (*Config, error) {
 	return &Config{
 		Server: ServerConfig{
@@ -53,7 +91,27 @@ func LoadConfig() (*Config, error) {
 			Port: getEnv("HTTP_PORT", "5000"),
 		},
 		GRPC: GRPCConfig{
-			Port: getEnvInt("GRPC_PORT", 50052),
+			Port: getEnvInt("GRPC_PORT", 50054),
+		},
+		RateLimit: RateLimitConfig{
+			Rules: getEnv("RATE_LIMITS", "posts.create=5/1m,replies.create=20/1m"),
+		},
+		JWT: JWTConfig{
+			Secret: getEnv("JWT_SECRET", "your-secret-key"),
+		},
+		Auth: AuthConfig{
+			GRPCAddr:       getEnv("AUTH_GRPC_ADDR", "localhost:50051"),
+			RevocationPoll: time.Duration(getEnvInt("AUTH_REVOCATION_POLL_SECONDS", 10)) * time.Second,
+			Timeout:        time.Duration(getEnvInt("AUTH_TIMEOUT_SECONDS", 2)) * time.Second,
+		},
+		ContentFilter: ContentFilterConfig{
+			Path:   getEnv("CONTENT_FILTER_CONFIG", ""),
+			DryRun: getEnv("CONTENT_FILTER_DRY_RUN", "false") == "true",
+		},
+		LinkPreview: LinkPreviewConfig{
+			Enabled:     getEnv("LINK_PREVIEWS", "true") == "true",
+			Timeout:     time.Duration(getEnvInt("LINK_PREVIEW_TIMEOUT_SECONDS", 5)) * time.Second,
+			MaxBodySize: int64(getEnvInt("LINK_PREVIEW_MAX_BYTES", 1<<20)),
 		},
 	}, nil
 }
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"context"
+	pb "forum-service/proto"
+	"net/http"
+	"strings"
+
+	"backend/pkg/ratelimit"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// rateLimitHeaders are passed from gRPC metadata to HTTP responses under their own names
+var rateLimitHeaders = []string{
+	ratelimit.HeaderLimit,
+	ratelimit.HeaderRemaining,
+	ratelimit.HeaderReset,
+	ratelimit.HeaderPolicy,
+	ratelimit.HeaderRetry,
+}
+
+// NewGateway returns the REST API generated from the HTTP annotations of proto/forum.proto.
+// Requests are sent to the gRPC server over conn, so both transports share its authentication,
+// validation and rate limits. JSON uses the field names of the proto file.
+func NewGateway(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+		runtime.WithForwardResponseOption(createdStatus),
+	)
+
+	if err := pb.RegisterForumServiceHandler(ctx, mux, conn); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// createdStatus answers created posts and replies with 201 Created and content held
+// by the content filter with 202 Accepted, other responses keep 200 OK
+func createdStatus(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+	var heldID int64
+	switch resp := resp.(type) {
+	case *pb.CreatePostResponse:
+		heldID = resp.GetHeldId()
+	case *pb.CreateReplyResponse:
+		heldID = resp.GetHeldId()
+	default:
+		return nil
+	}
+
+	if heldID != 0 {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	return nil
+}
+
+// outgoingHeader keeps the names of rate limit headers, other metadata gets the Grpc-Metadata- prefix
+func outgoingHeader(key string) (string, bool) {
+	for _, header := range rateLimitHeaders {
+		if strings.EqualFold(key, header) {
+			return header, true
+		}
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
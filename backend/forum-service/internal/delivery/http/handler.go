@@ -1,23 +1,28 @@
 package http
 
 import (
-	"forum-service/internal/entity"
-	"forum-service/internal/usecase"
+	"forum-service/docs"
 	"net/http"
-	"strconv"
 	"time"
 
+	"backend/pkg/ratelimit"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
+// Handler serves the REST API next to health checks, metrics and the OpenAPI spec
 type Handler struct {
-	postUC usecase.PostUseCase
+	gateway http.Handler
+	metrics http.Handler
 }
 
-func NewHandler(postUC usecase.PostUseCase) *Handler {
+// NewHandler creates the HTTP handler. gateway serves the REST API, see NewGateway,
+// metrics is served on /metrics when it is not nil.
+func NewHandler(gateway, metrics http.Handler) *Handler {
 	return &Handler{
-		postUC: postUC,
+		gateway: gateway,
+		metrics: metrics,
 	}
 }
 
@@ -26,10 +31,17 @@ func (h *Handler) Init() *gin.Engine {
 
 	// CORS middleware
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:8080", "http://localhost:8081", "http://localhost:8082", "http://localhost:8083"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins: []string{"http://localhost:3000", "http://localhost:8080", "http://localhost:8081", "http://localhost:8082", "http://localhost:8083"},
+		AllowMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ExposeHeaders: []string{
+			"Content-Length",
+			ratelimit.HeaderLimit,
+			ratelimit.HeaderRemaining,
+			ratelimit.HeaderReset,
+			ratelimit.HeaderPolicy,
+			ratelimit.HeaderRetry,
+		},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -46,219 +58,21 @@ func (h *Handler) Init() *gin.Engine {
 		})
 	})
 
-	// Routes
-	api := router.Group("") // Убираем /api префикс
-	{
-		posts := api.Group("/posts")
-		{
-			posts.GET("", h.GetPosts)
-			posts.GET("/:id", h.GetPost)
-			posts.POST("", h.CreatePost)
-			posts.PUT("/:id", h.UpdatePost)
-			posts.DELETE("/:id", h.DeletePost)
-
-			// Replies
-			posts.GET("/:id/replies", h.GetReplies)
-			posts.POST("/:id/replies", h.CreateReply)
-		}
-	}
-
-	return router
-}
-
-// @Summary Get all posts
-// @Description Get all posts with pagination
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param page query int false "Page number"
-// @Param limit query int false "Items per page"
-// @Success 200 {array} entity.Post
-// @Router /api/posts [get]
-func (h *Handler) GetPosts(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-
-	offset := (page - 1) * limit
-
-	posts, total, err := h.postUC.GetAll(c.Request.Context(), offset, limit)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		return
-	}
-
-	if posts == nil {
-		posts = make([]*entity.Post, 0)
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"posts": posts,
-		"total": total,
-	})
-}
-
-// @Summary Get post by ID
-// @Description Get post by ID with replies
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param id path int true "Post ID"
-// @Success 200 {object} entity.Post
-// @Router /api/posts/{id} [get]
-func (h *Handler) GetPost(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	post, err := h.postUC.GetByID(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, post)
-}
-
-// @Summary Create post
-// @Description Create a new post
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param input body entity.CreatePostInput true "Post input"
-// @Success 201 {object} entity.Post
-// @Router /api/posts [post]
-func (h *Handler) CreatePost(c *gin.Context) {
-	var input entity.CreatePostInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
-		return
-	}
-
-	post, err := h.postUC.Create(c.Request.Context(), input)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
-		return
+	if h.metrics != nil {
+		router.GET("/metrics", gin.WrapH(h.metrics))
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"post":    post,
-		"message": "Пост успешно создан",
+	// OpenAPI spec generated from proto/forum.proto
+	router.GET("/swagger/doc.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", docs.SwaggerJSON)
 	})
-}
-
-// @Summary Update post
-// @Description Update an existing post
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param id path int true "Post ID"
-// @Param input body entity.UpdatePostInput true "Post update input"
-// @Success 200 "No Content"
-// @Router /api/posts/{id} [put]
-func (h *Handler) UpdatePost(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	var input entity.UpdatePostInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	err = h.postUC.Update(c.Request.Context(), id, input)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
 
-	c.Status(http.StatusOK)
-}
-
-// @Summary Delete post
-// @Description Delete an existing post
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param id path int true "Post ID"
-// @Success 204 "No Content"
-// @Router /api/posts/{id} [delete]
-func (h *Handler) DeletePost(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	if err := h.postUC.Delete(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.Status(http.StatusNoContent)
-}
+	// Posts, replies and moderation are served by the gateway to the gRPC API,
+	// routes are defined by the HTTP annotations of proto/forum.proto
+	gateway := gin.WrapH(h.gateway)
+	router.Any("/posts", gateway)
+	router.Any("/posts/*path", gateway)
+	router.Any("/moderation/*path", gateway)
 
-// @Summary Get post replies
-// @Description Get all replies for a post
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param id path int true "Post ID"
-// @Success 200 {array} entity.Reply
-// @Router /api/posts/{id}/replies [get]
-func (h *Handler) GetReplies(c *gin.Context) {
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	replies, err := h.postUC.GetReplies(c.Request.Context(), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, replies)
-}
-
-// @Summary Create reply
-// @Description Create a new reply for a post
-// @Tags posts
-// @Accept json
-// @Produce json
-// @Param id path int true "Post ID"
-// @Param input body entity.CreateReplyInput true "Reply input"
-// @Success 201 {object} entity.Reply
-// @Router /api/posts/{id}/replies [post]
-func (h *Handler) CreateReply(c *gin.Context) {
-	postID, err := strconv.ParseInt(c.Param("id"), 10, 64)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid post id"})
-		return
-	}
-
-	var input entity.CreateReplyInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	reply, err := h.postUC.CreateReply(c.Request.Context(), postID, input)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusCreated, reply)
+	return router
 }
@@ -0,0 +1,147 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"forum-service/internal/entity"
+	grpcdelivery "forum-service/internal/grpc"
+	"forum-service/internal/usecase"
+	pb "forum-service/proto"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"backend/pkg/jwt"
+	"backend/pkg/ratelimit"
+	"backend/pkg/rbac"
+)
+
+// fakePosts keeps posts in memory
+type fakePosts struct {
+	usecase.PostUseCase
+	posts map[int64]*entity.Post
+}
+
+func (f *fakePosts) GetByID(ctx context.Context, id int64) (*entity.Post, error) {
+	post, ok := f.posts[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return post, nil
+}
+
+func (f *fakePosts) Create(ctx context.Context, input entity.CreatePostInput) (*entity.Post, error) {
+	if input.Title == "" {
+		return nil, fmt.Errorf("%w: title and content are required", usecase.ErrInvalidInput)
+	}
+	if input.Title == "held" {
+		return nil, &usecase.HeldError{ID: 5}
+	}
+	post := &entity.Post{ID: int64(len(f.posts) + 1), Title: input.Title, Content: input.Content, AuthorID: input.AuthorID}
+	f.posts[post.ID] = post
+	return post, nil
+}
+
+// newTestRouter serves the gateway to an in-memory gRPC server
+func newTestRouter(t *testing.T, posts usecase.PostUseCase, tokens *jwt.Manager, limiter *ratelimit.Limiter) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	handler := grpcdelivery.NewServer(posts, tokens, nil, limiter, nil, zap.NewNop())
+	server := grpc.NewServer(handler.ServerOptions()...)
+	pb.RegisterForumServiceServer(server, handler)
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	gateway, err := NewGateway(context.Background(), conn)
+	require.NoError(t, err)
+	return NewHandler(gateway, nil).Init()
+}
+
+func serve(router http.Handler, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGateway(t *testing.T) {
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	token, err := tokens.NewAccessToken(2, "user2", rbac.Access{}, time.Minute)
+	require.NoError(t, err)
+	rules, err := ratelimit.ParseRules(grpcdelivery.RuleCreatePost + "=3/1m")
+	require.NoError(t, err)
+
+	posts := &fakePosts{posts: map[int64]*entity.Post{1: {ID: 1, Title: "t", Content: "c", AuthorID: 1}}}
+	router := newTestRouter(t, posts, tokens, ratelimit.New(rules))
+
+	rec := serve(router, http.MethodGet, "/posts/1", "", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var post map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &post))
+	assert.Equal(t, "t", post["title"])
+	assert.Equal(t, "1", post["author_id"])
+
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodGet, "/posts/42", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, http.MethodGet, "/posts/abc", "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(router, http.MethodPost, "/posts", "", `{"title":"t","content":"c"}`).Code)
+
+	// Errors of the gRPC server become HTTP codes, unknown fields are ignored
+	rec = serve(router, http.MethodPost, "/posts", token, `{"title":"","content":"c","author_id":1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "title and content are required")
+
+	rec = serve(router, http.MethodPost, "/posts", token, `{"title":"new","content":"c","author_id":1}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created struct {
+		Post map[string]interface{} `json:"post"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, "2", created.Post["author_id"])
+
+	// Content held by the content filter is accepted without a post
+	rec = serve(router, http.MethodPost, "/posts", token, `{"title":"held","content":"c"}`)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	var held map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &held))
+	assert.Equal(t, "5", held["held_id"])
+	assert.Nil(t, held["post"])
+	assert.Equal(t, "0", rec.Header().Get(ratelimit.HeaderRemaining))
+
+	rec = serve(router, http.MethodPost, "/posts", token, `{"title":"new","content":"c"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(ratelimit.HeaderRetry))
+
+	rec = serve(router, http.MethodGet, "/swagger/doc.json", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"/posts/{id}"`)
+}
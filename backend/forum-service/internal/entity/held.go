@@ -0,0 +1,25 @@
+package entity
+
+import "time"
+
+// Kinds of held content
+const (
+	HeldPost  = "post"
+	HeldReply = "reply"
+)
+
+// HeldContent is a post or reply held by the content filter until a moderator reviews it.
+// Rules are the names of the filter rules it matched.
+type HeldContent struct {
+	ID       int64  `json:"id" db:"id"`
+	Kind     string `json:"kind" db:"kind"`
+	PostID   int64  `json:"post_id,omitempty" db:"post_id"`
+	Title    string `json:"title,omitempty" db:"title"`
+	Content  string `json:"content" db:"content"`
+	AuthorID int64  `json:"author_id" db:"author_id"`
+	// Rules are the names of the content filter rules the content matched
+	Rules     []string  `json:"rules" db:"rules"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// PublishedID is the ID of the post or reply created when the content was approved
+	PublishedID int64 `json:"published_id,omitempty" db:"-"`
+}
@@ -1,6 +1,10 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"backend/pkg/unfurl"
+)
 
 // Post represents a forum post
 type Post struct {
@@ -11,6 +15,8 @@ type Post struct {
 	Author    *User     `json:"author,omitempty"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Previews are link previews of the content, attached in the background after the post is saved
+	Previews []unfurl.Preview `json:"previews,omitempty"`
 }
 
 // Reply represents a reply to a post
@@ -26,41 +32,21 @@ type Reply struct {
 
 // CreatePostInput represents input data for creating a new post
 type CreatePostInput struct {
-	Title    string `json:"title" binding:"required,min=1,max=255"`
-	Content  string `json:"content" binding:"required,min=1"`
-	AuthorID int64  `json:"author_id" binding:"required"`
+	Title    string `json:"title"`
+	Content  string `json:"content"`
+	AuthorID int64  `json:"author_id"`
 }
 
 // UpdatePostInput represents input data for updating a post
 type UpdatePostInput struct {
-	Title   string `json:"title" binding:"omitempty,min=1,max=255"`
-	Content string `json:"content" binding:"omitempty,min=1"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
 }
 
 // CreateReplyInput represents input data for creating a new reply
 type CreateReplyInput struct {
-	Content  string `json:"content" binding:"required,min=1"`
-	AuthorID int64  `json:"author_id" binding:"required"`
-}
-
-type CreatePostDTO struct {
-	Title    string `json:"title" validate:"required,min=5,max=200"`
-	Content  string `json:"content" validate:"required,min=10"`
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-}
-
-type UpdatePostDTO struct {
-	Title    *string `json:"title,omitempty" validate:"omitempty,min=5,max=200"`
-	Content  *string `json:"content,omitempty" validate:"omitempty,min=10"`
-	IsLocked *bool   `json:"is_locked,omitempty"`
-}
-
-type CreateReplyDTO struct {
-	PostID   int64  `json:"post_id"`
-	Content  string `json:"content" validate:"required,min=1"`
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
+	Content  string `json:"content"`
+	AuthorID int64  `json:"author_id"`
 }
 
 type ForumStats struct {
@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"forum-service/internal/usecase"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errInternal hides the cause of unexpected errors from clients, it is logged instead
+var errInternal = status.Error(codes.Internal, "internal error")
+
+// errorCode maps use case and database errors to gRPC codes.
+// codes.Unknown means the error is unexpected.
+func errorCode(err error) codes.Code {
+	var held *usecase.HeldError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded), pgconn.Timeout(err):
+		return codes.DeadlineExceeded
+	case errors.Is(err, pgx.ErrNoRows), errors.Is(err, usecase.ErrHeldNotFound):
+		return codes.NotFound
+	case errors.Is(err, usecase.ErrInvalidInput), errors.Is(err, usecase.ErrContentRejected):
+		return codes.InvalidArgument
+	case errors.As(err, &held):
+		return codes.FailedPrecondition
+	case errors.As(err, &pgErr):
+		return pgErrorCode(pgErr)
+	case pgconn.SafeToRetry(err):
+		// The query never reached the database
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
+
+// pgErrorCode maps PostgreSQL error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+func pgErrorCode(err *pgconn.PgError) codes.Code {
+	switch err.Code {
+	case "23505": // unique_violation
+		return codes.AlreadyExists
+	case "23503": // foreign_key_violation
+		return codes.FailedPrecondition
+	case "23502", "23514": // not_null_violation, check_violation
+		return codes.InvalidArgument
+	case "40001", "40P01": // serialization_failure, deadlock_detected
+		return codes.Aborted
+	case "57014": // query_canceled
+		return codes.DeadlineExceeded
+	case "53300", "57P01", "57P03": // too_many_connections, admin_shutdown, cannot_connect_now
+		return codes.Unavailable
+	}
+
+	if len(err.Code) < 2 {
+		return codes.Unknown
+	}
+	switch err.Code[:2] {
+	case "22": // data exception
+		return codes.InvalidArgument
+	case "08": // connection exception
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
+
+// statusError converts err to a gRPC status. what names the requested object
+// in NotFound messages, unexpected errors become errInternal.
+func statusError(err error, what string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	switch code := errorCode(err); code {
+	case codes.Unknown:
+		return errInternal
+	case codes.NotFound:
+		return status.Error(code, what+" not found")
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		return status.Error(code, err.Error())
+	case codes.AlreadyExists:
+		return status.Error(code, what+" already exists")
+	default:
+		return status.Error(code, code.String())
+	}
+}
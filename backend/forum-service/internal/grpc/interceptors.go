@@ -0,0 +1,184 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pb "forum-service/proto"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"backend/pkg/jwt"
+)
+
+// publicMethods may be called anonymously, the other forum methods require an access token.
+// Health checks and reflection are not authenticated at all.
+var publicMethods = map[string]bool{
+	pb.ForumService_GetPost_FullMethodName:     true,
+	pb.ForumService_ListPosts_FullMethodName:   true,
+	pb.ForumService_ListReplies_FullMethodName: true,
+}
+
+const forumServicePrefix = "/forum.ForumService/"
+
+type claimsKey struct{}
+
+// ServerOptions returns the interceptor chains: metrics, logging, panic recovery, authentication
+// and rate limiting of unary calls
+func (s *Server) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.metricsUnary, s.loggingUnary, s.recoveryUnary, s.authUnary, s.rateLimitUnary),
+		grpc.ChainStreamInterceptor(s.metricsStream, s.loggingStream, s.recoveryStream, s.authStream),
+	}
+}
+
+func (s *Server) metricsUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.metrics.observe(info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+func (s *Server) metricsStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.metrics.observe(info.FullMethod, status.Code(err), time.Since(start))
+	return err
+}
+
+func (s *Server) loggingUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.logCall(ctx, info.FullMethod, err, time.Since(start))
+	return resp, err
+}
+
+func (s *Server) loggingStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.logCall(stream.Context(), info.FullMethod, err, time.Since(start))
+	return err
+}
+
+func (s *Server) logCall(ctx context.Context, method string, err error, elapsed time.Duration) {
+	code := status.Code(err)
+	level := zapcore.InfoLevel
+	switch code {
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		level = zapcore.ErrorLevel
+	}
+
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("code", code.String()),
+		zap.Duration("duration", elapsed),
+	}
+	if err != nil {
+		fields = append(fields, zap.String("error", status.Convert(err).Message()))
+	}
+	s.logger.Check(level, "gRPC request").Write(fields...)
+}
+
+func (s *Server) recoveryUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer s.recoverPanic(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func (s *Server) recoveryStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer s.recoverPanic(info.FullMethod, &err)
+	return handler(srv, stream)
+}
+
+// recoverPanic turns a panic of a handler into an Internal error instead of crashing the service
+func (s *Server) recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		s.logger.Error("Panic in gRPC handler",
+			zap.String("method", method), zap.Any("panic", r), zap.Stack("stack"))
+		*err = errInternal
+	}
+}
+
+func (s *Server) authUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: stream, ctx: ctx})
+}
+
+// contextStream replaces the stream context with the authenticated one
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate parses the Bearer token from the authorization metadata and stores its claims.
+// Calls without a token continue anonymously for public methods, invalid tokens and tokens
+// revoked by a logout, ended sessions or a removed role are always rejected.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, forumServicePrefix) {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		if publicMethods[method] {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata is required")
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok || token == "" || s.tokens == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	claims, err := s.tokens.ParseClaims(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	userID, err := claims.UserID()
+	if err != nil || s.revocations != nil && s.revocations.Revoked(userID, claims.IssuedTime()) {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	return context.WithValue(ctx, claimsKey{}, claims), nil
+}
+
+// claimsFromContext returns the claims of the caller or nil for anonymous calls
+func claimsFromContext(ctx context.Context) *jwt.Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*jwt.Claims)
+	return claims
+}
+
+// requireClaims returns the claims of the caller or Unauthenticated
+func requireClaims(ctx context.Context) (*jwt.Claims, int64, error) {
+	claims := claimsFromContext(ctx)
+	if claims == nil {
+		return nil, 0, status.Error(codes.Unauthenticated, "authorization metadata is required")
+	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return nil, 0, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return claims, userID, nil
+}
@@ -0,0 +1,97 @@
+package grpc
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+type callKey struct {
+	method string
+	code   codes.Code
+}
+
+type latency struct {
+	count uint64
+	sum   time.Duration
+}
+
+// Metrics counts gRPC calls by method and code and sums their latency by method.
+// It serves them in the Prometheus text format.
+type Metrics struct {
+	mu        sync.Mutex
+	calls     map[callKey]uint64
+	latencies map[string]*latency
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		calls:     make(map[callKey]uint64),
+		latencies: make(map[string]*latency),
+	}
+}
+
+func (m *Metrics) observe(method string, code codes.Code, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls[callKey{method: method, code: code}]++
+	l, ok := m.latencies[method]
+	if !ok {
+		l = &latency{}
+		m.latencies[method] = l
+	}
+	l.count++
+	l.sum += elapsed
+}
+
+// Calls returns the number of calls of the method that ended with the code
+func (m *Metrics) Calls(method string, code codes.Code) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls[callKey{method: method, code: code}]
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	calls := make([]callKey, 0, len(m.calls))
+	for key := range m.calls {
+		calls = append(calls, key)
+	}
+	sort.Slice(calls, func(i, j int) bool {
+		if calls[i].method != calls[j].method {
+			return calls[i].method < calls[j].method
+		}
+		return calls[i].code < calls[j].code
+	})
+	methods := make([]string, 0, len(m.latencies))
+	for method := range m.latencies {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	// Formatted under the lock and written after it, so a slow scraper does not block calls
+	var b strings.Builder
+	fmt.Fprintln(&b, "# HELP forum_grpc_requests_total Completed gRPC calls.")
+	fmt.Fprintln(&b, "# TYPE forum_grpc_requests_total counter")
+	for _, key := range calls {
+		fmt.Fprintf(&b, "forum_grpc_requests_total{method=%q,code=%q} %d\n", key.method, key.code.String(), m.calls[key])
+	}
+	fmt.Fprintln(&b, "# HELP forum_grpc_request_duration_seconds Latency of gRPC calls.")
+	fmt.Fprintln(&b, "# TYPE forum_grpc_request_duration_seconds summary")
+	for _, method := range methods {
+		l := m.latencies[method]
+		fmt.Fprintf(&b, "forum_grpc_request_duration_seconds_sum{method=%q} %g\n", method, l.sum.Seconds())
+		fmt.Fprintf(&b, "forum_grpc_request_duration_seconds_count{method=%q} %d\n", method, l.count)
+	}
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	pb "forum-service/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"backend/pkg/ratelimit"
+)
+
+// Rate limit rule names
+const (
+	RuleCreatePost  = "posts.create"
+	RuleCreateReply = "replies.create"
+)
+
+// rateLimitedMethods maps methods to the rate limit rules they are checked against
+var rateLimitedMethods = map[string]string{
+	pb.ForumService_CreatePost_FullMethodName:  RuleCreatePost,
+	pb.ForumService_CreateReply_FullMethodName: RuleCreateReply,
+}
+
+// rateLimitUnary rejects calls exceeding the rule of the method with ResourceExhausted.
+// RateLimit-* headers are sent as metadata, the gateway turns them back into HTTP headers.
+func (s *Server) rateLimitUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	rule, ok := rateLimitedMethods[info.FullMethod]
+	if !ok || s.limiter == nil {
+		return handler(ctx, req)
+	}
+	limit, ok := s.limiter.Rule(rule)
+	if !ok {
+		return handler(ctx, req)
+	}
+
+	result := s.limiter.Allow(rule, rateLimitKey(ctx))
+	headers := http.Header{}
+	ratelimit.SetHeaders(headers, limit, result)
+	md := metadata.MD{}
+	for key, values := range headers {
+		md.Append(strings.ToLower(key), values...)
+	}
+	_ = grpc.SetHeader(ctx, md)
+
+	if !result.Allowed {
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry after %ds", ratelimit.Seconds(result.RetryAfter))
+	}
+	return handler(ctx, req)
+}
+
+// rateLimitKey returns the user key for authenticated calls and the peer IP otherwise
+func rateLimitKey(ctx context.Context) string {
+	if claims := claimsFromContext(ctx); claims != nil {
+		if userID, err := claims.UserID(); err == nil {
+			return ratelimit.UserKey(userID)
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return "ip:" + host
+	}
+	return "ip:unknown"
+}
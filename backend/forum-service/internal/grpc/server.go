@@ -2,66 +2,113 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"forum-service/internal/entity"
 	"forum-service/internal/usecase"
 	pb "forum-service/proto"
 
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"backend/pkg/jwt"
+	"backend/pkg/ratelimit"
+	"backend/pkg/rbac"
+	"backend/pkg/revocation"
+	"backend/pkg/unfurl"
+)
+
+// Page sizes of ListPosts
+const (
+	defaultPostsLimit = 10
+	maxPostsLimit     = 100
 )
 
 type Server struct {
 	pb.UnimplementedForumServiceServer
-	postUC usecase.PostUseCase
+	postUC      usecase.PostUseCase
+	tokens      *jwt.Manager
+	revocations *revocation.List
+	limiter     *ratelimit.Limiter
+	logger      *zap.Logger
+	metrics     *Metrics
 }
 
-func NewServer(postUC usecase.PostUseCase) *Server {
+// NewServer creates the forum gRPC service. Access tokens are verified with tokens and
+// rejected when revocations lists a later logout of their user, create calls are limited
+// by limiter and all calls are counted in metrics.
+func NewServer(postUC usecase.PostUseCase, tokens *jwt.Manager, revocations *revocation.List, limiter *ratelimit.Limiter, metrics *Metrics, logger *zap.Logger) *Server {
+	if metrics == nil {
+		metrics = NewMetrics()
+	}
 	return &Server{
-		postUC: postUC,
+		postUC:      postUC,
+		tokens:      tokens,
+		revocations: revocations,
+		limiter:     limiter,
+		logger:      logger,
+		metrics:     metrics,
+	}
+}
+
+// fail converts err to a gRPC status and logs the errors clients do not see
+func (s *Server) fail(ctx context.Context, err error, what string) error {
+	st := statusError(err, what)
+	if st == errInternal {
+		method, _ := grpc.Method(ctx)
+		s.logger.Error("gRPC request failed", zap.String("method", method), zap.Error(err))
 	}
+	return st
+}
+
+// authorize checks that the caller is the author or holds the permission
+func authorize(ctx context.Context, authorID int64, perm string) error {
+	claims, userID, err := requireClaims(ctx)
+	if err != nil {
+		return err
+	}
+	if userID != authorID && !claims.Has(perm) {
+		return status.Error(codes.PermissionDenied, "permission required: "+perm)
+	}
+	return nil
 }
 
 func (s *Server) GetPost(ctx context.Context, req *pb.GetPostRequest) (*pb.Post, error) {
 	post, err := s.postUC.GetByID(ctx, req.Id)
 	if err != nil {
-		return nil, status.Error(codes.NotFound, "post not found")
+		return nil, s.fail(ctx, err, "post")
 	}
 
-	return &pb.Post{
-		Id:      post.ID,
-		Title:   post.Title,
-		Content: post.Content,
-		UserId:  int32(post.AuthorID),
-		Author: &pb.User{
-			Id:       int32(post.AuthorID),
-			Username: post.Author.Username,
-		},
-		CreatedAt: timestamppb.New(post.CreatedAt),
-		UpdatedAt: timestamppb.New(post.UpdatedAt),
-	}, nil
+	return toPost(post), nil
 }
 
 func (s *Server) ListPosts(ctx context.Context, req *pb.ListPostsRequest) (*pb.ListPostsResponse, error) {
-	posts, total, err := s.postUC.GetAll(ctx, int(req.Offset), int(req.Limit))
+	if req.Offset < 0 || req.Limit < 0 || req.Page < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset, limit and page must not be negative")
+	}
+
+	limit := int(req.Limit)
+	switch {
+	case limit == 0:
+		limit = defaultPostsLimit
+	case limit > maxPostsLimit:
+		limit = maxPostsLimit
+	}
+	offset := int(req.Offset)
+	if req.Page > 0 {
+		offset = (int(req.Page) - 1) * limit
+	}
+
+	posts, total, err := s.postUC.GetAll(ctx, offset, limit)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to list posts")
+		return nil, s.fail(ctx, err, "post")
 	}
 
 	pbPosts := make([]*pb.Post, len(posts))
 	for i, post := range posts {
-		pbPosts[i] = &pb.Post{
-			Id:      post.ID,
-			Title:   post.Title,
-			Content: post.Content,
-			UserId:  int32(post.AuthorID),
-			Author: &pb.User{
-				Id:       int32(post.AuthorID),
-				Username: post.Author.Username,
-			},
-			CreatedAt: timestamppb.New(post.CreatedAt),
-			UpdatedAt: timestamppb.New(post.UpdatedAt),
-		}
+		pbPosts[i] = toPost(post)
 	}
 
 	return &pb.ListPostsResponse{
@@ -71,107 +118,260 @@ func (s *Server) ListPosts(ctx context.Context, req *pb.ListPostsRequest) (*pb.L
 }
 
 func (s *Server) CreatePost(ctx context.Context, req *pb.CreatePostRequest) (*pb.CreatePostResponse, error) {
+	_, userID, err := requireClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	input := entity.CreatePostInput{
 		Title:    req.Title,
 		Content:  req.Content,
-		AuthorID: int64(req.UserId),
+		AuthorID: userID,
 	}
 
 	post, err := s.postUC.Create(ctx, input)
+	var held *usecase.HeldError
+	if errors.As(err, &held) {
+		return &pb.CreatePostResponse{HeldId: held.ID}, nil
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create post")
+		return nil, s.fail(ctx, err, "post")
 	}
 
 	return &pb.CreatePostResponse{
-		Id: post.ID,
+		Id:   post.ID,
+		Post: toPost(post),
 	}, nil
 }
 
 func (s *Server) UpdatePost(ctx context.Context, req *pb.UpdatePostRequest) (*pb.Post, error) {
+	post, err := s.postUC.GetByID(ctx, req.Id)
+	if err != nil {
+		return nil, s.fail(ctx, err, "post")
+	}
+	if err := authorize(ctx, post.AuthorID, rbac.PostEditAny); err != nil {
+		return nil, err
+	}
+
 	input := entity.UpdatePostInput{
 		Title:   req.Title,
 		Content: req.Content,
 	}
 
-	err := s.postUC.Update(ctx, req.Id, input)
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to update post")
+	if err := s.postUC.Update(ctx, req.Id, input); err != nil {
+		return nil, s.fail(ctx, err, "post")
 	}
 
 	// Fetch the updated post
-	post, err := s.postUC.GetByID(ctx, req.Id)
+	post, err = s.postUC.GetByID(ctx, req.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get updated post")
+		return nil, s.fail(ctx, err, "post")
 	}
 
-	return &pb.Post{
-		Id:        post.ID,
-		Title:     post.Title,
-		Content:   post.Content,
-		UserId:    int32(post.AuthorID),
-		CreatedAt: timestamppb.New(post.CreatedAt),
-		UpdatedAt: timestamppb.New(post.UpdatedAt),
-	}, nil
+	return toPost(post), nil
 }
 
 func (s *Server) DeletePost(ctx context.Context, req *pb.DeletePostRequest) (*pb.DeletePostResponse, error) {
-	err := s.postUC.Delete(ctx, req.Id)
+	post, err := s.postUC.GetByID(ctx, req.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to delete post")
+		return nil, s.fail(ctx, err, "post")
+	}
+	if err := authorize(ctx, post.AuthorID, rbac.PostDeleteAny); err != nil {
+		return nil, err
+	}
+
+	if err := s.postUC.Delete(ctx, req.Id); err != nil {
+		return nil, s.fail(ctx, err, "post")
 	}
 
 	return &pb.DeletePostResponse{}, nil
 }
 
 func (s *Server) ListReplies(ctx context.Context, req *pb.ListRepliesRequest) (*pb.ListRepliesResponse, error) {
+	if req.Offset < 0 || req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset and limit must not be negative")
+	}
+
 	replies, err := s.postUC.GetReplies(ctx, req.PostId)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get replies")
-	}
-
-	pbReplies := make([]*pb.Reply, len(replies))
-	for i, reply := range replies {
-		pbReplies[i] = &pb.Reply{
-			Id:      reply.ID,
-			PostId:  reply.PostID,
-			Content: reply.Content,
-			UserId:  int32(reply.AuthorID),
-			Author: &pb.User{
-				Id:       int32(reply.AuthorID),
-				Username: reply.Author.Username,
-			},
-			CreatedAt: timestamppb.New(reply.CreatedAt),
-			UpdatedAt: timestamppb.New(reply.UpdatedAt),
-		}
+		return nil, s.fail(ctx, err, "post")
+	}
+
+	total := len(replies)
+	page := replies[min(int(req.Offset), total):]
+	if req.Limit > 0 {
+		page = page[:min(int(req.Limit), len(page))]
+	}
+
+	pbReplies := make([]*pb.Reply, len(page))
+	for i, reply := range page {
+		pbReplies[i] = toReply(reply)
 	}
 
 	return &pb.ListRepliesResponse{
 		Replies: pbReplies,
-		Total:   int32(len(replies)),
+		Total:   int32(total),
 	}, nil
 }
 
 func (s *Server) CreateReply(ctx context.Context, req *pb.CreateReplyRequest) (*pb.CreateReplyResponse, error) {
+	_, userID, err := requireClaims(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	input := entity.CreateReplyInput{
 		Content:  req.Content,
-		AuthorID: int64(req.UserId),
+		AuthorID: userID,
 	}
 
 	reply, err := s.postUC.CreateReply(ctx, req.PostId, input)
+	var held *usecase.HeldError
+	if errors.As(err, &held) {
+		return &pb.CreateReplyResponse{HeldId: held.ID}, nil
+	}
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to create reply")
+		return nil, s.fail(ctx, err, "post")
 	}
 
 	return &pb.CreateReplyResponse{
-		Id: reply.ID,
+		Id:    reply.ID,
+		Reply: toReply(reply),
 	}, nil
 }
 
 func (s *Server) DeleteReply(ctx context.Context, req *pb.DeleteReplyRequest) (*pb.DeleteReplyResponse, error) {
-	err := s.postUC.DeleteReply(ctx, req.Id)
+	reply, err := s.postUC.GetReply(ctx, req.Id)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to delete reply")
+		return nil, s.fail(ctx, err, "reply")
+	}
+	// post_id is part of the REST path, a reply of another post is not found under it
+	if req.PostId != 0 && reply.PostID != req.PostId {
+		return nil, status.Error(codes.NotFound, "reply not found")
+	}
+	if err := authorize(ctx, reply.AuthorID, rbac.ReplyDeleteAny); err != nil {
+		return nil, err
+	}
+
+	if err := s.postUC.DeleteReply(ctx, req.Id); err != nil {
+		return nil, s.fail(ctx, err, "reply")
 	}
 
 	return &pb.DeleteReplyResponse{}, nil
 }
+
+func (s *Server) ListHeld(ctx context.Context, req *pb.ListHeldRequest) (*pb.ListHeldResponse, error) {
+	if err := requirePermission(ctx, rbac.PostEditAny); err != nil {
+		return nil, err
+	}
+
+	items, err := s.postUC.ListHeld(ctx, int(req.Limit))
+	if err != nil {
+		return nil, s.fail(ctx, err, "held content")
+	}
+
+	pbItems := make([]*pb.HeldContent, len(items))
+	for i, held := range items {
+		pbItems[i] = toHeld(held)
+	}
+
+	return &pb.ListHeldResponse{Items: pbItems}, nil
+}
+
+func (s *Server) ReviewHeld(ctx context.Context, req *pb.ReviewHeldRequest) (*pb.HeldContent, error) {
+	if err := requirePermission(ctx, rbac.PostEditAny); err != nil {
+		return nil, err
+	}
+
+	var approve bool
+	switch req.Decision {
+	case "approve":
+		approve = true
+	case "decline":
+	default:
+		return nil, status.Error(codes.InvalidArgument, "decision must be approve or decline")
+	}
+
+	held, err := s.postUC.ReviewHeld(ctx, req.Id, approve)
+	if err != nil {
+		return nil, s.fail(ctx, err, "held content")
+	}
+
+	return toHeld(held), nil
+}
+
+// requirePermission rejects callers without the permission
+func requirePermission(ctx context.Context, perm string) error {
+	claims, _, err := requireClaims(ctx)
+	if err != nil {
+		return err
+	}
+	if !claims.Has(perm) {
+		return status.Error(codes.PermissionDenied, "permission required: "+perm)
+	}
+	return nil
+}
+
+func toUser(id int64, author *entity.User) *pb.User {
+	user := &pb.User{Id: id}
+	if author != nil {
+		user.Username = author.Username
+	}
+	return user
+}
+
+func toPost(post *entity.Post) *pb.Post {
+	return &pb.Post{
+		Id:        post.ID,
+		Title:     post.Title,
+		Content:   post.Content,
+		AuthorId:  post.AuthorID,
+		Author:    toUser(post.AuthorID, post.Author),
+		CreatedAt: timestamppb.New(post.CreatedAt),
+		UpdatedAt: timestamppb.New(post.UpdatedAt),
+		Previews:  toPreviews(post.Previews),
+	}
+}
+
+func toPreviews(previews []unfurl.Preview) []*pb.LinkPreview {
+	if len(previews) == 0 {
+		return nil
+	}
+	result := make([]*pb.LinkPreview, len(previews))
+	for i, p := range previews {
+		result[i] = &pb.LinkPreview{
+			Url:         p.URL,
+			Title:       p.Title,
+			Description: p.Description,
+			Image:       p.Image,
+			SiteName:    p.SiteName,
+		}
+	}
+	return result
+}
+
+func toReply(reply *entity.Reply) *pb.Reply {
+	return &pb.Reply{
+		Id:        reply.ID,
+		PostId:    reply.PostID,
+		Content:   reply.Content,
+		AuthorId:  reply.AuthorID,
+		Author:    toUser(reply.AuthorID, reply.Author),
+		CreatedAt: timestamppb.New(reply.CreatedAt),
+		UpdatedAt: timestamppb.New(reply.UpdatedAt),
+	}
+}
+
+func toHeld(held *entity.HeldContent) *pb.HeldContent {
+	return &pb.HeldContent{
+		Id:          held.ID,
+		Kind:        held.Kind,
+		PostId:      held.PostID,
+		Title:       held.Title,
+		Content:     held.Content,
+		AuthorId:    held.AuthorID,
+		Rules:       held.Rules,
+		CreatedAt:   timestamppb.New(held.CreatedAt),
+		PublishedId: held.PublishedID,
+	}
+}
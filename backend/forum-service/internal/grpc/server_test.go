@@ -0,0 +1,253 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"forum-service/internal/entity"
+	"forum-service/internal/usecase"
+	pb "forum-service/proto"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"backend/pkg/jwt"
+	"backend/pkg/ratelimit"
+	"backend/pkg/rbac"
+	"backend/pkg/revocation"
+)
+
+// fakePosts keeps posts in memory, failing GetByID with err when it is set
+type fakePosts struct {
+	usecase.PostUseCase
+	posts   map[int64]*entity.Post
+	created []entity.CreatePostInput
+	hold    bool
+	err     error
+}
+
+func (f *fakePosts) GetByID(ctx context.Context, id int64) (*entity.Post, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	post, ok := f.posts[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return post, nil
+}
+
+func (f *fakePosts) GetAll(ctx context.Context, offset, limit int) ([]*entity.Post, int, error) {
+	panic("boom")
+}
+
+func (f *fakePosts) Create(ctx context.Context, input entity.CreatePostInput) (*entity.Post, error) {
+	f.created = append(f.created, input)
+	if f.hold {
+		return nil, &usecase.HeldError{ID: 7}
+	}
+	post := &entity.Post{ID: int64(len(f.posts) + 1), Title: input.Title, Content: input.Content, AuthorID: input.AuthorID}
+	f.posts[post.ID] = post
+	return post, nil
+}
+
+func (f *fakePosts) Delete(ctx context.Context, id int64) error {
+	delete(f.posts, id)
+	return nil
+}
+
+func startServer(t *testing.T, posts usecase.PostUseCase, tokens *jwt.Manager, revocations *revocation.List, limiter *ratelimit.Limiter) (*grpc.ClientConn, *Metrics) {
+	t.Helper()
+
+	metrics := NewMetrics()
+	handler := NewServer(posts, tokens, revocations, limiter, metrics, zap.NewNop())
+	server := grpc.NewServer(handler.ServerOptions()...)
+	pb.RegisterForumServiceServer(server, handler)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, metrics
+}
+
+func withToken(t *testing.T, tokens *jwt.Manager, userID int, access rbac.Access) context.Context {
+	t.Helper()
+	token, err := tokens.NewAccessToken(userID, fmt.Sprintf("user%d", userID), access, time.Minute)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestServer_Auth(t *testing.T) {
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	posts := &fakePosts{posts: map[int64]*entity.Post{1: {ID: 1, Title: "t", Content: "c", AuthorID: 1}}}
+	conn, _ := startServer(t, posts, tokens, nil, nil)
+	client := pb.NewForumServiceClient(conn)
+
+	// Reads are public and a post without a loaded author is returned without it
+	post, err := client.GetPost(context.Background(), &pb.GetPostRequest{Id: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), post.Author.Id)
+	assert.Empty(t, post.Author.Username)
+
+	_, err = client.CreatePost(context.Background(), &pb.CreatePostRequest{Title: "t", Content: "c"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	bad := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope")
+	_, err = client.GetPost(bad, &pb.GetPostRequest{Id: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// The author comes from the token
+	resp, err := client.CreatePost(withToken(t, tokens, 2, rbac.Access{}), &pb.CreatePostRequest{Title: "t", Content: "c"})
+	require.NoError(t, err)
+	assert.NotZero(t, resp.Id)
+	assert.Equal(t, resp.Id, resp.Post.Id)
+	assert.Equal(t, int64(2), resp.Post.AuthorId)
+	assert.Equal(t, int64(2), posts.created[0].AuthorID)
+
+	_, err = client.DeletePost(withToken(t, tokens, 2, rbac.Access{}), &pb.DeletePostRequest{Id: 1})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.DeletePost(withToken(t, tokens, 3, rbac.Access{Permissions: []string{rbac.PostDeleteAny}}), &pb.DeletePostRequest{Id: 1})
+	assert.NoError(t, err)
+
+	_, err = client.ListHeld(withToken(t, tokens, 2, rbac.Access{}), &pb.ListHeldRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// Health checks do not need a token
+	health, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, health.Status)
+}
+
+func TestServer_RevokedToken(t *testing.T) {
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	posts := &fakePosts{posts: map[int64]*entity.Post{1: {ID: 1, AuthorID: 2}}}
+	revocations := revocation.NewList()
+	conn, _ := startServer(t, posts, tokens, revocations, nil)
+	client := pb.NewForumServiceClient(conn)
+
+	old := withToken(t, tokens, 2, rbac.Access{})
+	_, err = client.GetPost(old, &pb.GetPostRequest{Id: 1})
+	require.NoError(t, err)
+
+	// After a logout the signature is still valid, but the token is rejected even on public methods
+	revocations.Add(revocation.Revocation{UserID: 2, RevokedAt: time.Now()})
+	_, err = client.GetPost(old, &pb.GetPostRequest{Id: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.DeletePost(old, &pb.DeletePostRequest{Id: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// Tokens of the next login and of other users are accepted
+	_, err = client.DeletePost(withToken(t, tokens, 2, rbac.Access{}), &pb.DeletePostRequest{Id: 1})
+	assert.NoError(t, err)
+	_, err = client.GetPost(withToken(t, tokens, 3, rbac.Access{}), &pb.GetPostRequest{Id: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestServer_Errors(t *testing.T) {
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	posts := &fakePosts{posts: map[int64]*entity.Post{}, hold: true}
+	conn, metrics := startServer(t, posts, tokens, nil, nil)
+	client := pb.NewForumServiceClient(conn)
+
+	_, err = client.GetPost(context.Background(), &pb.GetPostRequest{Id: 42})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	resp, err := client.CreatePost(withToken(t, tokens, 1, rbac.Access{}), &pb.CreatePostRequest{Title: "t", Content: "c"})
+	require.NoError(t, err)
+	assert.Zero(t, resp.Id)
+	assert.Equal(t, int64(7), resp.HeldId)
+
+	posts.err = errors.New("connection reset")
+	_, err = client.GetPost(context.Background(), &pb.GetPostRequest{Id: 42})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, "internal error", status.Convert(err).Message())
+
+	moderator := withToken(t, tokens, 1, rbac.Access{Permissions: []string{rbac.PostEditAny}})
+	_, err = client.ReviewHeld(moderator, &pb.ReviewHeldRequest{Id: 7, Decision: "maybe"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// A panic becomes Internal and the server keeps serving
+	_, err = client.ListPosts(context.Background(), &pb.ListPostsRequest{Limit: 10})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, uint64(1), metrics.Calls(pb.ForumService_ListPosts_FullMethodName, codes.Internal))
+	assert.Equal(t, uint64(1), metrics.Calls(pb.ForumService_GetPost_FullMethodName, codes.NotFound))
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	tokens, err := jwt.NewManager("secret")
+	require.NoError(t, err)
+	rules, err := ratelimit.ParseRules(RuleCreatePost + "=1/1m")
+	require.NoError(t, err)
+	posts := &fakePosts{posts: map[int64]*entity.Post{}}
+	conn, _ := startServer(t, posts, tokens, nil, ratelimit.New(rules))
+	client := pb.NewForumServiceClient(conn)
+
+	var header metadata.MD
+	_, err = client.CreatePost(withToken(t, tokens, 1, rbac.Access{}), &pb.CreatePostRequest{Title: "t", Content: "c"}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	_, err = client.CreatePost(withToken(t, tokens, 1, rbac.Access{}), &pb.CreatePostRequest{Title: "t", Content: "c"}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+
+	// Limits are kept per user
+	_, err = client.CreatePost(withToken(t, tokens, 2, rbac.Access{}), &pb.CreatePostRequest{Title: "t", Content: "c"})
+	assert.NoError(t, err)
+	assert.Len(t, posts.created, 2)
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{pgx.ErrNoRows, codes.NotFound},
+		{fmt.Errorf("get post: %w", pgx.ErrNoRows), codes.NotFound},
+		{usecase.ErrHeldNotFound, codes.NotFound},
+		{fmt.Errorf("%w: title and content are required", usecase.ErrInvalidInput), codes.InvalidArgument},
+		{usecase.ErrContentRejected, codes.InvalidArgument},
+		{&usecase.HeldError{ID: 1}, codes.FailedPrecondition},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{context.Canceled, codes.Canceled},
+		{&pgconn.PgError{Code: "23505"}, codes.AlreadyExists},
+		{&pgconn.PgError{Code: "23503"}, codes.FailedPrecondition},
+		{&pgconn.PgError{Code: "22P02"}, codes.InvalidArgument},
+		{&pgconn.PgError{Code: "40P01"}, codes.Aborted},
+		{&pgconn.PgError{Code: "08006"}, codes.Unavailable},
+		{&pgconn.PgError{Code: "XX000"}, codes.Unknown},
+		{errors.New("unexpected"), codes.Unknown},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.code, errorCode(tt.err), tt.err.Error())
+	}
+}
@@ -32,6 +32,33 @@ func InitDB(ctx context.Context, pool *pgxpool.Pool) error {
 		
 		CREATE INDEX IF NOT EXISTS idx_replies_post_id ON replies(post_id);
 		CREATE INDEX IF NOT EXISTS idx_replies_author_id ON replies(author_id);
+
+		CREATE TABLE IF NOT EXISTS held_content (
+			id BIGSERIAL PRIMARY KEY,
+			kind VARCHAR(16) NOT NULL,
+			post_id BIGINT NOT NULL DEFAULT 0,
+			title VARCHAR(255) NOT NULL DEFAULT '',
+			content TEXT NOT NULL,
+			author_id BIGINT NOT NULL,
+			rules TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS link_previews (
+			url TEXT PRIMARY KEY,
+			title TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			image TEXT NOT NULL DEFAULT '',
+			site_name TEXT NOT NULL DEFAULT '',
+			fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS post_previews (
+			post_id BIGINT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+			previews JSONB NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
 	`)
 	return err
 }
@@ -30,6 +30,32 @@ func InitDatabase(ctx context.Context, pool *pgxpool.Pool) error {
 			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 		)`,
+		// Посты и ответы, задержанные фильтром до проверки модератором
+		`CREATE TABLE IF NOT EXISTS held_content (
+			id BIGSERIAL PRIMARY KEY,
+			kind VARCHAR(16) NOT NULL,
+			post_id BIGINT NOT NULL DEFAULT 0,
+			title VARCHAR(255) NOT NULL DEFAULT '',
+			content TEXT NOT NULL,
+			author_id BIGINT NOT NULL,
+			rules TEXT[] NOT NULL DEFAULT '{}',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		// Кэш превью ссылок и превью, прикрепленные к постам
+		`CREATE TABLE IF NOT EXISTS link_previews (
+			url TEXT PRIMARY KEY,
+			title TEXT NOT NULL DEFAULT '',
+			description TEXT NOT NULL DEFAULT '',
+			image TEXT NOT NULL DEFAULT '',
+			site_name TEXT NOT NULL DEFAULT '',
+			fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS post_previews (
+			post_id BIGINT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+			previews JSONB NOT NULL,
+			updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
 		// Добавим тестового пользователя, если его еще нет
 		`INSERT INTO users (username) 
 		VALUES ('testuser') 
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"forum-service/internal/entity"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ErrHeldNotFound is returned when the held content does not exist or was already reviewed
+var ErrHeldNotFound = errors.New("held content not found")
+
+// maxReputation caps the number of posts and replies counted for reputation
+const maxReputation = 1000
+
+// ModerationRepository stores content held by the content filter
+type ModerationRepository interface {
+	Hold(ctx context.Context, held *entity.HeldContent) error
+	// ListHeld returns held content, oldest first
+	ListHeld(ctx context.Context, limit int) ([]*entity.HeldContent, error)
+	// TakeHeld removes held content from the queue and returns it
+	TakeHeld(ctx context.Context, id int64) (*entity.HeldContent, error)
+	// Reputation returns the number of posts and replies of the user, at most maxReputation each
+	Reputation(ctx context.Context, userID int64) (int, error)
+}
+
+type moderationRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewModerationRepository(pool *pgxpool.Pool) ModerationRepository {
+	return &moderationRepository{
+		pool: pool,
+	}
+}
+
+const heldColumns = `id, kind, post_id, title, content, author_id, rules, created_at`
+
+func scanHeld(row pgx.Row) (*entity.HeldContent, error) {
+	held := &entity.HeldContent{}
+	err := row.Scan(
+		&held.ID,
+		&held.Kind,
+		&held.PostID,
+		&held.Title,
+		&held.Content,
+		&held.AuthorID,
+		&held.Rules,
+		&held.CreatedAt,
+	)
+	return held, err
+}
+
+func (r *moderationRepository) Hold(ctx context.Context, held *entity.HeldContent) error {
+	query := `
+		INSERT INTO held_content (kind, post_id, title, content, author_id, rules)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	return r.pool.QueryRow(ctx, query,
+		held.Kind,
+		held.PostID,
+		held.Title,
+		held.Content,
+		held.AuthorID,
+		held.Rules,
+	).Scan(&held.ID, &held.CreatedAt)
+}
+
+func (r *moderationRepository) ListHeld(ctx context.Context, limit int) ([]*entity.HeldContent, error) {
+	query := `
+		SELECT ` + heldColumns + `
+		FROM held_content
+		ORDER BY id
+		LIMIT $1
+	`
+
+	rows, err := r.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*entity.HeldContent
+	for rows.Next() {
+		held, err := scanHeld(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, held)
+	}
+
+	return items, rows.Err()
+}
+
+func (r *moderationRepository) TakeHeld(ctx context.Context, id int64) (*entity.HeldContent, error) {
+	query := `DELETE FROM held_content WHERE id = $1 RETURNING ` + heldColumns
+
+	held, err := scanHeld(r.pool.QueryRow(ctx, query, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrHeldNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+func (r *moderationRepository) Reputation(ctx context.Context, userID int64) (int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM (SELECT 1 FROM posts WHERE author_id = $1 LIMIT $2) p) +
+			(SELECT COUNT(*) FROM (SELECT 1 FROM replies WHERE author_id = $1 LIMIT $2) r)
+	`
+
+	var count int
+	err := r.pool.QueryRow(ctx, query, userID, maxReputation).Scan(&count)
+	return count, err
+}
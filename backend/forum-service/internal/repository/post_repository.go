@@ -5,6 +5,7 @@ import (
 	"forum-service/internal/entity"
 	"time"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
@@ -15,6 +16,7 @@ type PostRepository interface {
 	Update(ctx context.Context, id int64, input entity.UpdatePostInput) error
 	Delete(ctx context.Context, id int64) error
 	GetReplies(ctx context.Context, postID int64) ([]*entity.Reply, error)
+	GetReply(ctx context.Context, id int64) (*entity.Reply, error)
 	CreateReply(ctx context.Context, postID int64, input entity.CreateReplyInput) (*entity.Reply, error)
 	DeleteReply(ctx context.Context, id int64) error
 }
@@ -122,19 +124,19 @@ func (r *postRepository) Update(ctx context.Context, id int64, input entity.Upda
 	`
 
 	now := time.Now()
-	_, err := r.pool.Exec(ctx, query,
+	tag, err := r.pool.Exec(ctx, query,
 		input.Title,
 		input.Content,
 		now,
 		id,
 	)
-	return err
+	return affected(tag.RowsAffected(), err)
 }
 
 func (r *postRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM posts WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
-	return err
+	tag, err := r.pool.Exec(ctx, query, id)
+	return affected(tag.RowsAffected(), err)
 }
 
 func (r *postRepository) GetReplies(ctx context.Context, postID int64) ([]*entity.Reply, error) {
@@ -171,6 +173,29 @@ func (r *postRepository) GetReplies(ctx context.Context, postID int64) ([]*entit
 	return replies, nil
 }
 
+func (r *postRepository) GetReply(ctx context.Context, id int64) (*entity.Reply, error) {
+	query := `
+		SELECT id, post_id, content, author_id, created_at, updated_at
+		FROM replies
+		WHERE id = $1
+	`
+
+	reply := &entity.Reply{}
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&reply.ID,
+		&reply.PostID,
+		&reply.Content,
+		&reply.AuthorID,
+		&reply.CreatedAt,
+		&reply.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply, nil
+}
+
 func (r *postRepository) CreateReply(ctx context.Context, postID int64, input entity.CreateReplyInput) (*entity.Reply, error) {
 	query := `
 		INSERT INTO replies (post_id, content, author_id, created_at, updated_at)
@@ -200,6 +225,18 @@ func (r *postRepository) CreateReply(ctx context.Context, postID int64, input en
 
 func (r *postRepository) DeleteReply(ctx context.Context, id int64) error {
 	query := `DELETE FROM replies WHERE id = $1`
-	_, err := r.pool.Exec(ctx, query, id)
-	return err
+	tag, err := r.pool.Exec(ctx, query, id)
+	return affected(tag.RowsAffected(), err)
+}
+
+// affected returns pgx.ErrNoRows when a statement changed no rows,
+// so callers report a missing post or reply the same way as GetByID
+func affected(rows int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
 }
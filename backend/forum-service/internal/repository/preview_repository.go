@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+
+	"backend/pkg/unfurl"
+)
+
+// PreviewRepository stores link previews attached to posts
+type PreviewRepository interface {
+	// SetPostPreviews replaces the previews of the post, an empty list removes them
+	SetPostPreviews(ctx context.Context, postID int64, previews []unfurl.Preview) error
+	// PostPreviews returns the previews of the posts that have them
+	PostPreviews(ctx context.Context, postIDs []int64) (map[int64][]unfurl.Preview, error)
+}
+
+type previewRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPreviewRepository(pool *pgxpool.Pool) PreviewRepository {
+	return &previewRepository{
+		pool: pool,
+	}
+}
+
+func (r *previewRepository) SetPostPreviews(ctx context.Context, postID int64, previews []unfurl.Preview) error {
+	if len(previews) == 0 {
+		_, err := r.pool.Exec(ctx, `DELETE FROM post_previews WHERE post_id = $1`, postID)
+		return err
+	}
+
+	data, err := json.Marshal(previews)
+	if err != nil {
+		return err
+	}
+
+	// The post may be deleted while its links are loaded, then nothing is stored
+	query := `
+		INSERT INTO post_previews (post_id, previews, updated_at)
+		SELECT id, $2, NOW() FROM posts WHERE id = $1
+		ON CONFLICT (post_id) DO UPDATE SET previews = EXCLUDED.previews, updated_at = EXCLUDED.updated_at
+	`
+	_, err = r.pool.Exec(ctx, query, postID, data)
+	return err
+}
+
+func (r *previewRepository) PostPreviews(ctx context.Context, postIDs []int64) (map[int64][]unfurl.Preview, error) {
+	result := make(map[int64][]unfurl.Preview)
+	if len(postIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.pool.Query(ctx, `SELECT post_id, previews FROM post_previews WHERE post_id = ANY($1)`, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int64
+		var data []byte
+		if err := rows.Scan(&postID, &data); err != nil {
+			return nil, err
+		}
+		var previews []unfurl.Preview
+		if err := json.Unmarshal(data, &previews); err != nil {
+			return nil, err
+		}
+		result[postID] = previews
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"forum-service/internal/entity"
+	"forum-service/internal/repository"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"backend/pkg/contentfilter"
+)
+
+var (
+	ErrContentRejected = errors.New("content rejected by content filter")
+	ErrContentHeld     = errors.New("content is held for moderation")
+	ErrHeldNotFound    = repository.ErrHeldNotFound
+)
+
+const defaultHeldLimit = 50
+
+// HeldError is returned when the content filter holds a post or reply for moderation
+type HeldError struct {
+	ID int64
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("%v (queue id %d)", ErrContentHeld, e.ID)
+}
+
+func (e *HeldError) Unwrap() error {
+	return ErrContentHeld
+}
+
+// Option configures the post use case
+type Option func(*postUseCase)
+
+// WithContentFilter checks posts and replies before they are stored
+func WithContentFilter(filter *contentfilter.Pipeline) Option {
+	return func(uc *postUseCase) {
+		uc.filter = filter
+	}
+}
+
+// WithModeration keeps held content for review. Without it held content is rejected.
+func WithModeration(moderation repository.ModerationRepository) Option {
+	return func(uc *postUseCase) {
+		uc.moderation = moderation
+	}
+}
+
+// fieldSeparator joins the title and the content so that both are checked in one pass
+const fieldSeparator = "\n\n"
+
+// screen runs the title and the content through the filter and returns them masked
+// if needed. Held content is stored for review and reported with HeldError;
+// edits cannot be held, so held is nil for them and they are rejected instead.
+func (uc *postUseCase) screen(ctx context.Context, kind string, authorID int64, title, content string, held *entity.HeldContent) (string, string, error) {
+	text := content
+	if title != "" {
+		text = title + fieldSeparator + content
+	}
+
+	verdict := uc.filter.Check(ctx, contentfilter.Content{Kind: kind, UserID: authorID, Text: text})
+	if verdict.Flagged() {
+		log.Printf("content filter: %s of user %d matched %s, action %s (dry run %t)",
+			kind, authorID, strings.Join(verdict.Rules(), ","), verdict.Intended, verdict.DryRun)
+	}
+
+	switch verdict.Action {
+	case contentfilter.Mask:
+		if title == "" {
+			return title, verdict.Text, nil
+		}
+		// Masking replaces every rune with one asterisk, so the title keeps its length in runes
+		masked := []rune(verdict.Text)
+		n := utf8.RuneCountInString(title)
+		return string(masked[:n]), string(masked[n+utf8.RuneCountInString(fieldSeparator):]), nil
+	case contentfilter.Hold:
+		if held == nil || uc.moderation == nil {
+			return "", "", ErrContentRejected
+		}
+		held.Rules = verdict.Rules()
+		if err := uc.moderation.Hold(ctx, held); err != nil {
+			return "", "", err
+		}
+		return "", "", &HeldError{ID: held.ID}
+	case contentfilter.Reject:
+		return "", "", ErrContentRejected
+	}
+	return title, content, nil
+}
+
+func (uc *postUseCase) ListHeld(ctx context.Context, limit int) ([]*entity.HeldContent, error) {
+	if uc.moderation == nil {
+		return nil, nil
+	}
+	if limit <= 0 || limit > 200 {
+		limit = defaultHeldLimit
+	}
+	return uc.moderation.ListHeld(ctx, limit)
+}
+
+// ReviewHeld publishes held content when approved and drops it otherwise.
+// The returned content has PublishedID set when it was published.
+func (uc *postUseCase) ReviewHeld(ctx context.Context, id int64, approve bool) (*entity.HeldContent, error) {
+	if uc.moderation == nil {
+		return nil, ErrHeldNotFound
+	}
+
+	held, err := uc.moderation.TakeHeld(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("held %s %d of user %d: approved %t", held.Kind, held.ID, held.AuthorID, approve)
+	if !approve {
+		return held, nil
+	}
+
+	if err := uc.publishHeld(ctx, held); err != nil {
+		// Put it back so that it can be reviewed again
+		if holdErr := uc.moderation.Hold(ctx, held); holdErr != nil {
+			log.Printf("failed to return held %s of user %d: %v", held.Kind, held.AuthorID, holdErr)
+		}
+		return nil, err
+	}
+	return held, nil
+}
+
+func (uc *postUseCase) publishHeld(ctx context.Context, held *entity.HeldContent) error {
+	if held.Kind == entity.HeldReply {
+		reply, err := uc.postRepo.CreateReply(ctx, held.PostID, entity.CreateReplyInput{
+			Content:  held.Content,
+			AuthorID: held.AuthorID,
+		})
+		if err != nil {
+			return err
+		}
+		held.PublishedID = reply.ID
+		return nil
+	}
+
+	id, err := uc.postRepo.Create(ctx, entity.CreatePostInput{
+		Title:    held.Title,
+		Content:  held.Content,
+		AuthorID: held.AuthorID,
+	})
+	if err != nil {
+		return err
+	}
+	held.PublishedID = id
+	uc.unfurlPost(id, held.Content, false)
+	return nil
+}
@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"context"
+	"forum-service/internal/entity"
+	"testing"
+
+	"backend/pkg/contentfilter"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryModeration struct {
+	held []*entity.HeldContent
+}
+
+func (m *memoryModeration) Hold(ctx context.Context, held *entity.HeldContent) error {
+	held.ID = int64(len(m.held) + 1)
+	m.held = append(m.held, held)
+	return nil
+}
+
+func (m *memoryModeration) ListHeld(ctx context.Context, limit int) ([]*entity.HeldContent, error) {
+	return m.held, nil
+}
+
+func (m *memoryModeration) TakeHeld(ctx context.Context, id int64) (*entity.HeldContent, error) {
+	for i, held := range m.held {
+		if held.ID == id {
+			m.held = append(m.held[:i], m.held[i+1:]...)
+			return held, nil
+		}
+	}
+	return nil, ErrHeldNotFound
+}
+
+func (m *memoryModeration) Reputation(ctx context.Context, userID int64) (int, error) {
+	return 0, nil
+}
+
+func newTestFilter() *contentfilter.Pipeline {
+	filter := contentfilter.NewPipeline(nil, false)
+	filter.Use(contentfilter.NewWordRule("profanity", contentfilter.Mask, []string{"дурак"}), 0)
+	filter.Use(contentfilter.NewWordRule("scam", contentfilter.Reject, []string{"casino"}), 0)
+	filter.Use(contentfilter.NewLinkRule("links", contentfilter.Hold, 0), 0)
+	return filter
+}
+
+func TestPostUseCase_CreateFiltered(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	moderation := &memoryModeration{}
+	useCase := NewPostUseCase(mockRepo, new(MockUserRepository),
+		WithContentFilter(newTestFilter()), WithModeration(moderation))
+	ctx := context.Background()
+
+	masked := entity.CreatePostInput{Title: "Дурак", Content: "сам дурак", AuthorID: 1}
+	mockRepo.On("Create", ctx, entity.CreatePostInput{Title: "*****", Content: "сам *****", AuthorID: 1}).
+		Return(int64(0), assert.AnError)
+	_, err := useCase.Create(ctx, masked)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	_, err = useCase.Create(ctx, entity.CreatePostInput{Title: "casino", Content: "win", AuthorID: 1})
+	assert.ErrorIs(t, err, ErrContentRejected)
+
+	_, err = useCase.Create(ctx, entity.CreatePostInput{Title: "Links", Content: "https://example.com", AuthorID: 1})
+	var held *HeldError
+	require.ErrorAs(t, err, &held)
+	require.Len(t, moderation.held, 1)
+	assert.Equal(t, []string{"links"}, moderation.held[0].Rules)
+
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(input entity.CreatePostInput) bool {
+		return input.Title == "Links"
+	})).Return(int64(7), nil)
+
+	reviewed, err := useCase.ReviewHeld(ctx, held.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), reviewed.PublishedID)
+	assert.Empty(t, moderation.held)
+}
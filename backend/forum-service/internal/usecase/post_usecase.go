@@ -3,10 +3,21 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"forum-service/internal/entity"
 	"forum-service/internal/repository"
+	"unicode/utf8"
+
+	"backend/pkg/contentfilter"
+	"backend/pkg/unfurl"
 )
 
+// ErrInvalidInput is wrapped by errors about missing or malformed input
+var ErrInvalidInput = errors.New("invalid input")
+
+// MaxTitleLength limits post titles, in characters
+const MaxTitleLength = 255
+
 type PostUseCase interface {
 	GetByID(ctx context.Context, id int64) (*entity.Post, error)
 	GetAll(ctx context.Context, offset, limit int) ([]*entity.Post, int, error)
@@ -14,20 +25,34 @@ type PostUseCase interface {
 	Update(ctx context.Context, id int64, input entity.UpdatePostInput) error
 	Delete(ctx context.Context, id int64) error
 	GetReplies(ctx context.Context, postID int64) ([]*entity.Reply, error)
+	GetReply(ctx context.Context, id int64) (*entity.Reply, error)
 	CreateReply(ctx context.Context, postID int64, input entity.CreateReplyInput) (*entity.Reply, error)
 	DeleteReply(ctx context.Context, id int64) error
+	// ListHeld returns posts and replies held by the content filter, oldest first
+	ListHeld(ctx context.Context, limit int) ([]*entity.HeldContent, error)
+	ReviewHeld(ctx context.Context, id int64, approve bool) (*entity.HeldContent, error)
 }
 
 type postUseCase struct {
-	postRepo repository.PostRepository
-	userRepo repository.UserRepository
+	postRepo   repository.PostRepository
+	userRepo   repository.UserRepository
+	filter     *contentfilter.Pipeline
+	moderation repository.ModerationRepository
+
+	unfurler    *unfurl.Unfurler
+	previews    repository.PreviewRepository
+	unfurlSlots chan struct{}
 }
 
-func NewPostUseCase(postRepo repository.PostRepository, userRepo repository.UserRepository) PostUseCase {
-	return &postUseCase{
+func NewPostUseCase(postRepo repository.PostRepository, userRepo repository.UserRepository, opts ...Option) PostUseCase {
+	uc := &postUseCase{
 		postRepo: postRepo,
 		userRepo: userRepo,
 	}
+	for _, opt := range opts {
+		opt(uc)
+	}
+	return uc
 }
 
 func (uc *postUseCase) GetByID(ctx context.Context, id int64) (*entity.Post, error) {
@@ -35,6 +60,7 @@ func (uc *postUseCase) GetByID(ctx context.Context, id int64) (*entity.Post, err
 	if err != nil {
 		return nil, err
 	}
+	uc.attachPreviews(ctx, post)
 
 	author, err := uc.userRepo.GetByID(ctx, post.AuthorID)
 	if err != nil {
@@ -51,6 +77,7 @@ func (uc *postUseCase) GetAll(ctx context.Context, offset, limit int) ([]*entity
 	if err != nil {
 		return nil, 0, err
 	}
+	uc.attachPreviews(ctx, posts...)
 
 	// Получаем информацию об авторах для всех постов
 	for _, post := range posts {
@@ -65,7 +92,17 @@ func (uc *postUseCase) GetAll(ctx context.Context, offset, limit int) ([]*entity
 
 func (uc *postUseCase) Create(ctx context.Context, input entity.CreatePostInput) (*entity.Post, error) {
 	if input.Title == "" || input.Content == "" {
-		return nil, errors.New("title and content are required")
+		return nil, fmt.Errorf("%w: title and content are required", ErrInvalidInput)
+	}
+	if err := validateTitle(input.Title); err != nil {
+		return nil, err
+	}
+
+	var err error
+	input.Title, input.Content, err = uc.screen(ctx, contentfilter.KindPost, input.AuthorID, input.Title, input.Content,
+		&entity.HeldContent{Kind: entity.HeldPost, Title: input.Title, Content: input.Content, AuthorID: input.AuthorID})
+	if err != nil {
+		return nil, err
 	}
 
 	// Create post and get its ID
@@ -73,18 +110,47 @@ func (uc *postUseCase) Create(ctx context.Context, input entity.CreatePostInput)
 	if err != nil {
 		return nil, err
 	}
+	uc.unfurlPost(id, input.Content, false)
 
 	// Get the created post
 	return uc.GetByID(ctx, id)
 }
 
 func (uc *postUseCase) Update(ctx context.Context, id int64, input entity.UpdatePostInput) error {
-	_, err := uc.postRepo.GetByID(ctx, id)
+	post, err := uc.postRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Omitted fields keep their values
+	if input.Title == "" {
+		input.Title = post.Title
+	}
+	if input.Content == "" {
+		input.Content = post.Content
+	}
+	if err := validateTitle(input.Title); err != nil {
+		return err
+	}
+
+	// Edits are checked like new posts, but cannot be held: the old text is already public
+	input.Title, input.Content, err = uc.screen(ctx, contentfilter.KindPost, post.AuthorID, input.Title, input.Content, nil)
 	if err != nil {
 		return err
 	}
 
-	return uc.postRepo.Update(ctx, id, input)
+	if err := uc.postRepo.Update(ctx, id, input); err != nil {
+		return err
+	}
+	uc.unfurlPost(id, input.Content, true)
+	return nil
+}
+
+func validateTitle(title string) error {
+	if utf8.RuneCountInString(title) > MaxTitleLength {
+		return fmt.Errorf("%w: title is longer than %d characters", ErrInvalidInput, MaxTitleLength)
+	}
+	return nil
 }
 
 func (uc *postUseCase) Delete(ctx context.Context, id int64) error {
@@ -108,9 +174,23 @@ func (uc *postUseCase) GetReplies(ctx context.Context, postID int64) ([]*entity.
 	return replies, nil
 }
 
+func (uc *postUseCase) GetReply(ctx context.Context, id int64) (*entity.Reply, error) {
+	reply, err := uc.postRepo.GetReply(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	author, err := uc.userRepo.GetByID(ctx, reply.AuthorID)
+	if err == nil {
+		reply.Author = author
+	}
+
+	return reply, nil
+}
+
 func (uc *postUseCase) CreateReply(ctx context.Context, postID int64, input entity.CreateReplyInput) (*entity.Reply, error) {
 	if input.Content == "" {
-		return nil, errors.New("reply content is required")
+		return nil, fmt.Errorf("%w: reply content is required", ErrInvalidInput)
 	}
 
 	// Check if post exists
@@ -119,6 +199,12 @@ func (uc *postUseCase) CreateReply(ctx context.Context, postID int64, input enti
 		return nil, err
 	}
 
+	_, input.Content, err = uc.screen(ctx, contentfilter.KindReply, input.AuthorID, "", input.Content,
+		&entity.HeldContent{Kind: entity.HeldReply, PostID: postID, Content: input.Content, AuthorID: input.AuthorID})
+	if err != nil {
+		return nil, err
+	}
+
 	reply, err := uc.postRepo.CreateReply(ctx, postID, input)
 	if err != nil {
 		return nil, err
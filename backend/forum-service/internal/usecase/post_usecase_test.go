@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"forum-service/internal/entity"
+	"strings"
 	"testing"
 	"time"
 
@@ -47,6 +48,14 @@ func (m *MockPostRepository) GetReplies(ctx context.Context, postID int64) ([]*e
 	return args.Get(0).([]*entity.Reply), args.Error(1)
 }
 
+func (m *MockPostRepository) GetReply(ctx context.Context, id int64) (*entity.Reply, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entity.Reply), args.Error(1)
+}
+
 func (m *MockPostRepository) CreateReply(ctx context.Context, postID int64, input entity.CreateReplyInput) (*entity.Reply, error) {
 	args := m.Called(ctx, postID, input)
 	return args.Get(0).(*entity.Reply), args.Error(1)
@@ -114,3 +123,21 @@ func TestPostUseCase_GetByID(t *testing.T) {
 	assert.Equal(t, expectedPost, post)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestPostUseCase_Update(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	mockUserRepo := new(MockUserRepository)
+	useCase := NewPostUseCase(mockRepo, mockUserRepo)
+	ctx := context.Background()
+
+	mockRepo.On("GetByID", ctx, int64(1)).Return(&entity.Post{ID: 1, Title: "Old title", Content: "Old content", AuthorID: 1}, nil)
+	mockRepo.On("Update", ctx, int64(1), entity.UpdatePostInput{Title: "Old title", Content: "New content"}).Return(nil)
+
+	// Omitted fields keep their values
+	err := useCase.Update(ctx, 1, entity.UpdatePostInput{Content: "New content"})
+	assert.NoError(t, err)
+
+	err = useCase.Update(ctx, 1, entity.UpdatePostInput{Title: strings.Repeat("я", MaxTitleLength+1)})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	mockRepo.AssertExpectations(t)
+}
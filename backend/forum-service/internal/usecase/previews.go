@@ -0,0 +1,96 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"forum-service/internal/entity"
+	"forum-service/internal/repository"
+	"log"
+	"time"
+
+	"backend/pkg/unfurl"
+)
+
+const (
+	// maxPreviews is the number of links of a post that get a preview
+	maxPreviews = 3
+	// maxConcurrentUnfurls is the number of posts whose links are loaded at the same time
+	maxConcurrentUnfurls = 8
+	// unfurlTimeout bounds the previews of one post, including the wait for a free slot
+	unfurlTimeout = 20 * time.Second
+)
+
+// WithUnfurler turns on link previews. Previews are loaded in the background after
+// a post is saved and are returned with the post once ready.
+func WithUnfurler(unfurler *unfurl.Unfurler, previews repository.PreviewRepository) Option {
+	return func(uc *postUseCase) {
+		if unfurler == nil {
+			return
+		}
+		uc.unfurler = unfurler
+		uc.previews = previews
+		uc.unfurlSlots = make(chan struct{}, maxConcurrentUnfurls)
+	}
+}
+
+// unfurlPost loads previews of the links in the content in the background and
+// replaces the previews of the post with them. An edited post without links loses its previews.
+func (uc *postUseCase) unfurlPost(postID int64, content string, edited bool) {
+	if uc.unfurler == nil {
+		return
+	}
+	urls := unfurl.ExtractURLs(content, maxPreviews)
+	if len(urls) == 0 && !edited {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), unfurlTimeout)
+		defer cancel()
+
+		select {
+		case uc.unfurlSlots <- struct{}{}:
+			defer func() { <-uc.unfurlSlots }()
+		case <-ctx.Done():
+			log.Printf("link previews of post %d skipped: too many posts in the queue", postID)
+			return
+		}
+
+		var previews []unfurl.Preview
+		for _, url := range urls {
+			preview, err := uc.unfurler.Unfurl(ctx, url)
+			if err != nil {
+				if !errors.Is(err, unfurl.ErrNoPreview) {
+					log.Printf("failed to unfurl %s: %v", url, err)
+				}
+				continue
+			}
+			previews = append(previews, *preview)
+		}
+
+		if err := uc.previews.SetPostPreviews(ctx, postID, previews); err != nil {
+			log.Printf("failed to save link previews of post %d: %v", postID, err)
+		}
+	}()
+}
+
+// attachPreviews sets the stored previews on the posts. Previews are optional,
+// so a failure is logged and the posts are returned without them.
+func (uc *postUseCase) attachPreviews(ctx context.Context, posts ...*entity.Post) {
+	if uc.previews == nil || len(posts) == 0 {
+		return
+	}
+
+	ids := make([]int64, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	previews, err := uc.previews.PostPreviews(ctx, ids)
+	if err != nil {
+		log.Printf("failed to load link previews: %v", err)
+		return
+	}
+	for _, post := range posts {
+		post.Previews = previews[post.ID]
+	}
+}
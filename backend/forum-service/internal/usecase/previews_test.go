@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"forum-service/internal/entity"
+	"testing"
+
+	"backend/pkg/unfurl"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryPreviews struct {
+	posts map[int64][]unfurl.Preview
+}
+
+func (m *memoryPreviews) SetPostPreviews(ctx context.Context, postID int64, previews []unfurl.Preview) error {
+	m.posts[postID] = previews
+	return nil
+}
+
+func (m *memoryPreviews) PostPreviews(ctx context.Context, postIDs []int64) (map[int64][]unfurl.Preview, error) {
+	result := make(map[int64][]unfurl.Preview)
+	for _, id := range postIDs {
+		if previews, ok := m.posts[id]; ok {
+			result[id] = previews
+		}
+	}
+	return result, nil
+}
+
+func TestPostUseCase_AttachesPreviews(t *testing.T) {
+	mockRepo := new(MockPostRepository)
+	userRepo := new(MockUserRepository)
+	previews := &memoryPreviews{posts: map[int64][]unfurl.Preview{
+		1: {{URL: "https://go.dev", Title: "The Go Programming Language"}},
+	}}
+	useCase := NewPostUseCase(mockRepo, userRepo, WithUnfurler(unfurl.New(nil, unfurl.Options{}), previews))
+	ctx := context.Background()
+
+	mockRepo.On("GetAll", ctx, 0, 10).Return([]*entity.Post{{ID: 1, AuthorID: 1}, {ID: 2, AuthorID: 1}}, 2, nil)
+	userRepo.On("GetByID", ctx, int64(1)).Return(&entity.User{ID: 1}, nil)
+
+	posts, _, err := useCase.GetAll(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, previews.posts[1], posts[0].Previews)
+	assert.Empty(t, posts[1].Previews)
+}
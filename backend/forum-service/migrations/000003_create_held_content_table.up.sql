@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS held_content (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(16) NOT NULL,
+    post_id BIGINT NOT NULL DEFAULT 0,
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    author_id BIGINT NOT NULL,
+    rules TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
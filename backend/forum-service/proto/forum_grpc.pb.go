@@ -27,20 +27,30 @@ const (
 	ForumService_CreateReply_FullMethodName = "/forum.ForumService/CreateReply"
 	ForumService_ListReplies_FullMethodName = "/forum.ForumService/ListReplies"
 	ForumService_DeleteReply_FullMethodName = "/forum.ForumService/DeleteReply"
+	ForumService_ListHeld_FullMethodName    = "/forum.ForumService/ListHeld"
+	ForumService_ReviewHeld_FullMethodName  = "/forum.ForumService/ReviewHeld"
 )
 
 // ForumServiceClient is the client API for ForumService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ForumServiceClient interface {
+	// Creates a post of the caller. Held posts return held_id instead of the post.
 	CreatePost(ctx context.Context, in *CreatePostRequest, opts ...grpc.CallOption) (*CreatePostResponse, error)
 	GetPost(ctx context.Context, in *GetPostRequest, opts ...grpc.CallOption) (*Post, error)
 	ListPosts(ctx context.Context, in *ListPostsRequest, opts ...grpc.CallOption) (*ListPostsResponse, error)
+	// Updates a post of the caller, requires post.edit.any for posts of other users
 	UpdatePost(ctx context.Context, in *UpdatePostRequest, opts ...grpc.CallOption) (*Post, error)
+	// Deletes a post of the caller, requires post.delete.any for posts of other users
 	DeletePost(ctx context.Context, in *DeletePostRequest, opts ...grpc.CallOption) (*DeletePostResponse, error)
+	// Creates a reply of the caller. Held replies return held_id instead of the reply.
 	CreateReply(ctx context.Context, in *CreateReplyRequest, opts ...grpc.CallOption) (*CreateReplyResponse, error)
 	ListReplies(ctx context.Context, in *ListRepliesRequest, opts ...grpc.CallOption) (*ListRepliesResponse, error)
+	// Deletes a reply of the caller, requires reply.delete.any for replies of other users
 	DeleteReply(ctx context.Context, in *DeleteReplyRequest, opts ...grpc.CallOption) (*DeleteReplyResponse, error)
+	// Moderation queue of the content filter, requires post.edit.any
+	ListHeld(ctx context.Context, in *ListHeldRequest, opts ...grpc.CallOption) (*ListHeldResponse, error)
+	ReviewHeld(ctx context.Context, in *ReviewHeldRequest, opts ...grpc.CallOption) (*HeldContent, error)
 }
 
 type forumServiceClient struct {
@@ -123,18 +133,44 @@ func (c *forumServiceClient) DeleteReply(ctx context.Context, in *DeleteReplyReq
 	return out, nil
 }
 
+func (c *forumServiceClient) ListHeld(ctx context.Context, in *ListHeldRequest, opts ...grpc.CallOption) (*ListHeldResponse, error) {
+	out := new(ListHeldResponse)
+	err := c.cc.Invoke(ctx, ForumService_ListHeld_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *forumServiceClient) ReviewHeld(ctx context.Context, in *ReviewHeldRequest, opts ...grpc.CallOption) (*HeldContent, error) {
+	out := new(HeldContent)
+	err := c.cc.Invoke(ctx, ForumService_ReviewHeld_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ForumServiceServer is the server API for ForumService service.
 // All implementations must embed UnimplementedForumServiceServer
 // for forward compatibility
 type ForumServiceServer interface {
+	// Creates a post of the caller. Held posts return held_id instead of the post.
 	CreatePost(context.Context, *CreatePostRequest) (*CreatePostResponse, error)
 	GetPost(context.Context, *GetPostRequest) (*Post, error)
 	ListPosts(context.Context, *ListPostsRequest) (*ListPostsResponse, error)
+	// Updates a post of the caller, requires post.edit.any for posts of other users
 	UpdatePost(context.Context, *UpdatePostRequest) (*Post, error)
+	// Deletes a post of the caller, requires post.delete.any for posts of other users
 	DeletePost(context.Context, *DeletePostRequest) (*DeletePostResponse, error)
+	// Creates a reply of the caller. Held replies return held_id instead of the reply.
 	CreateReply(context.Context, *CreateReplyRequest) (*CreateReplyResponse, error)
 	ListReplies(context.Context, *ListRepliesRequest) (*ListRepliesResponse, error)
+	// Deletes a reply of the caller, requires reply.delete.any for replies of other users
 	DeleteReply(context.Context, *DeleteReplyRequest) (*DeleteReplyResponse, error)
+	// Moderation queue of the content filter, requires post.edit.any
+	ListHeld(context.Context, *ListHeldRequest) (*ListHeldResponse, error)
+	ReviewHeld(context.Context, *ReviewHeldRequest) (*HeldContent, error)
 	mustEmbedUnimplementedForumServiceServer()
 }
 
@@ -166,6 +202,12 @@ func (UnimplementedForumServiceServer) ListReplies(context.Context, *ListReplies
 func (UnimplementedForumServiceServer) DeleteReply(context.Context, *DeleteReplyRequest) (*DeleteReplyResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteReply not implemented")
 }
+func (UnimplementedForumServiceServer) ListHeld(context.Context, *ListHeldRequest) (*ListHeldResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListHeld not implemented")
+}
+func (UnimplementedForumServiceServer) ReviewHeld(context.Context, *ReviewHeldRequest) (*HeldContent, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReviewHeld not implemented")
+}
 func (UnimplementedForumServiceServer) mustEmbedUnimplementedForumServiceServer() {}
 
 // UnsafeForumServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -323,6 +365,42 @@ func _ForumService_DeleteReply_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ForumService_ListHeld_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListHeldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForumServiceServer).ListHeld(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ForumService_ListHeld_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForumServiceServer).ListHeld(ctx, req.(*ListHeldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ForumService_ReviewHeld_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReviewHeldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ForumServiceServer).ReviewHeld(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ForumService_ReviewHeld_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ForumServiceServer).ReviewHeld(ctx, req.(*ReviewHeldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ForumService_ServiceDesc is the grpc.ServiceDesc for ForumService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -362,6 +440,14 @@ var ForumService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteReply",
 			Handler:    _ForumService_DeleteReply_Handler,
 		},
+		{
+			MethodName: "ListHeld",
+			Handler:    _ForumService_ListHeld_Handler,
+		},
+		{
+			MethodName: "ReviewHeld",
+			Handler:    _ForumService_ReviewHeld_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/forum.proto",
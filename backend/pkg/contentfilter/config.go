@@ -0,0 +1,172 @@
+package contentfilter
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RuleConfig holds the settings common to all rules.
+type RuleConfig struct {
+	Name   string `json:"name"`
+	Action Action `json:"action"`
+	// ExemptReputation is the reputation at which users skip the rule, zero applies it to everybody.
+	ExemptReputation int `json:"exempt_reputation"`
+}
+
+// WordsConfig is a word list given inline, in a file with one word per line, or both.
+type WordsConfig struct {
+	RuleConfig
+	Words []string `json:"words"`
+	File  string   `json:"file"`
+}
+
+// PatternConfig is a regular expression in the regexp package syntax.
+type PatternConfig struct {
+	RuleConfig
+	Pattern string `json:"pattern"`
+}
+
+// LinksConfig limits the number of links in a single text.
+type LinksConfig struct {
+	RuleConfig
+	Max int `json:"max"`
+}
+
+// DuplicatesConfig limits how many times a user can repeat the same text within Window,
+// e.g. "1m".
+type DuplicatesConfig struct {
+	RuleConfig
+	Max    int    `json:"max"`
+	Window string `json:"window"`
+}
+
+// Config describes a pipeline. Rules run in the order: words, patterns, links, duplicates.
+type Config struct {
+	// DryRun only reports matches, the content is always allowed.
+	DryRun     bool              `json:"dry_run"`
+	Words      []WordsConfig     `json:"words"`
+	Patterns   []PatternConfig   `json:"patterns"`
+	Links      *LinksConfig      `json:"links"`
+	Duplicates *DuplicatesConfig `json:"duplicates"`
+}
+
+// LoadConfig reads a JSON config. Relative word list files are resolved
+// against the directory of the config.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read content filter config: %v", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse content filter config %s: %v", path, err)
+	}
+
+	for i := range cfg.Words {
+		if file := cfg.Words[i].File; file != "" && !filepath.IsAbs(file) {
+			cfg.Words[i].File = filepath.Join(filepath.Dir(path), file)
+		}
+	}
+	return cfg, nil
+}
+
+// Load builds a pipeline from the JSON config at path. An empty path disables
+// filtering and returns a nil pipeline. dryRun turns the dry-run mode on
+// regardless of the config.
+func Load(path string, dryRun bool, reputation Reputation) (*Pipeline, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg.DryRun = cfg.DryRun || dryRun
+	return New(cfg, reputation)
+}
+
+// New builds a pipeline from the config.
+func New(cfg Config, reputation Reputation) (*Pipeline, error) {
+	p := NewPipeline(reputation, cfg.DryRun)
+
+	for i, w := range cfg.Words {
+		words := w.Words
+		if w.File != "" {
+			fromFile, err := readWords(w.File)
+			if err != nil {
+				return nil, err
+			}
+			words = append(append([]string(nil), words...), fromFile...)
+		}
+		p.Use(NewWordRule(ruleName(w.Name, "words", i), w.Action, words), w.ExemptReputation)
+	}
+
+	for i, pc := range cfg.Patterns {
+		name := ruleName(pc.Name, "pattern", i)
+		pattern, err := regexp.Compile(pc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %s: %v", name, err)
+		}
+		p.Use(NewPatternRule(name, pc.Action, pattern), pc.ExemptReputation)
+	}
+
+	if l := cfg.Links; l != nil {
+		if l.Max < 0 {
+			return nil, fmt.Errorf("invalid links rule: max must not be negative")
+		}
+		p.Use(NewLinkRule(ruleName(l.Name, "links", -1), l.Action, l.Max), l.ExemptReputation)
+	}
+
+	if d := cfg.Duplicates; d != nil {
+		window, err := time.ParseDuration(d.Window)
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid duplicates rule: bad window %q", d.Window)
+		}
+		if d.Max <= 0 {
+			return nil, fmt.Errorf("invalid duplicates rule: max must be positive")
+		}
+		p.Use(NewDuplicateRule(ruleName(d.Name, "duplicates", -1), d.Action, d.Max, window), d.ExemptReputation)
+	}
+
+	return p, nil
+}
+
+// ruleName returns the configured name or a generated one.
+func ruleName(name, kind string, index int) string {
+	switch {
+	case name != "":
+		return name
+	case index < 0:
+		return kind
+	}
+	return fmt.Sprintf("%s.%d", kind, index)
+}
+
+// readWords reads a word list, one word per line. Empty lines and lines starting with # are skipped.
+func readWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read word list: %v", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			words = append(words, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list %s: %v", path, err)
+	}
+	return words, nil
+}
@@ -0,0 +1,244 @@
+// Package contentfilter checks user content against word lists, regex rules and
+// spam heuristics before it is stored, shared by the forum and chat services.
+package contentfilter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action is what the pipeline does with content. Actions are ordered by severity,
+// the strictest action of all matched rules wins.
+type Action int
+
+const (
+	// Allow stores the content as is.
+	Allow Action = iota
+	// Mask replaces the matched fragments with asterisks.
+	Mask
+	// Hold keeps the content out of sight until a moderator approves it.
+	Hold
+	// Reject refuses the content.
+	Reject
+)
+
+var actionNames = []string{"allow", "mask", "hold", "reject"}
+
+func (a Action) String() string {
+	if a < Allow || a > Reject {
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+	return actionNames[a]
+}
+
+// ParseAction parses an action name: allow, mask, hold or reject.
+func ParseAction(s string) (Action, error) {
+	for i, name := range actionNames {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return Action(i), nil
+		}
+	}
+	return Allow, fmt.Errorf("invalid action %q: expected allow, mask, hold or reject", s)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Action) UnmarshalText(text []byte) error {
+	action, err := ParseAction(string(text))
+	if err != nil {
+		return err
+	}
+	*a = action
+	return nil
+}
+
+// Content kinds used by the services. Duplicate detection counts each kind separately.
+const (
+	KindPost  = "post"
+	KindReply = "reply"
+	KindChat  = "chat"
+)
+
+// Content is a piece of user content to check.
+type Content struct {
+	Kind   string
+	UserID int64
+	Text   string
+}
+
+// Span is a byte range [Start, End) of the text.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Match describes a rule that fired.
+type Match struct {
+	Rule   string `json:"rule"`
+	Action Action `json:"action"`
+	// Spans are the fragments to mask. Rules that judge the text as a whole leave it empty.
+	Spans []Span `json:"-"`
+}
+
+// Rule is one stage of the pipeline. Check reports whether the content matched.
+type Rule interface {
+	Name() string
+	Check(c Content) (Match, bool)
+}
+
+// Reputation reports how trusted a user is, e.g. the amount of content they have published.
+type Reputation interface {
+	Reputation(ctx context.Context, userID int64) (int, error)
+}
+
+// ReputationFunc adapts a function to the Reputation interface.
+type ReputationFunc func(ctx context.Context, userID int64) (int, error)
+
+// Reputation calls f.
+func (f ReputationFunc) Reputation(ctx context.Context, userID int64) (int, error) {
+	return f(ctx, userID)
+}
+
+// Verdict is the decision of the pipeline.
+type Verdict struct {
+	// Action is what the caller must do with the content.
+	Action Action
+	// Intended is the action the rules decided on. It differs from Action only in dry-run mode.
+	Intended Action
+	// Text is the content to store: masked when Action is Mask, otherwise unchanged.
+	Text    string
+	Matches []Match
+	DryRun  bool
+}
+
+// Rules returns the names of the matched rules.
+func (v Verdict) Rules() []string {
+	names := make([]string, len(v.Matches))
+	for i, m := range v.Matches {
+		names[i] = m.Rule
+	}
+	return names
+}
+
+// Flagged reports whether any rule matched.
+func (v Verdict) Flagged() bool {
+	return len(v.Matches) > 0
+}
+
+type stage struct {
+	rule Rule
+	// exempt is the reputation at which users skip the rule, zero means nobody does.
+	exempt int
+}
+
+// Pipeline runs content through its rules in order.
+// A nil pipeline allows everything.
+type Pipeline struct {
+	stages     []stage
+	reputation Reputation
+	dryRun     bool
+}
+
+// NewPipeline creates an empty pipeline. reputation may be nil, then rules with
+// a reputation threshold apply to everybody.
+func NewPipeline(reputation Reputation, dryRun bool) *Pipeline {
+	return &Pipeline{reputation: reputation, dryRun: dryRun}
+}
+
+// Use appends a rule. Users whose reputation reaches exemptReputation skip it,
+// zero applies the rule to everybody.
+func (p *Pipeline) Use(rule Rule, exemptReputation int) {
+	p.stages = append(p.stages, stage{rule: rule, exempt: exemptReputation})
+}
+
+// DryRun reports whether the pipeline only reports matches without acting on them.
+func (p *Pipeline) DryRun() bool {
+	return p != nil && p.dryRun
+}
+
+// Check runs all rules against the content. If the reputation lookup fails,
+// the user is treated as having no reputation.
+func (p *Pipeline) Check(ctx context.Context, c Content) Verdict {
+	verdict := Verdict{Text: c.Text}
+	if p == nil || len(p.stages) == 0 {
+		return verdict
+	}
+
+	reputation, loaded := 0, false
+	var spans []Span
+	for _, s := range p.stages {
+		if s.exempt > 0 && c.UserID != 0 && p.reputation != nil {
+			if !loaded {
+				if r, err := p.reputation.Reputation(ctx, c.UserID); err == nil {
+					reputation = r
+				}
+				loaded = true
+			}
+			if reputation >= s.exempt {
+				continue
+			}
+		}
+
+		m, ok := s.rule.Check(c)
+		if !ok {
+			continue
+		}
+		if m.Rule == "" {
+			m.Rule = s.rule.Name()
+		}
+		// Nothing to mask means the text as a whole is suspicious
+		if m.Action == Mask && len(m.Spans) == 0 {
+			m.Action = Hold
+		}
+		if m.Action == Mask {
+			spans = append(spans, m.Spans...)
+		}
+		if m.Action > verdict.Intended {
+			verdict.Intended = m.Action
+		}
+		verdict.Matches = append(verdict.Matches, m)
+	}
+
+	verdict.DryRun = p.dryRun
+	if p.dryRun {
+		return verdict
+	}
+
+	verdict.Action = verdict.Intended
+	if verdict.Action == Mask {
+		verdict.Text = mask(c.Text, spans)
+	}
+	return verdict
+}
+
+// mask replaces every rune inside spans with an asterisk.
+func mask(text string, spans []Span) string {
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+
+	var b strings.Builder
+	b.Grow(len(text))
+	pos := 0
+	for _, s := range spans {
+		if s.End <= pos {
+			continue
+		}
+		if s.Start > pos {
+			b.WriteString(text[pos:s.Start])
+			pos = s.Start
+		}
+		b.WriteString(strings.Repeat("*", utf8.RuneCountInString(text[pos:s.End])))
+		pos = s.End
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
@@ -0,0 +1,134 @@
+package contentfilter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline_MasksWords(t *testing.T) {
+	p := NewPipeline(nil, false)
+	p.Use(NewWordRule("profanity", Mask, []string{"дурак", "spam*"}), 0)
+
+	v := p.Check(context.Background(), Content{Kind: KindChat, UserID: 1, Text: "Ты Дурак, spammer! Дураковаляние"})
+	assert.Equal(t, Mask, v.Action)
+	assert.Equal(t, "Ты *****, *******! Дураковаляние", v.Text)
+	assert.Equal(t, []string{"profanity"}, v.Rules())
+
+	v = p.Check(context.Background(), Content{Kind: KindChat, UserID: 1, Text: "привет"})
+	assert.Equal(t, Allow, v.Action)
+	assert.False(t, v.Flagged())
+}
+
+func TestPipeline_StrictestActionWins(t *testing.T) {
+	p := NewPipeline(nil, false)
+	p.Use(NewWordRule("words", Mask, []string{"bad"}), 0)
+	p.Use(NewLinkRule("links", Hold, 1), 0)
+
+	text := "bad https://a.example www.b.example"
+	v := p.Check(context.Background(), Content{Text: text})
+	assert.Equal(t, Hold, v.Action)
+	assert.Equal(t, text, v.Text)
+	assert.Equal(t, []string{"words", "links"}, v.Rules())
+}
+
+func TestPipeline_ReputationExemption(t *testing.T) {
+	lookups := 0
+	reputation := ReputationFunc(func(ctx context.Context, userID int64) (int, error) {
+		lookups++
+		return int(userID) * 10, nil
+	})
+
+	p := NewPipeline(reputation, false)
+	p.Use(NewLinkRule("links", Reject, 0), 20)
+	p.Use(NewLinkRule("links.many", Hold, 0), 50)
+
+	v := p.Check(context.Background(), Content{UserID: 1, Text: "see https://example.com"})
+	assert.Equal(t, Reject, v.Action)
+	assert.Equal(t, 1, lookups)
+
+	v = p.Check(context.Background(), Content{UserID: 3, Text: "see https://example.com"})
+	assert.Equal(t, Hold, v.Action)
+
+	v = p.Check(context.Background(), Content{UserID: 5, Text: "see https://example.com"})
+	assert.Equal(t, Allow, v.Action)
+}
+
+func TestPipeline_DryRun(t *testing.T) {
+	p := NewPipeline(nil, true)
+	p.Use(NewWordRule("words", Reject, []string{"bad"}), 0)
+
+	v := p.Check(context.Background(), Content{Text: "bad word"})
+	assert.Equal(t, Allow, v.Action)
+	assert.Equal(t, Reject, v.Intended)
+	assert.Equal(t, "bad word", v.Text)
+	assert.True(t, v.DryRun)
+	assert.True(t, v.Flagged())
+}
+
+func TestPipeline_NilAllows(t *testing.T) {
+	var p *Pipeline
+	v := p.Check(context.Background(), Content{Text: "anything"})
+	assert.Equal(t, Allow, v.Action)
+	assert.Equal(t, "anything", v.Text)
+}
+
+func TestDuplicateRule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	r := NewDuplicateRule("duplicates", Reject, 2, time.Minute)
+	r.now = func() time.Time { return now }
+
+	check := func(userID int64, text string) bool {
+		_, ok := r.Check(Content{Kind: KindChat, UserID: userID, Text: text})
+		return ok
+	}
+
+	assert.False(t, check(1, "Buy now"))
+	assert.False(t, check(1, "buy  NOW"))
+	assert.True(t, check(1, "buy now"))
+	// Other users and kinds are counted separately
+	assert.False(t, check(2, "buy now"))
+	_, ok := r.Check(Content{Kind: KindPost, UserID: 1, Text: "buy now"})
+	assert.False(t, ok)
+
+	now = now.Add(time.Minute)
+	assert.False(t, check(1, "buy now"))
+}
+
+func TestNew_FromConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "words.txt"), []byte("# profanity\nfoo\n\nbar*\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "filter.json"), []byte(`{
+		"words": [{"name": "profanity", "action": "mask", "file": "words.txt", "words": ["baz"]}],
+		"patterns": [{"name": "phone", "action": "hold", "pattern": "\\+?\\d{11}"}],
+		"links": {"action": "hold", "max": 2, "exempt_reputation": 10},
+		"duplicates": {"action": "reject", "max": 3, "window": "1m"}
+	}`), 0o644))
+
+	cfg, err := LoadConfig(filepath.Join(dir, "filter.json"))
+	require.NoError(t, err)
+
+	p, err := New(cfg, nil)
+	require.NoError(t, err)
+
+	v := p.Check(context.Background(), Content{Text: "foo barbell baz"})
+	assert.Equal(t, "*** ******* ***", v.Text)
+
+	v = p.Check(context.Background(), Content{Text: "call +79991234567"})
+	assert.Equal(t, Hold, v.Action)
+	assert.Equal(t, []string{"phone"}, v.Rules())
+
+	_, err = New(Config{Patterns: []PatternConfig{{Pattern: "("}}}, nil)
+	assert.Error(t, err)
+
+	_, err = New(Config{Duplicates: &DuplicatesConfig{Max: 1, Window: "soon"}}, nil)
+	assert.Error(t, err)
+
+	_, err = ParseAction("drop")
+	assert.Error(t, err)
+}
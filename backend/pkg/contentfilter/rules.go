@@ -0,0 +1,241 @@
+package contentfilter
+
+import (
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// WordRule matches whole words from a list, case-insensitively.
+// A word ending with "*" matches every word starting with it.
+type WordRule struct {
+	name     string
+	action   Action
+	words    map[string]bool
+	prefixes []string
+}
+
+// NewWordRule creates a word list rule.
+func NewWordRule(name string, action Action, words []string) *WordRule {
+	r := &WordRule{name: name, action: action, words: make(map[string]bool)}
+	for _, w := range words {
+		w = strings.ToLower(strings.TrimSpace(w))
+		switch {
+		case w == "" || w == "*":
+		case strings.HasSuffix(w, "*"):
+			r.prefixes = append(r.prefixes, strings.TrimSuffix(w, "*"))
+		default:
+			r.words[w] = true
+		}
+	}
+	return r
+}
+
+func (r *WordRule) Name() string { return r.name }
+
+func (r *WordRule) Check(c Content) (Match, bool) {
+	var spans []Span
+	forEachWord(c.Text, func(start, end int) {
+		if r.matches(strings.ToLower(c.Text[start:end])) {
+			spans = append(spans, Span{Start: start, End: end})
+		}
+	})
+	return Match{Action: r.action, Spans: spans}, len(spans) > 0
+}
+
+func (r *WordRule) matches(word string) bool {
+	if r.words[word] {
+		return true
+	}
+	for _, p := range r.prefixes {
+		if strings.HasPrefix(word, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachWord calls fn with the byte range of every run of letters and digits.
+// Unlike \b in regexp it works for any script, not only ASCII.
+func forEachWord(text string, fn func(start, end int)) {
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			fn(start, i)
+			start = -1
+		}
+	}
+	if start >= 0 {
+		fn(start, len(text))
+	}
+}
+
+// PatternRule matches a regular expression.
+type PatternRule struct {
+	name    string
+	action  Action
+	pattern *regexp.Regexp
+}
+
+// NewPatternRule creates a regex rule.
+func NewPatternRule(name string, action Action, pattern *regexp.Regexp) *PatternRule {
+	return &PatternRule{name: name, action: action, pattern: pattern}
+}
+
+func (r *PatternRule) Name() string { return r.name }
+
+func (r *PatternRule) Check(c Content) (Match, bool) {
+	found := r.pattern.FindAllStringIndex(c.Text, -1)
+	spans := make([]Span, 0, len(found))
+	for _, f := range found {
+		if f[1] > f[0] {
+			spans = append(spans, Span{Start: f[0], End: f[1]})
+		}
+	}
+	return Match{Action: r.action, Spans: spans}, len(spans) > 0
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)[^\s<>"']+`)
+
+// LinkRule fires when the text contains more than max links.
+type LinkRule struct {
+	name   string
+	action Action
+	max    int
+}
+
+// NewLinkRule creates a link count rule.
+func NewLinkRule(name string, action Action, max int) *LinkRule {
+	return &LinkRule{name: name, action: action, max: max}
+}
+
+func (r *LinkRule) Name() string { return r.name }
+
+func (r *LinkRule) Check(c Content) (Match, bool) {
+	found := linkPattern.FindAllStringIndex(c.Text, -1)
+	if len(found) <= r.max {
+		return Match{}, false
+	}
+	spans := make([]Span, len(found))
+	for i, f := range found {
+		spans[i] = Span{Start: f[0], End: f[1]}
+	}
+	return Match{Action: r.action, Spans: spans}, true
+}
+
+const (
+	sweepInterval = time.Minute
+	// maxRemembered bounds the history kept for a single user
+	maxRemembered = 64
+)
+
+type sent struct {
+	hash uint64
+	at   time.Time
+}
+
+// DuplicateRule fires when a user sends the same text more than max times within window.
+// Texts are compared ignoring case and whitespace. It remembers recent texts in memory,
+// so each service instance counts on its own.
+type DuplicateRule struct {
+	name      string
+	action    Action
+	max       int
+	window    time.Duration
+	history   map[string][]sent
+	lastSweep time.Time
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// NewDuplicateRule creates a duplicate message rule.
+func NewDuplicateRule(name string, action Action, max int, window time.Duration) *DuplicateRule {
+	return &DuplicateRule{
+		name:      name,
+		action:    action,
+		max:       max,
+		window:    window,
+		history:   make(map[string][]sent),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (r *DuplicateRule) Name() string { return r.name }
+
+func (r *DuplicateRule) Check(c Content) (Match, bool) {
+	if c.UserID == 0 {
+		return Match{}, false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := r.now()
+	r.sweep(now)
+
+	key := c.Kind + "|" + strconv.FormatInt(c.UserID, 10)
+	history := r.recent(r.history[key], now)
+
+	hash := normalizedHash(c.Text)
+	repeats := 0
+	for _, s := range history {
+		if s.hash == hash {
+			repeats++
+		}
+	}
+
+	history = append(history, sent{hash: hash, at: now})
+	if len(history) > maxRemembered {
+		history = history[len(history)-maxRemembered:]
+	}
+	r.history[key] = history
+
+	return Match{Action: r.action}, repeats >= r.max
+}
+
+// recent drops the entries that are out of the window.
+func (r *DuplicateRule) recent(history []sent, now time.Time) []sent {
+	i := 0
+	for i < len(history) && now.Sub(history[i].at) >= r.window {
+		i++
+	}
+	return history[i:]
+}
+
+// sweep forgets the users that sent nothing within the window.
+func (r *DuplicateRule) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < sweepInterval {
+		return
+	}
+	r.lastSweep = now
+
+	for key, history := range r.history {
+		if len(r.recent(history, now)) == 0 {
+			delete(r.history, key)
+		}
+	}
+}
+
+// normalizedHash hashes the text ignoring case and whitespace.
+func normalizedHash(text string) uint64 {
+	h := fnv.New64a()
+	var buf [utf8.UTFMax]byte
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		n := utf8.EncodeRune(buf[:], unicode.ToLower(r))
+		h.Write(buf[:n])
+	}
+	return h.Sum64()
+}
@@ -2,8 +2,11 @@ package jwt
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
+	"backend/pkg/rbac"
+
 	"github.com/golang-jwt/jwt/v4"
 )
 
@@ -13,6 +16,27 @@ type TokenManager interface {
 	NewRefreshToken() (string, error)
 }
 
+// Claims are the access token claims shared by all services.
+// Roles and permissions are embedded so services can authorize requests
+// without calling the auth service.
+type Claims struct {
+	jwt.RegisteredClaims
+	// IssuedMicro is the issue time in microseconds since the Unix epoch. iat only has
+	// whole seconds, too coarse to order a token against a revocation in the same second.
+	IssuedMicro int64  `json:"iat_us,omitempty"`
+	Username    string `json:"username,omitempty"`
+	rbac.Access
+}
+
+// UserID returns the user ID stored in the subject.
+func (c *Claims) UserID() (int64, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing user ID from subject")
+	}
+	return id, nil
+}
+
 type Manager struct {
 	signingKey string
 }
@@ -34,6 +58,88 @@ func (m *Manager) NewJWT(userID int, ttl time.Duration) (string, error) {
 	return token.SignedString([]byte(m.signingKey))
 }
 
+// NewAccessToken creates a token carrying the user's name, roles and permissions.
+func (m *Manager) NewAccessToken(userID int, username string, access rbac.Access, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			Subject:   strconv.Itoa(userID),
+		},
+		Username: username,
+		Access:   access,
+	}
+	claims.SetIssuedTime(now)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(m.signingKey))
+}
+
+// ParseClaims verifies the token and returns its claims.
+func (m *Manager) ParseClaims(accessToken string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(m.signingKey), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := claims.UserID(); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// ParseUnverified returns the token claims without verifying the signature. It is meant
+// for tokens already verified elsewhere, e.g. by the auth service.
+func ParseUnverified(accessToken string) (*Claims, error) {
+	claims := &Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(accessToken, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ExpiresTime returns the expiration time, zero if the token does not expire.
+func (c *Claims) ExpiresTime() time.Time {
+	if c.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return c.ExpiresAt.Time
+}
+
+// RevocationCutoff returns the time recorded when the tokens of a user are revoked at t:
+// tokens issued before the cutoff are invalid. The cutoff is truncated to the microsecond,
+// the precision of issue times and of the stored revocations, so a token issued after the
+// revocation is never older than it. Both times must come from the clock of the service
+// issuing the tokens.
+func RevocationCutoff(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+// SetIssuedTime records t as the issue time in iat and, with microsecond precision, in iat_us.
+func (c *Claims) SetIssuedTime(t time.Time) {
+	c.IssuedAt = jwt.NewNumericDate(t)
+	c.IssuedMicro = t.UnixMicro()
+}
+
+// IssuedTime returns the issue time, zero for tokens issued without it. Tokens issued
+// before iat_us was added only have the whole second of iat.
+func (c *Claims) IssuedTime() time.Time {
+	if c.IssuedMicro != 0 {
+		return time.UnixMicro(c.IssuedMicro)
+	}
+	if c.IssuedAt == nil {
+		return time.Time{}
+	}
+	return c.IssuedAt.Time
+}
+
 func (m *Manager) Parse(accessToken string) (int, error) {
 	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
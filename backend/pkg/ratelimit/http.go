@@ -0,0 +1,42 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Standard rate limit response headers (draft-ietf-httpapi-ratelimit-headers).
+const (
+	HeaderLimit     = "RateLimit-Limit"
+	HeaderRemaining = "RateLimit-Remaining"
+	HeaderReset     = "RateLimit-Reset"
+	HeaderPolicy    = "RateLimit-Policy"
+	HeaderRetry     = "Retry-After"
+)
+
+// SetHeaders writes the RateLimit-* headers for result, and Retry-After when it was rejected.
+func SetHeaders(h http.Header, limit Limit, result Result) {
+	if result.Limit == 0 {
+		return
+	}
+
+	h.Set(HeaderLimit, strconv.Itoa(result.Limit))
+	h.Set(HeaderRemaining, strconv.Itoa(result.Remaining))
+	h.Set(HeaderReset, strconv.Itoa(Seconds(result.Reset)))
+	h.Set(HeaderPolicy, strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(Seconds(limit.Period)))
+	if !result.Allowed {
+		h.Set(HeaderRetry, strconv.Itoa(Seconds(result.RetryAfter)))
+	}
+}
+
+// Seconds rounds d up to whole seconds, as required by the rate limit headers.
+func Seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// UserKey builds a key for an authenticated user.
+func UserKey(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
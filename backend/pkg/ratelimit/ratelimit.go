@@ -0,0 +1,216 @@
+// Package ratelimit provides token bucket rate limiting keyed by user or IP,
+// shared by the forum and chat services.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// Limit describes a token bucket: Requests tokens are refilled every Period,
+// and at most Burst tokens can be accumulated.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// IsZero reports whether the limit is unset and therefore unlimited.
+func (l Limit) IsZero() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// rate returns the refill speed in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// String formats the limit in the same form ParseLimit accepts.
+func (l Limit) String() string {
+	if l.Burst > 0 && l.Burst != l.Requests {
+		return fmt.Sprintf("%d/%s/%d", l.Requests, l.Period, l.Burst)
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit parses a limit in the form "requests/period[/burst]", e.g. "10/1m" or "5/10s/20".
+func ParseLimit(s string) (Limit, error) {
+	parts := strings.Split(strings.TrimSpace(s), "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected requests/period[/burst]", s)
+	}
+
+	requests, err := strconv.Atoi(parts[0])
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: bad request count", s)
+	}
+
+	period, err := time.ParseDuration(parts[1])
+	if err != nil || period <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: bad period", s)
+	}
+
+	limit := Limit{Requests: requests, Period: period}
+	if len(parts) == 3 {
+		burst, err := strconv.Atoi(parts[2])
+		if err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q: bad burst", s)
+		}
+		limit.Burst = burst
+	}
+
+	return limit, nil
+}
+
+// Rules maps a rule name (a route or a message type) to its limit.
+type Rules map[string]Limit
+
+// ParseRules parses a comma separated list of "name=limit" pairs,
+// e.g. "posts.create=5/1m,replies.create=20/1m/30".
+func ParseRules(s string) (Rules, error) {
+	rules := make(Rules)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q: expected name=limit", item)
+		}
+
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+		rules[strings.TrimSpace(name)] = limit
+	}
+	return rules, nil
+}
+
+// Result is the outcome of a single Allow call.
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity; zero means the rule is not limited.
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request will be allowed, set only when rejected.
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// Limiter keeps one token bucket per rule and key.
+type Limiter struct {
+	rules     Rules
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// New creates a new limiter with the given rules
+func New(rules Rules) *Limiter {
+	if rules == nil {
+		rules = make(Rules)
+	}
+	return &Limiter{
+		rules:     rules,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Rule returns the limit configured for name.
+func (l *Limiter) Rule(name string) (Limit, bool) {
+	limit, ok := l.rules[name]
+	return limit, ok && !limit.IsZero()
+}
+
+// Allow takes one token from the bucket of key under the named rule.
+// Rules without a configured limit always allow.
+func (l *Limiter) Allow(rule, key string) Result {
+	limit, ok := l.Rule(rule)
+	if !ok {
+		return Result{Allowed: true}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	id := rule + "|" + key
+	b, ok := l.buckets[id]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: limit.capacity(), updated: now, limit: limit}
+		l.buckets[id] = b
+	}
+	b.refill(now)
+
+	result := Result{Limit: int(limit.capacity())}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = durationFor(1-b.tokens, limit.rate())
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = durationFor(limit.capacity()-b.tokens, limit.rate())
+
+	return result
+}
+
+// refill adds the tokens accumulated since the last update.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.limit.capacity(), b.tokens+elapsed*b.limit.rate())
+		b.updated = now
+	}
+}
+
+// sweep drops buckets that have refilled completely, they are equal to new ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for id, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= b.limit.capacity() {
+			delete(l.buckets, id)
+		}
+	}
+}
+
+func durationFor(tokens, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
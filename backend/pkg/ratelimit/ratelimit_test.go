@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLimiter(rules Rules) (*Limiter, *time.Time) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(rules)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("posts.create=5/1m, replies.create=20/1m/30")
+	require.NoError(t, err)
+
+	assert.Equal(t, Limit{Requests: 5, Period: time.Minute}, rules["posts.create"])
+	assert.Equal(t, Limit{Requests: 20, Period: time.Minute, Burst: 30}, rules["replies.create"])
+
+	_, err = ParseRules("posts.create=5")
+	assert.Error(t, err)
+
+	_, err = ParseRules("posts.create")
+	assert.Error(t, err)
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, now := newTestLimiter(Rules{"message": {Requests: 2, Period: time.Second}})
+
+	first := l.Allow("message", "user:1")
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 2, first.Limit)
+	assert.Equal(t, 1, first.Remaining)
+
+	assert.True(t, l.Allow("message", "user:1").Allowed)
+
+	rejected := l.Allow("message", "user:1")
+	assert.False(t, rejected.Allowed)
+	assert.Equal(t, 0, rejected.Remaining)
+	assert.Equal(t, 500*time.Millisecond, rejected.RetryAfter)
+
+	// Other keys have their own bucket
+	assert.True(t, l.Allow("message", "user:2").Allowed)
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.True(t, l.Allow("message", "user:1").Allowed)
+}
+
+func TestLimiter_UnknownRuleIsUnlimited(t *testing.T) {
+	l, _ := newTestLimiter(nil)
+
+	for i := 0; i < 100; i++ {
+		result := l.Allow("posts.create", "ip:127.0.0.1")
+		assert.True(t, result.Allowed)
+		assert.Zero(t, result.Limit)
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	limit := Limit{Requests: 1, Period: time.Minute}
+	l, _ := newTestLimiter(Rules{"posts.create": limit})
+
+	h := http.Header{}
+	SetHeaders(h, limit, l.Allow("posts.create", "ip:127.0.0.1"))
+	assert.Equal(t, "1", h.Get(HeaderLimit))
+	assert.Equal(t, "0", h.Get(HeaderRemaining))
+	assert.Equal(t, "60", h.Get(HeaderReset))
+	assert.Equal(t, "1;w=60", h.Get(HeaderPolicy))
+	assert.Empty(t, h.Get(HeaderRetry))
+
+	h = http.Header{}
+	SetHeaders(h, limit, l.Allow("posts.create", "ip:127.0.0.1"))
+	assert.Equal(t, "60", h.Get(HeaderRetry))
+
+	// Unknown rules are unlimited and get no headers
+	h = http.Header{}
+	SetHeaders(h, Limit{}, l.Allow("posts.edit", "ip:127.0.0.1"))
+	assert.Empty(t, h)
+}
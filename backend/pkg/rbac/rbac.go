@@ -0,0 +1,134 @@
+// Package rbac defines the permissions shared by all forum services and
+// the access set embedded into access tokens.
+package rbac
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Permissions
+const (
+	// All grants every permission, it is held by the admin role.
+	All = "*"
+
+	PostCreate     = "post.create"
+	PostEditAny    = "post.edit.any"
+	PostDeleteAny  = "post.delete.any"
+	ReplyCreate    = "reply.create"
+	ReplyDeleteAny = "reply.delete.any"
+
+	ChatSend      = "chat.send"
+	ChatMute      = "chat.mute"
+	ChatKick      = "chat.kick"
+	ChatBan       = "chat.ban"
+	ChatDeleteAny = "chat.message.delete.any"
+	ChatEditAny   = "chat.message.edit.any"
+	ChatSlowMode  = "chat.slowmode"
+
+	ChatChannelManage = "chat.channel.manage"
+
+	UserView   = "user.view"
+	UserBlock  = "user.block"
+	UserDelete = "user.delete"
+	UserUnlock = "user.unlock"
+
+	UserPasswordReset = "user.password.reset"
+
+	SessionTerminate = "session.terminate"
+	AuditView        = "audit.view"
+
+	RoleManage = "role.manage"
+	RoleAssign = "role.assign"
+
+	StatsView = "stats.view"
+)
+
+// Built-in role names
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var known = map[string]bool{
+	All:               true,
+	PostCreate:        true,
+	PostEditAny:       true,
+	PostDeleteAny:     true,
+	ReplyCreate:       true,
+	ReplyDeleteAny:    true,
+	ChatSend:          true,
+	ChatMute:          true,
+	ChatKick:          true,
+	ChatBan:           true,
+	ChatDeleteAny:     true,
+	ChatEditAny:       true,
+	ChatSlowMode:      true,
+	ChatChannelManage: true,
+	UserView:          true,
+	UserBlock:         true,
+	UserDelete:        true,
+	UserUnlock:        true,
+	UserPasswordReset: true,
+	SessionTerminate:  true,
+	AuditView:         true,
+	RoleManage:        true,
+	RoleAssign:        true,
+	StatsView:         true,
+}
+
+// IsKnown reports whether p is a defined permission.
+func IsKnown(p string) bool {
+	return known[p]
+}
+
+// Known returns all defined permissions in alphabetical order.
+func Known() []string {
+	perms := make([]string, 0, len(known))
+	for p := range known {
+		perms = append(perms, p)
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// Access is the set of roles and permissions of a user. Scoped holds
+// permissions granted only inside a forum category, keyed by category ID.
+type Access struct {
+	Roles       []string            `json:"roles,omitempty"`
+	Permissions []string            `json:"permissions,omitempty"`
+	Scoped      map[string][]string `json:"scoped_permissions,omitempty"`
+}
+
+// Has reports whether the access grants p globally.
+func (a Access) Has(p string) bool {
+	return contains(a.Permissions, p)
+}
+
+// HasIn reports whether the access grants p globally or inside the category.
+func (a Access) HasIn(p string, categoryID int64) bool {
+	if a.Has(p) {
+		return true
+	}
+	return contains(a.Scoped[strconv.FormatInt(categoryID, 10)], p)
+}
+
+// HasRole reports whether the access includes the named role.
+func (a Access) HasRole(role string) bool {
+	for _, r := range a.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(perms []string, p string) bool {
+	for _, perm := range perms {
+		if perm == p || perm == All {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccess_Has(t *testing.T) {
+	moderator := Access{
+		Roles:       []string{RoleUser, RoleModerator},
+		Permissions: []string{PostCreate, PostDeleteAny},
+		Scoped:      map[string][]string{"7": {ChatMute}},
+	}
+
+	assert.True(t, moderator.Has(PostDeleteAny))
+	assert.False(t, moderator.Has(UserDelete))
+	assert.False(t, moderator.Has(ChatMute))
+	assert.True(t, moderator.HasIn(ChatMute, 7))
+	assert.False(t, moderator.HasIn(ChatMute, 8))
+	assert.True(t, moderator.HasIn(PostCreate, 8))
+	assert.True(t, moderator.HasRole(RoleModerator))
+
+	admin := Access{Roles: []string{RoleAdmin}, Permissions: []string{All}}
+	assert.True(t, admin.Has(UserDelete))
+	assert.True(t, admin.HasIn(ChatBan, 42))
+
+	assert.False(t, Access{}.Has(PostCreate))
+}
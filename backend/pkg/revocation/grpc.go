@@ -0,0 +1,41 @@
+package revocation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"backend/pkg/authpb"
+)
+
+// GRPCSource lists revocations with the ListRevocations call of the auth-service gRPC API
+type GRPCSource struct {
+	client authpb.AuthServiceClient
+}
+
+// NewGRPCSource creates a source over the connection conn to auth-service
+func NewGRPCSource(conn grpc.ClientConnInterface) *GRPCSource {
+	return &GRPCSource{client: authpb.NewAuthServiceClient(conn)}
+}
+
+// Revocations returns the revocations after since and the auth-service time of the answer
+func (s *GRPCSource) Revocations(ctx context.Context, since time.Time) ([]Revocation, time.Time, error) {
+	req := &authpb.ListRevocationsRequest{}
+	if !since.IsZero() {
+		req.Since = timestamppb.New(since)
+	}
+
+	resp, err := s.client.ListRevocations(ctx, req)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to list token revocations: %w", err)
+	}
+
+	revocations := make([]Revocation, 0, len(resp.GetRevocations()))
+	for _, r := range resp.GetRevocations() {
+		revocations = append(revocations, Revocation{UserID: r.GetUserId(), RevokedAt: r.GetRevokedAt().AsTime()})
+	}
+	return revocations, resp.GetNow().AsTime(), nil
+}
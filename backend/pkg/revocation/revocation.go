@@ -0,0 +1,116 @@
+// Package revocation keeps a local copy of the token revocations of auth-service, so that
+// the forum and chat services can reject the tokens of ended sessions without asking
+// auth-service about every token.
+package revocation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"backend/pkg/jwt"
+)
+
+const (
+	// Retention is how long a revocation is remembered: access tokens of auth-service live this long
+	Retention = 24 * time.Hour
+	// overlap makes the next poll reach a bit into the past: revocations written while the
+	// previous poll ran may be stamped earlier than its response
+	overlap = 5 * time.Second
+)
+
+// Revocation invalidates the tokens of the user issued before RevokedAt
+type Revocation struct {
+	UserID    int64
+	RevokedAt time.Time
+}
+
+// Source lists the revocations after since along with its own clock at the time of the answer
+type Source interface {
+	Revocations(ctx context.Context, since time.Time) ([]Revocation, time.Time, error)
+}
+
+// List holds the latest revocation of each user
+type List struct {
+	mu     sync.RWMutex
+	byUser map[int64]time.Time
+}
+
+// NewList creates an empty list
+func NewList() *List {
+	return &List{byUser: make(map[int64]time.Time)}
+}
+
+// Add records r unless a later revocation of the user is already known
+func (l *List) Add(r Revocation) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if r.RevokedAt.After(l.byUser[r.UserID]) {
+		l.byUser[r.UserID] = r.RevokedAt
+	}
+}
+
+// Revoked reports whether the token of the user issued at issuedAt is revoked. auth-service
+// records a revocation before it signs new tokens and tokens carry their issue time to the
+// microsecond, so a login right after a logout is accepted while a token issued before the
+// logout in the same second is not.
+func (l *List) Revoked(userID int64, issuedAt time.Time) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	revokedAt, ok := l.byUser[userID]
+	return ok && issuedAt.Before(jwt.RevocationCutoff(revokedAt))
+}
+
+// Prune forgets the revocations before before: the tokens they revoke have expired
+func (l *List) Prune(before time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for userID, revokedAt := range l.byUser {
+		if revokedAt.Before(before) {
+			delete(l.byUser, userID)
+		}
+	}
+}
+
+// Poll loads the revocations of src after since and returns since for the next poll.
+// On error the list and since are left as they were.
+func (l *List) Poll(ctx context.Context, src Source, since time.Time) (time.Time, error) {
+	revocations, now, err := src.Revocations(ctx, since)
+	if err != nil {
+		return since, err
+	}
+
+	for _, r := range revocations {
+		l.Add(r)
+	}
+	l.Prune(now.Add(-Retention))
+	return now.Add(-overlap), nil
+}
+
+// Run polls src every interval until ctx is canceled, each poll is limited by timeout.
+// Failed polls are reported to onError, meanwhile the last loaded list stays in effect.
+func (l *List) Run(ctx context.Context, src Source, interval, timeout time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var since time.Time
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, timeout)
+		var err error
+		since, err = l.Poll(pollCtx, src, since)
+		cancel()
+		if err != nil && !errors.Is(err, context.Canceled) && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSource struct {
+	revocations []Revocation
+	now         time.Time
+	err         error
+	since       []time.Time
+}
+
+func (s *fakeSource) Revocations(ctx context.Context, since time.Time) ([]Revocation, time.Time, error) {
+	s.since = append(s.since, since)
+	return s.revocations, s.now, s.err
+}
+
+func TestList_Revoked(t *testing.T) {
+	l := NewList()
+	logout := time.Date(2024, 5, 1, 12, 0, 0, 500_000_000, time.UTC)
+	l.Add(Revocation{UserID: 7, RevokedAt: logout})
+	// An earlier revocation does not move the cutoff back
+	l.Add(Revocation{UserID: 7, RevokedAt: logout.Add(-time.Hour)})
+
+	assert.True(t, l.Revoked(7, logout.Add(-time.Microsecond)))
+	assert.True(t, l.Revoked(7, logout.Add(-time.Hour/2)))
+	assert.False(t, l.Revoked(7, logout))
+	assert.False(t, l.Revoked(8, logout.Add(-time.Hour)))
+
+	l.Prune(logout.Add(time.Second))
+	assert.False(t, l.Revoked(7, logout.Add(-time.Hour/2)))
+}
+
+func TestList_Poll(t *testing.T) {
+	l := NewList()
+	now := time.Now()
+	src := &fakeSource{
+		revocations: []Revocation{
+			{UserID: 1, RevokedAt: now.Add(-time.Minute)},
+			{UserID: 2, RevokedAt: now.Add(-2 * Retention)},
+		},
+		now: now,
+	}
+
+	since, err := l.Poll(context.Background(), src, time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-overlap), since)
+	assert.True(t, l.Revoked(1, now.Add(-2*time.Minute)))
+	// Revocations older than the token lifetime are dropped
+	assert.False(t, l.Revoked(2, now.Add(-3*Retention)))
+
+	// A failed poll keeps the list and asks for the same revocations again
+	src.err = errors.New("unavailable")
+	next, err := l.Poll(context.Background(), src, since)
+	assert.Error(t, err)
+	assert.Equal(t, since, next)
+	assert.True(t, l.Revoked(1, now.Add(-2*time.Minute)))
+	assert.Equal(t, []time.Time{{}, since}, src.since)
+}
+
+func TestList_Run(t *testing.T) {
+	l := NewList()
+	src := &fakeSource{err: errors.New("unavailable")}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	errs := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		l.Run(ctx, src, time.Hour, time.Second, func(err error) { errs <- err })
+		close(done)
+	}()
+
+	// The first poll runs right away, errors are reported
+	select {
+	case err := <-errs:
+		assert.EqualError(t, err, "unavailable")
+	case <-time.After(time.Second):
+		t.Fatal("revocations were not polled")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop")
+	}
+}
@@ -0,0 +1,76 @@
+package unfurl
+
+import (
+	"net"
+	"syscall"
+)
+
+// blockedNetworks are private, loopback, link-local and other reserved ranges
+// that must not be reachable through link previews.
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.88.99.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"100::/64",
+	"2001::/23",
+	"2001:db8::/32",
+	"2002::/16",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isPublicIP reports that the address is outside of all blocked ranges.
+// IPv4-mapped IPv6 addresses are checked as IPv4.
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// control runs before every connection with the already resolved address, so it
+// also covers redirects and hosts that resolve to different addresses over time.
+func (u *Unfurler) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !u.allowIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Preview fields are cut to these lengths in runes
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 500
+	maxSiteNameLength    = 100
+)
+
+// pageMeta is the metadata found in the head of a page
+type pageMeta struct {
+	title   string
+	props   map[string]string
+	oembed  string
+	baseURL *url.URL
+}
+
+// parseHTML reads metadata until the body starts: previews only use the head,
+// and large pages do not have to be read to the end.
+func parseHTML(r io.Reader, baseURL *url.URL) *pageMeta {
+	meta := &pageMeta{props: make(map[string]string), baseURL: baseURL}
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return meta
+		case html.TextToken:
+			if inTitle && meta.title == "" {
+				meta.title = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Title:
+				inTitle = false
+			case atom.Head:
+				return meta
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				return meta
+			case atom.Title:
+				inTitle = true
+			case atom.Meta:
+				if hasAttr {
+					meta.readMeta(attributes(z))
+				}
+			case atom.Link:
+				if hasAttr {
+					meta.readLink(attributes(z))
+				}
+			}
+		}
+	}
+}
+
+func attributes(z *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, value, more := z.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+// readMeta keeps OpenGraph (property="og:...") and Twitter card (name="twitter:...") tags
+// and the plain description
+func (m *pageMeta) readMeta(attrs map[string]string) {
+	key := attrs["property"]
+	if key == "" {
+		key = attrs["name"]
+	}
+	key = strings.ToLower(strings.TrimSpace(key))
+	if !strings.HasPrefix(key, "og:") && !strings.HasPrefix(key, "twitter:") && key != "description" {
+		return
+	}
+	if _, ok := m.props[key]; !ok {
+		m.props[key] = strings.TrimSpace(attrs["content"])
+	}
+}
+
+func (m *pageMeta) readLink(attrs map[string]string) {
+	if m.oembed != "" || !strings.EqualFold(attrs["rel"], "alternate") ||
+		!strings.EqualFold(attrs["type"], "application/json+oembed") {
+		return
+	}
+	m.oembed = m.resolve(attrs["href"])
+}
+
+func (m *pageMeta) first(keys ...string) string {
+	for _, key := range keys {
+		if value := m.props[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func (m *pageMeta) preview(target string) *Preview {
+	title := m.first("og:title", "twitter:title")
+	if title == "" {
+		title = m.title
+	}
+	return &Preview{
+		URL:         target,
+		Title:       truncate(title, maxTitleLength),
+		Description: truncate(m.first("og:description", "twitter:description", "description"), maxDescriptionLength),
+		Image:       m.resolve(m.first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src")),
+		SiteName:    truncate(m.first("og:site_name"), maxSiteNameLength),
+	}
+}
+
+// resolve makes a link absolute. Links that are not http or https are dropped.
+func (m *pageMeta) resolve(ref string) string {
+	return resolveURL(m.baseURL, ref)
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	if checkURL(parsed) != nil {
+		return ""
+	}
+	return parsed.String()
+}
+
+// oEmbed is the part of an oEmbed response used in previews
+type oEmbed struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	URL          string `json:"url"`
+}
+
+func (u *Unfurler) fetchOEmbed(ctx context.Context, endpoint string) (*oEmbed, error) {
+	resp, err := u.get(ctx, endpoint, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var embed oEmbed
+	if err := json.NewDecoder(io.LimitReader(resp.Body, u.opts.MaxBodySize)).Decode(&embed); err != nil {
+		return nil, err
+	}
+	return &embed, nil
+}
+
+// fill completes the fields the page itself did not provide
+func (e *oEmbed) fill(p *Preview, base *url.URL) {
+	if p.Title == "" {
+		p.Title = truncate(e.Title, maxTitleLength)
+	}
+	if p.Description == "" && e.AuthorName != "" {
+		p.Description = truncate(e.AuthorName, maxDescriptionLength)
+	}
+	if p.SiteName == "" {
+		p.SiteName = truncate(e.ProviderName, maxSiteNameLength)
+	}
+	if p.Image == "" {
+		p.Image = resolveURL(base, e.ThumbnailURL)
+	}
+	if p.Image == "" && e.Type == "photo" {
+		p.Image = resolveURL(base, e.URL)
+	}
+}
+
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
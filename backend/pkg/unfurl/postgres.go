@@ -0,0 +1,61 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+)
+
+// DB is the part of *pgxpool.Pool used by PostgresCache.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// PostgresCache keeps previews in the link_previews table, created by the
+// migrations of each service that uses it.
+type PostgresCache struct {
+	db DB
+}
+
+// NewPostgresCache creates a cache on top of the pool.
+func NewPostgresCache(db DB) *PostgresCache {
+	return &PostgresCache{db: db}
+}
+
+// Get returns the preview if it has not expired.
+func (c *PostgresCache) Get(ctx context.Context, url string) (*Preview, error) {
+	p := &Preview{URL: url}
+	err := c.db.QueryRow(ctx, `
+		SELECT title, description, image, site_name
+		FROM link_previews
+		WHERE url = $1 AND expires_at > NOW()
+	`, url).Scan(&p.Title, &p.Description, &p.Image, &p.SiteName)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Put stores the preview for ttl, replacing an older one.
+func (c *PostgresCache) Put(ctx context.Context, p *Preview, ttl time.Duration) error {
+	now := time.Now()
+	_, err := c.db.Exec(ctx, `
+		INSERT INTO link_previews (url, title, description, image, site_name, fetched_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (url) DO UPDATE SET
+			title = EXCLUDED.title,
+			description = EXCLUDED.description,
+			image = EXCLUDED.image,
+			site_name = EXCLUDED.site_name,
+			fetched_at = EXCLUDED.fetched_at,
+			expires_at = EXCLUDED.expires_at
+	`, p.URL, p.Title, p.Description, p.Image, p.SiteName, now, now.Add(ttl))
+	return err
+}
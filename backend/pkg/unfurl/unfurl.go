@@ -0,0 +1,292 @@
+// Package unfurl builds link previews from OpenGraph, Twitter card and oEmbed
+// metadata of pages linked in chat messages and forum posts.
+//
+// Pages are fetched with strict timeouts and size limits, and only from public
+// addresses: every connection, including redirects, is checked after DNS
+// resolution, so a host cannot point the service at its own network.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidURL is returned for URLs that are not absolute http or https URLs.
+	ErrInvalidURL = errors.New("unfurl: only absolute http and https URLs are supported")
+	// ErrBlockedAddress is returned when a host resolves to a private or reserved address.
+	ErrBlockedAddress = errors.New("unfurl: address is not allowed")
+	// ErrNoPreview is returned when the page has no metadata for a preview.
+	ErrNoPreview = errors.New("unfurl: page has no preview metadata")
+)
+
+// Preview is the card shown under a message or a post.
+type Preview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// Empty reports that the preview has nothing to show. Failed fetches are cached as empty previews.
+func (p *Preview) Empty() bool {
+	return p.Title == "" && p.Description == "" && p.Image == ""
+}
+
+// Cache stores previews by URL. Get returns nil without an error when there is
+// no fresh entry.
+type Cache interface {
+	Get(ctx context.Context, url string) (*Preview, error)
+	Put(ctx context.Context, preview *Preview, ttl time.Duration) error
+}
+
+// Options limits what the unfurler fetches. Zero values are replaced with defaults.
+type Options struct {
+	// Timeout bounds a whole unfurl, including redirects and the oEmbed request.
+	Timeout time.Duration
+	// MaxBodySize is the number of bytes read from a page or an oEmbed response.
+	MaxBodySize int64
+	// MaxRedirects is the number of redirects followed.
+	MaxRedirects int
+	UserAgent    string
+	// CacheTTL keeps previews, FailureTTL keeps failed fetches so that they are not retried on every link.
+	CacheTTL   time.Duration
+	FailureTTL time.Duration
+}
+
+// DefaultOptions returns the limits used for zero Options fields.
+func DefaultOptions() Options {
+	return Options{
+		Timeout:      5 * time.Second,
+		MaxBodySize:  1 << 20,
+		MaxRedirects: 3,
+		UserAgent:    "ForumLinkPreview/1.0",
+		CacheTTL:     24 * time.Hour,
+		FailureTTL:   time.Hour,
+	}
+}
+
+// Unfurler fetches and caches previews. It is safe for concurrent use.
+type Unfurler struct {
+	client *http.Client
+	cache  Cache
+	opts   Options
+	// allowIP decides which addresses can be dialed, tests replace it to reach httptest servers
+	allowIP func(net.IP) bool
+}
+
+// New creates an unfurler. A nil cache disables caching.
+func New(cache Cache, opts Options) *Unfurler {
+	defaults := DefaultOptions()
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaults.MaxBodySize
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = defaults.MaxRedirects
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = defaults.UserAgent
+	}
+	if opts.CacheTTL <= 0 {
+		opts.CacheTTL = defaults.CacheTTL
+	}
+	if opts.FailureTTL <= 0 {
+		opts.FailureTTL = defaults.FailureTTL
+	}
+
+	u := &Unfurler{cache: cache, opts: opts, allowIP: isPublicIP}
+
+	dialer := &net.Dialer{Timeout: opts.Timeout, Control: u.control}
+	u.client = &http.Client{
+		Transport: &http.Transport{
+			// A proxy would connect on our behalf and bypass the address check
+			Proxy:                  nil,
+			DialContext:            dialer.DialContext,
+			TLSHandshakeTimeout:    opts.Timeout,
+			ResponseHeaderTimeout:  opts.Timeout,
+			MaxResponseHeaderBytes: 64 << 10,
+			MaxIdleConns:           16,
+			IdleConnTimeout:        30 * time.Second,
+		},
+		Timeout: opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > opts.MaxRedirects {
+				return fmt.Errorf("unfurl: stopped after %d redirects", opts.MaxRedirects)
+			}
+			return checkURL(req.URL)
+		},
+	}
+	return u
+}
+
+// Unfurl returns the preview of the page at rawURL, from the cache when possible.
+// Pages without metadata return ErrNoPreview.
+func (u *Unfurler) Unfurl(ctx context.Context, rawURL string) (*Preview, error) {
+	target, err := normalizeURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.cache != nil {
+		cached, err := u.cache.Get(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		if cached != nil {
+			if cached.Empty() {
+				return nil, ErrNoPreview
+			}
+			return cached, nil
+		}
+	}
+
+	preview, err := u.fetch(ctx, target)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up, the page itself may be fine
+		return nil, err
+	}
+
+	if u.cache != nil {
+		entry, ttl := preview, u.opts.CacheTTL
+		if err != nil {
+			entry, ttl = &Preview{URL: target}, u.opts.FailureTTL
+		}
+		if cacheErr := u.cache.Put(ctx, entry, ttl); cacheErr != nil && err == nil {
+			err = cacheErr
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return preview, nil
+}
+
+func (u *Unfurler) fetch(ctx context.Context, target string) (*Preview, error) {
+	ctx, cancel := context.WithTimeout(ctx, u.opts.Timeout)
+	defer cancel()
+
+	resp, err := u.get(ctx, target, "text/html,application/xhtml+xml;q=0.9,*/*;q=0.5")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	final := resp.Request.URL
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return &Preview{URL: target, Image: final.String()}, nil
+	case mediaType != "text/html" && mediaType != "application/xhtml+xml":
+		return nil, ErrNoPreview
+	}
+
+	meta := parseHTML(io.LimitReader(resp.Body, u.opts.MaxBodySize), final)
+	preview := meta.preview(target)
+
+	if meta.oembed != "" && (preview.Title == "" || preview.Image == "") {
+		if embed, err := u.fetchOEmbed(ctx, meta.oembed); err == nil {
+			embed.fill(preview, final)
+		}
+	}
+
+	if preview.Empty() {
+		return nil, ErrNoPreview
+	}
+	return preview, nil
+}
+
+// get requests the URL and returns a response with status 200
+func (u *Unfurler) get(ctx context.Context, target, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	req.Header.Set("User-Agent", u.opts.UserAgent)
+	req.Header.Set("Accept", accept)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unfurl: %s returned status %d", target, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// normalizeURL checks the URL and drops the fragment, which the server never sees,
+// so that links to different anchors of a page share a cache entry
+func normalizeURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	if err := checkURL(parsed); err != nil {
+		return "", err
+	}
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+	return parsed.String(), nil
+}
+
+func checkURL(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+var urlPattern = regexp.MustCompile(`(?i)\bhttps?://[^\s<>"']+`)
+
+// ExtractURLs returns up to max distinct http and https URLs in the text, in order.
+// Trailing punctuation is not considered a part of a URL.
+func ExtractURLs(text string, max int) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, found := range urlPattern.FindAllString(text, -1) {
+		if len(urls) >= max {
+			break
+		}
+		found = trimTrailing(found)
+		if seen[found] {
+			continue
+		}
+		if _, err := normalizeURL(found); err != nil {
+			continue
+		}
+		seen[found] = true
+		urls = append(urls, found)
+	}
+	return urls
+}
+
+// trimTrailing drops punctuation that ends a sentence and closing brackets without a pair in the URL
+func trimTrailing(s string) string {
+	for len(s) > 0 {
+		last := s[len(s)-1]
+		switch {
+		case strings.IndexByte(".,;:!?'\"", last) >= 0:
+			s = s[:len(s)-1]
+		case last == ')' && strings.Count(s, "(") < strings.Count(s, ")"),
+			last == ']' && strings.Count(s, "[") < strings.Count(s, "]"):
+			s = s[:len(s)-1]
+		default:
+			return s
+		}
+	}
+	return s
+}
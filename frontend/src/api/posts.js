@@ -3,6 +3,12 @@ import { enrichWithUserInfo } from './users';
 
 const API_URL = process.env.REACT_APP_FORUM_URL || 'http://localhost:8082';
 
+// Заголовок с токеном доступа: автор поста определяется сервером по токену
+export const authHeaders = () => {
+  const token = localStorage.getItem('token');
+  return token ? { Authorization: `Bearer ${token}` } : {};
+};
+
 // Создаем переменную для хранения таймера дебаунсинга
 let debounceTimer;
 
@@ -66,6 +72,7 @@ export const fetchPosts = async () => {
 export const createPost = async (postData) => {
   try {
     const response = await axios.post(`${API_URL}/posts`, postData, {
+      headers: authHeaders(),
       withCredentials: true,
     });
     console.log('Create post response:', response);
@@ -79,6 +86,7 @@ export const createPost = async (postData) => {
 export const deletePost = async (postId) => {
   try {
     const response = await axios.delete(`${API_URL}/posts/${postId}`, {
+      headers: authHeaders(),
       withCredentials: true,
     });
     console.log('Delete post response:', response);
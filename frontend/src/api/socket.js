@@ -1,7 +1,31 @@
+import { refreshTokens } from './auth';
+import { setAuthToken, setRefreshToken } from '../utils/auth';
+
 let socket = null;
 let reconnectAttempts = 0;
+let renewing = null;
 const MAX_RECONNECT_ATTEMPTS = 5;
 const RECONNECT_DELAY = 2000;
+const TOKEN_EXPIRED = 4402;
+
+// Получает новый access токен по refresh токену. Прежний токен отправлять бесполезно:
+// сервер продлевает авторизацию только токеном с более поздним сроком. Параллельные
+// вызовы ждут один запрос, потому что refresh токен одноразовый
+const renewToken = () => {
+  if (!renewing) {
+    renewing = refreshTokens()
+      .then((response) => {
+        const { access_token, refresh_token } = response.data;
+        setAuthToken(access_token);
+        setRefreshToken(refresh_token);
+        return access_token;
+      })
+      .finally(() => {
+        renewing = null;
+      });
+  }
+  return renewing;
+};
 
 export const connectSocket = (token) => {
   return new Promise((resolve, reject) => {
@@ -12,13 +36,17 @@ export const connectSocket = (token) => {
         return;
       }
 
-      const wsUrl = `ws://localhost:8083/api/chat/ws?token=${token}`;
+      // Токен передается первым сообщением, а не в URL, чтобы не попадать в журналы прокси
+      const wsUrl = 'ws://localhost:8083/api/chat/ws';
       console.log('Attempting to connect to WebSocket:', { url: wsUrl });
 
       socket = new WebSocket(wsUrl);
 
       socket.onopen = () => {
         console.log('WebSocket Connected', { readyState: socket.readyState });
+        if (token) {
+          socket.send(JSON.stringify({ type: 'auth', token }));
+        }
         reconnectAttempts = 0;
         resolve(socket);
       };
@@ -31,6 +59,14 @@ export const connectSocket = (token) => {
           readyState: socket?.readyState 
         });
 
+        // Токен истек: переподключаемся с новым, без него нужен повторный вход
+        if (event.code === TOKEN_EXPIRED) {
+          renewToken()
+            .then((fresh) => connectSocket(fresh))
+            .then(resolve, reject);
+          return;
+        }
+
         // Пытаемся переподключиться только если это не было чистое закрытие
         if (!event.wasClean && reconnectAttempts < MAX_RECONNECT_ATTEMPTS) {
           console.log(`Attempting to reconnect (${reconnectAttempts + 1}/${MAX_RECONNECT_ATTEMPTS})`);
@@ -60,6 +96,17 @@ export const connectSocket = (token) => {
           if (message.type === 'auth_success') {
             console.log('Authentication successful');
           }
+
+          // Токен скоро истечет: продлеваем авторизацию новым токеном без переподключения
+          if (message.type === 'reauth') {
+            renewToken()
+              .then((fresh) => {
+                if (socket && socket.readyState === WebSocket.OPEN) {
+                  socket.send(JSON.stringify({ type: 'auth', token: fresh }));
+                }
+              })
+              .catch((err) => console.error('Failed to refresh token:', err));
+          }
           
           // Добавляем обработчик для пинг-сообщений
           if (message.type === 'ping') {
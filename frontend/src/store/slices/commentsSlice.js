@@ -1,5 +1,6 @@
 import { createSlice, createAsyncThunk } from '@reduxjs/toolkit';
 import axios from 'axios';
+import { authHeaders } from '../../api/posts';
 import { enrichWithUserInfo } from '../../api/users';
 
 const API_URL = process.env.REACT_APP_FORUM_URL || 'http://localhost:8082';
@@ -41,12 +42,12 @@ export const fetchComments = createAsyncThunk(
 
 export const addComment = createAsyncThunk(
   'comments/addComment',
-  async ({ postId, content, userId }, { rejectWithValue, dispatch }) => {
+  async ({ postId, content }, { rejectWithValue, dispatch }) => {
     try {
       const response = await axios.post(`${API_URL}/posts/${postId}/replies`, {
         content,
-        author_id: userId,
       }, {
+        headers: authHeaders(),
         withCredentials: true,
       });
       console.log('Added comment:', response.data);
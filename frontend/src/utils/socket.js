@@ -1,9 +1,39 @@
 import store from '../store/index';
 import { setConnected, setConnecting } from '../store/chatSlice';
+import { refresh } from '../store/authSlice';
 
 let socket = null;
 let messageCallback;
 let reconnectAttempts = 0;
+let renewing = null;
+
+const AUTH_FAILED = 4401;
+const TOKEN_EXPIRED = 4402;
+
+// Получает новый access токен по refresh токену. Прежний токен отправлять бесполезно:
+// сервер продлевает авторизацию только токеном с более поздним сроком. Параллельные
+// вызовы ждут один запрос, потому что refresh токен одноразовый
+const renewToken = () => {
+  if (!renewing) {
+    renewing = store.dispatch(refresh()).unwrap()
+      .then((data) => data.access_token)
+      .finally(() => {
+        renewing = null;
+      });
+  }
+  return renewing;
+};
+
+const reconnect = (token, onMessage) => {
+  if (reconnectAttempts < 5) {
+    const delay = Math.min(1000 * Math.pow(2, reconnectAttempts), 30000);
+    reconnectAttempts++;
+
+    setTimeout(() => {
+      connectSocket(token, onMessage);
+    }, delay);
+  }
+};
 
 export const connectSocket = (token, onMessage) => {
   if (socket && socket.readyState === WebSocket.OPEN) {
@@ -13,7 +43,8 @@ export const connectSocket = (token, onMessage) => {
 
   store.dispatch(setConnecting(true));
   
-  const wsUrl = `${process.env.REACT_APP_CHAT_WS_URL}?token=${token}`;
+  // Токен передается первым сообщением, а не в URL, чтобы не попадать в журналы прокси
+  const wsUrl = process.env.REACT_APP_CHAT_WS_URL;
   console.log('Attempting to connect to WebSocket:', wsUrl);
   
   socket = new WebSocket(wsUrl);
@@ -21,23 +52,30 @@ export const connectSocket = (token, onMessage) => {
   
   socket.onopen = () => {
     console.log('WebSocket Connected');
+    if (token) {
+      socket.send(JSON.stringify({ type: 'auth', token }));
+    }
     reconnectAttempts = 0;
     store.dispatch(setConnected(true));
     store.dispatch(setConnecting(false));
   };
 
-  socket.onclose = () => {
+  socket.onclose = (event) => {
     console.log('WebSocket Disconnected');
     store.dispatch(setConnected(false));
-    
-    if (reconnectAttempts < 5) {
-      const delay = Math.min(1000 * Math.pow(2, reconnectAttempts), 30000);
-      reconnectAttempts++;
-      
-      setTimeout(() => {
-        connectSocket(token, onMessage);
-      }, delay);
+
+    // 4401 — токен недействителен, переподключение с ним не поможет
+    if (event.code === AUTH_FAILED) {
+      return;
+    }
+    // 4402 — токен истек, подключаемся с новым. Без него нужен повторный вход
+    if (event.code === TOKEN_EXPIRED) {
+      renewToken()
+        .then((fresh) => reconnect(fresh, onMessage))
+        .catch((err) => console.error('Failed to refresh token:', err));
+      return;
     }
+    reconnect(token, onMessage);
   };
 
   socket.onerror = (error) => {
@@ -47,6 +85,16 @@ export const connectSocket = (token, onMessage) => {
   socket.onmessage = (event) => {
     try {
       const message = JSON.parse(event.data);
+      // Токен скоро истечет: продлеваем авторизацию новым токеном без переподключения
+      if (message.type === 'reauth') {
+        renewToken()
+          .then((fresh) => {
+            if (socket && socket.readyState === WebSocket.OPEN) {
+              socket.send(JSON.stringify({ type: 'auth', token: fresh }));
+            }
+          })
+          .catch((err) => console.error('Failed to refresh token:', err));
+      }
       if (messageCallback) {
         messageCallback(message);
       }